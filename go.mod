@@ -7,7 +7,7 @@ require github.com/piquette/finance-go v1.0.0
 require (
 	github.com/gdamore/tcell v1.4.0
 	github.com/rivo/tview v0.0.0-20200915114512-42866ecf6ca6
-	github.com/shopspring/decimal v1.2.0 // indirect
+	github.com/shopspring/decimal v1.2.0
 	github.com/spf13/cobra v1.1.1
 	github.com/stretchr/testify v1.6.1 // indirect
 	golang.org/x/sys v0.0.0-20200922070232-aee5d888a860 // indirect
@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/cimomo/portfolio-go/pkg/portfolio"
+	"github.com/spf13/cobra"
+)
+
+var (
+	exportFormat string
+	exportOutput string
+)
+
+func newExportCmd() *cobra.Command {
+	exportCmd := &cobra.Command{
+		Use:   "export",
+		Short: "Export the profile and its transactions for plain-text accounting",
+		Long: `Export writes commodity declarations, accounts, lot-annotated postings, the latest prices
+and balance assertions of the profile in beancount or ledger-cli syntax.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			exportProfile(profile)
+		},
+	}
+
+	exportCmd.PersistentFlags().StringVar(&profile, "profile", "./examples/profile.yml", "profile for portfolio")
+	exportCmd.PersistentFlags().StringVar(&exportFormat, "format", portfolio.FormatBeancount, "export format: beancount or ledger")
+	exportCmd.PersistentFlags().StringVar(&exportOutput, "output", "", "output file (defaults to standard output)")
+
+	return exportCmd
+}
+
+func exportProfile(profileFile string) error {
+	p := portfolio.NewProfile("Main")
+
+	err := p.Load(profileFile)
+	if err != nil {
+		fmt.Println(err)
+		return err
+	}
+
+	err = p.Refresh()
+	if err != nil {
+		fmt.Println(err)
+		return err
+	}
+
+	// The output file is only replaced once the export has been verified
+	var buffer bytes.Buffer
+	err = p.Export(&buffer, exportFormat, time.Now())
+	if err != nil {
+		fmt.Println(err)
+		return err
+	}
+
+	if exportOutput == "" {
+		_, err = buffer.WriteTo(os.Stdout)
+	} else {
+		err = ioutil.WriteFile(exportOutput, buffer.Bytes(), 0644)
+	}
+	if err != nil {
+		fmt.Println(err)
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/cimomo/portfolio-go/pkg/portfolio"
+	"github.com/spf13/cobra"
+)
+
+var (
+	importFormat    string
+	importMapping   string
+	importPortfolio string
+	importAccount   string
+)
+
+func newImportCmd() *cobra.Command {
+	importCmd := &cobra.Command{
+		Use:   "import [file]",
+		Short: "Import positions and transactions from a broker export into the profile",
+		Long: `Import parses a positions or transaction history export, or an OFX/QFX investment
+statement, and merges it into a portfolio of the profile, creating the profile and the
+portfolio if they do not exist yet. Importing the same file again leaves the profile unchanged.`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			importStatement(profile, args[0])
+		},
+	}
+
+	importCmd.PersistentFlags().StringVar(&profile, "profile", "./examples/profile.yml", "profile for portfolio")
+	importCmd.PersistentFlags().StringVar(&importFormat, "format", portfolio.FormatGeneric, "export format: fidelity, schwab, vanguard, generic or ofx")
+	importCmd.PersistentFlags().StringVar(&importMapping, "mapping", "", "YAML column mapping for brokers without a built-in format")
+	importCmd.PersistentFlags().StringVar(&importPortfolio, "portfolio", "", "portfolio to import into (defaults to the broker name)")
+	importCmd.PersistentFlags().StringVar(&importAccount, "account", "", "only import rows of this account")
+
+	return importCmd
+}
+
+func importStatement(profileFile string, file string) error {
+	statement, name, err := parseStatement(file)
+	if err != nil {
+		fmt.Println(err)
+		return err
+	}
+
+	if importPortfolio != "" {
+		name = importPortfolio
+	}
+
+	p := portfolio.NewProfile("Main")
+
+	err = p.LoadOrCreate(profileFile)
+	if err != nil {
+		fmt.Println(err)
+		return err
+	}
+
+	err = p.Import(name, statement)
+	if err != nil {
+		fmt.Println(err)
+		return err
+	}
+
+	_, err = os.Stat(profileFile)
+	existing := err == nil
+
+	err = p.Save(profileFile)
+	if err != nil {
+		fmt.Println(err)
+		return err
+	}
+
+	fmt.Printf("Imported %d positions and %d transactions into %s\n", len(statement.Positions), len(statement.Transactions), name)
+	if existing {
+		fmt.Printf("The previous profile was saved to %s.bak\n", profileFile)
+	}
+
+	return nil
+}
+
+func parseStatement(file string) (*portfolio.Statement, string, error) {
+	reader, err := os.Open(file)
+	if err != nil {
+		return nil, "", err
+	}
+	defer reader.Close()
+
+	if importFormat == portfolio.FormatOFX {
+		return portfolio.ParseOFX(reader)
+	}
+
+	var mapping *portfolio.ColumnMapping
+
+	if importMapping != "" {
+		mapping, err = portfolio.LoadColumnMapping(importMapping)
+	} else {
+		mapping, err = portfolio.BrokerMapping(importFormat)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	statement, err := portfolio.ParseCSV(reader, mapping, importAccount)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return statement, mapping.Name, nil
+}
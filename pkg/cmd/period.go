@@ -0,0 +1,28 @@
+package cmd
+
+import (
+	"github.com/cimomo/portfolio-go/pkg/portfolio"
+	"github.com/spf13/cobra"
+)
+
+var (
+	periodStart string
+	periodEnd   string
+	periodDaily bool
+)
+
+// addPeriodFlags adds the flags that select the dates and the granularity of the backtests
+func addPeriodFlags(cmd *cobra.Command) {
+	cmd.PersistentFlags().StringVar(&periodStart, "start", "", "start date of the backtest, YYYY-MM-DD or YYYY-MM (defaults to the earliest common date)")
+	cmd.PersistentFlags().StringVar(&periodEnd, "end", "", "end date of the backtest, YYYY-MM-DD or YYYY-MM (defaults to now)")
+	cmd.PersistentFlags().BoolVar(&periodDaily, "daily", false, "backtest on daily instead of monthly bars")
+}
+
+// parsePeriodFlags returns the backtest period selected by the flags, or nil for the full history on monthly bars
+func parsePeriodFlags() (*portfolio.Period, error) {
+	if periodStart == "" && periodEnd == "" && !periodDaily {
+		return nil, nil
+	}
+
+	return portfolio.ParsePeriod(periodStart, periodEnd, periodDaily)
+}
@@ -20,5 +20,10 @@ func NewPortfolioCmd() *cobra.Command {
 func addCommands(cmd *cobra.Command) {
 	cmd.AddCommand(
 		newStartCmd(),
+		newImportCmd(),
+		newExportCmd(),
+		newProjectCmd(),
+		newStressCmd(),
+		newShockCmd(),
 	)
 }
@@ -0,0 +1,169 @@
+package cmd
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"text/tabwriter"
+
+	"github.com/cimomo/portfolio-go/pkg/portfolio"
+	"github.com/spf13/cobra"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+var (
+	projectPortfolio    string
+	projectModel        string
+	projectYears        int
+	projectPaths        int
+	projectBlockSize    int
+	projectBalance      float64
+	projectContribution float64
+	projectGoal         float64
+	projectSeed         int64
+)
+
+func newProjectCmd() *cobra.Command {
+	projectCmd := &cobra.Command{
+		Use:   "project",
+		Short: "Project the future value of a portfolio with a Monte Carlo simulation",
+		Long: `Project simulates thousands of future paths of a portfolio's target allocation from its
+backtested monthly returns, either by resampling blocks of historical months or by drawing from
+a log-normal model calibrated to the history, and reports the percentiles of the ending balance
+and the probability of reaching a goal. The same seed always produces the same projection.
+Settings that are not given as flags are taken from the projection section of the profile.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			projectPortfolioValue(cmd, profile)
+		},
+	}
+
+	projectCmd.PersistentFlags().StringVar(&profile, "profile", "./examples/profile.yml", "profile for portfolio")
+	projectCmd.PersistentFlags().StringVar(&projectPortfolio, "portfolio", "", "portfolio to project (defaults to the entire profile)")
+	projectCmd.PersistentFlags().StringVar(&projectModel, "model", string(portfolio.ProjectionBootstrap), "return model: bootstrap or parametric")
+	projectCmd.PersistentFlags().IntVar(&projectYears, "years", 20, "number of years to project")
+	projectCmd.PersistentFlags().IntVar(&projectPaths, "paths", 10000, "number of simulated paths")
+	projectCmd.PersistentFlags().IntVar(&projectBlockSize, "block", 12, "months per block of the bootstrap model")
+	projectCmd.PersistentFlags().Float64Var(&projectBalance, "balance", 0, "initial balance (defaults to the current value)")
+	projectCmd.PersistentFlags().Float64Var(&projectContribution, "contribution", 0, "amount added at the end of every month")
+	projectCmd.PersistentFlags().Float64Var(&projectGoal, "goal", 0, "target ending balance")
+	projectCmd.PersistentFlags().Int64Var(&projectSeed, "seed", 1, "seed of the random number generator")
+	addPeriodFlags(projectCmd)
+
+	return projectCmd
+}
+
+func projectPortfolioValue(cmd *cobra.Command, profileFile string) error {
+	period, err := parsePeriodFlags()
+	if err != nil {
+		fmt.Println(err)
+		return err
+	}
+
+	p := portfolio.NewProfile("Main")
+
+	err = p.Load(profileFile)
+	if err != nil {
+		fmt.Println(err)
+		return err
+	}
+	p.SetPeriod(period)
+
+	err = p.Refresh()
+	if err != nil {
+		fmt.Println(err)
+		return err
+	}
+
+	target := p.MergedPortfolio
+
+	if projectPortfolio != "" {
+		target = nil
+		for _, port := range p.Portfolios {
+			if port.Name == projectPortfolio {
+				target = port
+			}
+		}
+
+		if target == nil {
+			err = fmt.Errorf("Portfolio not found: %s", projectPortfolio)
+			fmt.Println(err)
+			return err
+		}
+	}
+
+	balance := target.Status.Value
+	if projectBalance > 0 {
+		balance = projectBalance
+	}
+
+	err = target.Performance.Compute()
+	if err != nil {
+		fmt.Println(err)
+		return err
+	}
+
+	projection := *p.Projection
+	projection.InitialBalance = balance
+
+	flags := cmd.Flags()
+	if flags.Changed("model") {
+		projection.Model = portfolio.ProjectionModel(projectModel)
+	}
+	if flags.Changed("years") {
+		projection.Years = projectYears
+	}
+	if flags.Changed("paths") {
+		projection.Paths = projectPaths
+	}
+	if flags.Changed("block") {
+		projection.BlockSize = projectBlockSize
+	}
+	if flags.Changed("contribution") {
+		projection.Contribution = projectContribution
+	}
+	if flags.Changed("goal") {
+		projection.Goal = projectGoal
+	}
+	if flags.Changed("seed") {
+		projection.Seed = projectSeed
+	}
+
+	err = projection.Run(target.Performance.Result)
+	if err != nil {
+		fmt.Println(err)
+		return err
+	}
+
+	printProjection(target.Name, &projection)
+
+	return nil
+}
+
+func printProjection(name string, projection *portfolio.Projection) {
+	printer := message.NewPrinter(language.English)
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+
+	dollars := func(value float64) string {
+		return printer.Sprintf("$%.0f", value)
+	}
+
+	fmt.Printf("Projection of %s over %d years (%s model, %d paths, seed %d)\n\n",
+		name, projection.Years, projection.Model, projection.Paths, projection.Seed)
+
+	fmt.Fprintf(writer, "Initial Balance\t%15s\n", dollars(projection.InitialBalance))
+	fmt.Fprintf(writer, "Monthly Contribution\t%15s\n", dollars(projection.Contribution))
+	fmt.Fprintf(writer, "Total Invested\t%15s\n", dollars(projection.Contributed))
+	fmt.Fprintln(writer)
+
+	for i, percentile := range portfolio.ProjectionPercentiles {
+		fmt.Fprintf(writer, "%.0fth Percentile\t%15s\n", percentile, dollars(projection.Ending[i]))
+	}
+
+	if !math.IsNaN(projection.Probability) {
+		fmt.Fprintln(writer)
+		fmt.Fprintf(writer, "Probability of Reaching %s\t%14.1f%%\n", dollars(projection.Goal), projection.Probability)
+	}
+
+	writer.Flush()
+}
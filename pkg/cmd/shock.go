@@ -0,0 +1,180 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/cimomo/portfolio-go/pkg/portfolio"
+	"github.com/spf13/cobra"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+var (
+	shockScenarios string
+	shockScenario  string
+	shockText      string
+	shockSave      string
+)
+
+func newShockCmd() *cobra.Command {
+	shockCmd := &cobra.Command{
+		Use:   "shock",
+		Short: "Apply hypothetical price shocks to the current holdings",
+		Long: `Shock applies immediate price changes, keyed by group of asset classes (equities, bonds,
+real estate, commodities, gold, crypto), asset class, tag or symbol, to the current holdings of the
+profile, and reports the gain or loss of every portfolio and the allocation after the shock against
+the targets. A change of "usd" moves the dollar, which lowers foreign assets when it rises.
+Scenarios are read from the scenarios file, and a shock given as a flag can be saved to it.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			shockProfile(profile)
+		},
+	}
+
+	shockCmd.PersistentFlags().StringVar(&profile, "profile", "./examples/profile.yml", "profile for portfolio")
+	shockCmd.PersistentFlags().StringVar(&shockScenarios, "scenarios", "", "scenarios file (defaults to <profile>.scenarios.yml)")
+	shockCmd.PersistentFlags().StringVar(&shockScenario, "scenario", "", "name of the scenario to apply (defaults to all)")
+	shockCmd.PersistentFlags().StringVar(&shockText, "shock", "", "shocks to apply, e.g. \"equities -30%, bonds +5%, gold +10%, USD +8%\"")
+	shockCmd.PersistentFlags().StringVar(&shockSave, "save", "", "save the shocks given with --shock to the scenarios file under this name")
+
+	return shockCmd
+}
+
+func shockProfile(profileFile string) error {
+	p := portfolio.NewProfile("Main")
+
+	err := p.Load(profileFile)
+	if err != nil {
+		fmt.Println(err)
+		return err
+	}
+
+	file := shockScenarios
+	if file == "" {
+		file = portfolio.ShockScenariosFile(profileFile)
+	}
+
+	scenarios := portfolio.NewShockScenarios()
+
+	err = scenarios.Load(file)
+	if err != nil {
+		fmt.Println(err)
+		return err
+	}
+
+	selected := scenarios.Scenarios
+
+	if shockText != "" {
+		shocks, err := portfolio.ParseShocks(shockText)
+		if err != nil {
+			fmt.Println(err)
+			return err
+		}
+
+		name := shockSave
+		if name == "" {
+			name = shockText
+		}
+		scenario := &portfolio.ShockScenario{Name: name, Shocks: shocks}
+		selected = []*portfolio.ShockScenario{scenario}
+
+		if shockSave != "" {
+			scenarios.Set(scenario)
+
+			err = scenarios.Save(file)
+			if err != nil {
+				fmt.Println(err)
+				return err
+			}
+			fmt.Printf("Saved %s to %s\n\n", name, file)
+		}
+
+	} else if shockSave != "" {
+		err = errors.New("Only shocks given with --shock can be saved")
+		fmt.Println(err)
+		return err
+
+	} else if shockScenario != "" {
+		scenario := scenarios.Find(shockScenario)
+		if scenario == nil {
+			err = fmt.Errorf("Shock scenario not found: %s", shockScenario)
+			fmt.Println(err)
+			return err
+		}
+		selected = []*portfolio.ShockScenario{scenario}
+	}
+
+	if len(selected) == 0 {
+		err = fmt.Errorf("No shock scenarios in %s", file)
+		fmt.Println(err)
+		return err
+	}
+
+	err = p.Refresh()
+	if err != nil {
+		fmt.Println(err)
+		return err
+	}
+
+	for _, scenario := range selected {
+		result, err := p.Shock(scenario)
+		if err != nil {
+			fmt.Println(err)
+			return err
+		}
+
+		printShock(result)
+	}
+
+	return nil
+}
+
+func printShock(result *portfolio.ShockResult) {
+	printer := message.NewPrinter(language.English)
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+
+	dollars := func(value float64) string {
+		if value < 0 {
+			return printer.Sprintf("-$%.0f", -value)
+		}
+		return printer.Sprintf("$%.0f", value)
+	}
+
+	keys := make([]string, 0, len(result.Scenario.Shocks))
+	for key := range result.Scenario.Shocks {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	fmt.Printf("%s:", result.Scenario.Name)
+	for _, key := range keys {
+		fmt.Printf(" %s %+g%%", key, result.Scenario.Shocks[key])
+	}
+	fmt.Print("\n\n")
+
+	fmt.Fprintf(writer, "Portfolio\t%15s\t%15s\t%15s\t%8s\t%8s\t%8s\t%8s\n", "Value", "Shocked", "Change", "Change %", "Before", "After", "Target")
+	for _, allocation := range result.Allocation {
+		fmt.Fprintf(writer, "%s\t%15s\t%15s\t%15s\t%7.1f%%\t%7.1f%%\t%7.1f%%\t%7.1f%%\n", allocation.Name,
+			dollars(allocation.Value), dollars(allocation.Shocked), dollars(allocation.Shocked-allocation.Value),
+			allocation.Shock, allocation.Before, allocation.After, allocation.Target)
+	}
+	fmt.Fprintf(writer, "Total\t%15s\t%15s\t%15s\t%7.1f%%\t\t\t\n", dollars(result.Value), dollars(result.Shocked), dollars(result.Change), result.Percent)
+	writer.Flush()
+
+	for _, shocked := range result.Portfolios {
+		fmt.Printf("\n%s: %s (%.1f%%)\n\n", shocked.Name, dollars(shocked.Change), shocked.Percent)
+
+		fmt.Fprintf(writer, "Holding\t%15s\t%15s\t%15s\t%8s\t%8s\t%8s\t%8s\n", "Value", "Shocked", "Change", "Shock", "Before", "After", "Target")
+		for _, allocation := range shocked.Allocation {
+			fmt.Fprintf(writer, "%s\t%15s\t%15s\t%15s\t%7.1f%%\t%7.1f%%\t%7.1f%%\t%7.1f%%\n", allocation.Name,
+				dollars(allocation.Value), dollars(allocation.Shocked), dollars(allocation.Shocked-allocation.Value),
+				allocation.Shock, allocation.Before, allocation.After, allocation.Target)
+		}
+		writer.Flush()
+	}
+
+	fmt.Println()
+}
@@ -20,14 +20,21 @@ func newStartCmd() *cobra.Command {
 	}
 
 	startCmd.PersistentFlags().StringVar(&profile, "profile", "./examples/profile.yml", "profile for portfolio")
+	addPeriodFlags(startCmd)
 
 	return startCmd
 }
 
 func startTerminal(profile string) error {
-	term := terminal.NewTerminal(profile)
+	period, err := parsePeriodFlags()
+	if err != nil {
+		fmt.Println(err)
+		return err
+	}
+
+	term := terminal.NewTerminal(profile).SetPeriod(period)
 
-	err := term.Start()
+	err = term.Start()
 	if err != nil {
 		fmt.Println(err)
 		return err
@@ -0,0 +1,150 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/cimomo/portfolio-go/pkg/portfolio"
+	"github.com/spf13/cobra"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+var (
+	stressPortfolio string
+	stressScenario  string
+	stressFrom      string
+	stressTo        string
+)
+
+func newStressCmd() *cobra.Command {
+	stressCmd := &cobra.Command{
+		Use:   "stress",
+		Short: "Replay the current holdings through historical crises",
+		Long: `Stress replays the current holdings of a portfolio through historical crises on daily bars,
+backfilled by the proxies of the profile, and reports the peak-to-trough loss of every holding and
+of the portfolio, against the same amount invested in the benchmark. The crises are the dot-com
+crash, the global financial crisis, the COVID crash and the 2022 rate shock, followed by the
+scenarios in the stress section of the profile, or a single range of dates given as flags.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			stressPortfolioValue(profile)
+		},
+	}
+
+	stressCmd.PersistentFlags().StringVar(&profile, "profile", "./examples/profile.yml", "profile for portfolio")
+	stressCmd.PersistentFlags().StringVar(&stressPortfolio, "portfolio", "", "portfolio to stress (defaults to the entire profile)")
+	stressCmd.PersistentFlags().StringVar(&stressScenario, "scenario", "", "name of the scenario to replay (defaults to all)")
+	stressCmd.PersistentFlags().StringVar(&stressFrom, "from", "", "start date of a custom scenario, YYYY-MM-DD or YYYY-MM")
+	stressCmd.PersistentFlags().StringVar(&stressTo, "to", "", "end date of a custom scenario, YYYY-MM-DD or YYYY-MM")
+
+	return stressCmd
+}
+
+func stressPortfolioValue(profileFile string) error {
+	p := portfolio.NewProfile("Main")
+
+	err := p.Load(profileFile)
+	if err != nil {
+		fmt.Println(err)
+		return err
+	}
+
+	scenarios := p.StressScenarios
+
+	if stressFrom != "" || stressTo != "" {
+		scenario, err := portfolio.NewStressScenario(stressScenario, stressFrom, stressTo)
+		if err != nil {
+			fmt.Println(err)
+			return err
+		}
+		scenarios = []*portfolio.StressScenario{scenario}
+
+	} else if stressScenario != "" {
+		scenarios = nil
+		for _, scenario := range p.StressScenarios {
+			if strings.EqualFold(scenario.Name, stressScenario) {
+				scenarios = append(scenarios, scenario)
+			}
+		}
+
+		if len(scenarios) == 0 {
+			err = fmt.Errorf("Stress scenario not found: %s", stressScenario)
+			fmt.Println(err)
+			return err
+		}
+	}
+
+	err = p.Refresh()
+	if err != nil {
+		fmt.Println(err)
+		return err
+	}
+
+	target := p.MergedPortfolio
+
+	if stressPortfolio != "" {
+		target = nil
+		for _, port := range p.Portfolios {
+			if port.Name == stressPortfolio {
+				target = port
+			}
+		}
+
+		if target == nil {
+			err = fmt.Errorf("Portfolio not found: %s", stressPortfolio)
+			fmt.Println(err)
+			return err
+		}
+	}
+
+	for _, scenario := range scenarios {
+		result, err := target.Stress(scenario)
+		if err != nil {
+			fmt.Printf("%s: %v\n\n", scenario.Name, err)
+			continue
+		}
+
+		printStress(result)
+	}
+
+	return nil
+}
+
+func printStress(result *portfolio.StressResult) {
+	printer := message.NewPrinter(language.English)
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+
+	dollars := func(value float64) string {
+		if value < 0 {
+			return printer.Sprintf("-$%.0f", -value)
+		}
+		return printer.Sprintf("$%.0f", value)
+	}
+
+	scenario := result.Scenario
+	fmt.Printf("%s (%s to %s), peak %s, trough %s\n\n", scenario.Name,
+		scenario.Start.Format("2006-01-02"), scenario.End.Format("2006-01-02"),
+		result.Loss.Peak.Format("2006-01-02"), result.Loss.Trough.Format("2006-01-02"))
+
+	fmt.Fprintf(writer, "Holding\t%15s\t%15s\t%8s\n", "Value", "Loss", "Loss %")
+	for _, holding := range result.Holdings {
+		fmt.Fprintf(writer, "%s\t%15s\t%15s\t%7.1f%%\n", holding.Symbol, dollars(holding.Value), dollars(holding.Loss), holding.Percent)
+	}
+	if result.Cash != 0 {
+		fmt.Fprintf(writer, "Cash\t%15s\t%15s\t%7.1f%%\n", dollars(result.Cash), dollars(0), 0.0)
+	}
+	fmt.Fprintf(writer, "Portfolio\t%15s\t%15s\t%7.1f%%\n", dollars(result.Value), dollars(result.Loss.Loss), result.Loss.Percent)
+	fmt.Fprintf(writer, "Benchmark (%s)\t%15s\t%15s\t%7.1f%%\n", result.Benchmark.Symbol, dollars(result.Benchmark.Value), dollars(result.Benchmark.Loss), result.Benchmark.Percent)
+	writer.Flush()
+
+	for _, simulated := range result.Simulated {
+		fmt.Printf("%s is simulated by %s before %s\n", simulated.Symbol, strings.Join(simulated.Proxies, ", "), simulated.End.Format("2006-01-02"))
+	}
+	if len(result.Missing) > 0 {
+		fmt.Printf("No data for part of the scenario, held flat: %s\n", strings.Join(result.Missing, ", "))
+	}
+
+	fmt.Println()
+}
@@ -0,0 +1,268 @@
+package portfolio
+
+import (
+	"sort"
+	"time"
+)
+
+var attributionPeriods = []struct {
+	name   string
+	months int
+}{
+	{"1-Month", 1}, {"3-Month", 3}, {"6-Month", 6}, {"YTD", -1},
+	{"1-Year", 12}, {"3-Year", 36}, {"5-Year", 60}, {"10-Year", 120}, {"Max", 0},
+}
+
+// Attribution breaks the cumulative return of a portfolio over a trailing period down into the contributions
+// of its holdings and of its asset classes, and its excess return over the benchmark into the allocation, the
+// selection and the interaction effects of every asset class. The periods are made of whole monthly bars, so
+// the 1-Month period is the current month, and the returns of the longer periods are not annualized.
+type Attribution struct {
+	Period     string
+	Start      time.Time
+	Months     int
+	Return     float64
+	Benchmark  float64
+	Holdings   []*AttributionItem
+	Classes    []*AttributionItem
+	Subclasses []*AttributionItem
+}
+
+// AttributionItem is the share of a holding or an asset class in the return of a portfolio. The weight is the
+// weight at the start of the period, and the return is the contribution divided by the weight, so that the
+// contributions add up to the return of the portfolio even if it is rebalanced during the period. The effects
+// are only computed for asset classes.
+type AttributionItem struct {
+	Name         string
+	Class        AssetClass
+	Weight       float64
+	Return       float64
+	Contribution float64
+	Allocation   float64
+	Selection    float64
+	Interaction  float64
+}
+
+// ComputeAttribution computes the attribution of the backtest for every trailing period
+func (performance *Performance) ComputeAttribution() []*Attribution {
+	attributions := make([]*Attribution, 0, len(attributionPeriods))
+
+	for _, period := range attributionPeriods {
+		attribution := computeAttribution(performance.Result, performance.Benchmark, period.months)
+		attribution.Period = period.name
+		attributions = append(attributions, attribution)
+	}
+
+	return attributions
+}
+
+func computeAttribution(result *PerformanceResult, benchmark *PerformanceResult, months int) *Attribution {
+	from := attributionStart(result.Historic, months)
+
+	weights, contributions, total := computeHoldingContributions(result, from)
+
+	attribution := &Attribution{
+		Start:      result.Historic[from].Date,
+		Months:     len(result.Historic) - from,
+		Return:     total,
+		Holdings:   make([]*AttributionItem, 0),
+		Classes:    make([]*AttributionItem, 0),
+		Subclasses: make([]*AttributionItem, 0),
+	}
+
+	classes := make(map[AssetClass]*AttributionItem)
+	subclasses := make(map[AssetClass]*AttributionItem)
+
+	for _, symbol := range result.Portfolio.Symbols {
+		if _, ok := weights[symbol]; !ok {
+			continue
+		}
+
+		asset := result.Portfolio.Holdings[symbol].Asset
+		holding := newAttributionItem(symbol, asset.Class, weights[symbol], contributions[symbol])
+		attribution.Holdings = append(attribution.Holdings, holding)
+		delete(weights, symbol)
+
+		attribution.Classes = addAttribution(attribution.Classes, classes, asset.Class, asset.Class, holding)
+		attribution.Subclasses = addAttribution(attribution.Subclasses, subclasses, asset.Subclass, asset.Class, holding)
+	}
+
+	for _, items := range [][]*AttributionItem{attribution.Classes, attribution.Subclasses} {
+		for _, item := range items {
+			item.Return = effectiveReturn(item.Weight, item.Contribution)
+		}
+	}
+
+	sortAttribution(attribution.Holdings)
+	sortAttribution(attribution.Classes)
+	sortAttribution(attribution.Subclasses)
+
+	attribution.Benchmark = computeAttributionEffects(attribution, benchmark, months)
+
+	return attribution
+}
+
+// attributionStart returns the index of the first monthly bar of the trailing period, where zero months
+// selects the entire backtest and negative months select the current year
+func attributionStart(historic []Historic, months int) int {
+	last := len(historic) - 1
+
+	switch {
+	case months < 0:
+		from := last
+		for from > 0 && historic[from-1].Date.Year() == historic[last].Date.Year() {
+			from--
+		}
+		return from
+	case months == 0 || months > len(historic):
+		return 0
+	default:
+		return len(historic) - months
+	}
+}
+
+// computeHoldingContributions returns the weight of every holding at the start of the period, its gain over the
+// period in percent of the starting value of the portfolio, and the cumulative return of the portfolio. The gains
+// are those of the quantities held in every month, since flows and rebalancing trades do not change the value.
+func computeHoldingContributions(result *PerformanceResult, from int) (map[string]float64, map[string]float64, float64) {
+	weights := make(map[string]float64)
+	contributions := make(map[string]float64)
+
+	start := result.Historic[from].Open
+	if from > 0 {
+		start = result.Historic[from-1].Close
+	}
+
+	var total float64
+
+	// A merged portfolio may list a symbol held in several portfolios more than once, and so does its value
+	for _, symbol := range result.Portfolio.Symbols {
+		quotes := result.Assets[symbol]
+
+		for i := from; i < len(quotes) && i < len(result.Historic); i++ {
+			quantity := quantitiesForDate(result.allocations, result.Historic[i].Date)[symbol]
+
+			previous := quotes[i].Open
+			if i > 0 {
+				previous = quotes[i-1].Close
+			}
+
+			if i == from {
+				weights[symbol] += quantity * previous
+			}
+
+			gain := quantity * (quotes[i].Close - previous)
+			contributions[symbol] += gain
+			total += gain
+		}
+	}
+
+	if start == 0 {
+		return weights, contributions, 0
+	}
+
+	for symbol := range weights {
+		weights[symbol] = weights[symbol] / start * 100
+		contributions[symbol] = contributions[symbol] / start * 100
+	}
+
+	return weights, contributions, total / start * 100
+}
+
+// computeAttributionEffects splits the excess return of the portfolio over the benchmark by asset class, as in
+// the Brinson-Fachler model, and returns the return of the benchmark. The return of an asset class that the
+// benchmark does not hold is that of the portfolio, so that holding it at all is an allocation decision.
+func computeAttributionEffects(attribution *Attribution, benchmark *PerformanceResult, months int) float64 {
+	from := attributionStart(benchmark.Historic, months)
+	weights, contributions, total := computeHoldingContributions(benchmark, from)
+
+	benchmarkWeights := make(map[AssetClass]float64)
+	benchmarkContributions := make(map[AssetClass]float64)
+	for symbol, weight := range weights {
+		class := benchmark.Portfolio.Holdings[symbol].Asset.Class
+		benchmarkWeights[class] += weight
+		benchmarkContributions[class] += contributions[symbol]
+	}
+
+	for _, class := range attribution.Classes {
+		benchmarkWeight := benchmarkWeights[class.Class] / 100
+		benchmarkReturn := class.Return
+		if benchmarkWeight > 0 {
+			benchmarkReturn = effectiveReturn(benchmarkWeights[class.Class], benchmarkContributions[class.Class])
+		}
+
+		portfolioReturn := class.Return
+		if class.Weight == 0 {
+			portfolioReturn = benchmarkReturn
+		}
+
+		active := class.Weight/100 - benchmarkWeight
+		class.Allocation = active * (benchmarkReturn - total)
+		class.Selection = benchmarkWeight * (portfolioReturn - benchmarkReturn)
+		class.Interaction = active * (portfolioReturn - benchmarkReturn)
+	}
+
+	// The asset classes held only by the benchmark are underweighted entirely
+	for class, weight := range benchmarkWeights {
+		if _, ok := findAttribution(attribution.Classes, class); ok {
+			continue
+		}
+
+		benchmarkReturn := effectiveReturn(weight, benchmarkContributions[class])
+		attribution.Classes = append(attribution.Classes, &AttributionItem{
+			Name:       string(class),
+			Class:      class,
+			Return:     benchmarkReturn,
+			Allocation: -weight / 100 * (benchmarkReturn - total),
+		})
+	}
+
+	return total
+}
+
+func newAttributionItem(name string, class AssetClass, weight float64, contribution float64) *AttributionItem {
+	return &AttributionItem{
+		Name:         name,
+		Class:        class,
+		Weight:       weight,
+		Return:       effectiveReturn(weight, contribution),
+		Contribution: contribution,
+	}
+}
+
+func addAttribution(items []*AttributionItem, index map[AssetClass]*AttributionItem, name AssetClass, class AssetClass, holding *AttributionItem) []*AttributionItem {
+	item, ok := index[name]
+	if !ok {
+		item = &AttributionItem{Name: string(name), Class: class}
+		index[name] = item
+		items = append(items, item)
+	}
+
+	item.Weight += holding.Weight
+	item.Contribution += holding.Contribution
+
+	return items
+}
+
+func findAttribution(items []*AttributionItem, class AssetClass) (*AttributionItem, bool) {
+	for _, item := range items {
+		if item.Class == class {
+			return item, true
+		}
+	}
+	return nil, false
+}
+
+// sortAttribution orders the items from the largest to the smallest contribution
+func sortAttribution(items []*AttributionItem) {
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].Contribution > items[j].Contribution
+	})
+}
+
+func effectiveReturn(weight float64, contribution float64) float64 {
+	if weight == 0 {
+		return 0
+	}
+	return contribution / weight * 100
+}
@@ -0,0 +1,124 @@
+package portfolio
+
+import (
+	"math"
+	"testing"
+
+	"github.com/piquette/finance-go"
+)
+
+func TestAttributionStart(t *testing.T) {
+	// 14 months from January 2020 to February 2021
+	historic := testHistoric(100, repeat(1, 14)...)
+
+	tests := []struct {
+		name   string
+		months int
+		want   int
+	}{
+		{"1-Month", 1, 13},
+		{"3-Month", 3, 11},
+		{"YTD", -1, 12},
+		{"1-Year", 12, 2},
+		{"Max", 0, 0},
+		{"longer than the backtest", 36, 0},
+	}
+
+	for _, test := range tests {
+		if got := attributionStart(historic, test.months); got != test.want {
+			t.Errorf("%s: first month = %d, want %d", test.name, got, test.want)
+		}
+	}
+
+	// A backtest within a single year starts its YTD period at its first month
+	if got := attributionStart(historic[:5], -1); got != 0 {
+		t.Errorf("YTD of a backtest within a year: first month = %d, want 0", got)
+	}
+}
+
+func TestComputeAttribution(t *testing.T) {
+	// The portfolio holds US stocks, bonds and gold, and the benchmark US and international stocks, so some
+	// classes are held by only one of them
+	restore := setTestBars(map[string][]finance.ChartBar{
+		"VTI":  testBars(100, 3, -2, 4, 1, -5, 2, 3, -1, 2, 6, -3, 1, 2, -4),
+		"BND":  testBars(50, 0.5, 0.2, -0.3, 0.4, 0.1, -0.2, 0.3, 0.5, -0.1, 0.2, 0.3, -0.4, 0.1, 0.2),
+		"GLD":  testBars(150, -1, 4, 2, -3, 5, -2, 1, 0, 3, -4, 2, 1, -1, 2),
+		"SPY":  testBars(300, 2.5, -1.5, 3.5, 1.5, -4, 2, 2.5, -1.5, 2, 5, -2.5, 1, 2.5, -3.5),
+		"VXUS": testBars(60, 1, -3, 2, 2, -6, 3, 1, 2, -1, 4, -2, 0, 3, -2),
+	})
+	defer restore()
+
+	portfolio := newTestPortfolio(map[string]float64{"VTI": 60, "BND": 30, "GLD": 10})
+	portfolio.Rebalance = &Rebalance{Strategy: RebalanceQuarterly}
+	benchmark := newTestPortfolio(map[string]float64{"SPY": 50, "VXUS": 50})
+
+	result := testAttributionResult(t, portfolio)
+	benchmarkResult := testAttributionResult(t, benchmark)
+
+	for _, period := range attributionPeriods {
+		attribution := computeAttribution(result, benchmarkResult, period.months)
+
+		// The holdings contribute the return of the portfolio, measured from its balances
+		from := attributionStart(result.Historic, period.months)
+		start := result.Historic[from].Open
+		if from > 0 {
+			start = result.Historic[from-1].Close
+		}
+		want := (result.Historic[len(result.Historic)-1].Close/start - 1) * 100
+		if math.Abs(attribution.Return-want) > 1e-9 {
+			t.Errorf("%s: return = %v, want %v", period.name, attribution.Return, want)
+		}
+
+		var contributions, weights float64
+		for _, holding := range attribution.Holdings {
+			contributions += holding.Contribution
+			weights += holding.Weight
+		}
+		if math.Abs(contributions-attribution.Return) > 1e-9 {
+			t.Errorf("%s: holdings contribute %v, want the return of %v", period.name, contributions, attribution.Return)
+		}
+		if math.Abs(weights-100) > 1e-9 {
+			t.Errorf("%s: holdings weigh %v, want 100", period.name, weights)
+		}
+
+		// The effects of the asset classes add up to the excess return over the benchmark
+		var effects float64
+		for _, class := range attribution.Classes {
+			effects += class.Allocation + class.Selection + class.Interaction
+		}
+		if excess := attribution.Return - attribution.Benchmark; math.Abs(effects-excess) > 1e-9 {
+			t.Errorf("%s: effects add up to %v, want the excess return of %v", period.name, effects, excess)
+		}
+
+		// International stocks are only held by the benchmark
+		if _, ok := findAttribution(attribution.Classes, AssetClassInternationalStock); !ok {
+			t.Errorf("%s: no attribution for the international stocks of the benchmark", period.name)
+		}
+	}
+}
+
+// testBars returns monthly bars from January 2020 that start at the given price and change by the given returns
+func testBars(price float64, returns ...float64) []finance.ChartBar {
+	bars := make([]finance.ChartBar, len(returns))
+	for i, r := range returns {
+		close := price * (1 + r/100)
+		bars[i] = testBar(i, price, close, close)
+		price = close
+	}
+	return bars
+}
+
+func testAttributionResult(t *testing.T, portfolio *Portfolio) *PerformanceResult {
+	backtest, err := computeMonthlyBalances(portfolio, backtestOptions{startDate: month(0), endDate: month(13), initialBalance: 10000})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result := NewPerformanceResult()
+	result.Portfolio = portfolio
+	result.Historic = backtest.Monthly
+	result.Assets = backtest.Assets
+	result.allocations = backtest.Allocations
+
+	return result
+}
@@ -0,0 +1,459 @@
+package portfolio
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Broker export formats
+const (
+	FormatFidelity string = "fidelity"
+	FormatSchwab   string = "schwab"
+	FormatVanguard string = "vanguard"
+	FormatGeneric  string = "generic"
+)
+
+// transactionTransfer is a mapping-only type that becomes a deposit or a withdrawal depending on the amount
+const transactionTransfer TransactionType = "transfer"
+
+// ColumnMapping defines how the columns of a broker CSV export map to positions and transactions.
+// Alternative column names are separated by "|", and all names are matched case-insensitively.
+type ColumnMapping struct {
+	Name        string          `yaml:"name"`
+	Account     string          `yaml:"account"`
+	Symbol      string          `yaml:"symbol"`
+	Description string          `yaml:"description"`
+	Quantity    string          `yaml:"quantity"`
+	Price       string          `yaml:"price"`
+	Value       string          `yaml:"value"`
+	CostBasis   string          `yaml:"basis"`
+	Date        string          `yaml:"date"`
+	Action      string          `yaml:"action"`
+	Amount      string          `yaml:"amount"`
+	DateFormat  string          `yaml:"dateFormat"`
+	Cash        []string        `yaml:"cash"`
+	Actions     []ActionMapping `yaml:"actions"`
+}
+
+// ActionMapping maps the action text of a transaction row to a transaction type. The first
+// mapping whose match is contained in the action text wins.
+type ActionMapping struct {
+	Match string          `yaml:"match"`
+	Type  TransactionType `yaml:"type"`
+}
+
+// BrokerMapping returns the built-in column mapping for a broker export format
+func BrokerMapping(format string) (*ColumnMapping, error) {
+	switch format {
+	case FormatFidelity:
+		return &ColumnMapping{
+			Name:        "Fidelity",
+			Account:     "Account Number|Account",
+			Symbol:      "Symbol",
+			Description: "Description|Security Description",
+			Quantity:    "Quantity",
+			Price:       "Last Price|Price ($)",
+			Value:       "Current Value",
+			CostBasis:   "Cost Basis Total|Cost Basis",
+			Date:        "Run Date",
+			Action:      "Action",
+			Amount:      "Amount ($)",
+			DateFormat:  "01/02/2006",
+			Cash:        []string{"SPAXX**", "FDRXX**", "FZFXX**", "FCASH**", "CORE**"},
+			Actions: []ActionMapping{
+				{"REINVESTMENT", TransactionBuy},
+				{"YOU BOUGHT", TransactionBuy},
+				{"YOU SOLD", TransactionSell},
+				{"DIVIDEND", TransactionDividend},
+				{"INTEREST", TransactionInterest},
+				{"FEE", TransactionFee},
+				{"CONTRIBUTION", TransactionDeposit},
+				{"DIRECT DEPOSIT", TransactionDeposit},
+				{"DISTRIBUTION", TransactionWithdrawal},
+				{"DIRECT DEBIT", TransactionWithdrawal},
+				{"TRANSFER", transactionTransfer},
+			},
+		}, nil
+
+	case FormatSchwab:
+		return &ColumnMapping{
+			Name:        "Schwab",
+			Symbol:      "Symbol",
+			Description: "Description",
+			Quantity:    "Quantity|Qty (Quantity)",
+			Price:       "Price",
+			Value:       "Market Value|Mkt Val (Market Value)",
+			CostBasis:   "Cost Basis",
+			Date:        "Date",
+			Action:      "Action",
+			Amount:      "Amount",
+			DateFormat:  "01/02/2006",
+			Cash:        []string{"Cash & Cash Investments"},
+			Actions: []ActionMapping{
+				{"Reinvest Shares", TransactionBuy},
+				{"Buy", TransactionBuy},
+				{"Sell", TransactionSell},
+				{"Div", TransactionDividend},
+				{"Cap Gain", TransactionDividend},
+				{"Interest", TransactionInterest},
+				{"Fee", TransactionFee},
+				{"MoneyLink", transactionTransfer},
+				{"Funds", transactionTransfer},
+				{"Wire", transactionTransfer},
+				{"Journal", transactionTransfer},
+			},
+		}, nil
+
+	case FormatVanguard:
+		return &ColumnMapping{
+			Name:        "Vanguard",
+			Account:     "Account Number",
+			Symbol:      "Symbol",
+			Description: "Investment Name",
+			Quantity:    "Shares",
+			Price:       "Share Price",
+			Value:       "Total Value",
+			Date:        "Trade Date",
+			Action:      "Transaction Type",
+			Amount:      "Net Amount",
+			DateFormat:  "2006-01-02|01/02/2006",
+			Cash:        []string{"VMFXX"},
+			Actions: []ActionMapping{
+				{"Sweep", TransactionOther},
+				{"Reinvestment", TransactionBuy},
+				{"Buy", TransactionBuy},
+				{"Sell", TransactionSell},
+				{"Dividend", TransactionDividend},
+				{"Capital gain", TransactionDividend},
+				{"Interest", TransactionInterest},
+				{"Fee", TransactionFee},
+				{"Contribution", TransactionDeposit},
+				{"Funds Received", TransactionDeposit},
+				{"Distribution", TransactionWithdrawal},
+				{"Withdrawal", TransactionWithdrawal},
+				{"Transfer", transactionTransfer},
+			},
+		}, nil
+
+	case FormatGeneric:
+		return &ColumnMapping{
+			Name:        "Generic",
+			Account:     "account",
+			Symbol:      "symbol",
+			Description: "description",
+			Quantity:    "quantity|shares",
+			Price:       "price",
+			Value:       "value|market value",
+			CostBasis:   "basis|cost basis",
+			Date:        "date",
+			Action:      "type|action",
+			Amount:      "amount",
+			DateFormat:  "2006-01-02|01/02/2006",
+			Cash:        []string{"CASH"},
+			Actions: []ActionMapping{
+				{"buy", TransactionBuy},
+				{"sell", TransactionSell},
+				{"dividend", TransactionDividend},
+				{"interest", TransactionInterest},
+				{"deposit", TransactionDeposit},
+				{"withdrawal", TransactionWithdrawal},
+				{"fee", TransactionFee},
+				{"transfer", transactionTransfer},
+			},
+		}, nil
+	}
+
+	return nil, fmt.Errorf("Unknown broker format: %s", format)
+}
+
+// LoadColumnMapping loads a column mapping for an unknown broker from the given YAML file.
+// Fields missing from the file are taken from the generic mapping.
+func LoadColumnMapping(name string) (*ColumnMapping, error) {
+	file, err := ioutil.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+
+	mapping, err := BrokerMapping(FormatGeneric)
+	if err != nil {
+		return nil, err
+	}
+
+	err = yaml.Unmarshal(file, mapping)
+	if err != nil {
+		return nil, err
+	}
+
+	return mapping, nil
+}
+
+// ParseCSV reads the positions and transaction history sections of a broker CSV export.
+// If account is not empty, rows belonging to other accounts are skipped.
+func ParseCSV(reader io.Reader, mapping *ColumnMapping, account string) (*Statement, error) {
+	r := csv.NewReader(reader)
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+	r.TrimLeadingSpace = true
+
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	parser := &csvParser{
+		mapping:   mapping,
+		account:   account,
+		statement: NewStatement(),
+		positions: make(map[string]*Position),
+	}
+
+	for _, record := range records {
+		parser.parseRecord(record)
+	}
+
+	parser.statement.assignTransactionIDs()
+
+	return parser.statement, nil
+}
+
+type csvSection int
+
+const (
+	sectionNone csvSection = iota
+	sectionPositions
+	sectionTransactions
+)
+
+type csvParser struct {
+	mapping   *ColumnMapping
+	account   string
+	section   csvSection
+	columns   map[string]int
+	statement *Statement
+	positions map[string]*Position
+}
+
+func (parser *csvParser) parseRecord(record []string) {
+	if isBlankRecord(record) {
+		parser.section = sectionNone
+		return
+	}
+
+	columns := make(map[string]int)
+	for i, name := range record {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+
+	mapping := parser.mapping
+	if hasColumn(columns, mapping.Date) && hasColumn(columns, mapping.Action) {
+		parser.section = sectionTransactions
+		parser.columns = columns
+		return
+	}
+
+	if hasColumn(columns, mapping.Symbol) && hasColumn(columns, mapping.Quantity) {
+		parser.section = sectionPositions
+		parser.columns = columns
+		return
+	}
+
+	if parser.account != "" {
+		account, ok := parser.field(record, mapping.Account)
+		if ok && account != parser.account {
+			return
+		}
+	}
+
+	switch parser.section {
+	case sectionPositions:
+		parser.parsePosition(record)
+	case sectionTransactions:
+		parser.parseTransaction(record)
+	}
+}
+
+func (parser *csvParser) parsePosition(record []string) {
+	symbol, _ := parser.field(record, parser.mapping.Symbol)
+	if symbol == "" {
+		return
+	}
+
+	value, hasValue := parser.amount(record, parser.mapping.Value)
+	quantity, hasQuantity := parser.amount(record, parser.mapping.Quantity)
+
+	if parser.isCash(symbol) {
+		if !hasValue {
+			value = quantity
+		}
+		parser.statement.Cash += value
+		parser.statement.HasCash = true
+		return
+	}
+
+	// Rows without a quantity are totals, pending activity or disclaimers
+	if !hasQuantity {
+		return
+	}
+
+	price, _ := parser.amount(record, parser.mapping.Price)
+	basis, _ := parser.amount(record, parser.mapping.CostBasis)
+	description, _ := parser.field(record, parser.mapping.Description)
+
+	if !hasValue {
+		value = quantity * price
+	}
+
+	// The same symbol may show up once per account, so positions are aggregated
+	position, ok := parser.positions[symbol]
+	if !ok {
+		position = &Position{
+			Symbol:      symbol,
+			Description: description,
+			Price:       price,
+		}
+		parser.positions[symbol] = position
+		parser.statement.Positions = append(parser.statement.Positions, position)
+	}
+
+	position.Quantity += quantity
+	position.Value += value
+	position.CostBasis += basis
+}
+
+func (parser *csvParser) parseTransaction(record []string) {
+	field, _ := parser.field(record, parser.mapping.Date)
+	date, err := parseDate(field, parser.mapping.DateFormat)
+	if err != nil {
+		return
+	}
+
+	action, _ := parser.field(record, parser.mapping.Action)
+	symbol, _ := parser.field(record, parser.mapping.Symbol)
+	quantity, _ := parser.amount(record, parser.mapping.Quantity)
+	price, _ := parser.amount(record, parser.mapping.Price)
+	amount, hasAmount := parser.amount(record, parser.mapping.Amount)
+
+	transaction := &Transaction{
+		Date:        date,
+		Type:        parser.transactionType(action),
+		Symbol:      symbol,
+		Quantity:    math.Abs(quantity),
+		Price:       math.Abs(price),
+		Amount:      amount,
+		Description: action,
+	}
+
+	if !hasAmount {
+		switch transaction.Type {
+		case TransactionBuy:
+			transaction.Amount = -transaction.Quantity * transaction.Price
+		case TransactionSell:
+			transaction.Amount = transaction.Quantity * transaction.Price
+		}
+	}
+
+	if transaction.Type == transactionTransfer {
+		transaction.Type = TransactionDeposit
+		if transaction.Amount < 0 {
+			transaction.Type = TransactionWithdrawal
+		}
+	}
+
+	// Money market sweeps are cash, not holdings
+	if parser.isCash(symbol) {
+		transaction.Symbol = ""
+		if transaction.Type == TransactionBuy || transaction.Type == TransactionSell {
+			transaction.Type = TransactionOther
+		}
+	}
+
+	parser.statement.Transactions = append(parser.statement.Transactions, transaction)
+}
+
+func (parser *csvParser) transactionType(action string) TransactionType {
+	action = strings.ToLower(action)
+
+	for _, mapping := range parser.mapping.Actions {
+		if strings.Contains(action, strings.ToLower(mapping.Match)) {
+			return mapping.Type
+		}
+	}
+
+	return TransactionOther
+}
+
+func (parser *csvParser) isCash(symbol string) bool {
+	for _, cash := range parser.mapping.Cash {
+		if strings.EqualFold(symbol, cash) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (parser *csvParser) field(record []string, column string) (string, bool) {
+	for _, name := range strings.Split(column, "|") {
+		i, ok := parser.columns[strings.ToLower(strings.TrimSpace(name))]
+		if ok && name != "" && i < len(record) {
+			return strings.TrimSpace(record[i]), true
+		}
+	}
+
+	return "", false
+}
+
+func (parser *csvParser) amount(record []string, column string) (float64, bool) {
+	field, ok := parser.field(record, column)
+	if !ok {
+		return 0, false
+	}
+
+	return parseAmount(field)
+}
+
+func hasColumn(columns map[string]int, column string) bool {
+	for _, name := range strings.Split(column, "|") {
+		if _, ok := columns[strings.ToLower(strings.TrimSpace(name))]; ok && name != "" {
+			return true
+		}
+	}
+
+	return false
+}
+
+func isBlankRecord(record []string) bool {
+	for _, field := range record {
+		if strings.TrimSpace(field) != "" {
+			return false
+		}
+	}
+
+	return true
+}
+
+// parseDate parses the first word of a date field, e.g. "09/15/2023 as of 09/14/2023"
+func parseDate(value string, formats string) (time.Time, error) {
+	fields := strings.Fields(value)
+	if len(fields) == 0 {
+		return time.Time{}, fmt.Errorf("Missing date")
+	}
+
+	var err error
+	for _, format := range strings.Split(formats, "|") {
+		var date time.Time
+		date, err = time.Parse(format, fields[0])
+		if err == nil {
+			return date, nil
+		}
+	}
+
+	return time.Time{}, err
+}
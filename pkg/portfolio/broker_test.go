@@ -0,0 +1,267 @@
+package portfolio
+
+import (
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var brokerTests = []struct {
+	format       string
+	export       string
+	quantities   map[string]float64
+	cash         float64
+	transactions []TransactionType
+}{
+	{
+		format: FormatFidelity,
+		export: `Account Number,Account Name,Symbol,Description,Quantity,Last Price,Current Value,Cost Basis Total
+X123,Individual,SPAXX**,HELD IN MONEY MARKET,,,$1500.00,
+X123,Individual,VTI,VANGUARD TOTAL STOCK MKT ETF,10,$200.00,"$2,000.00","$1,800.00"
+X456,Roth IRA,VTI,VANGUARD TOTAL STOCK MKT ETF,5,$200.00,"$1,000.00",$950.00
+
+Run Date,Account,Action,Symbol,Description,Quantity,Price ($),Amount ($)
+09/01/2023,X123,Electronic Funds Transfer Received (Cash),,No Description,,,"3,300.00"
+09/15/2023 as of 09/14/2023,X123,YOU BOUGHT VANGUARD TOTAL STOCK MKT ETF (VTI) (Cash),VTI,VANGUARD TOTAL STOCK MKT ETF,10,180.00,"-1,800.00"
+09/20/2023,X123,DIVIDEND RECEIVED VANGUARD TOTAL STOCK MKT ETF (VTI) (Cash),VTI,VANGUARD TOTAL STOCK MKT ETF,,,12.34
+09/20/2023,X123,REINVESTMENT FIDELITY GOVERNMENT MONEY MARKET (SPAXX) (Cash),SPAXX**,FIDELITY GOVERNMENT MONEY MARKET,12.34,1.00,-12.34
+`,
+		quantities:   map[string]float64{"VTI": 15},
+		cash:         1500,
+		transactions: []TransactionType{TransactionDeposit, TransactionBuy, TransactionDividend, TransactionOther},
+	},
+	{
+		format: FormatSchwab,
+		export: `"Positions for account Individual ...123 as of 09:00 PM ET, 2023/09/30"
+
+"Symbol","Description","Quantity","Price","Market Value","Cost Basis"
+"VTI","VANGUARD TOTAL STOCK MKT ETF","10","$200.00","$2,000.00","$1,800.00"
+"Cash & Cash Investments","--","--","--","$1,500.00","--"
+"Account Total","--","--","--","$3,500.00","--"
+
+"Date","Action","Symbol","Description","Quantity","Price","Fees & Comm","Amount"
+"09/01/2023","MoneyLink Transfer","","Tfr BANK","","","","$3,300.00"
+"09/15/2023","Buy","VTI","VANGUARD TOTAL STOCK MKT ETF","10","$180.00","","-$1,800.00"
+"09/20/2023","Qualified Dividend","VTI","VANGUARD TOTAL STOCK MKT ETF","","","","$12.34"
+"09/25/2023","Service Fee","","MONTHLY FEE","","","","-$2.00"
+`,
+		quantities:   map[string]float64{"VTI": 10},
+		cash:         1500,
+		transactions: []TransactionType{TransactionDeposit, TransactionBuy, TransactionDividend, TransactionFee},
+	},
+	{
+		format: FormatVanguard,
+		export: `Account Number,Investment Name,Symbol,Shares,Share Price,Total Value,
+12345678,Vanguard Federal Money Market Fund,VMFXX,1500,1,1500,
+12345678,Vanguard Total Stock Market ETF,VTI,10,200,2000,
+
+Account Number,Trade Date,Settlement Date,Transaction Type,Transaction Description,Investment Name,Symbol,Shares,Share Price,Principal Amount,Commissions and Fees,Net Amount,Accrued Interest,Account Type,
+12345678,2023-09-01,2023-09-01,Funds Received,Funds Received via Bank,Vanguard Federal Money Market Fund,VMFXX,0.0,1.0,3300.0,0.0,3300.0,0.0,CASH,
+12345678,2023-09-15,2023-09-18,Buy,Buy,Vanguard Total Stock Market ETF,VTI,10.0,180.0,-1800.0,0.0,-1800.0,0.0,CASH,
+12345678,2023-09-15,2023-09-18,Sweep out,Sweep Out Of Settlement Fund,Vanguard Federal Money Market Fund,VMFXX,-1800.0,1.0,1800.0,0.0,1800.0,0.0,CASH,
+12345678,2023-09-20,2023-09-20,Dividend,Dividend Received,Vanguard Total Stock Market ETF,VTI,0.0,0.0,12.34,0.0,12.34,0.0,CASH,
+`,
+		quantities:   map[string]float64{"VTI": 10},
+		cash:         1500,
+		transactions: []TransactionType{TransactionDeposit, TransactionBuy, TransactionOther, TransactionDividend},
+	},
+	{
+		format: FormatGeneric,
+		export: `symbol,description,quantity,price,value,basis
+VTI,Total Stock Market,10,200,2000,1800
+BND,Total Bond Market,20,70,1400,1500
+CASH,Cash,,,100,
+
+date,type,symbol,quantity,price,amount
+2023-09-01,deposit,,,,3300
+2023-09-15,buy,VTI,10,180,-1800
+2023-09-15,buy,BND,20,75,-1500
+2023-09-20,dividend,VTI,,,12.34
+2023-09-30,withdrawal,,,,-100
+`,
+		quantities:   map[string]float64{"VTI": 10, "BND": 20},
+		cash:         100,
+		transactions: []TransactionType{TransactionDeposit, TransactionBuy, TransactionBuy, TransactionDividend, TransactionWithdrawal},
+	},
+}
+
+func TestParseCSV(t *testing.T) {
+	for _, test := range brokerTests {
+		statement := parseBrokerTest(t, test.format, test.export)
+
+		if !statement.HasCash || math.Abs(statement.Cash-test.cash) > 1e-9 {
+			t.Errorf("%s: cash = %v (%v), want %v", test.format, statement.Cash, statement.HasCash, test.cash)
+		}
+
+		if len(statement.Positions) != len(test.quantities) {
+			t.Errorf("%s: %d positions, want %d", test.format, len(statement.Positions), len(test.quantities))
+		}
+		for _, position := range statement.Positions {
+			if position.Quantity != test.quantities[position.Symbol] {
+				t.Errorf("%s: quantity of %s = %v, want %v", test.format, position.Symbol, position.Quantity, test.quantities[position.Symbol])
+			}
+		}
+
+		if len(statement.Transactions) != len(test.transactions) {
+			t.Fatalf("%s: %d transactions, want %d", test.format, len(statement.Transactions), len(test.transactions))
+		}
+		for i, transaction := range statement.Transactions {
+			if transaction.Type != test.transactions[i] {
+				t.Errorf("%s: transaction %d is a %s, want %s", test.format, i, transaction.Type, test.transactions[i])
+			}
+			if transaction.ID == "" {
+				t.Errorf("%s: transaction %d has no ID", test.format, i)
+			}
+		}
+	}
+}
+
+func TestParseCSVAccount(t *testing.T) {
+	mapping, err := BrokerMapping(FormatFidelity)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	statement, err := ParseCSV(strings.NewReader(brokerTests[0].export), mapping, "X456")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(statement.Positions) != 1 || statement.Positions[0].Quantity != 5 || statement.Positions[0].CostBasis != 950 {
+		t.Errorf("Positions of account X456 = %+v, want 5 VTI at a basis of 950", statement.Positions)
+	}
+	if len(statement.Transactions) != 0 {
+		t.Errorf("Account X456 has %d transactions, want none", len(statement.Transactions))
+	}
+}
+
+func TestImportTwice(t *testing.T) {
+	for _, test := range brokerTests {
+		portfolio := NewPortfolio()
+
+		err := portfolio.Import(parseBrokerTest(t, test.format, test.export))
+		if err != nil {
+			t.Fatalf("%s: %v", test.format, err)
+		}
+
+		transactions := len(portfolio.Transactions)
+		costBasis := portfolio.CostBasis
+		quantities := make(map[string]float64)
+		lots := make(map[string]int)
+		for symbol, holding := range portfolio.Holdings {
+			quantities[symbol] = holding.Quantity
+			lots[symbol] = len(holding.Lots)
+		}
+
+		if transactions != len(test.transactions) {
+			t.Errorf("%s: %d transactions imported, want %d", test.format, transactions, len(test.transactions))
+		}
+		for symbol, quantity := range test.quantities {
+			if quantities[symbol] != quantity {
+				t.Errorf("%s: quantity of %s = %v, want %v", test.format, symbol, quantities[symbol], quantity)
+			}
+		}
+
+		// The statement is parsed again, so only the content-derived IDs tell the transactions apart
+		err = portfolio.Import(parseBrokerTest(t, test.format, test.export))
+		if err != nil {
+			t.Fatalf("%s: %v", test.format, err)
+		}
+
+		if len(portfolio.Transactions) != transactions {
+			t.Errorf("%s: %d transactions after the second import, want %d", test.format, len(portfolio.Transactions), transactions)
+		}
+		if portfolio.CostBasis != costBasis {
+			t.Errorf("%s: cost basis = %v after the second import, want %v", test.format, portfolio.CostBasis, costBasis)
+		}
+		for symbol, holding := range portfolio.Holdings {
+			if holding.Quantity != quantities[symbol] || len(holding.Lots) != lots[symbol] {
+				t.Errorf("%s: %s changed on the second import: %v shares in %d lots, want %v in %d",
+					test.format, symbol, holding.Quantity, len(holding.Lots), quantities[symbol], lots[symbol])
+			}
+		}
+	}
+}
+
+func TestSaveBackup(t *testing.T) {
+	dir, err := ioutil.TempDir("", "portfolio")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	name := filepath.Join(dir, "profile.yml")
+	original := "# Comments are not preserved by Save\ncash:\n  value: 0\n"
+	err = ioutil.WriteFile(name, []byte(original), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	profile := NewProfile("Main")
+	err = profile.Import("Generic", parseBrokerTest(t, FormatGeneric, brokerTests[3].export))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = profile.Save(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	backup, err := ioutil.ReadFile(name + ".bak")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(backup) != original {
+		t.Errorf("Backup = %q, want %q", backup, original)
+	}
+
+	saved := NewProfile("Main")
+	err = saved.Load(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(saved.Portfolios) != 1 || len(saved.Portfolios[0].Transactions) != len(brokerTests[3].transactions) {
+		t.Errorf("Saved profile does not hold the imported portfolio")
+	}
+}
+
+func TestHoldingCloneLots(t *testing.T) {
+	portfolio := NewPortfolio()
+	err := portfolio.Import(parseBrokerTest(t, FormatGeneric, brokerTests[3].export))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	holding := portfolio.Holdings["VTI"]
+	holding.Tags = []string{"core"}
+	clone := holding.Clone()
+
+	clone.Lots[0].Quantity = 1
+	clone.Lots = append(clone.Lots[:0], clone.Lots[1:]...)
+	clone.Tags[0] = "satellite"
+
+	if len(holding.Lots) != 1 || holding.Lots[0].Quantity != 10 {
+		t.Errorf("Changing the lots of a clone changed the original: %+v", holding.Lots[0])
+	}
+	if holding.Tags[0] != "core" {
+		t.Errorf("Changing the tags of a clone changed the original: %v", holding.Tags)
+	}
+}
+
+func parseBrokerTest(t *testing.T, format string, export string) *Statement {
+	mapping, err := BrokerMapping(format)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	statement, err := ParseCSV(strings.NewReader(export), mapping, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return statement
+}
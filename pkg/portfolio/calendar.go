@@ -0,0 +1,62 @@
+package portfolio
+
+import (
+	"math"
+	"time"
+)
+
+// CalendarReturns contains the return of every calendar year and month of the backtest history. The first
+// and the last year may be partial, and the months outside of the backtest are NaN.
+type CalendarReturns struct {
+	Years   []int
+	Annual  []float64
+	Monthly [][12]float64
+}
+
+// computeCalendarReturns arranges the yearly returns and the monthly returns of the backtest by calendar year
+func computeCalendarReturns(historic []Historic, yearly []float64, startDate time.Time) *CalendarReturns {
+	calendar := &CalendarReturns{
+		Years:   make([]int, len(yearly)),
+		Annual:  yearly,
+		Monthly: make([][12]float64, len(yearly)),
+	}
+
+	for i := range yearly {
+		calendar.Years[i] = startDate.Year() + i
+		for m := range calendar.Monthly[i] {
+			calendar.Monthly[i][m] = math.NaN()
+		}
+	}
+
+	for i, r := range computeMonthlyReturns(historic) {
+		year := historic[i].Date.Year() - startDate.Year()
+		if year < 0 || year >= len(yearly) {
+			continue
+		}
+		calendar.Monthly[year][historic[i].Date.Month()-1] = r
+	}
+
+	return calendar
+}
+
+// Return returns the annual return of the given year, or NaN if the backtest does not cover it
+func (calendar *CalendarReturns) Return(year int) float64 {
+	for i, y := range calendar.Years {
+		if y == year {
+			return calendar.Annual[i]
+		}
+	}
+	return math.NaN()
+}
+
+// Excess returns the difference between the annual returns and those of the benchmark in the same years,
+// or NaN for the years the benchmark does not cover
+func (calendar *CalendarReturns) Excess(benchmark *CalendarReturns) []float64 {
+	excess := make([]float64, len(calendar.Years))
+
+	for i, year := range calendar.Years {
+		excess[i] = calendar.Annual[i] - benchmark.Return(year)
+	}
+
+	return excess
+}
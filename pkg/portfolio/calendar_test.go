@@ -0,0 +1,66 @@
+package portfolio
+
+import (
+	"math"
+	"testing"
+)
+
+func TestComputeCalendarReturns(t *testing.T) {
+	// A backtest from November 2020 to February 2022, gaining 1% every month
+	historic := testHistoric(100, repeat(1, 16)...)
+	for i := range historic {
+		historic[i].Date = month(10 + i)
+	}
+	start, end := historic[0].Date, historic[len(historic)-1].Date
+
+	yearly := computeYearlyReturns(historic, start, end)
+	calendar := computeCalendarReturns(historic, yearly, start)
+
+	twoMonths := (math.Pow(1.01, 2) - 1) * 100
+	wantAnnual := []float64{twoMonths, (math.Pow(1.01, 12) - 1) * 100, twoMonths}
+
+	if len(calendar.Years) != 3 || calendar.Years[0] != 2020 || calendar.Years[2] != 2022 {
+		t.Fatalf("Years = %v, want 2020 to 2022", calendar.Years)
+	}
+	for i, want := range wantAnnual {
+		if math.Abs(calendar.Annual[i]-want) > 1e-9 {
+			t.Errorf("Return of %d = %v, want %v", calendar.Years[i], calendar.Annual[i], want)
+		}
+	}
+
+	// Only November and December 2020, and January and February 2022, are covered in the partial years
+	covered := map[int][]bool{
+		2020: {false, false, false, false, false, false, false, false, false, false, true, true},
+		2021: {true, true, true, true, true, true, true, true, true, true, true, true},
+		2022: {true, true, false, false, false, false, false, false, false, false, false, false},
+	}
+	for i, year := range calendar.Years {
+		for m, value := range calendar.Monthly[i] {
+			if covered[year][m] && math.Abs(value-1) > 1e-9 {
+				t.Errorf("Return of %d-%02d = %v, want 1", year, m+1, value)
+			}
+			if !covered[year][m] && !math.IsNaN(value) {
+				t.Errorf("Return of %d-%02d = %v, want NaN", year, m+1, value)
+			}
+		}
+	}
+}
+
+func TestCalendarExcess(t *testing.T) {
+	portfolio := &CalendarReturns{Years: []int{2019, 2020, 2021}, Annual: []float64{10, -5, 20}}
+	benchmark := &CalendarReturns{Years: []int{2020, 2021}, Annual: []float64{-10, 15}}
+
+	// The benchmark does not cover 2019
+	excess := portfolio.Excess(benchmark)
+	want := []float64{math.NaN(), 5, 5}
+
+	for i := range want {
+		if !equalOrNaN(excess[i], want[i], 1e-9) {
+			t.Errorf("Excess return of %d = %v, want %v", portfolio.Years[i], excess[i], want[i])
+		}
+	}
+
+	if got := benchmark.Return(2022); !math.IsNaN(got) {
+		t.Errorf("Return of a year outside of the backtest = %v, want NaN", got)
+	}
+}
@@ -0,0 +1,247 @@
+package portfolio
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// ContributionFrequency defines how often a recurring contribution is made
+type ContributionFrequency string
+
+const (
+	// ContributeMonthly contributes at the end of every month
+	ContributeMonthly ContributionFrequency = "monthly"
+	// ContributeQuarterly contributes at the end of every quarter
+	ContributeQuarterly ContributionFrequency = "quarterly"
+	// ContributeAnnually contributes at the end of every year
+	ContributeAnnually ContributionFrequency = "annual"
+)
+
+// ContributionAllocation defines how a contribution is split between the holdings
+type ContributionAllocation string
+
+const (
+	// AllocateTarget splits contributions and withdrawals by the target allocation
+	AllocateTarget ContributionAllocation = "target"
+	// AllocateUnderweight buys the holdings furthest below their target, and sells the holdings furthest
+	// above it, which rebalances the portfolio with the flows instead of trades
+	AllocateUnderweight ContributionAllocation = "underweight"
+)
+
+// Contribution defines a recurring contribution to a backtested portfolio. A negative amount is a withdrawal.
+// An indexed amount grows with the CPI from the start of the backtest.
+type Contribution struct {
+	Amount     float64
+	Frequency  ContributionFrequency
+	Indexed    bool
+	Allocation ContributionAllocation
+}
+
+// ContributionResult contains the dollar balances of a backtest with recurring contributions, and compares
+// its time-weighted return, which ignores the timing of the flows, with its money-weighted return
+type ContributionResult struct {
+	Balances      []Historic
+	Flows         []CashFlow
+	Contributed   float64
+	Withdrawn     float64
+	EndingBalance float64
+	TimeWeighted  float64
+	MoneyWeighted float64
+}
+
+type contributionConfig struct {
+	Amount     float64 `yaml:"amount"`
+	Frequency  string  `yaml:"frequency,omitempty"`
+	Indexed    bool    `yaml:"indexed,omitempty"`
+	Allocation string  `yaml:"allocation,omitempty"`
+}
+
+func newContribution(config *contributionConfig) (*Contribution, error) {
+	if config == nil {
+		return nil, nil
+	}
+
+	contribution := &Contribution{
+		Amount:     config.Amount,
+		Frequency:  ContributeMonthly,
+		Indexed:    config.Indexed,
+		Allocation: AllocateTarget,
+	}
+
+	if config.Amount == 0 {
+		return nil, errors.New("Contribution requires a non-zero amount")
+	}
+
+	switch ContributionFrequency(config.Frequency) {
+	case "":
+	case ContributeMonthly, ContributeQuarterly, ContributeAnnually:
+		contribution.Frequency = ContributionFrequency(config.Frequency)
+	default:
+		return nil, fmt.Errorf("Unknown contribution frequency: %s", config.Frequency)
+	}
+
+	switch ContributionAllocation(config.Allocation) {
+	case "":
+	case AllocateTarget, AllocateUnderweight:
+		contribution.Allocation = ContributionAllocation(config.Allocation)
+	default:
+		return nil, fmt.Errorf("Unknown contribution allocation: %s", config.Allocation)
+	}
+
+	return contribution, nil
+}
+
+func (contribution *Contribution) config() *contributionConfig {
+	if contribution == nil {
+		return nil
+	}
+
+	config := &contributionConfig{
+		Amount:  contribution.Amount,
+		Indexed: contribution.Indexed,
+	}
+
+	if contribution.Frequency != ContributeMonthly {
+		config.Frequency = string(contribution.Frequency)
+	}
+	if contribution.Allocation != AllocateTarget {
+		config.Allocation = string(contribution.Allocation)
+	}
+
+	return config
+}
+
+// amount returns the contribution due at the close of the month, or zero if none is due
+func (contribution *Contribution) amount(month time.Time, startDate time.Time, cpi *CPI) float64 {
+	if contribution == nil {
+		return 0
+	}
+
+	switch contribution.Frequency {
+	case ContributeQuarterly:
+		if month.Month()%3 != 0 {
+			return 0
+		}
+	case ContributeAnnually:
+		if month.Month() != time.December {
+			return 0
+		}
+	}
+
+	if contribution.Indexed && cpi != nil {
+		inflation, _ := cpi.Inflation(startDate, month)
+		return contribution.Amount * inflation
+	}
+
+	return contribution.Amount
+}
+
+// allocate returns the dollar amount of the flow that goes to every holding, given their current values.
+// Withdrawals never sell more than a holding is worth.
+func (contribution *Contribution) allocate(flow float64, values map[string]float64, targets map[string]float64) map[string]float64 {
+	amounts := make(map[string]float64)
+
+	var balance float64
+	for _, value := range values {
+		balance += value
+	}
+
+	if flow < 0 && -flow >= balance {
+		for symbol, value := range values {
+			amounts[symbol] = -value
+		}
+		return amounts
+	}
+
+	if contribution.Allocation == AllocateUnderweight {
+		// Distribute the flow by how far every holding is from its target after the flow, in the
+		// direction of the flow, and split what is left over by the target allocation
+		gaps := make(map[string]float64)
+		var total float64
+
+		for symbol, target := range targets {
+			gap := (balance+flow)*target/100 - values[symbol]
+			if flow < 0 {
+				gap = -gap
+			}
+			if gap > 0 {
+				gaps[symbol] = gap
+				total += gap
+			}
+		}
+
+		remaining := math.Abs(flow)
+		if total > 0 {
+			filled := math.Min(remaining, total)
+			for symbol, gap := range gaps {
+				amounts[symbol] = math.Copysign(filled*gap/total, flow)
+			}
+			remaining -= filled
+		}
+
+		for symbol, target := range targets {
+			amounts[symbol] += math.Copysign(remaining*target/100, flow)
+		}
+	} else {
+		for symbol, target := range targets {
+			amounts[symbol] = flow * target / 100
+		}
+	}
+
+	// A holding may be worth less than its share of a withdrawal after drifting from its target. What it
+	// cannot cover is taken from the other holdings in proportion to what is left of them, which always
+	// suffices since the withdrawal is less than the balance.
+	var shortfall float64
+	for symbol, amount := range amounts {
+		if amount < -values[symbol] {
+			shortfall += -values[symbol] - amount
+			amounts[symbol] = -values[symbol]
+		}
+	}
+
+	if shortfall > 0 {
+		var left float64
+		for symbol, value := range values {
+			left += value + amounts[symbol]
+		}
+
+		for symbol, value := range values {
+			if rest := value + amounts[symbol]; rest > 0 {
+				amounts[symbol] -= shortfall * rest / left
+			}
+		}
+	}
+
+	return amounts
+}
+
+// computeContributionResult sums the flows of the backtest and computes its money-weighted return
+func computeContributionResult(balances []Historic, flows []CashFlow, endDate time.Time, cagr float64) *ContributionResult {
+	result := &ContributionResult{
+		Balances:     balances,
+		Flows:        flows,
+		TimeWeighted: cagr,
+	}
+
+	for _, flow := range flows {
+		// Flows are from the investor's point of view, so contributions are negative
+		if flow.Amount < 0 {
+			result.Contributed -= flow.Amount
+		} else {
+			result.Withdrawn += flow.Amount
+		}
+	}
+
+	result.EndingBalance = balances[len(balances)-1].Close
+
+	cashFlows := append(append([]CashFlow{}, flows...), CashFlow{Date: endDate, Amount: result.EndingBalance})
+
+	result.MoneyWeighted = math.NaN()
+	if xirr, err := computeXIRR(cashFlows); err == nil {
+		result.MoneyWeighted = xirr
+	}
+
+	return result
+}
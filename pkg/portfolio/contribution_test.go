@@ -0,0 +1,83 @@
+package portfolio
+
+import (
+	"math"
+	"testing"
+)
+
+func TestContributionAllocate(t *testing.T) {
+	tests := []struct {
+		name       string
+		allocation ContributionAllocation
+		flow       float64
+		values     map[string]float64
+		targets    map[string]float64
+		amounts    map[string]float64
+	}{
+		{
+			name:       "contribution",
+			allocation: AllocateTarget,
+			flow:       100,
+			values:     map[string]float64{"VTI": 500, "BND": 500},
+			targets:    map[string]float64{"VTI": 60, "BND": 40},
+			amounts:    map[string]float64{"VTI": 60, "BND": 40},
+		},
+		{
+			// VTI covers 100 of its 200, and BND, the only holding left, the other 100
+			name:       "shortfall",
+			allocation: AllocateTarget,
+			flow:       -400,
+			values:     map[string]float64{"VTI": 100, "BND": 900},
+			targets:    map[string]float64{"VTI": 50, "BND": 50},
+			amounts:    map[string]float64{"VTI": -100, "BND": -300},
+		},
+		{
+			// VTI covers 50 of its 100, and the other 50 is split by the 250 left of BND and the 300 left of VXUS
+			name:       "shortfall split",
+			allocation: AllocateTarget,
+			flow:       -500,
+			values:     map[string]float64{"VTI": 50, "BND": 450, "VXUS": 500},
+			targets:    map[string]float64{"VTI": 20, "BND": 40, "VXUS": 40},
+			amounts:    map[string]float64{"VTI": -50, "BND": -200 - 50*250.0/550, "VXUS": -200 - 50*300.0/550},
+		},
+		{
+			// After the withdrawal both holdings should be worth 300, so only BND is sold
+			name:       "underweight",
+			allocation: AllocateUnderweight,
+			flow:       -400,
+			values:     map[string]float64{"VTI": 100, "BND": 900},
+			targets:    map[string]float64{"VTI": 50, "BND": 50},
+			amounts:    map[string]float64{"VTI": 0, "BND": -400},
+		},
+		{
+			name:       "everything",
+			allocation: AllocateTarget,
+			flow:       -1500,
+			values:     map[string]float64{"VTI": 100, "BND": 900},
+			targets:    map[string]float64{"VTI": 50, "BND": 50},
+			amounts:    map[string]float64{"VTI": -100, "BND": -900},
+		},
+	}
+
+	for _, test := range tests {
+		contribution := &Contribution{Amount: test.flow, Frequency: ContributeMonthly, Allocation: test.allocation}
+		amounts := contribution.allocate(test.flow, test.values, test.targets)
+
+		var total float64
+		for symbol, want := range test.amounts {
+			if math.Abs(amounts[symbol]-want) > 1e-9 {
+				t.Errorf("%s: %s gets %v, want %v", test.name, symbol, amounts[symbol], want)
+			}
+			total += amounts[symbol]
+		}
+
+		// A withdrawal of more than the balance sells everything
+		var balance float64
+		for _, value := range test.values {
+			balance += value
+		}
+		if want := math.Max(test.flow, -balance); math.Abs(total-want) > 1e-9 {
+			t.Errorf("%s: allocated %v in total, want %v", test.name, total, want)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package portfolio
+
+import (
+	"math"
+	"sort"
+)
+
+// RedundantCorrelation is the correlation above which two holdings are considered redundant
+const RedundantCorrelation = 0.95
+
+// CorrelationMatrix contains the pairwise correlation of the monthly returns of the holdings of a portfolio
+type CorrelationMatrix struct {
+	Symbols   []string
+	Months    int
+	Values    [][]float64
+	Redundant []*CorrelatedPair
+}
+
+// CorrelatedPair defines two holdings whose returns are highly correlated
+type CorrelatedPair struct {
+	First       string
+	Second      string
+	Correlation float64
+}
+
+// ComputeCorrelation computes the correlation matrix of the holdings over the last months of the
+// backtest, or over the entire backtest if months is zero or exceeds the history
+func (result *PerformanceResult) ComputeCorrelation(months int) *CorrelationMatrix {
+	symbols := make([]string, 0)
+	returns := make(map[string][]float64)
+
+	for _, symbol := range result.Portfolio.Symbols {
+		historic, ok := result.Assets[symbol]
+		if _, seen := returns[symbol]; !ok || seen {
+			continue
+		}
+
+		assetReturns := computeMonthlyReturns(historic)
+		if months > 0 && months < len(assetReturns) {
+			assetReturns = assetReturns[len(assetReturns)-months:]
+		}
+
+		symbols = append(symbols, symbol)
+		returns[symbol] = assetReturns
+	}
+
+	matrix := &CorrelationMatrix{
+		Symbols:   symbols,
+		Values:    make([][]float64, len(symbols)),
+		Redundant: make([]*CorrelatedPair, 0),
+	}
+
+	if len(symbols) > 0 {
+		matrix.Months = len(returns[symbols[0]])
+	}
+
+	for i, first := range symbols {
+		matrix.Values[i] = make([]float64, len(symbols))
+
+		for j, second := range symbols {
+			if i == j {
+				matrix.Values[i][j] = 1
+				continue
+			}
+
+			correlation := computeCorrelation(returns[first], returns[second])
+			matrix.Values[i][j] = correlation
+
+			if j > i && correlation >= RedundantCorrelation {
+				matrix.Redundant = append(matrix.Redundant, &CorrelatedPair{
+					First:       first,
+					Second:      second,
+					Correlation: correlation,
+				})
+			}
+		}
+	}
+
+	sort.SliceStable(matrix.Redundant, func(i, j int) bool {
+		return matrix.Redundant[i].Correlation > matrix.Redundant[j].Correlation
+	})
+
+	return matrix
+}
+
+// computeCorrelation returns the Pearson correlation of the last months both series cover. A constant series
+// does not move with any other, so its correlation is 0.
+func computeCorrelation(a []float64, b []float64) float64 {
+	n := len(a)
+	if len(b) < n {
+		n = len(b)
+	}
+	if n < 2 {
+		return math.NaN()
+	}
+	a = a[len(a)-n:]
+	b = b[len(b)-n:]
+
+	meanA, deviationA := computeMeanAndDeviation(a)
+	meanB, deviationB := computeMeanAndDeviation(b)
+	if deviationA < minDeviation || deviationB < minDeviation {
+		return 0
+	}
+
+	var covariance float64
+	for i := 0; i < n; i++ {
+		covariance += (a[i] - meanA) * (b[i] - meanB)
+	}
+	covariance /= float64(n)
+
+	return math.Max(-1, math.Min(1, covariance/(deviationA*deviationB)))
+}
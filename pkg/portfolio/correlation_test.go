@@ -0,0 +1,62 @@
+package portfolio
+
+import (
+	"math"
+	"testing"
+)
+
+func TestComputeCorrelation(t *testing.T) {
+	tests := []struct {
+		name string
+		a    []float64
+		b    []float64
+		want float64
+	}{
+		{"correlated", []float64{1, 2, 3, 4}, []float64{2, 4, 6, 8}, 1},
+		{"anti-correlated", []float64{1, -1, 2, -2}, []float64{-3, 3, -6, 6}, -1},
+		// Both series move up in one month out of two, and in opposite directions in the other
+		{"uncorrelated", []float64{1, 1, -1, -1}, []float64{1, -1, 1, -1}, 0},
+		{"constant", []float64{1, 1, 1, 1}, []float64{1, 2, 3, 4}, 0},
+		// The longer series is aligned on its last months, so its first month is left out
+		{"unequal lengths", []float64{5, 1, 2, 3}, []float64{3, 2, 1}, -1},
+		{"too short", []float64{1}, []float64{1}, math.NaN()},
+	}
+
+	for _, test := range tests {
+		got := computeCorrelation(test.a, test.b)
+		if !equalOrNaN(got, test.want, 1e-9) {
+			t.Errorf("%s: correlation = %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestComputeCorrelationMatrix(t *testing.T) {
+	// BND stays flat, while VTI and VOO move together
+	result := NewPerformanceResult()
+	result.Portfolio = newTestPortfolio(map[string]float64{"BND": 20, "VOO": 40, "VTI": 40})
+	result.Portfolio.Symbols = append(result.Portfolio.Symbols, "VTI")
+	result.Assets = map[string][]Historic{
+		"BND": testHistoric(100, 0, 0, 0, 0),
+		"VOO": testHistoric(100, 2, -1, 3, -2),
+		"VTI": testHistoric(100, 2, -1, 3, -2),
+	}
+
+	matrix := result.ComputeCorrelation(0)
+
+	if len(matrix.Symbols) != 3 || matrix.Months != 4 {
+		t.Fatalf("Matrix of %v over %d months, want 3 symbols over 4 months", matrix.Symbols, matrix.Months)
+	}
+	for i := range matrix.Values {
+		for j, value := range matrix.Values[i] {
+			if math.IsNaN(value) || math.IsInf(value, 0) {
+				t.Errorf("Correlation of %s and %s = %v", matrix.Symbols[i], matrix.Symbols[j], value)
+			}
+		}
+	}
+	if matrix.Values[0][1] != 0 || matrix.Values[0][0] != 1 {
+		t.Errorf("Correlations of the flat BND = %v, want 1 with itself and 0 with the others", matrix.Values[0])
+	}
+	if len(matrix.Redundant) != 1 || matrix.Redundant[0].First != "VOO" || matrix.Redundant[0].Second != "VTI" {
+		t.Errorf("Redundant pairs = %+v, want VOO and VTI", matrix.Redundant)
+	}
+}
@@ -0,0 +1,110 @@
+package portfolio
+
+import (
+	"sort"
+	"time"
+)
+
+// maxDrawdowns is the number of worst drawdowns kept in a performance result
+const maxDrawdowns = 5
+
+// Drawdown describes a decline of a portfolio from a peak balance to a trough, until the balance
+// recovers to the peak again. The depth is a negative percentage of the peak. The length of a
+// drawdown that has not recovered yet runs until the end of the analysis period.
+type Drawdown struct {
+	Depth        float64
+	PeakDate     time.Time
+	TroughDate   time.Time
+	RecoveryDate time.Time
+	Recovered    bool
+	Decline      int
+	Length       int
+}
+
+// computeDrawdownSeries returns the underwater curve, i.e. the drawdown of the balance from its
+// running peak at the close of every month, in percent
+func computeDrawdownSeries(historic []Historic) []float64 {
+	series := make([]float64, len(historic))
+	if len(historic) == 0 {
+		return series
+	}
+
+	peak := historic[0].Open
+	for i, month := range historic {
+		if month.Close > peak {
+			peak = month.Close
+		}
+		if peak > 0 {
+			series[i] = (month.Close - peak) / peak * 100
+		}
+	}
+
+	return series
+}
+
+// computeDrawdowns finds every peak-to-trough decline of the monthly balances and returns the
+// worst ones, deepest first. The opening balance of the first month is the initial peak.
+func computeDrawdowns(historic []Historic, n int) []*Drawdown {
+	drawdowns := make([]*Drawdown, 0)
+	if len(historic) == 0 {
+		return drawdowns
+	}
+
+	peak := historic[0].Open
+	peakIndex := -1
+	var current *Drawdown
+
+	for i, month := range historic {
+		if month.Close >= peak {
+			if current != nil {
+				current.RecoveryDate = month.Date
+				current.Recovered = true
+				current.Length = i - peakIndex
+				drawdowns = append(drawdowns, current)
+				current = nil
+			}
+
+			peak = month.Close
+			peakIndex = i
+			continue
+		}
+
+		depth := (month.Close - peak) / peak * 100
+
+		if current == nil {
+			current = &Drawdown{
+				PeakDate: peakDate(historic, peakIndex),
+			}
+		}
+
+		if depth < current.Depth {
+			current.Depth = depth
+			current.TroughDate = month.Date
+			current.Decline = i - peakIndex
+		}
+	}
+
+	if current != nil {
+		current.Length = len(historic) - 1 - peakIndex
+		drawdowns = append(drawdowns, current)
+	}
+
+	sort.SliceStable(drawdowns, func(i, j int) bool {
+		return drawdowns[i].Depth < drawdowns[j].Depth
+	})
+
+	if len(drawdowns) > n {
+		drawdowns = drawdowns[:n]
+	}
+
+	return drawdowns
+}
+
+// peakDate returns the date of the month at whose close the balance peaked, or the start of the
+// first month if the peak is the opening balance
+func peakDate(historic []Historic, index int) time.Time {
+	if index < 0 {
+		return historic[0].Date
+	}
+	return historic[index].Date
+}
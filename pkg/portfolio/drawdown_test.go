@@ -0,0 +1,79 @@
+package portfolio
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestComputeDrawdowns(t *testing.T) {
+	tests := []struct {
+		name       string
+		open       float64
+		closes     []float64
+		underwater []float64
+		drawdowns  []Drawdown
+	}{
+		{
+			name:       "recovered and open",
+			open:       100,
+			closes:     []float64{110, 99, 88, 110, 121, 108.9},
+			underwater: []float64{0, -10, -20, 0, 0, -10},
+			drawdowns: []Drawdown{
+				{Depth: -20, PeakDate: month(0), TroughDate: month(2), RecoveryDate: month(3), Recovered: true, Decline: 2, Length: 3},
+				{Depth: -10, PeakDate: month(4), TroughDate: month(5), Decline: 1, Length: 1},
+			},
+		},
+		{
+			// The opening balance is the first peak, dated at the start of the first month
+			name:       "below the open",
+			open:       100,
+			closes:     []float64{90, 95, 100},
+			underwater: []float64{-10, -5, 0},
+			drawdowns: []Drawdown{
+				{Depth: -10, PeakDate: month(0), TroughDate: month(0), RecoveryDate: month(2), Recovered: true, Decline: 1, Length: 3},
+			},
+		},
+		{
+			name:       "no drawdown",
+			open:       100,
+			closes:     []float64{100, 101, 102},
+			underwater: []float64{0, 0, 0},
+		},
+	}
+
+	for _, test := range tests {
+		historic := make([]Historic, len(test.closes))
+		for i, close := range test.closes {
+			historic[i] = Historic{Date: month(i), Open: test.open, Close: close}
+		}
+
+		underwater := computeDrawdownSeries(historic)
+		for i := range underwater {
+			if math.Abs(underwater[i]-test.underwater[i]) > 1e-9 {
+				t.Errorf("%s: underwater = %v, want %v", test.name, underwater, test.underwater)
+				break
+			}
+		}
+
+		drawdowns := computeDrawdowns(historic, maxDrawdowns)
+		if len(drawdowns) != len(test.drawdowns) {
+			t.Fatalf("%s: %d drawdowns, want %d", test.name, len(drawdowns), len(test.drawdowns))
+		}
+		for i, want := range test.drawdowns {
+			got := *drawdowns[i]
+			if math.Abs(got.Depth-want.Depth) > 1e-9 {
+				t.Errorf("%s: depth of drawdown %d = %v, want %v", test.name, i, got.Depth, want.Depth)
+			}
+			got.Depth = want.Depth
+			if got != want {
+				t.Errorf("%s: drawdown %d = %+v, want %+v", test.name, i, got, want)
+			}
+		}
+	}
+}
+
+// month returns the first day of the month the given number of months after January 2020
+func month(i int) time.Time {
+	return time.Date(2020, time.January+time.Month(i), 1, 0, 0, 0, 0, time.UTC)
+}
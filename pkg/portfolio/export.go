@@ -0,0 +1,559 @@
+package portfolio
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Plain-text accounting formats
+const (
+	FormatBeancount string = "beancount"
+	FormatLedger    string = "ledger"
+)
+
+const (
+	currency              = "USD"
+	accountOpening        = "Equity:Opening-Balances"
+	accountContributions  = "Equity:Contributions"
+	accountAdjustments    = "Equity:Adjustments"
+	accountProfileCash    = "Assets:Cash"
+	accountInvestments    = "Assets:Investments"
+	accountDividends      = "Income:Dividends"
+	accountInterest       = "Income:Interest"
+	accountCapitalGains   = "Income:Capital-Gains"
+	accountFees           = "Expenses:Fees"
+	exportTolerance       = 0.01
+	exportQuantityDigits  = 6
+	exportAmountDigits    = 2
+	exportAccountFallback = "Portfolio"
+)
+
+// posting is a line of a plain-text accounting transaction. An empty commodity leaves the
+// amount to be inferred by the accounting tool.
+type posting struct {
+	account   string
+	quantity  float64
+	commodity string
+	cost      float64
+	costDate  time.Time
+	reduce    bool
+	price     float64
+}
+
+// exporter writes the directives of a profile in beancount or ledger syntax, keeping track of
+// the resulting account balances so that they can be checked against the profile
+type exporter struct {
+	format   string
+	writer   *bytes.Buffer
+	balances map[string]map[string]float64
+	accounts []string
+}
+
+// Export writes the commodities, accounts, opening lots, ledger transactions, latest prices
+// and balance assertions of the profile in the given plain-text accounting format. The
+// profile must have been refreshed, and the exported balances valued at the latest prices
+// are verified to match the value of the profile before anything is written.
+func (profile *Profile) Export(writer io.Writer, format string, date time.Time) error {
+	if format != FormatBeancount && format != FormatLedger {
+		return fmt.Errorf("Unknown export format: %s", format)
+	}
+
+	if profile.Status == nil {
+		return fmt.Errorf("Profile %s has not been refreshed", profile.Name)
+	}
+
+	e := &exporter{
+		format:   format,
+		writer:   &bytes.Buffer{},
+		balances: make(map[string]map[string]float64),
+	}
+
+	opening := profile.openingDate(date)
+
+	e.writeHeader()
+	e.writeCommodities(profile, opening)
+	e.writeAccounts(profile, opening)
+
+	if profile.Cash != 0 {
+		e.writeTransaction(opening, "Opening balance", []posting{
+			{account: accountProfileCash, quantity: profile.Cash, commodity: currency},
+			{account: accountOpening},
+		})
+	}
+
+	for _, portfolio := range profile.Portfolios {
+		err := e.writePortfolio(portfolio, opening, date)
+		if err != nil {
+			return err
+		}
+	}
+
+	e.writePrices(profile, date)
+	e.writeBalances(date)
+
+	err := e.verify(profile)
+	if err != nil {
+		return err
+	}
+
+	_, err = e.writer.WriteTo(writer)
+	return err
+}
+
+// openingDate returns the day before the earliest lot or transaction in the profile
+func (profile *Profile) openingDate(date time.Time) time.Time {
+	earliest := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+
+	for _, portfolio := range profile.Portfolios {
+		for _, transaction := range portfolio.Transactions {
+			if transaction.Date.Before(earliest) {
+				earliest = transaction.Date
+			}
+		}
+
+		for _, holding := range portfolio.Holdings {
+			for _, lot := range holding.Lots {
+				if lot.Date.Before(earliest) {
+					earliest = lot.Date
+				}
+			}
+		}
+	}
+
+	return earliest.AddDate(0, 0, -1)
+}
+
+func (e *exporter) writeHeader() {
+	if e.format == FormatBeancount {
+		fmt.Fprintf(e.writer, "option \"operating_currency\" \"%s\"\n", currency)
+		fmt.Fprintf(e.writer, "option \"booking_method\" \"FIFO\"\n\n")
+	}
+}
+
+func (e *exporter) writeCommodities(profile *Profile, date time.Time) {
+	seen := make(map[string]bool)
+	symbols := make([]string, 0)
+
+	for _, portfolio := range profile.Portfolios {
+		for _, symbol := range portfolio.exportedSymbols() {
+			if !seen[symbol] {
+				seen[symbol] = true
+				symbols = append(symbols, symbol)
+			}
+		}
+	}
+
+	for _, symbol := range symbols {
+		if e.format == FormatBeancount {
+			fmt.Fprintf(e.writer, "%s commodity %s\n", date.Format(dateFormat), e.commodity(symbol))
+			fmt.Fprintf(e.writer, "  ticker: \"%s\"\n", symbol)
+		} else {
+			fmt.Fprintf(e.writer, "commodity %s\n", e.commodity(symbol))
+			fmt.Fprintf(e.writer, "    note %s\n", symbol)
+		}
+	}
+
+	fmt.Fprintln(e.writer)
+}
+
+func (e *exporter) writeAccounts(profile *Profile, date time.Time) {
+	accounts := []string{accountOpening, accountContributions, accountAdjustments, accountProfileCash}
+
+	for _, portfolio := range profile.Portfolios {
+		name := accountName(portfolio.Name)
+		accounts = append(accounts,
+			accountInvestments+":"+name,
+			accountInvestments+":"+name+":Cash",
+			accountDividends+":"+name,
+			accountInterest+":"+name,
+			accountCapitalGains+":"+name,
+			accountFees+":"+name)
+	}
+
+	for _, account := range accounts {
+		if e.format == FormatBeancount {
+			fmt.Fprintf(e.writer, "%s open %s\n", date.Format(dateFormat), account)
+		} else {
+			fmt.Fprintf(e.writer, "account %s\n", account)
+		}
+	}
+
+	fmt.Fprintln(e.writer)
+}
+
+// writePortfolio writes the opening lots that the ledger of the portfolio does not account
+// for, followed by the ledger transactions themselves
+func (e *exporter) writePortfolio(portfolio *Portfolio, opening time.Time, date time.Time) error {
+	name := accountName(portfolio.Name)
+	investments := accountInvestments + ":" + name
+	cash := investments + ":Cash"
+
+	traded := make(map[string]float64)
+	hasTrades := make(map[string]bool)
+	adjustments := make([]posting, 0)
+	var netCash float64
+
+	firstPrice := make(map[string]float64)
+
+	for _, transaction := range portfolio.Transactions {
+		if _, ok := firstPrice[transaction.Symbol]; !ok && transaction.Quantity != 0 {
+			firstPrice[transaction.Symbol] = math.Abs(transaction.Amount) / transaction.Quantity
+		}
+
+		switch transaction.Type {
+		case TransactionBuy:
+			traded[transaction.Symbol] += transaction.Quantity
+			hasTrades[transaction.Symbol] = true
+		case TransactionSell:
+			traded[transaction.Symbol] -= transaction.Quantity
+			hasTrades[transaction.Symbol] = true
+		}
+		netCash += round(transaction.Amount, exportAmountDigits)
+	}
+
+	for _, symbol := range portfolio.exportedSymbols() {
+		holding, ok := portfolio.Holdings[symbol]
+		if !ok {
+			// The symbol was sold off before the positions were last imported
+			holding = NewHolding(symbol, 0, 0, 0)
+		}
+		commodity := e.commodity(symbol)
+
+		if !hasTrades[symbol] {
+			// Without a trading history, the lots of the holding are its opening balance
+			var quantity, basis float64
+			for _, lot := range holding.Lots {
+				e.writeOpeningLot(investments, commodity, lot.Quantity, lot.CostBasis, lot.Date)
+				quantity += lot.Quantity
+				basis += lot.CostBasis
+			}
+
+			e.writeOpeningLot(investments, commodity, holding.Quantity-quantity, holding.CostBasis-basis, opening)
+			continue
+		}
+
+		// Shares held before the first recorded trade are opened at the average cost, or at the price of the
+		// first trade if the holding has no basis
+		remaining := holding.Quantity - traded[symbol]
+		averageCost := firstPrice[symbol]
+		if holding.Quantity != 0 && holding.CostBasis != 0 {
+			averageCost = holding.CostBasis / holding.Quantity
+		}
+		if averageCost == 0 && round(remaining, exportQuantityDigits) > 0 {
+			return fmt.Errorf("Unknown cost of the opening shares of %s in %s", symbol, portfolio.Name)
+		}
+		e.writeOpeningLot(investments, commodity, remaining, remaining*averageCost, opening)
+
+		// The ledger holds more shares than the position, e.g. when the latest positions are not imported yet
+		if round(remaining, exportQuantityDigits) < 0 {
+			adjustments = append(adjustments, posting{account: investments, quantity: remaining, commodity: commodity, reduce: true})
+		}
+	}
+
+	openingCash := round(portfolio.Cash-netCash, exportAmountDigits)
+	if openingCash != 0 {
+		e.writeTransaction(opening, fmt.Sprintf("Opening cash for %s", portfolio.Name), []posting{
+			{account: cash, quantity: openingCash, commodity: currency},
+			{account: accountOpening},
+		})
+	}
+
+	for _, transaction := range portfolio.Transactions {
+		e.writeLedgerTransaction(transaction, name)
+	}
+
+	for _, adjustment := range adjustments {
+		e.writeTransaction(date, fmt.Sprintf("Adjustment of %s to the reported position", adjustment.commodity), []posting{
+			adjustment,
+			{account: accountAdjustments},
+		})
+	}
+
+	return nil
+}
+
+// exportedSymbols returns the symbols of the holdings followed by the symbols only found in buys and sells
+func (portfolio *Portfolio) exportedSymbols() []string {
+	symbols := make([]string, len(portfolio.Symbols))
+	copy(symbols, portfolio.Symbols)
+
+	seen := make(map[string]bool)
+	for _, symbol := range symbols {
+		seen[symbol] = true
+	}
+
+	for _, transaction := range portfolio.Transactions {
+		if transaction.Type != TransactionBuy && transaction.Type != TransactionSell {
+			continue
+		}
+
+		if !seen[transaction.Symbol] {
+			seen[transaction.Symbol] = true
+			symbols = append(symbols, transaction.Symbol)
+		}
+	}
+
+	return symbols
+}
+
+func (e *exporter) writeOpeningLot(account string, commodity string, quantity float64, basis float64, date time.Time) {
+	quantity = round(quantity, exportQuantityDigits)
+	if quantity <= 0 {
+		return
+	}
+
+	e.writeTransaction(date, fmt.Sprintf("Opening lot of %s", commodity), []posting{
+		{account: account, quantity: quantity, commodity: commodity, cost: basis / quantity, costDate: date},
+		{account: accountOpening},
+	})
+}
+
+func (e *exporter) writeLedgerTransaction(transaction *Transaction, name string) {
+	investments := accountInvestments + ":" + name
+	cash := investments + ":Cash"
+	amount := round(transaction.Amount, exportAmountDigits)
+	commodity := e.commodity(transaction.Symbol)
+
+	var postings []posting
+
+	switch transaction.Type {
+	case TransactionBuy:
+		if transaction.Quantity == 0 {
+			return
+		}
+		cost := math.Abs(amount) / transaction.Quantity
+		postings = []posting{
+			{account: investments, quantity: transaction.Quantity, commodity: commodity, cost: cost, costDate: transaction.Date},
+			{account: cash, quantity: amount, commodity: currency},
+		}
+		if amount == 0 {
+			postings[1] = posting{account: accountAdjustments}
+		}
+
+	case TransactionSell:
+		if transaction.Quantity == 0 {
+			return
+		}
+		price := math.Abs(amount) / transaction.Quantity
+		postings = []posting{
+			{account: investments, quantity: -transaction.Quantity, commodity: commodity, reduce: true, price: price},
+			{account: cash, quantity: amount, commodity: currency},
+			{account: accountCapitalGains + ":" + name},
+		}
+
+	case TransactionDividend:
+		postings = []posting{{account: cash, quantity: amount, commodity: currency}, {account: accountDividends + ":" + name}}
+	case TransactionInterest:
+		postings = []posting{{account: cash, quantity: amount, commodity: currency}, {account: accountInterest + ":" + name}}
+	case TransactionFee:
+		postings = []posting{{account: cash, quantity: amount, commodity: currency}, {account: accountFees + ":" + name}}
+	case TransactionDeposit, TransactionWithdrawal:
+		postings = []posting{{account: cash, quantity: amount, commodity: currency}, {account: accountContributions}}
+	default:
+		postings = []posting{{account: cash, quantity: amount, commodity: currency}, {account: accountAdjustments}}
+	}
+
+	if amount == 0 && transaction.Type != TransactionBuy && transaction.Type != TransactionSell {
+		return
+	}
+
+	e.writeTransaction(transaction.Date, transaction.Description, postings)
+}
+
+func (e *exporter) writeTransaction(date time.Time, narration string, postings []posting) {
+	narration = strings.Replace(narration, "\"", "'", -1)
+
+	if e.format == FormatBeancount {
+		fmt.Fprintf(e.writer, "%s * \"%s\"\n", date.Format(dateFormat), narration)
+	} else {
+		fmt.Fprintf(e.writer, "%s * %s\n", date.Format(dateFormat), narration)
+	}
+
+	for _, p := range postings {
+		if p.commodity == "" {
+			fmt.Fprintf(e.writer, "  %s\n", p.account)
+			continue
+		}
+
+		e.track(p.account, p.commodity, p.quantity)
+
+		line := fmt.Sprintf("  %-50s %s %s", p.account, formatNumber(p.quantity, exportQuantityDigits), p.commodity)
+
+		if p.costDate != (time.Time{}) {
+			if e.format == FormatBeancount {
+				line += fmt.Sprintf(" {%s %s, %s}", formatNumber(p.cost, exportQuantityDigits), currency, p.costDate.Format(dateFormat))
+			} else {
+				line += fmt.Sprintf(" {%s %s} [%s]", formatNumber(p.cost, exportQuantityDigits), currency, p.costDate.Format(dateFormat))
+			}
+		} else if p.reduce && e.format == FormatBeancount {
+			line += " {}"
+		}
+
+		if p.price != 0 {
+			line += fmt.Sprintf(" @ %s %s", formatNumber(p.price, exportQuantityDigits), currency)
+		}
+
+		fmt.Fprintln(e.writer, line)
+	}
+
+	fmt.Fprintln(e.writer)
+}
+
+func (e *exporter) writePrices(profile *Profile, date time.Time) {
+	seen := make(map[string]bool)
+
+	for _, portfolio := range profile.Portfolios {
+		for _, symbol := range portfolio.Symbols {
+			holding := portfolio.Holdings[symbol]
+			if seen[symbol] || holding.Quote.RegularMarketPrice == 0 {
+				continue
+			}
+			seen[symbol] = true
+
+			price := formatNumber(holding.Quote.RegularMarketPrice, exportQuantityDigits)
+			if e.format == FormatBeancount {
+				fmt.Fprintf(e.writer, "%s price %s %s %s\n", date.Format(dateFormat), e.commodity(symbol), price, currency)
+			} else {
+				fmt.Fprintf(e.writer, "P %s %s %s %s\n", date.Format(dateFormat), e.commodity(symbol), price, currency)
+			}
+		}
+	}
+
+	fmt.Fprintln(e.writer)
+}
+
+func (e *exporter) writeBalances(date time.Time) {
+	// Beancount checks balances at the beginning of the day, so assert them the day after
+	after := date.AddDate(0, 0, 1)
+
+	for _, account := range e.accounts {
+		commodities := make([]string, 0)
+		for commodity := range e.balances[account] {
+			commodities = append(commodities, commodity)
+		}
+		sort.Strings(commodities)
+
+		for _, commodity := range commodities {
+			digits := exportQuantityDigits
+			if commodity == currency {
+				digits = exportAmountDigits
+			}
+			quantity := formatNumber(e.balances[account][commodity], digits)
+
+			if e.format == FormatBeancount {
+				fmt.Fprintf(e.writer, "%s balance %s %s %s\n", after.Format(dateFormat), account, quantity, commodity)
+			} else {
+				fmt.Fprintf(e.writer, "%s * Balance assertion\n", date.Format(dateFormat))
+				fmt.Fprintf(e.writer, "  %-50s 0 %s = %s %s\n\n", account, commodity, quantity, commodity)
+			}
+		}
+	}
+}
+
+func (e *exporter) track(account string, commodity string, quantity float64) {
+	if !strings.HasPrefix(account, "Assets:") {
+		return
+	}
+
+	balance, ok := e.balances[account]
+	if !ok {
+		balance = make(map[string]float64)
+		e.balances[account] = balance
+		e.accounts = append(e.accounts, account)
+	}
+
+	balance[commodity] = round(balance[commodity]+quantity, exportQuantityDigits)
+}
+
+// verify values the exported balances at the latest prices and compares them with the profile
+func (e *exporter) verify(profile *Profile) error {
+	prices := make(map[string]float64)
+	for _, portfolio := range profile.Portfolios {
+		for symbol, holding := range portfolio.Holdings {
+			prices[e.commodity(symbol)] = holding.Quote.RegularMarketPrice
+		}
+	}
+	prices[currency] = 1
+
+	var value float64
+	for _, balance := range e.balances {
+		for commodity, quantity := range balance {
+			value += quantity * prices[commodity]
+		}
+	}
+
+	if math.Abs(value-profile.Status.Value) > exportTolerance {
+		return fmt.Errorf("Exported balances are worth %.2f, but the profile is worth %.2f", value, profile.Status.Value)
+	}
+
+	return nil
+}
+
+// commodity converts a symbol into a valid commodity name, e.g. "^GSPC" becomes "X-GSPC".
+// Ledger requires commodities with anything but letters to be quoted.
+func (e *exporter) commodity(symbol string) string {
+	if e.format == FormatLedger {
+		for _, r := range symbol {
+			if !unicode.IsLetter(r) {
+				return strconv.Quote(symbol)
+			}
+		}
+		return symbol
+	}
+
+	name := []rune(strings.ToUpper(symbol))
+	for i, r := range name {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '.' && r != '-' && r != '_' && r != '\'' {
+			name[i] = '-'
+		}
+	}
+
+	commodity := strings.TrimRight(string(name), "-._'")
+	if commodity == "" || !unicode.IsLetter(rune(commodity[0])) {
+		commodity = "X" + commodity
+	}
+
+	return commodity
+}
+
+// accountName converts a portfolio name into a valid account component, e.g. "my roth" becomes "My-Roth"
+func accountName(name string) string {
+	words := strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	for i, word := range words {
+		runes := []rune(word)
+		runes[0] = unicode.ToUpper(runes[0])
+		words[i] = string(runes)
+	}
+
+	account := strings.Join(words, "-")
+	if account == "" || !unicode.IsLetter(rune(account[0])) {
+		account = exportAccountFallback + account
+	}
+
+	return account
+}
+
+func formatNumber(value float64, digits int) string {
+	formatted := strconv.FormatFloat(round(value, digits), 'f', digits, 64)
+	if digits > exportAmountDigits && strings.Contains(formatted, ".") {
+		formatted = strings.TrimRight(formatted, "0")
+		formatted = strings.TrimSuffix(formatted, ".")
+	}
+
+	return formatted
+}
+
+func round(value float64, digits int) float64 {
+	scale := math.Pow(10, float64(digits))
+	return math.Round(value*scale) / scale
+}
@@ -0,0 +1,183 @@
+package portfolio
+
+import (
+	"bytes"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+var exportDate = time.Date(2023, 10, 2, 0, 0, 0, 0, time.UTC)
+
+func TestExport(t *testing.T) {
+	for _, format := range []string{FormatBeancount, FormatLedger} {
+		profile := newExportProfile()
+
+		var buffer bytes.Buffer
+		err := profile.Export(&buffer, format, exportDate)
+		if err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		output := buffer.String()
+
+		// 1000 of profile cash, 500 of cash, 15 VTI at 200 and 20 BND at 70 in the taxable account,
+		// and 10 VXUS at 55 in the Roth IRA
+		if profile.Status.Value != 6450 {
+			t.Fatalf("%s: profile is worth %v, want 6450", format, profile.Status.Value)
+		}
+
+		value := valueExport(t, format, output)
+		if math.Abs(value-profile.Status.Value) > exportTolerance {
+			t.Errorf("%s: exported balances are worth %v, want %v", format, value, profile.Status.Value)
+		}
+
+		// 5 VTI were held before the first trade, at the average cost of the holding, and 6 VXUS at the price of
+		// the first trade since that holding has no basis
+		lots := map[string]string{
+			FormatBeancount: "5 VTI {200 USD, 2022-01-02}|20 BND {75 USD, 2022-01-03}|6 VXUS {50 USD, 2022-01-02}",
+			FormatLedger:    "5 VTI {200 USD} [2022-01-02]|20 BND {75 USD} [2022-01-03]|6 VXUS {50 USD} [2022-01-02]",
+		}
+		for _, lot := range strings.Split(lots[format], "|") {
+			if !strings.Contains(output, lot) {
+				t.Errorf("%s: missing opening lot %q", format, lot)
+			}
+		}
+	}
+}
+
+func TestExportFailure(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(profile *Profile)
+	}{
+		{
+			name: "wrong value",
+			change: func(profile *Profile) {
+				profile.Status.Value += 100
+			},
+		},
+		{
+			name: "unknown cost",
+			change: func(profile *Profile) {
+				roth := profile.Portfolios[1]
+				roth.Transactions[0].Amount = 0
+			},
+		},
+		{
+			name: "not refreshed",
+			change: func(profile *Profile) {
+				profile.Status = nil
+			},
+		},
+	}
+
+	for _, test := range tests {
+		for _, format := range []string{FormatBeancount, FormatLedger} {
+			profile := newExportProfile()
+			test.change(profile)
+
+			var buffer bytes.Buffer
+			err := profile.Export(&buffer, format, exportDate)
+			if err == nil {
+				t.Errorf("%s in %s: want an error", test.name, format)
+			}
+			if buffer.Len() != 0 {
+				t.Errorf("%s in %s: a failed export should write nothing, got %d bytes", test.name, format, buffer.Len())
+			}
+		}
+	}
+}
+
+// newExportProfile returns a refreshed profile with opening lots, trades and cash
+func newExportProfile() *Profile {
+	profile := NewProfile("Main")
+	profile.Cash = 1000
+
+	taxable := NewPortfolio()
+	taxable.Name = "Taxable"
+	taxable.Cash = 500
+	addExportHolding(taxable, "VTI", 15, 3000, 200)
+	addExportHolding(taxable, "BND", 20, 1500, 70)
+	taxable.Holdings["BND"].Lots = []*Lot{{Date: time.Date(2022, 1, 3, 0, 0, 0, 0, time.UTC), Quantity: 20, CostBasis: 1500}}
+	taxable.Transactions = []*Transaction{
+		{ID: "1", Date: time.Date(2023, 9, 1, 0, 0, 0, 0, time.UTC), Type: TransactionDeposit, Amount: 3300, Description: "Deposit"},
+		{ID: "2", Date: time.Date(2023, 9, 15, 0, 0, 0, 0, time.UTC), Type: TransactionBuy, Symbol: "VTI", Quantity: 10, Price: 180, Amount: -1800, Description: "Buy VTI"},
+		{ID: "3", Date: time.Date(2023, 9, 20, 0, 0, 0, 0, time.UTC), Type: TransactionDividend, Symbol: "VTI", Amount: 12.34, Description: "Dividend VTI"},
+	}
+
+	roth := NewPortfolio()
+	roth.Name = "Roth IRA"
+	addExportHolding(roth, "VXUS", 10, 0, 55)
+	roth.Transactions = []*Transaction{
+		{ID: "4", Date: time.Date(2023, 9, 1, 0, 0, 0, 0, time.UTC), Type: TransactionBuy, Symbol: "VXUS", Quantity: 4, Price: 50, Amount: -200, Description: "Buy VXUS"},
+	}
+
+	for _, portfolio := range []*Portfolio{taxable, roth} {
+		for _, holding := range portfolio.Holdings {
+			holding.RefreshStatus()
+		}
+		portfolio.RefreshStatus()
+		profile.Portfolios = append(profile.Portfolios, portfolio)
+	}
+
+	profile.RefreshStatus()
+
+	return profile
+}
+
+func addExportHolding(portfolio *Portfolio, symbol string, quantity float64, basis float64, price float64) {
+	holding := NewHolding(symbol, quantity, basis, 0)
+	holding.Quote.RegularMarketPrice = price
+
+	portfolio.Symbols = append(portfolio.Symbols, symbol)
+	portfolio.Holdings[symbol] = holding
+}
+
+var (
+	beancountBalance = regexp.MustCompile(`^\S+ balance \S+ (\S+) (\S+)$`)
+	beancountPrice   = regexp.MustCompile(`^\S+ price (\S+) (\S+) USD$`)
+	ledgerBalance    = regexp.MustCompile(`^  \S+\s+0 \S+ = (\S+) (\S+)$`)
+	ledgerPrice      = regexp.MustCompile(`^P \S+ (\S+) (\S+) USD$`)
+)
+
+// valueExport reads back the balance assertions and prices of an export, and values the balances
+func valueExport(t *testing.T, format string, output string) float64 {
+	balanceLine, priceLine := beancountBalance, beancountPrice
+	if format == FormatLedger {
+		balanceLine, priceLine = ledgerBalance, ledgerPrice
+	}
+
+	prices := map[string]float64{currency: 1}
+	balances := make(map[string]float64)
+
+	for _, line := range strings.Split(output, "\n") {
+		if match := priceLine.FindStringSubmatch(line); match != nil {
+			prices[match[1]] = parseExportNumber(t, match[2])
+		}
+		if match := balanceLine.FindStringSubmatch(line); match != nil {
+			balances[match[2]] += parseExportNumber(t, match[1])
+		}
+	}
+
+	var value float64
+	for commodity, quantity := range balances {
+		price, ok := prices[commodity]
+		if !ok {
+			t.Errorf("%s: no price for %s", format, commodity)
+		}
+		value += quantity * price
+	}
+
+	return value
+}
+
+func parseExportNumber(t *testing.T, value string) float64 {
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return number
+}
@@ -12,9 +12,12 @@ import (
 // Holding defines a position in the portfolio
 type Holding struct {
 	Asset     *Asset
+	CUSIP     string
 	Quantity  float64
 	CostBasis float64
 	Watch     float64
+	Tags      []string
+	Lots      []*Lot
 	Quote     *finance.Quote
 	Status    *HoldingStatus
 }
@@ -33,6 +36,7 @@ func NewHolding(symbol string, quantity float64, basis float64, watch float64) *
 		Quantity:  quantity,
 		CostBasis: basis,
 		Watch:     watch,
+		Lots:      make([]*Lot, 0),
 		Quote:     &finance.Quote{},
 		Status:    &HoldingStatus{},
 	}
@@ -98,10 +102,27 @@ func (holding *Holding) GetHistoricalQuote(date time.Time) (*finance.ChartBar, e
 
 // Clone makes a copy of the Holding, with dynamic quote and status zeroed out
 func (holding *Holding) Clone() *Holding {
+	var tags []string
+	if holding.Tags != nil {
+		tags = append([]string{}, holding.Tags...)
+	}
+
+	var lots []*Lot
+	if holding.Lots != nil {
+		lots = make([]*Lot, 0, len(holding.Lots))
+		for _, lot := range holding.Lots {
+			clone := *lot
+			lots = append(lots, &clone)
+		}
+	}
+
 	return &Holding{
 		Asset:     holding.Asset.Clone(),
+		CUSIP:     holding.CUSIP,
 		Quantity:  holding.Quantity,
 		CostBasis: holding.CostBasis,
+		Tags:      tags,
+		Lots:      lots,
 		Quote:     &finance.Quote{},
 		Status:    &HoldingStatus{},
 	}
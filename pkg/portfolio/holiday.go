@@ -0,0 +1,92 @@
+package portfolio
+
+import "time"
+
+// IsTradingDay returns true if the US stock market is open on the day of the given date, i.e. on weekdays
+// other than the NYSE holidays
+func IsTradingDay(date time.Time) bool {
+	if date.Weekday() == time.Saturday || date.Weekday() == time.Sunday {
+		return false
+	}
+
+	day := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+
+	for _, holiday := range marketHolidays(day.Year()) {
+		if holiday.Equal(day) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// marketHolidays returns the weekdays the NYSE is closed for holidays in the given year
+func marketHolidays(year int) []time.Time {
+	holidays := []time.Time{
+		nthWeekday(year, time.January, time.Monday, 3),
+		nthWeekday(year, time.February, time.Monday, 3),
+		easter(year).AddDate(0, 0, -2),
+		lastWeekday(year, time.May, time.Monday),
+		observedHoliday(time.Date(year, time.July, 4, 0, 0, 0, 0, time.UTC)),
+		nthWeekday(year, time.September, time.Monday, 1),
+		nthWeekday(year, time.November, time.Thursday, 4),
+		observedHoliday(time.Date(year, time.December, 25, 0, 0, 0, 0, time.UTC)),
+	}
+
+	// A New Year's Day on a Saturday is not observed, since the Friday before closes the year
+	newYear := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+	if newYear.Weekday() != time.Saturday {
+		holidays = append(holidays, observedHoliday(newYear))
+	}
+
+	if year >= 2022 {
+		holidays = append(holidays, observedHoliday(time.Date(year, time.June, 19, 0, 0, 0, 0, time.UTC)))
+	}
+
+	return holidays
+}
+
+// observedHoliday moves a holiday on a Saturday to the Friday before, and one on a Sunday to the Monday after
+func observedHoliday(date time.Time) time.Time {
+	switch date.Weekday() {
+	case time.Saturday:
+		return date.AddDate(0, 0, -1)
+	case time.Sunday:
+		return date.AddDate(0, 0, 1)
+	}
+	return date
+}
+
+// nthWeekday returns the nth given weekday of the month, e.g. the 3rd Monday of January
+func nthWeekday(year int, month time.Month, weekday time.Weekday, n int) time.Time {
+	date := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+	offset := (int(weekday) - int(date.Weekday()) + 7) % 7
+	return date.AddDate(0, 0, offset+7*(n-1))
+}
+
+// lastWeekday returns the last given weekday of the month, e.g. the last Monday of May
+func lastWeekday(year int, month time.Month, weekday time.Weekday) time.Time {
+	date := time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC)
+	offset := (int(date.Weekday()) - int(weekday) + 7) % 7
+	return date.AddDate(0, 0, -offset)
+}
+
+// easter returns Easter Sunday of the given year, by the anonymous Gregorian algorithm
+func easter(year int) time.Time {
+	a := year % 19
+	b := year / 100
+	c := year % 100
+	d := b / 4
+	e := b % 4
+	f := (b + 8) / 25
+	g := (b - f + 1) / 3
+	h := (19*a + b - d - g + 15) % 30
+	i := c / 4
+	k := c % 4
+	l := (32 + 2*e + 2*i - h - k) % 7
+	m := (a + 11*h + 22*l) / 451
+	month := (h + l - 7*m + 114) / 31
+	day := (h+l-7*m+114)%31 + 1
+
+	return time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,46 @@
+package portfolio
+
+import (
+	"testing"
+	"time"
+)
+
+func TestIsTradingDay(t *testing.T) {
+	tests := []struct {
+		date string
+		want bool
+	}{
+		{"2023-01-02", false}, // New Year's Day observed on Monday
+		{"2023-01-16", false}, // Martin Luther King Jr. Day
+		{"2023-02-20", false}, // Washington's Birthday
+		{"2023-04-07", false}, // Good Friday
+		{"2023-05-29", false}, // Memorial Day
+		{"2023-06-19", false}, // Juneteenth
+		{"2023-07-04", false}, // Independence Day
+		{"2023-09-04", false}, // Labor Day
+		{"2023-11-23", false}, // Thanksgiving Day
+		{"2023-12-25", false}, // Christmas Day
+		{"2022-06-20", false}, // Juneteenth on a Sunday, observed on Monday
+		{"2021-06-18", true},  // Juneteenth was not a market holiday before 2022
+		{"2021-12-31", true},  // New Year's Day 2022 on a Saturday is not observed
+		{"2021-12-24", false}, // Christmas Day on a Saturday, observed on Friday
+		{"2024-03-29", false}, // Good Friday
+		{"2025-04-18", false}, // Good Friday
+		{"2023-11-24", true},  // The day after Thanksgiving closes early but trades
+		{"2023-10-09", true},  // Columbus Day is not a market holiday
+		{"2023-10-07", false}, // Saturday
+		{"2023-10-08", false}, // Sunday
+		{"2023-10-10", true},
+	}
+
+	for _, test := range tests {
+		date, err := time.Parse(dateFormat, test.date)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got := IsTradingDay(date); got != test.want {
+			t.Errorf("IsTradingDay(%s) = %v, want %v", test.date, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,191 @@
+package portfolio
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CPI is a monthly consumer price index series, used to adjust dollar amounts for inflation
+type CPI struct {
+	File   string
+	Dates  []time.Time
+	Values []float64
+}
+
+type inflationConfig struct {
+	CPI string `yaml:"cpi,omitempty"`
+}
+
+// LoadCPI reads a monthly CPI series from a CSV file. Both the two-column export of FRED
+// (e.g. CPIAUCSL, "2020-01-01,259.127") and the export of BLS, with "Year", "Period" ("M01"
+// to "M12") and "Value" columns, are supported.
+func LoadCPI(name string) (*CPI, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	cpi, err := ParseCPI(file)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to load CPI from %s: %v", name, err)
+	}
+	cpi.File = name
+
+	return cpi, nil
+}
+
+// ParseCPI reads a monthly CPI series in one of the formats supported by LoadCPI
+func ParseCPI(reader io.Reader) (*CPI, error) {
+	r := csv.NewReader(reader)
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[time.Time]float64)
+	year, period, value := -1, -1, -1
+
+	for _, record := range records {
+		if isBlankRecord(record) {
+			continue
+		}
+
+		if year < 0 && value < 0 {
+			for i, column := range record {
+				switch strings.ToLower(strings.TrimSpace(column)) {
+				case "year":
+					year = i
+				case "period":
+					period = i
+				case "value":
+					value = i
+				}
+			}
+			if year >= 0 && period >= 0 && value >= 0 {
+				continue
+			}
+			year, period, value = -1, -1, -1
+		}
+
+		var month time.Time
+		var field string
+
+		if year >= 0 {
+			month, err = parseCPIPeriod(record[year], record[period])
+			field = record[value]
+		} else if len(record) >= 2 {
+			month, err = parseDate(record[0], "2006-01-02|2006-01|01/02/2006")
+			field = record[1]
+		} else {
+			continue
+		}
+
+		if err != nil {
+			// Headers and annual averages (period "M13") are skipped
+			continue
+		}
+
+		index, err := strconv.ParseFloat(strings.TrimSpace(field), 64)
+		if err != nil || index <= 0 {
+			continue
+		}
+
+		values[startOfMonth(month)] = index
+	}
+
+	if len(values) == 0 {
+		return nil, errors.New("No monthly CPI values found")
+	}
+
+	cpi := &CPI{
+		Dates:  make([]time.Time, 0, len(values)),
+		Values: make([]float64, 0, len(values)),
+	}
+
+	for month := range values {
+		cpi.Dates = append(cpi.Dates, month)
+	}
+	sort.Slice(cpi.Dates, func(i, j int) bool {
+		return cpi.Dates[i].Before(cpi.Dates[j])
+	})
+	for _, month := range cpi.Dates {
+		cpi.Values = append(cpi.Values, values[month])
+	}
+
+	return cpi, nil
+}
+
+// Index returns the CPI of the month of the given date. The latest value is used for months that
+// have not been published yet. It fails for months before the start of the series.
+func (cpi *CPI) Index(date time.Time) (float64, bool) {
+	month := startOfMonth(date)
+
+	i := sort.Search(len(cpi.Dates), func(i int) bool {
+		return cpi.Dates[i].After(month)
+	})
+	if i == 0 {
+		return 0, false
+	}
+
+	return cpi.Values[i-1], true
+}
+
+// Inflation returns the growth of prices between the months of the two dates, e.g. 1.1 for 10% inflation
+func (cpi *CPI) Inflation(from time.Time, to time.Time) (float64, bool) {
+	start, ok := cpi.Index(from)
+	if !ok {
+		return 1, false
+	}
+
+	end, ok := cpi.Index(to)
+	if !ok {
+		return 1, false
+	}
+
+	return end / start, true
+}
+
+// newCPI loads the CPI file configured in the profile, relative to the directory of the profile
+func newCPI(config inflationConfig, profileFile string) (*CPI, error) {
+	if config.CPI == "" {
+		return nil, nil
+	}
+
+	name := config.CPI
+	if !filepath.IsAbs(name) {
+		name = filepath.Join(filepath.Dir(profileFile), name)
+	}
+
+	cpi, err := LoadCPI(name)
+	if err != nil {
+		return nil, err
+	}
+	cpi.File = config.CPI
+
+	return cpi, nil
+}
+
+func parseCPIPeriod(year string, period string) (time.Time, error) {
+	period = strings.TrimSpace(period)
+	if !strings.HasPrefix(period, "M") {
+		return time.Time{}, fmt.Errorf("Unknown CPI period: %s", period)
+	}
+
+	return time.Parse("2006-01", strings.TrimSpace(year)+"-"+strings.TrimPrefix(period, "M"))
+}
+
+func startOfMonth(date time.Time) time.Time {
+	return time.Date(date.Year(), date.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
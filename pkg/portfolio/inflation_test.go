@@ -0,0 +1,91 @@
+package portfolio
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseCPI(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   string
+		months []time.Time
+		values []float64
+	}{
+		{
+			name:   "FRED",
+			data:   "DATE,CPIAUCSL\n2020-02-01,259.250\n2020-01-01,259.127\n\n2020-03-01,.\n",
+			months: []time.Time{month(0), month(1)},
+			values: []float64{259.127, 259.250},
+		},
+		{
+			name:   "FRED monthly dates",
+			data:   "observation_date,CPIAUCSL\n2020-01,259.127\n02/01/2020,259.250\n",
+			months: []time.Time{month(0), month(1)},
+			values: []float64{259.127, 259.250},
+		},
+		{
+			// The preamble of the BLS export and the annual average of period M13 are skipped
+			name: "BLS",
+			data: `Consumer Price Index for All Urban Consumers (CPI-U)
+Series Id:,CUUR0000SA0
+
+Series ID,Year,Period,Label,Value
+CUUR0000SA0,2020,M01,2020 Jan,257.971
+CUUR0000SA0,2020,M02,2020 Feb,258.678
+CUUR0000SA0,2020,M13,2020 Annual,258.811
+`,
+			months: []time.Time{month(0), month(1)},
+			values: []float64{257.971, 258.678},
+		},
+	}
+
+	for _, test := range tests {
+		cpi, err := ParseCPI(strings.NewReader(test.data))
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+
+		if len(cpi.Dates) != len(test.months) {
+			t.Fatalf("%s: %d months, want %d", test.name, len(cpi.Dates), len(test.months))
+		}
+		for i := range cpi.Dates {
+			if !cpi.Dates[i].Equal(test.months[i]) || cpi.Values[i] != test.values[i] {
+				t.Errorf("%s: month %d = %v at %v, want %v at %v", test.name, i, cpi.Values[i], cpi.Dates[i], test.values[i], test.months[i])
+			}
+		}
+	}
+
+	_, err := ParseCPI(strings.NewReader("DATE,CPIAUCSL\n2020-01-01,.\n"))
+	if err == nil {
+		t.Errorf("A series without values should fail")
+	}
+}
+
+func TestCPIInflation(t *testing.T) {
+	cpi, err := ParseCPI(strings.NewReader("2020-01-01,100\n2020-02-01,102\n2020-03-01,105\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		from, to  time.Time
+		inflation float64
+		ok        bool
+	}{
+		{name: "within the series", from: month(0), to: month(2), inflation: 1.05, ok: true},
+		{name: "within a month", from: month(1).AddDate(0, 0, 10), to: month(2).AddDate(0, 0, 20), inflation: 105.0 / 102, ok: true},
+		{name: "after the series", from: month(1), to: month(6), inflation: 105.0 / 102, ok: true},
+		{name: "before the series", from: month(-1), to: month(2), inflation: 1, ok: false},
+	}
+
+	for _, test := range tests {
+		inflation, ok := cpi.Inflation(test.from, test.to)
+		if ok != test.ok || math.Abs(inflation-test.inflation) > 1e-12 {
+			t.Errorf("%s: inflation = %v (%v), want %v (%v)", test.name, inflation, ok, test.inflation, test.ok)
+		}
+	}
+}
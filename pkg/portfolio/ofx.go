@@ -0,0 +1,377 @@
+package portfolio
+
+import (
+	"crypto/sha1"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"strings"
+	"time"
+)
+
+// FormatOFX is the format for OFX and QFX investment statements
+const FormatOFX string = "ofx"
+
+// ofxNode is an element of an OFX document. Leaf elements carry a value, aggregates carry children.
+type ofxNode struct {
+	Name     string
+	Value    string
+	Children []*ofxNode
+	parent   *ofxNode
+}
+
+// ParseOFX reads the positions, cash and transactions of an OFX 1.x (SGML) or 2.x (XML) investment statement
+func ParseOFX(reader io.Reader) (*Statement, string, error) {
+	data, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return nil, "", err
+	}
+
+	root, err := parseOFXDocument(string(data))
+	if err != nil {
+		return nil, "", err
+	}
+
+	statementNode := root.find("INVSTMTRS")
+	if statementNode == nil {
+		return nil, "", errors.New("No investment statement found in OFX document")
+	}
+
+	name := root.value("FI", "ORG")
+	if name == "" {
+		name = statementNode.value("INVACCTFROM", "BROKERID")
+	}
+
+	parser := &ofxParser{
+		statement:  NewStatement(),
+		account:    statementNode.value("INVACCTFROM", "BROKERID") + "/" + statementNode.value("INVACCTFROM", "ACCTID"),
+		securities: make(map[string]string),
+	}
+
+	parser.parseSecurities(root.find("SECLIST"))
+	parser.parsePositions(statementNode.find("INVPOSLIST"))
+	parser.parseBalance(statementNode.find("INVBAL"))
+	parser.parseTransactions(statementNode.find("INVTRANLIST"))
+
+	parser.statement.assignTransactionIDs()
+
+	return parser.statement, name, nil
+}
+
+// parseOFXDocument builds the element tree of an OFX document. SGML documents do not close
+// leaf elements, so a leaf is implicitly closed by the next tag, and a closing tag pops every
+// element up to the matching one. An element without a value is a leaf too, unless the document
+// closes an element of that name somewhere.
+func parseOFXDocument(data string) (*ofxNode, error) {
+	root := &ofxNode{Name: "ROOT"}
+	current := root
+
+	start := strings.Index(data, "<OFX>")
+	if start < 0 {
+		return nil, errors.New("Missing <OFX> element")
+	}
+	data = data[start:]
+	closed := closedOFXTags(data)
+
+	for len(data) > 0 {
+		open := strings.Index(data, "<")
+		if open < 0 {
+			break
+		}
+
+		text := strings.TrimSpace(data[:open])
+		if text != "" && current != root && len(current.Children) == 0 {
+			current.Value = unescapeOFX(text)
+		}
+
+		end := strings.Index(data[open:], ">")
+		if end < 0 {
+			return nil, errors.New("Unterminated OFX tag")
+		}
+
+		tag := strings.TrimSpace(data[open+1 : open+end])
+		data = data[open+end+1:]
+
+		if strings.HasPrefix(tag, "?") || strings.HasPrefix(tag, "!") {
+			continue
+		}
+
+		// A pending leaf element is closed by whatever tag comes next
+		if current != root && (current.Value != "" || (len(current.Children) == 0 && !closed[current.Name])) {
+			current = current.parent
+		}
+
+		if strings.HasPrefix(tag, "/") {
+			name := strings.ToUpper(strings.TrimSpace(tag[1:]))
+			if name == "" {
+				return nil, errors.New("Empty OFX tag")
+			}
+			for node := current; node != root; node = node.parent {
+				if node.Name == name {
+					current = node.parent
+					break
+				}
+			}
+			continue
+		}
+
+		selfClosing := strings.HasSuffix(tag, "/")
+		fields := strings.Fields(strings.TrimSuffix(tag, "/"))
+		if len(fields) == 0 {
+			return nil, errors.New("Empty OFX tag")
+		}
+		name := strings.ToUpper(fields[0])
+
+		node := &ofxNode{Name: name, parent: current}
+		current.Children = append(current.Children, node)
+
+		if !selfClosing {
+			current = node
+		}
+	}
+
+	return root, nil
+}
+
+// closedOFXTags returns the names of the elements closed anywhere in the document, which are the
+// aggregates of an SGML document
+func closedOFXTags(data string) map[string]bool {
+	closed := make(map[string]bool)
+
+	for _, tag := range strings.Split(data, "</")[1:] {
+		if end := strings.Index(tag, ">"); end >= 0 {
+			closed[strings.ToUpper(strings.TrimSpace(tag[:end]))] = true
+		}
+	}
+
+	return closed
+}
+
+func unescapeOFX(text string) string {
+	return strings.NewReplacer("&amp;", "&", "&lt;", "<", "&gt;", ">", "&quot;", "\"", "&apos;", "'").Replace(text)
+}
+
+// find returns the first descendant with the given name
+func (node *ofxNode) find(name string) *ofxNode {
+	if node == nil {
+		return nil
+	}
+
+	for _, child := range node.Children {
+		if child.Name == name {
+			return child
+		}
+		if found := child.find(name); found != nil {
+			return found
+		}
+	}
+
+	return nil
+}
+
+// value returns the value of the leaf at the end of the given path of descendants
+func (node *ofxNode) value(path ...string) string {
+	for _, name := range path {
+		node = node.find(name)
+		if node == nil {
+			return ""
+		}
+	}
+
+	return node.Value
+}
+
+func (node *ofxNode) amount(path ...string) float64 {
+	amount, _ := parseAmount(node.value(path...))
+	return amount
+}
+
+type ofxParser struct {
+	statement  *Statement
+	account    string
+	securities map[string]string
+}
+
+func (parser *ofxParser) parseSecurities(list *ofxNode) {
+	if list == nil {
+		return
+	}
+
+	for _, info := range list.Children {
+		id := info.value("SECID", "UNIQUEID")
+		ticker := info.value("TICKER")
+		if id == "" {
+			continue
+		}
+
+		parser.securities[id] = ticker
+
+		if info.value("SECID", "UNIQUEIDTYPE") == "CUSIP" && ticker != "" {
+			parser.statement.CUSIPs[ticker] = id
+		}
+	}
+}
+
+// symbol resolves a security ID to its ticker, falling back to the ID itself
+func (parser *ofxParser) symbol(node *ofxNode) string {
+	id := node.value("SECID", "UNIQUEID")
+
+	ticker := parser.securities[id]
+	if ticker == "" {
+		if node.value("SECID", "UNIQUEIDTYPE") == "CUSIP" {
+			parser.statement.CUSIPs[id] = id
+		}
+		return id
+	}
+
+	return ticker
+}
+
+func (parser *ofxParser) parsePositions(list *ofxNode) {
+	if list == nil {
+		return
+	}
+
+	for _, position := range list.Children {
+		pos := position.find("INVPOS")
+		if pos == nil {
+			continue
+		}
+
+		parser.statement.Positions = append(parser.statement.Positions, &Position{
+			Symbol:      parser.symbol(pos),
+			Description: pos.value("MEMO"),
+			Quantity:    pos.amount("UNITS"),
+			Price:       pos.amount("UNITPRICE"),
+			Value:       pos.amount("MKTVAL"),
+		})
+	}
+}
+
+func (parser *ofxParser) parseBalance(balance *ofxNode) {
+	if balance == nil {
+		return
+	}
+
+	cash, ok := parseAmount(balance.value("AVAILCASH"))
+	if ok {
+		parser.statement.Cash = cash
+		parser.statement.HasCash = true
+	}
+}
+
+func (parser *ofxParser) parseTransactions(list *ofxNode) {
+	if list == nil {
+		return
+	}
+
+	for _, node := range list.Children {
+		var transaction *Transaction
+
+		switch {
+		case strings.HasPrefix(node.Name, "BUY"):
+			transaction = parser.parseTrade(node, TransactionBuy)
+		case strings.HasPrefix(node.Name, "SELL"):
+			transaction = parser.parseTrade(node, TransactionSell)
+		case node.Name == "REINVEST":
+			transaction = parser.parseTrade(node, TransactionBuy)
+		case node.Name == "INCOME":
+			transaction = parser.parseTrade(node, TransactionDividend)
+			if transaction != nil && node.value("INCOMETYPE") == "INTEREST" {
+				transaction.Type = TransactionInterest
+			}
+		case node.Name == "INVEXPENSE" || node.Name == "MARGININTEREST":
+			transaction = parser.parseTrade(node, TransactionFee)
+		case node.Name == "INVBANKTRAN":
+			transaction = parser.parseBankTransaction(node)
+		case node.Name == "DTSTART" || node.Name == "DTEND":
+			continue
+		default:
+			transaction = parser.parseTrade(node, TransactionOther)
+		}
+
+		if transaction == nil {
+			continue
+		}
+
+		parser.statement.Transactions = append(parser.statement.Transactions, transaction)
+	}
+}
+
+func (parser *ofxParser) parseTrade(node *ofxNode, transactionType TransactionType) *Transaction {
+	date, err := parseOFXDate(node.value("INVTRAN", "DTTRADE"))
+	if err != nil {
+		return nil
+	}
+
+	transaction := &Transaction{
+		ID:          parser.transactionID(node.value("INVTRAN", "FITID")),
+		Date:        date,
+		Type:        transactionType,
+		Quantity:    math.Abs(node.amount("UNITS")),
+		Price:       node.amount("UNITPRICE"),
+		Amount:      node.amount("TOTAL"),
+		Description: node.value("INVTRAN", "MEMO"),
+	}
+
+	if node.find("SECID") != nil {
+		transaction.Symbol = parser.symbol(node)
+	}
+
+	if transaction.Description == "" {
+		transaction.Description = node.Name
+	}
+
+	return transaction
+}
+
+func (parser *ofxParser) parseBankTransaction(node *ofxNode) *Transaction {
+	date, err := parseOFXDate(node.value("STMTTRN", "DTPOSTED"))
+	if err != nil {
+		return nil
+	}
+
+	amount := node.amount("STMTTRN", "TRNAMT")
+
+	transaction := &Transaction{
+		ID:          parser.transactionID(node.value("STMTTRN", "FITID")),
+		Date:        date,
+		Type:        TransactionDeposit,
+		Amount:      amount,
+		Description: strings.TrimSpace(node.value("STMTTRN", "NAME") + " " + node.value("STMTTRN", "MEMO")),
+	}
+
+	switch node.value("STMTTRN", "TRNTYPE") {
+	case "INT", "DIV":
+		transaction.Type = TransactionInterest
+	case "FEE", "SRVCHG":
+		transaction.Type = TransactionFee
+	default:
+		if amount < 0 {
+			transaction.Type = TransactionWithdrawal
+		}
+	}
+
+	return transaction
+}
+
+// transactionID derives the transaction ID from the FITID, which the institution keeps unique per account
+func (parser *ofxParser) transactionID(fitid string) string {
+	if fitid == "" {
+		return ""
+	}
+
+	sum := sha1.Sum([]byte(fmt.Sprintf("ofx|%s|%s", parser.account, fitid)))
+	return fmt.Sprintf("%x", sum[:6])
+}
+
+// parseOFXDate parses dates such as "20230105", "20230105120000" or "20230105120000.000[-5:EST]"
+func parseOFXDate(value string) (time.Time, error) {
+	if len(value) < 8 {
+		return time.Time{}, fmt.Errorf("Invalid OFX date: %s", value)
+	}
+
+	return time.Parse("20060102", value[:8])
+}
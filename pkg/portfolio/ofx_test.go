@@ -0,0 +1,310 @@
+package portfolio
+
+import (
+	"io"
+	"math"
+	"os"
+	"strings"
+	"testing"
+)
+
+// ofxXMLStatement is examples/statement.ofx as an OFX 2.x document
+const ofxXMLStatement = `<?xml version="1.0" encoding="UTF-8" standalone="no"?>
+<?OFX OFXHEADER="200" VERSION="220" SECURITY="NONE" OLDFILEUID="NONE" NEWFILEUID="NONE"?>
+<OFX>
+  <SIGNONMSGSRSV1><SONRS>
+    <STATUS><CODE>0</CODE><SEVERITY>INFO</SEVERITY></STATUS>
+    <DTSERVER>20231002120000.000[-5:EST]</DTSERVER><LANGUAGE>ENG</LANGUAGE>
+    <FI><ORG>Brokerage</ORG><FID>1234</FID></FI>
+  </SONRS></SIGNONMSGSRSV1>
+  <INVSTMTMSGSRSV1><INVSTMTTRNRS><TRNUID>1</TRNUID>
+    <STATUS><CODE>0</CODE><SEVERITY>INFO</SEVERITY></STATUS>
+    <INVSTMTRS>
+      <DTASOF>20231002120000.000[-5:EST]</DTASOF><CURDEF>USD</CURDEF>
+      <INVACCTFROM><BROKERID>brokerage.com</BROKERID><ACCTID>123456789</ACCTID></INVACCTFROM>
+      <INVTRANLIST>
+        <DTSTART>20230901</DTSTART><DTEND>20231002</DTEND>
+        <BUYSTOCK>
+          <INVBUY>
+            <INVTRAN><FITID>20230905-1</FITID><DTTRADE>20230905</DTTRADE><MEMO>Buy VTI</MEMO></INVTRAN>
+            <SECID><UNIQUEID>922908769</UNIQUEID><UNIQUEIDTYPE>CUSIP</UNIQUEIDTYPE></SECID>
+            <UNITS>50</UNITS><UNITPRICE>220.00</UNITPRICE><COMMISSION>0</COMMISSION><TOTAL>-11000.00</TOTAL>
+            <SUBACCTSEC>CASH</SUBACCTSEC><SUBACCTFUND>CASH</SUBACCTFUND>
+          </INVBUY>
+          <BUYTYPE>BUY</BUYTYPE>
+        </BUYSTOCK>
+        <INCOME>
+          <INVTRAN><FITID>20230928-1</FITID><DTTRADE>20230928</DTTRADE><MEMO>Dividend VTI</MEMO></INVTRAN>
+          <SECID><UNIQUEID>922908769</UNIQUEID><UNIQUEIDTYPE>CUSIP</UNIQUEIDTYPE></SECID>
+          <INCOMETYPE>DIV</INCOMETYPE><TOTAL>82.50</TOTAL><SUBACCTSEC>CASH</SUBACCTSEC><SUBACCTFUND>CASH</SUBACCTFUND>
+        </INCOME>
+        <REINVEST>
+          <INVTRAN><FITID>20230928-2</FITID><DTTRADE>20230928</DTTRADE><MEMO>Reinvest BND</MEMO></INVTRAN>
+          <SECID><UNIQUEID>921937835</UNIQUEID><UNIQUEIDTYPE>CUSIP</UNIQUEIDTYPE></SECID>
+          <INCOMETYPE>DIV</INCOMETYPE><TOTAL>-21.60</TOTAL><SUBACCTSEC>CASH</SUBACCTSEC>
+          <UNITS>0.3</UNITS><UNITPRICE>72.00</UNITPRICE>
+        </REINVEST>
+        <INVBANKTRAN>
+          <STMTTRN><TRNTYPE>CREDIT</TRNTYPE><DTPOSTED>20230901</DTPOSTED><TRNAMT>15000.00</TRNAMT>
+            <FITID>20230901-1</FITID><NAME>Transfer from checking</NAME></STMTTRN>
+          <SUBACCTFUND>CASH</SUBACCTFUND>
+        </INVBANKTRAN>
+      </INVTRANLIST>
+      <INVPOSLIST>
+        <POSSTOCK><INVPOS>
+          <SECID><UNIQUEID>922908769</UNIQUEID><UNIQUEIDTYPE>CUSIP</UNIQUEIDTYPE></SECID>
+          <HELDINACCT>CASH</HELDINACCT><POSTYPE>LONG</POSTYPE>
+          <UNITS>150</UNITS><UNITPRICE>212.10</UNITPRICE><MKTVAL>31815.00</MKTVAL><DTPRICEASOF>20231002</DTPRICEASOF>
+        </INVPOS></POSSTOCK>
+        <POSMF><INVPOS>
+          <SECID><UNIQUEID>921937835</UNIQUEID><UNIQUEIDTYPE>CUSIP</UNIQUEIDTYPE></SECID>
+          <HELDINACCT>CASH</HELDINACCT><POSTYPE>LONG</POSTYPE>
+          <UNITS>200.3</UNITS><UNITPRICE>70.10</UNITPRICE><MKTVAL>14041.03</MKTVAL><DTPRICEASOF>20231002</DTPRICEASOF>
+        </INVPOS></POSMF>
+      </INVPOSLIST>
+      <INVBAL><AVAILCASH>4000.00</AVAILCASH><MARGINBALANCE>0</MARGINBALANCE><SHORTBALANCE>0</SHORTBALANCE></INVBAL>
+    </INVSTMTRS>
+  </INVSTMTTRNRS></INVSTMTMSGSRSV1>
+  <SECLISTMSGSRSV1><SECLIST>
+    <STOCKINFO><SECINFO>
+      <SECID><UNIQUEID>922908769</UNIQUEID><UNIQUEIDTYPE>CUSIP</UNIQUEIDTYPE></SECID>
+      <SECNAME>Vanguard Total Stock Market ETF</SECNAME><TICKER>VTI</TICKER>
+    </SECINFO></STOCKINFO>
+    <MFINFO><SECINFO>
+      <SECID><UNIQUEID>921937835</UNIQUEID><UNIQUEIDTYPE>CUSIP</UNIQUEIDTYPE></SECID>
+      <SECNAME>Vanguard Total Bond Market ETF</SECNAME><TICKER>BND</TICKER>
+    </SECINFO></MFINFO>
+  </SECLIST></SECLISTMSGSRSV1>
+</OFX>
+`
+
+func TestParseOFX(t *testing.T) {
+	sgml, err := os.Open("../../examples/statement.ofx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sgml.Close()
+
+	documents := map[string]*Statement{
+		"SGML": parseOFXTest(t, sgml),
+		"XML":  parseOFXTest(t, strings.NewReader(ofxXMLStatement)),
+	}
+
+	for format, statement := range documents {
+		if !statement.HasCash || statement.Cash != 4000 {
+			t.Errorf("%s: cash = %v, want 4000", format, statement.Cash)
+		}
+
+		positions := map[string]float64{"VTI": 150, "BND": 200.3}
+		if len(statement.Positions) != len(positions) {
+			t.Errorf("%s: %d positions, want %d", format, len(statement.Positions), len(positions))
+		}
+		for _, position := range statement.Positions {
+			if position.Quantity != positions[position.Symbol] {
+				t.Errorf("%s: quantity of %s = %v, want %v", format, position.Symbol, position.Quantity, positions[position.Symbol])
+			}
+			if position.CostBasis != 0 {
+				t.Errorf("%s: OFX positions have no basis, got %v for %s", format, position.CostBasis, position.Symbol)
+			}
+		}
+
+		cusips := map[string]string{"VTI": "922908769", "BND": "921937835"}
+		for symbol, cusip := range cusips {
+			if statement.CUSIPs[symbol] != cusip {
+				t.Errorf("%s: CUSIP of %s = %q, want %q", format, symbol, statement.CUSIPs[symbol], cusip)
+			}
+		}
+
+		transactions := []struct {
+			kind     TransactionType
+			symbol   string
+			quantity float64
+			amount   float64
+		}{
+			{TransactionBuy, "VTI", 50, -11000},
+			{TransactionDividend, "VTI", 0, 82.5},
+			{TransactionBuy, "BND", 0.3, -21.6},
+			{TransactionDeposit, "", 0, 15000},
+		}
+		if len(statement.Transactions) != len(transactions) {
+			t.Fatalf("%s: %d transactions, want %d", format, len(statement.Transactions), len(transactions))
+		}
+		for i, want := range transactions {
+			got := statement.Transactions[i]
+			if got.Type != want.kind || got.Symbol != want.symbol || got.Quantity != want.quantity || got.Amount != want.amount {
+				t.Errorf("%s: transaction %d = %s %v %s for %v, want %s %v %s for %v", format, i,
+					got.Type, got.Quantity, got.Symbol, got.Amount, want.kind, want.quantity, want.symbol, want.amount)
+			}
+		}
+	}
+
+	if documents["SGML"].Transactions[0].ID != documents["XML"].Transactions[0].ID {
+		t.Errorf("The same FITID in both formats should give the same transaction ID")
+	}
+}
+
+func TestParseOFXDocument(t *testing.T) {
+	// SGML leaves are not closed, and SECNAME is empty
+	document := "<OFX><SECLIST><STOCKINFO><SECINFO><SECID><UNIQUEID>922908769<UNIQUEIDTYPE>CUSIP</SECID>" +
+		"<SECNAME>\n<TICKER>VTI</SECINFO></STOCKINFO></SECLIST></OFX>"
+
+	root, err := parseOFXDocument(document)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	info := root.find("SECINFO")
+	names := make([]string, 0)
+	for _, child := range info.Children {
+		names = append(names, child.Name)
+	}
+	if strings.Join(names, " ") != "SECID SECNAME TICKER" {
+		t.Errorf("Children of SECINFO = %v, want SECID, SECNAME and TICKER", names)
+	}
+	if info.value("SECNAME") != "" || info.value("TICKER") != "VTI" || info.value("SECID", "UNIQUEIDTYPE") != "CUSIP" {
+		t.Errorf("SECNAME = %q and TICKER = %q, want an empty name and VTI", info.value("SECNAME"), info.value("TICKER"))
+	}
+
+	for _, tag := range []string{"<>", "< />", "</>"} {
+		if _, err := parseOFXDocument("<OFX><SECLIST>" + tag + "</SECLIST></OFX>"); err == nil {
+			t.Errorf("Parsed an empty tag %s, want an error", tag)
+		}
+	}
+}
+
+func TestParseOFXIncome(t *testing.T) {
+	tests := []struct {
+		name   string
+		income string
+		want   TransactionType
+	}{
+		{"dividend", "<DTTRADE>20230928</DTTRADE></INVTRAN><INCOMETYPE>DIV</INCOMETYPE>", TransactionDividend},
+		{"capital gain", "<DTTRADE>20230928</DTTRADE></INVTRAN><INCOMETYPE>CGLONG</INCOMETYPE>", TransactionDividend},
+		{"interest", "<DTTRADE>20230928</DTTRADE></INVTRAN><INCOMETYPE>INTEREST</INCOMETYPE>", TransactionInterest},
+		{"missing date", "</INVTRAN><INCOMETYPE>INTEREST</INCOMETYPE>", ""},
+		{"bad date", "<DTTRADE>2023</DTTRADE></INVTRAN><INCOMETYPE>INTEREST</INCOMETYPE>", ""},
+	}
+
+	for _, test := range tests {
+		document := "<OFX><INVSTMTRS><INVTRANLIST><INCOME><INVTRAN><FITID>1</FITID>" + test.income +
+			"<TOTAL>10.00</TOTAL></INCOME></INVTRANLIST></INVSTMTRS></OFX>"
+
+		statement := parseOFXTest(t, strings.NewReader(document))
+
+		if test.want == "" {
+			if len(statement.Transactions) != 0 {
+				t.Errorf("%s: income without a valid date should be skipped, got %+v", test.name, statement.Transactions[0])
+			}
+			continue
+		}
+
+		if len(statement.Transactions) != 1 || statement.Transactions[0].Type != test.want {
+			t.Errorf("%s: transactions = %+v, want one %s", test.name, statement.Transactions, test.want)
+		}
+	}
+}
+
+func TestImportOFX(t *testing.T) {
+	tests := []struct {
+		name     string
+		holdings map[string]*Holding
+		cusips   map[string]string
+		symbol   string
+		basis    map[string]float64
+	}{
+		{
+			// Only 50 of the 150 VTI shares were bought in the statement period, so the rest is at their cost
+			name:   "new holdings",
+			symbol: "VTI",
+			basis:  map[string]float64{"VTI": 11000.0 / 50 * 150, "BND": 21.6 / 0.3 * 200.3},
+		},
+		{
+			name: "existing basis",
+			holdings: map[string]*Holding{
+				"VTI": NewHolding("VTI", 100, 25000, 0),
+			},
+			symbol: "VTI",
+			basis:  map[string]float64{"VTI": 25000, "BND": 21.6 / 0.3 * 200.3},
+		},
+		{
+			name: "CUSIP match",
+			holdings: map[string]*Holding{
+				"TSM": NewHolding("TSM", 100, 25000, 0),
+			},
+			cusips: map[string]string{"TSM": "922908769"},
+			symbol: "TSM",
+			basis:  map[string]float64{"TSM": 25000, "BND": 21.6 / 0.3 * 200.3},
+		},
+	}
+
+	for _, test := range tests {
+		portfolio := NewPortfolio()
+		for symbol, holding := range test.holdings {
+			portfolio.Symbols = append(portfolio.Symbols, symbol)
+			portfolio.Holdings[symbol] = holding
+		}
+		for symbol, cusip := range test.cusips {
+			portfolio.Holdings[symbol].CUSIP = cusip
+		}
+
+		for i := 0; i < 2; i++ {
+			err := portfolio.Import(parseOFXTest(t, strings.NewReader(ofxXMLStatement)))
+			if err != nil {
+				t.Fatalf("%s: %v", test.name, err)
+			}
+		}
+
+		if len(portfolio.Holdings) != len(test.basis) {
+			t.Errorf("%s: %d holdings, want %d", test.name, len(portfolio.Holdings), len(test.basis))
+		}
+		if holding := portfolio.Holdings[test.symbol]; holding == nil || holding.Quantity != 150 {
+			t.Fatalf("%s: %s should hold the 150 VTI shares", test.name, test.symbol)
+		}
+
+		for symbol, basis := range test.basis {
+			if math.Abs(portfolio.Holdings[symbol].CostBasis-basis) > 1e-6 {
+				t.Errorf("%s: basis of %s = %v, want %v", test.name, symbol, portfolio.Holdings[symbol].CostBasis, basis)
+			}
+		}
+
+		if len(portfolio.Transactions) != 4 {
+			t.Errorf("%s: %d transactions, want 4", test.name, len(portfolio.Transactions))
+		}
+	}
+}
+
+func TestComputeLotsBasis(t *testing.T) {
+	lots := []*Lot{{Quantity: 10, CostBasis: 1000}, {Quantity: 10, CostBasis: 1200}}
+
+	tests := []struct {
+		name     string
+		lots     []*Lot
+		quantity float64
+		existing float64
+		want     float64
+	}{
+		{"covered", lots, 20, 0, 2200},
+		{"covered replaces existing", lots, 20, 1500, 2200},
+		{"partial at average cost", lots, 40, 0, 4400},
+		{"partial keeps existing", lots, 40, 4000, 4000},
+		{"no lots keeps existing", nil, 40, 4000, 4000},
+		{"no lots", nil, 40, 0, 0},
+		{"sold out", lots, 0, 0, 0},
+	}
+
+	for _, test := range tests {
+		got := computeLotsBasis(test.lots, test.quantity, test.existing)
+		if math.Abs(got-test.want) > 1e-9 {
+			t.Errorf("%s: basis = %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func parseOFXTest(t *testing.T, reader io.Reader) *Statement {
+	statement, _, err := ParseOFX(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return statement
+}
@@ -0,0 +1,674 @@
+package portfolio
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+const (
+	minCovarianceMonths = 12
+	optimizerIterations = 5000
+	optimizerTolerance  = 1e-10
+	bisectionIterations = 100
+)
+
+// Optimizer finds the efficient portfolios of the holdings of a portfolio from the means and the covariances
+// of their monthly returns. The weights are long-only, within the minimum and the maximum weight of every
+// symbol, and the total weight of an asset class, subclass or group of classes (equities, bonds, ...) can be
+// capped. Weights, caps and returns are in percent.
+type Optimizer struct {
+	Target  float64
+	Min     map[string]float64
+	Max     map[string]float64
+	Classes map[string]float64
+}
+
+// Frontier is the efficient frontier of the holdings of a portfolio, from the minimum-variance portfolio to
+// the portfolio with the highest return. The returns are the annualized means of the monthly returns, and the
+// volatilities the annualized standard deviations. The target portfolio is nil if no target return is set,
+// and Reachable tells whether the constraints allow the target return.
+type Frontier struct {
+	Symbols     []string
+	Months      int
+	RiskFree    float64
+	Returns     []float64
+	Volatility  []float64
+	Points      []*EfficientPortfolio
+	MinVariance *EfficientPortfolio
+	MaxSharpe   *EfficientPortfolio
+	Target      *EfficientPortfolio
+	Reachable   bool
+	Current     *EfficientPortfolio
+}
+
+// EfficientPortfolio is a set of weights of the holdings, with its expected return, volatility and Sharpe ratio
+type EfficientPortfolio struct {
+	Weights    map[string]float64
+	Return     float64
+	Volatility float64
+	Sharpe     float64
+}
+
+type optimizerConfig struct {
+	Target  float64            `yaml:"target,omitempty"`
+	Min     map[string]float64 `yaml:"min,omitempty"`
+	Max     map[string]float64 `yaml:"max,omitempty"`
+	Classes map[string]float64 `yaml:"classes,omitempty"`
+}
+
+// frontierProblem minimizes the variance of the weights minus gamma times their return, within the bounds
+// of every weight and the caps of the asset classes. Weights and returns are fractions.
+type frontierProblem struct {
+	mean  []float64
+	cov   [][]float64
+	lo    []float64
+	hi    []float64
+	class []int
+	caps  []float64
+	step  float64
+}
+
+// NewOptimizer returns an optimizer without constraints other than long-only weights
+func NewOptimizer() *Optimizer {
+	return &Optimizer{
+		Min:     make(map[string]float64),
+		Max:     make(map[string]float64),
+		Classes: make(map[string]float64),
+	}
+}
+
+func newOptimizer(config *optimizerConfig) (*Optimizer, error) {
+	optimizer := NewOptimizer()
+	if config == nil {
+		return optimizer, nil
+	}
+
+	optimizer.Target = config.Target
+
+	for symbol, weight := range config.Min {
+		optimizer.Min[symbol] = weight
+	}
+	for symbol, weight := range config.Max {
+		optimizer.Max[symbol] = weight
+	}
+	for class, weight := range config.Classes {
+		optimizer.Classes[strings.ToLower(class)] = weight
+	}
+
+	for _, weights := range []map[string]float64{config.Min, config.Max, config.Classes} {
+		for name, weight := range weights {
+			if weight < 0 || weight > 100 {
+				return nil, fmt.Errorf("Optimizer weight of %s should be between 0%% and 100%%", name)
+			}
+		}
+	}
+
+	known := make(map[string]bool)
+	for group := range shockGroups {
+		known[group] = true
+	}
+	for _, asset := range AssetDB() {
+		known[strings.ToLower(string(asset.Class))] = true
+		known[strings.ToLower(string(asset.Subclass))] = true
+	}
+	for class := range optimizer.Classes {
+		if !known[class] {
+			return nil, fmt.Errorf("Unknown asset class in the optimizer: %s", class)
+		}
+	}
+
+	for symbol, min := range optimizer.Min {
+		if max, ok := optimizer.Max[symbol]; ok && min > max {
+			return nil, fmt.Errorf("Optimizer minimum weight of %s should not exceed its maximum weight", symbol)
+		}
+	}
+
+	return optimizer, nil
+}
+
+func (optimizer *Optimizer) config() *optimizerConfig {
+	config := optimizerConfig{Target: optimizer.Target}
+
+	if len(optimizer.Min) > 0 {
+		config.Min = optimizer.Min
+	}
+	if len(optimizer.Max) > 0 {
+		config.Max = optimizer.Max
+	}
+	if len(optimizer.Classes) > 0 {
+		config.Classes = optimizer.Classes
+	}
+
+	if config.Target == 0 && config.Min == nil && config.Max == nil && config.Classes == nil {
+		return nil
+	}
+
+	return &config
+}
+
+// ComputeFrontier computes the efficient frontier of the holdings of the backtest, and compares the efficient
+// portfolios with the target allocation of the portfolio
+func (result *PerformanceResult) ComputeFrontier(optimizer *Optimizer, riskFree float64) (*Frontier, error) {
+	frontier := &Frontier{
+		RiskFree: riskFree,
+		Points:   make([]*EfficientPortfolio, 0),
+	}
+
+	symbols, returns, err := result.holdingReturns()
+	if err != nil {
+		return nil, err
+	}
+	frontier.Symbols = symbols
+	frontier.Months = len(returns[0])
+
+	problem, err := newFrontierProblem(result.Portfolio, frontier.Symbols, returns, optimizer)
+	if err != nil {
+		return nil, err
+	}
+
+	frontier.Returns = make([]float64, len(frontier.Symbols))
+	frontier.Volatility = make([]float64, len(frontier.Symbols))
+	for i := range frontier.Symbols {
+		frontier.Returns[i] = problem.mean[i] * 100
+		frontier.Volatility[i] = math.Sqrt(problem.cov[i][i]) * 100
+	}
+
+	evaluate := func(weights []float64) *EfficientPortfolio {
+		return frontier.evaluate(problem, weights)
+	}
+
+	// The frontier is traced by raising the weight of the return against the variance, until the weights
+	// reach the portfolio with the highest return
+	maxReturn := problem.dot(problem.mean, problem.maxReturn())
+
+	gammas := []float64{0}
+	solutions := [][]float64{problem.solve(0, problem.project(make([]float64, len(frontier.Symbols))))}
+	for gamma := 0.005; len(gammas) < 60; gamma *= 1.4 {
+		weights := problem.solve(gamma, solutions[len(solutions)-1])
+		gammas = append(gammas, gamma)
+		solutions = append(solutions, weights)
+
+		if problem.dot(problem.mean, weights) >= maxReturn-1e-6 {
+			break
+		}
+	}
+
+	for _, weights := range solutions {
+		point := evaluate(weights)
+		last := len(frontier.Points) - 1
+		if last >= 0 && math.Abs(point.Return-frontier.Points[last].Return) < 1e-3 && math.Abs(point.Volatility-frontier.Points[last].Volatility) < 1e-3 {
+			continue
+		}
+		frontier.Points = append(frontier.Points, point)
+	}
+
+	frontier.MinVariance = evaluate(solutions[0])
+	frontier.MaxSharpe = frontier.maxSharpe(problem, gammas, solutions)
+
+	if optimizer.Target != 0 {
+		frontier.Target, frontier.Reachable = frontier.target(problem, gammas, solutions, optimizer.Target/100, maxReturn)
+	}
+
+	current := make([]float64, len(frontier.Symbols))
+	var total float64
+	for i, symbol := range frontier.Symbols {
+		current[i] = result.Portfolio.TargetAllocation[symbol] / 100
+		total += current[i]
+	}
+	if total > 0 {
+		for i := range current {
+			current[i] /= total
+		}
+		frontier.Current = evaluate(current)
+	}
+
+	return frontier, nil
+}
+
+// maxSharpe finds the portfolio with the highest Sharpe ratio on the frontier, which is refined between the
+// neighbors of the best traced portfolio by a golden-section search
+func (frontier *Frontier) maxSharpe(problem *frontierProblem, gammas []float64, solutions [][]float64) *EfficientPortfolio {
+	best := 0
+	bestSharpe := math.Inf(-1)
+	for i, weights := range solutions {
+		sharpe := frontier.evaluate(problem, weights).Sharpe
+		if sharpe > bestSharpe {
+			best, bestSharpe = i, sharpe
+		}
+	}
+
+	low, high := gammas[best], gammas[best]
+	if best > 0 {
+		low = gammas[best-1]
+	}
+	if best < len(gammas)-1 {
+		high = gammas[best+1]
+	}
+
+	ratio := (math.Sqrt(5) - 1) / 2
+	weights := solutions[best]
+	sharpe := func(gamma float64) float64 {
+		weights = problem.solve(gamma, weights)
+		return frontier.evaluate(problem, weights).Sharpe
+	}
+
+	a, b := high-ratio*(high-low), low+ratio*(high-low)
+	sa, sb := sharpe(a), sharpe(b)
+	for i := 0; i < 40 && high-low > 1e-6; i++ {
+		if sa > sb {
+			high, b, sb = b, a, sa
+			a = high - ratio*(high-low)
+			sa = sharpe(a)
+		} else {
+			low, a, sa = a, b, sb
+			b = low + ratio*(high-low)
+			sb = sharpe(b)
+		}
+	}
+
+	refined := frontier.evaluate(problem, problem.solve((low+high)/2, weights))
+	if refined.Sharpe < bestSharpe {
+		return frontier.evaluate(problem, solutions[best])
+	}
+	return refined
+}
+
+// target finds the efficient portfolio with the target return by a bisection between the traced portfolios.
+// A target below the return of the minimum-variance portfolio is not efficient, so it returns that portfolio.
+func (frontier *Frontier) target(problem *frontierProblem, gammas []float64, solutions [][]float64, target float64, maxReturn float64) (*EfficientPortfolio, bool) {
+	if target > maxReturn+1e-9 {
+		return frontier.evaluate(problem, solutions[len(solutions)-1]), false
+	}
+
+	i := 0
+	for i < len(solutions)-1 && problem.dot(problem.mean, solutions[i]) < target {
+		i++
+	}
+	if i == 0 {
+		return frontier.evaluate(problem, solutions[0]), true
+	}
+
+	low, high := gammas[i-1], gammas[i]
+	weights := solutions[i]
+	for j := 0; j < 50 && high-low > 1e-9; j++ {
+		gamma := (low + high) / 2
+		weights = problem.solve(gamma, weights)
+		if problem.dot(problem.mean, weights) < target {
+			low = gamma
+		} else {
+			high = gamma
+		}
+	}
+
+	return frontier.evaluate(problem, problem.solve(high, weights)), true
+}
+
+func (frontier *Frontier) evaluate(problem *frontierProblem, weights []float64) *EfficientPortfolio {
+	portfolio := &EfficientPortfolio{
+		Weights:    make(map[string]float64),
+		Return:     problem.dot(problem.mean, weights) * 100,
+		Volatility: math.Sqrt(math.Max(quadratic(problem.cov, weights), 0)) * 100,
+	}
+
+	for i, symbol := range frontier.Symbols {
+		portfolio.Weights[symbol] = weights[i] * 100
+	}
+
+	if portfolio.Volatility > 0 {
+		portfolio.Sharpe = (portfolio.Return - frontier.RiskFree) / portfolio.Volatility
+	}
+
+	return portfolio
+}
+
+func newFrontierProblem(portfolio *Portfolio, symbols []string, returns [][]float64, optimizer *Optimizer) (*frontierProblem, error) {
+	n := len(symbols)
+
+	problem := &frontierProblem{
+		lo:    make([]float64, n),
+		hi:    make([]float64, n),
+		class: make([]int, n),
+		caps:  make([]float64, 0),
+	}
+
+	problem.mean, problem.cov = computeCovariance(returns)
+
+	// Gershgorin's bound on the largest eigenvalue gives a safe step for the gradient descent
+	var lipschitz float64
+	for i := range symbols {
+		var row float64
+		for j := range symbols {
+			row += math.Abs(problem.cov[i][j])
+		}
+		lipschitz = math.Max(lipschitz, 2*row)
+	}
+	problem.step = 1
+	if lipschitz > 0 {
+		problem.step = 1 / lipschitz
+	}
+
+	classes := make(map[string]int)
+	for i, symbol := range symbols {
+		problem.lo[i] = lookupWeight(optimizer.Min, symbol, 0) / 100
+		problem.hi[i] = lookupWeight(optimizer.Max, symbol, 100) / 100
+		problem.class[i] = -1
+
+		// Every holding is capped by at most one class, so that the caps can be met one class at a time
+		asset := portfolio.Holdings[symbol].Asset
+		keys := []string{strings.ToLower(string(asset.Subclass)), strings.ToLower(string(asset.Class))}
+		for _, group := range sortedShockGroups() {
+			for _, class := range shockGroups[group] {
+				if class == asset.Class || class == asset.Subclass {
+					keys = append(keys, group)
+				}
+			}
+		}
+
+		capped := ""
+		for _, key := range keys {
+			if _, ok := optimizer.Classes[key]; !ok || key == capped {
+				continue
+			}
+			if capped != "" {
+				return nil, fmt.Errorf("Optimizer caps of %s and %s overlap on %s", capped, key, symbol)
+			}
+			capped = key
+		}
+		if capped == "" {
+			continue
+		}
+
+		index, ok := classes[capped]
+		if !ok {
+			index = len(problem.caps)
+			classes[capped] = index
+			problem.caps = append(problem.caps, optimizer.Classes[capped]/100)
+		}
+		problem.class[i] = index
+	}
+
+	// The constraints are feasible if the minimum weights fit in the budget and in the caps, and the maximum
+	// weights can fill the budget
+	minimum := problem.sum(problem.lo)
+	maximum := problem.sum(problem.hi)
+	if minimum > 1+1e-9 || maximum < 1-1e-9 {
+		return nil, errors.New("Optimizer constraints cannot be met: the weights should be able to add up to 100%")
+	}
+	for k, capped := range problem.caps {
+		var floor float64
+		for i := range symbols {
+			if problem.class[i] == k {
+				floor += problem.lo[i]
+			}
+		}
+		if floor > capped+1e-9 {
+			return nil, errors.New("Optimizer constraints cannot be met: the minimum weights exceed a class cap")
+		}
+	}
+
+	return problem, nil
+}
+
+// holdingReturns returns the monthly returns of every holding of the backtest, in percent
+func (result *PerformanceResult) holdingReturns() ([]string, [][]float64, error) {
+	symbols := make([]string, 0)
+	returns := make([][]float64, 0)
+
+	for _, symbol := range result.Portfolio.Symbols {
+		historic, ok := result.Assets[symbol]
+		if !ok || containsString(symbols, symbol) {
+			continue
+		}
+
+		symbols = append(symbols, symbol)
+		returns = append(returns, computeMonthlyReturns(historic))
+	}
+
+	if len(symbols) == 0 {
+		return nil, nil, errors.New("Portfolio has no holdings to analyze")
+	}
+
+	months := len(returns[0])
+	if months < minCovarianceMonths {
+		return nil, nil, fmt.Errorf("At least %d months of history are needed to estimate the covariance of the holdings", minCovarianceMonths)
+	}
+
+	for i := range returns {
+		if len(returns[i]) != months {
+			return nil, nil, errors.New("Mismatch length between the returns of the holdings")
+		}
+	}
+
+	return symbols, returns, nil
+}
+
+// computeCovariance returns the annualized means and covariances of the monthly returns, as fractions
+func computeCovariance(returns [][]float64) ([]float64, [][]float64) {
+	n := len(returns)
+	months := len(returns[0])
+
+	mean := make([]float64, n)
+	for i := range returns {
+		var sum float64
+		for _, r := range returns[i] {
+			sum += r / 100
+		}
+		mean[i] = sum / float64(months) * 12
+	}
+
+	cov := make([][]float64, n)
+	for i := range returns {
+		cov[i] = make([]float64, n)
+		for j := range returns {
+			var sum float64
+			for m := 0; m < months; m++ {
+				sum += (returns[i][m]/100 - mean[i]/12) * (returns[j][m]/100 - mean[j]/12)
+			}
+			cov[i][j] = sum / float64(months) * 12
+		}
+	}
+
+	return mean, cov
+}
+
+// solve minimizes the objective with the accelerated projected gradient method, starting from feasible weights
+func (problem *frontierProblem) solve(gamma float64, start []float64) []float64 {
+	n := len(problem.mean)
+
+	x := append([]float64{}, start...)
+	z := append([]float64{}, start...)
+	t := 1.0
+
+	for iteration := 0; iteration < optimizerIterations; iteration++ {
+		y := make([]float64, n)
+		for i := range z {
+			gradient := -gamma * problem.mean[i]
+			for j := range z {
+				gradient += 2 * problem.cov[i][j] * z[j]
+			}
+			y[i] = z[i] - problem.step*gradient
+		}
+
+		next := problem.project(y)
+		nextT := (1 + math.Sqrt(1+4*t*t)) / 2
+
+		var change float64
+		for i := range z {
+			z[i] = next[i] + (t-1)/nextT*(next[i]-x[i])
+			change = math.Max(change, math.Abs(next[i]-x[i]))
+		}
+
+		x, t = next, nextT
+		if change < optimizerTolerance {
+			break
+		}
+	}
+
+	return x
+}
+
+// project returns the feasible weights closest to y. The weights are y shifted by a common amount, and by a
+// further amount within every asset class whose cap binds, and clipped to their bounds. Both shifts are found
+// by bisection.
+func (problem *frontierProblem) project(y []float64) []float64 {
+	low, high := math.Inf(1), math.Inf(-1)
+	for i := range y {
+		low = math.Min(low, y[i]-problem.hi[i]-1)
+		high = math.Max(high, y[i]-problem.lo[i]+1)
+	}
+
+	shifted := func(shift float64) []float64 {
+		weights := make([]float64, len(y))
+		for i := range y {
+			weights[i] = clip(y[i]-shift, problem.lo[i], problem.hi[i])
+		}
+		return weights
+	}
+
+	for i := 0; i < bisectionIterations; i++ {
+		middle := (low + high) / 2
+		if problem.sum(shifted(middle)) > 1 {
+			low = middle
+		} else {
+			high = middle
+		}
+	}
+
+	shift := (low + high) / 2
+	weights := shifted(shift)
+
+	for k, capped := range problem.caps {
+		var total float64
+		for i := range weights {
+			if problem.class[i] == k {
+				total += weights[i]
+			}
+		}
+		if total <= capped {
+			continue
+		}
+
+		classLow, classHigh := 0.0, high-low+2
+		for i := range y {
+			if problem.class[i] == k {
+				classHigh = math.Max(classHigh, y[i]-shift-problem.lo[i]+1)
+			}
+		}
+
+		for j := 0; j < bisectionIterations; j++ {
+			middle := (classLow + classHigh) / 2
+			total = 0
+			for i := range y {
+				if problem.class[i] == k {
+					total += clip(y[i]-shift-middle, problem.lo[i], problem.hi[i])
+				}
+			}
+			if total > capped {
+				classLow = middle
+			} else {
+				classHigh = middle
+			}
+		}
+
+		for i := range y {
+			if problem.class[i] == k {
+				weights[i] = clip(y[i]-shift-classHigh, problem.lo[i], problem.hi[i])
+			}
+		}
+	}
+
+	return weights
+}
+
+// maxReturn returns the weights with the highest return. Filling the weights with the highest returns first,
+// up to their maximum and the caps of their classes, is optimal since every holding belongs to a single class.
+func (problem *frontierProblem) maxReturn() []float64 {
+	weights := append([]float64{}, problem.lo...)
+
+	room := append([]float64{}, problem.caps...)
+	budget := 1.0
+	for i, weight := range weights {
+		budget -= weight
+		if problem.class[i] >= 0 {
+			room[problem.class[i]] -= weight
+		}
+	}
+
+	order := make([]int, len(weights))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return problem.mean[order[a]] > problem.mean[order[b]]
+	})
+
+	for _, i := range order {
+		add := math.Min(problem.hi[i]-weights[i], budget)
+		if problem.class[i] >= 0 {
+			add = math.Min(add, room[problem.class[i]])
+			room[problem.class[i]] -= math.Max(add, 0)
+		}
+		if add <= 0 {
+			continue
+		}
+
+		weights[i] += add
+		budget -= add
+	}
+
+	return weights
+}
+
+// sum adds up the weights, counting no more than the cap of every asset class
+func (problem *frontierProblem) sum(weights []float64) float64 {
+	classes := make([]float64, len(problem.caps))
+
+	var total float64
+	for i, weight := range weights {
+		if problem.class[i] >= 0 {
+			classes[problem.class[i]] += weight
+			continue
+		}
+		total += weight
+	}
+
+	for k, capped := range problem.caps {
+		total += math.Min(classes[k], capped)
+	}
+
+	return total
+}
+
+func (problem *frontierProblem) dot(a []float64, b []float64) float64 {
+	var product float64
+	for i := range a {
+		product += a[i] * b[i]
+	}
+	return product
+}
+
+// lookupWeight returns the weight of the symbol, case-insensitive, or the default weight
+func lookupWeight(weights map[string]float64, symbol string, defaultWeight float64) float64 {
+	for name, weight := range weights {
+		if strings.EqualFold(name, symbol) {
+			return weight
+		}
+	}
+	return defaultWeight
+}
+
+func clip(value float64, low float64, high float64) float64 {
+	return math.Max(low, math.Min(high, value))
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
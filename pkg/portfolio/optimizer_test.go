@@ -0,0 +1,134 @@
+package portfolio
+
+import (
+	"math"
+	"testing"
+)
+
+func TestProject(t *testing.T) {
+	tests := []struct {
+		name  string
+		y     []float64
+		hi    []float64
+		class []int
+		caps  []float64
+		want  []float64
+	}{
+		{
+			name: "feasible",
+			y:    []float64{0.5, 0.3, 0.2},
+			want: []float64{0.5, 0.3, 0.2},
+		},
+		{
+			// A common shift of 0.15 clips the last weight to 0, and the other two add up to 1
+			name: "clipped at 0",
+			y:    []float64{0.7, 0.6, 0.1},
+			want: []float64{0.55, 0.45, 0},
+		},
+		{
+			// The first weight is clipped to its maximum of 40%, and a common shift of -0.2 fills the rest
+			name: "clipped at the maximum",
+			y:    []float64{0.8, 0.2, 0},
+			hi:   []float64{0.4, 1, 1},
+			want: []float64{0.4, 0.4, 0.2},
+		},
+		{
+			// The first two weights are capped at 50% together, so the third weight takes the other 50%,
+			// a shift of -0.3, and the capped class is shifted by a further 0.45
+			name:  "class cap",
+			y:     []float64{0.4, 0.4, 0.2},
+			class: []int{0, 0, -1},
+			caps:  []float64{0.5},
+			want:  []float64{0.25, 0.25, 0.5},
+		},
+	}
+
+	for _, test := range tests {
+		problem := newTestProblem(len(test.y))
+		if test.hi != nil {
+			problem.hi = test.hi
+		}
+		if test.class != nil {
+			problem.class, problem.caps = test.class, test.caps
+		}
+
+		weights := problem.project(test.y)
+		for i := range weights {
+			if math.Abs(weights[i]-test.want[i]) > 1e-9 {
+				t.Errorf("%s: weights = %v, want %v", test.name, weights, test.want)
+				break
+			}
+		}
+	}
+}
+
+func TestMaxReturn(t *testing.T) {
+	// The best holding is capped at 60% and its class at 70%, so the second best holding of the class only gets
+	// 10%, and the rest goes to the worst holding
+	problem := newTestProblem(3)
+	problem.mean = []float64{0.10, 0.08, 0.04}
+	problem.hi = []float64{0.6, 1, 1}
+	problem.class = []int{0, 0, -1}
+	problem.caps = []float64{0.7}
+
+	weights := problem.maxReturn()
+	want := []float64{0.6, 0.1, 0.3}
+	for i := range weights {
+		if math.Abs(weights[i]-want[i]) > 1e-9 {
+			t.Errorf("Weights = %v, want %v", weights, want)
+			break
+		}
+	}
+}
+
+func TestComputeCovariance(t *testing.T) {
+	// Monthly returns of 1% and 3% have a mean of 2% and a variance of 0.01% a month; the second holding moves
+	// against the first with twice the amplitude
+	mean, cov := computeCovariance([][]float64{{1, 3}, {2, -2}})
+
+	wantMean := []float64{0.24, 0}
+	wantCov := [][]float64{{0.0012, -0.0024}, {-0.0024, 0.0048}}
+
+	for i := range mean {
+		if math.Abs(mean[i]-wantMean[i]) > 1e-12 {
+			t.Errorf("Means = %v, want %v", mean, wantMean)
+		}
+		for j := range cov[i] {
+			if math.Abs(cov[i][j]-wantCov[i][j]) > 1e-12 {
+				t.Errorf("Covariances = %v, want %v", cov, wantCov)
+			}
+		}
+	}
+}
+
+func TestSolveMinimumVariance(t *testing.T) {
+	// The minimum-variance weights of uncorrelated holdings are proportional to the inverse of their variances
+	problem := newTestProblem(2)
+	problem.mean = []float64{0.08, 0.04}
+	problem.cov = [][]float64{{0.04, 0}, {0, 0.01}}
+	problem.step = 1 / 0.08
+
+	weights := problem.solve(0, []float64{0.5, 0.5})
+	if math.Abs(weights[0]-0.2) > 1e-6 || math.Abs(weights[1]-0.8) > 1e-6 {
+		t.Errorf("Minimum-variance weights = %v, want [0.2 0.8]", weights)
+	}
+}
+
+// newTestProblem returns a long-only problem of n holdings without class caps
+func newTestProblem(n int) *frontierProblem {
+	problem := &frontierProblem{
+		mean:  make([]float64, n),
+		lo:    make([]float64, n),
+		hi:    make([]float64, n),
+		class: make([]int, n),
+		caps:  make([]float64, 0),
+		step:  1,
+	}
+
+	for i := 0; i < n; i++ {
+		problem.hi[i] = 1
+		problem.class[i] = -1
+	}
+
+	return problem
+}
@@ -2,6 +2,7 @@ package portfolio
 
 import (
 	"errors"
+	"fmt"
 	"math"
 	"time"
 
@@ -13,28 +14,76 @@ import (
 
 // Performance analyzes the historic performance of a portfolio and compares it against a benchmark
 type Performance struct {
-	Portfolio       *Portfolio
-	BenchmarkSymbol string
-	InitialBalance  float64
-	StartDate       time.Time
-	EndDate         time.Time
-	Result          *PerformanceResult
-	Benchmark       *PerformanceResult
-	Ready           bool
+	Portfolio        *Portfolio
+	BenchmarkSymbol  string
+	InitialBalance   float64
+	ConfidenceLevels []float64
+	CPI              *CPI
+	Period           *Period
+	Proxies          Proxies
+	StartDate        time.Time
+	EndDate          time.Time
+	RiskFree         float64
+	Result           *PerformanceResult
+	Benchmark        *PerformanceResult
+	Relative         *RelativeStatistics
+	Ready            bool
 }
 
 // PerformanceResult contains the historic performance of a portfolio
 type PerformanceResult struct {
-	Portfolio    *Portfolio
-	Historic     []Historic
-	FinalBalance float64
-	CAGR         float64
-	Stdev        float64
-	BestYear     float64
-	WorstYear    float64
-	MaxDrawdown  float64
-	SharpeRatio  float64
-	Return       *Return
+	Portfolio     *Portfolio
+	Historic      []Historic
+	Daily         []Historic
+	Assets        map[string][]Historic
+	FinalBalance  float64
+	CAGR          float64
+	Stdev         float64
+	BestYear      float64
+	WorstYear     float64
+	MaxDrawdown   float64
+	Drawdowns     []*Drawdown
+	Underwater    []float64
+	Rolling       []*Rolling
+	Calendar      *CalendarReturns
+	SharpeRatio   float64
+	Risk          *RiskMetrics
+	Rebalances    int
+	Turnover      float64
+	Strategy      string
+	Return        *Return
+	Real          *RealResult
+	Contributions *ContributionResult
+	Simulated     []*SimulatedPeriod
+	allocations   []allocation
+}
+
+// backtest contains the time-weighted and the dollar monthly balances of a backtested portfolio, its
+// time-weighted daily balances if it ran on daily bars, its cash flows, the monthly quotes of its assets,
+// the quantities held after every trade, the number of rebalances, the total turnover and the periods
+// simulated by proxies
+type backtest struct {
+	Monthly     []Historic
+	Daily       []Historic
+	Balances    []Historic
+	Flows       []CashFlow
+	Assets      map[string][]Historic
+	Allocations []allocation
+	Rebalances  int
+	Turnover    float64
+	Simulated   []*SimulatedPeriod
+}
+
+// backtestOptions are the settings shared by the backtests of the portfolio and of its benchmark
+type backtestOptions struct {
+	startDate        time.Time
+	endDate          time.Time
+	initialBalance   float64
+	riskFree         float64
+	confidenceLevels []float64
+	cpi              *CPI
+	daily            bool
+	proxies          Proxies
 }
 
 // Historic represents a historic quote or portfolio value
@@ -60,10 +109,11 @@ type Return struct {
 // NewPerformance creates a new analysis of the historic performance of a portfolio
 func NewPerformance(portfolio *Portfolio, benchmark string, initialBalance float64) *Performance {
 	return &Performance{
-		Portfolio:       portfolio,
-		BenchmarkSymbol: benchmark,
-		InitialBalance:  initialBalance,
-		Ready:           false,
+		Portfolio:        portfolio,
+		BenchmarkSymbol:  benchmark,
+		InitialBalance:   initialBalance,
+		ConfidenceLevels: defaultConfidenceLevels,
+		Ready:            false,
 	}
 }
 
@@ -79,7 +129,12 @@ func NewReturn() *Return {
 
 // Compute generates the performance data for the portfolio
 func (performance *Performance) Compute() error {
-	startDate, endDate, err := computeStartAndEndDateForPortfolio(performance.Portfolio)
+	startDate, endDate, err := computeStartAndEndDateForPortfolio(performance.Portfolio, performance.Proxies)
+	if err != nil {
+		return err
+	}
+
+	startDate, endDate, err = performance.Period.dates(startDate, endDate)
 	if err != nil {
 		return err
 	}
@@ -88,36 +143,72 @@ func (performance *Performance) Compute() error {
 
 	normalized := computeNormalizedPortfolio(performance.Portfolio)
 
-	result, err := computeResult(normalized, performance.StartDate, performance.EndDate, performance.InitialBalance)
+	riskFree, err := computeRiskFreeReturn()
+	if err != nil {
+		return err
+	}
+	performance.RiskFree = riskFree
+
+	options := backtestOptions{
+		startDate:        performance.StartDate,
+		endDate:          performance.EndDate,
+		initialBalance:   performance.InitialBalance,
+		riskFree:         riskFree,
+		confidenceLevels: performance.ConfidenceLevels,
+		cpi:              performance.CPI,
+		daily:            performance.Period.daily(),
+		proxies:          performance.Proxies,
+	}
+
+	result, err := computeResult(normalized, options)
 	if err != nil {
 		return err
 	}
 	performance.Result = result
 
+	// The benchmark receives the same contributions, so that its ending balance is comparable
 	benchmark := computeBenchmark(performance.BenchmarkSymbol)
+	benchmark.Contribution = normalized.Contribution
 
-	benchmarkResult, err := computeResult(benchmark, performance.StartDate, performance.EndDate, performance.InitialBalance)
+	benchmarkResult, err := computeResult(benchmark, options)
 	if err != nil {
 		return err
 	}
 	performance.Benchmark = benchmarkResult
 
+	performance.Relative = computeRelativeStatistics(result, benchmarkResult, riskFree)
+
 	performance.Ready = true
 
 	return nil
 }
 
-func computeResult(portfolio *Portfolio, startDate time.Time, endDate time.Time, initialBalance float64) (*PerformanceResult, error) {
+func computeResult(portfolio *Portfolio, options backtestOptions) (*PerformanceResult, error) {
+	startDate, endDate := options.startDate, options.endDate
+
 	result := NewPerformanceResult()
 
 	result.Portfolio = portfolio
 
-	monthly, err := computeMonthlyBalances(portfolio, startDate, endDate, initialBalance)
+	backtest, err := computeMonthlyBalances(portfolio, options)
 	if err != nil {
 		return nil, err
 	}
+	monthly := backtest.Monthly
 	result.Historic = monthly
+	result.Daily = backtest.Daily
+	result.Simulated = backtest.Simulated
+	result.Assets = backtest.Assets
 	result.FinalBalance = monthly[len(monthly)-1].Close
+	result.allocations = backtest.Allocations
+	result.Rebalances = backtest.Rebalances
+	result.Strategy = strategyName(portfolio)
+
+	// Turnover is reported as the average fraction of the portfolio traded per year
+	years := endDate.Sub(startDate).Hours() / 24 / 365
+	if years > 0 {
+		result.Turnover = backtest.Turnover / years * 100
+	}
 
 	cagr := computeCAGR(startDate, endDate, monthly[0].Open, result.FinalBalance)
 	result.CAGR = cagr
@@ -127,26 +218,37 @@ func computeResult(portfolio *Portfolio, startDate time.Time, endDate time.Time,
 	sd := computeStandardDeviation(monthlyReturns)
 	result.Stdev = sd
 
-	maxDrawdown := computeMaxDrawdown(monthlyReturns)
-	result.MaxDrawdown = maxDrawdown
+	result.Underwater = computeDrawdownSeries(result.Historic)
+	result.Drawdowns = computeDrawdowns(result.Historic, maxDrawdowns)
+	if len(result.Drawdowns) > 0 {
+		result.MaxDrawdown = result.Drawdowns[0].Depth
+	}
 
 	yearly := computeYearlyReturns(result.Historic, startDate, endDate)
 	best, worst := computeBestAndWorstYears(yearly)
 	result.BestYear = best
 	result.WorstYear = worst
+	result.Calendar = computeCalendarReturns(result.Historic, yearly, startDate)
 
-	sharpe, err := computeSharpeRatio(result.CAGR, result.Stdev)
-	if err != nil {
-		return nil, err
-	}
+	sharpe := computeSharpeRatio(result.CAGR, result.Stdev, options.riskFree)
 	result.SharpeRatio = sharpe
 
+	result.Risk = computeRiskMetrics(result, monthlyReturns, options.riskFree, options.confidenceLevels)
+
+	result.Rolling = computeRolling(result.Historic)
+
 	portfolioReturn, err := computeReturns(result, startDate, endDate)
 	if err != nil {
 		return nil, err
 	}
 	result.Return = portfolioReturn
 
+	if portfolio.Contribution != nil {
+		result.Contributions = computeContributionResult(backtest.Balances, backtest.Flows, endDate, result.CAGR)
+	}
+
+	result.Real = computeRealResult(result, options.cpi, options.riskFree, startDate, endDate)
+
 	return result, nil
 }
 
@@ -155,19 +257,19 @@ func computeReturns(result *PerformanceResult, startDate time.Time, endDate time
 
 	portfolioReturn.Max = result.CAGR
 
-	oneMonth, err := computeXMonthReturn(result.Portfolio, startDate, endDate, 1, portfolioReturn.Max, result.FinalBalance)
+	oneMonth, err := computeXMonthReturn(result, startDate, endDate, 1, portfolioReturn.Max)
 	if err != nil {
 		return nil, err
 	}
 	portfolioReturn.OneMonth = oneMonth
 
-	threeMonth, err := computeXMonthReturn(result.Portfolio, startDate, endDate, 3, portfolioReturn.Max, result.FinalBalance)
+	threeMonth, err := computeXMonthReturn(result, startDate, endDate, 3, portfolioReturn.Max)
 	if err != nil {
 		return nil, err
 	}
 	portfolioReturn.ThreeMonth = threeMonth
 
-	sixMonth, err := computeXMonthReturn(result.Portfolio, startDate, endDate, 6, portfolioReturn.Max, result.FinalBalance)
+	sixMonth, err := computeXMonthReturn(result, startDate, endDate, 6, portfolioReturn.Max)
 	if err != nil {
 		return nil, err
 	}
@@ -178,29 +280,29 @@ func computeReturns(result *PerformanceResult, startDate time.Time, endDate time
 	if startOfYear.Before(startDate) {
 		ytd = portfolioReturn.Max
 	} else {
-		ytd, err = computeShortTermReturn(result.Portfolio, startOfYear, endDate, result.FinalBalance)
+		ytd, err = computeShortTermReturn(result, startOfYear, endDate)
 	}
 	portfolioReturn.YTD = ytd
 
-	oneYear, err := computeXYearReturn(result.Portfolio, startDate, endDate, 1, portfolioReturn.Max, result.FinalBalance)
+	oneYear, err := computeXYearReturn(result, startDate, endDate, 1, portfolioReturn.Max)
 	if err != nil {
 		return nil, err
 	}
 	portfolioReturn.OneYear = oneYear
 
-	threeYear, err := computeXYearReturn(result.Portfolio, startDate, endDate, 3, portfolioReturn.Max, result.FinalBalance)
+	threeYear, err := computeXYearReturn(result, startDate, endDate, 3, portfolioReturn.Max)
 	if err != nil {
 		return nil, err
 	}
 	portfolioReturn.ThreeYear = threeYear
 
-	fiveYear, err := computeXYearReturn(result.Portfolio, startDate, endDate, 5, portfolioReturn.Max, result.FinalBalance)
+	fiveYear, err := computeXYearReturn(result, startDate, endDate, 5, portfolioReturn.Max)
 	if err != nil {
 		return nil, err
 	}
 	portfolioReturn.FiveYear = fiveYear
 
-	tenYear, err := computeXYearReturn(result.Portfolio, startDate, endDate, 10, portfolioReturn.Max, result.FinalBalance)
+	tenYear, err := computeXYearReturn(result, startDate, endDate, 10, portfolioReturn.Max)
 	if err != nil {
 		return nil, err
 	}
@@ -209,77 +311,100 @@ func computeReturns(result *PerformanceResult, startDate time.Time, endDate time
 	return portfolioReturn, nil
 }
 
-func computeXMonthReturn(portfolio *Portfolio, startDate time.Time, endDate time.Time, monthsAgo int, max float64, finalBalance float64) (float64, error) {
-	var result float64
+func computeXMonthReturn(result *PerformanceResult, startDate time.Time, endDate time.Time, monthsAgo int, max float64) (float64, error) {
+	var xMonthReturn float64
 	xMonthsAgo := endDate.AddDate(0, (-1)*monthsAgo, 0)
 	if xMonthsAgo.Before(startDate) {
-		result = max
+		xMonthReturn = max
 	} else {
-		xMonth, err := computeShortTermReturn(portfolio, xMonthsAgo, endDate, finalBalance)
+		xMonth, err := computeShortTermReturn(result, xMonthsAgo, endDate)
 		if err != nil {
 			return 0, err
 		}
-		result = xMonth
+		xMonthReturn = xMonth
 	}
 
-	return result, nil
+	return xMonthReturn, nil
 }
 
-func computeXYearReturn(portfolio *Portfolio, startDate time.Time, endDate time.Time, yearsAgo int, max float64, finalBalance float64) (float64, error) {
-	var result float64
+func computeXYearReturn(result *PerformanceResult, startDate time.Time, endDate time.Time, yearsAgo int, max float64) (float64, error) {
+	var xYearReturn float64
 	xYearsAgo := endDate.AddDate((-1)*yearsAgo, 0, 0)
 	if xYearsAgo.Before(startDate) {
-		result = max
+		xYearReturn = max
 	} else {
-		xYear, err := computeLongTermReturn(portfolio, xYearsAgo, endDate, finalBalance)
+		xYear, err := computeLongTermReturn(result, xYearsAgo, endDate)
 		if err != nil {
 			return 0, err
 		}
-		result = xYear
+		xYearReturn = xYear
 	}
 
-	return result, nil
+	return xYearReturn, nil
 }
 
-func computeShortTermReturn(portfolio *Portfolio, startDate time.Time, endDate time.Time, finalBalance float64) (float64, error) {
-	value, err := computePortfolioValueForDate(portfolio, startDate, endDate)
+func computeShortTermReturn(result *PerformanceResult, startDate time.Time, endDate time.Time) (float64, error) {
+	value, err := computePortfolioValueForDate(result, startDate, endDate)
 	if err != nil {
 		return 0, err
 	}
 
-	result := ((finalBalance - value) / value) * 100
+	shortTerm := ((result.FinalBalance - value) / value) * 100
 
-	return result, nil
+	return shortTerm, nil
 }
 
-func computeLongTermReturn(portfolio *Portfolio, startDate time.Time, endDate time.Time, finalBalance float64) (float64, error) {
-	value, err := computePortfolioValueForDate(portfolio, startDate, endDate)
+func computeLongTermReturn(result *PerformanceResult, startDate time.Time, endDate time.Time) (float64, error) {
+	value, err := computePortfolioValueForDate(result, startDate, endDate)
 	if err != nil {
 		return 0, err
 	}
 
-	result := computeCAGR(startDate, endDate, value, finalBalance)
+	longTerm := computeCAGR(startDate, endDate, value, result.FinalBalance)
 
-	return result, nil
+	return longTerm, nil
 }
 
-func computePortfolioValueForDate(portfolio *Portfolio, earliest time.Time, endDate time.Time) (float64, error) {
+// computePortfolioValueForDate values the quantities held on the date. Rebalancing trades are
+// self-financing, so the growth from that value to the final balance is the return of the period.
+// Daily backtests already have the value at the close of the previous day. Dates simulated by proxies,
+// which have no quotes of their own, are valued at the close of the previous month.
+func computePortfolioValueForDate(result *PerformanceResult, earliest time.Time, endDate time.Time) (float64, error) {
+	if len(result.Daily) > 0 {
+		return computeValueFromHistoric(result.Daily, earliest), nil
+	}
+	if simulatedOn(result.Simulated, earliest) {
+		return computeValueFromHistoric(result.Historic, earliest), nil
+	}
+
 	var value float64
 
-	for _, symbol := range portfolio.Symbols {
-		holding := portfolio.Holdings[symbol]
+	quantities := quantitiesForDate(result.allocations, earliest)
 
+	for _, symbol := range result.Portfolio.Symbols {
 		assetValue, err := computeAssetValueForDate(earliest, endDate, symbol)
 		if err != nil {
 			return 0, nil
 		}
 
-		value += assetValue * holding.Quantity
+		value += assetValue * quantities[symbol]
 	}
 
 	return value, nil
 }
 
+// computeValueFromHistoric returns the close of the last bar before the date
+func computeValueFromHistoric(historic []Historic, date time.Time) float64 {
+	value := historic[0].Open
+	for _, bar := range historic {
+		if !bar.Date.Before(date) {
+			break
+		}
+		value = bar.Close
+	}
+	return value
+}
+
 func computeAssetValueForDate(earliest time.Time, endDate time.Time, symbol string) (float64, error) {
 	p := &chart.Params{
 		Symbol:   symbol,
@@ -308,8 +433,16 @@ func computeNormalizedPortfolio(portfolio *Portfolio) *Portfolio {
 
 	// If there is no target allocation specified, use the actual allocation instead
 	if total == 0 {
+		var invested float64
+		for _, v := range portfolio.Status.Allocation {
+			invested += v
+		}
+
+		// Cash held in the portfolio is not part of the backtest, so scale the holdings up to 100%
 		for k, v := range portfolio.Status.Allocation {
-			normalized.TargetAllocation[k] = v
+			if invested != 0 {
+				normalized.TargetAllocation[k] = v * 100 / invested
+			}
 		}
 	}
 
@@ -329,7 +462,7 @@ func computeBenchmark(symbol string) *Portfolio {
 	return benchmark
 }
 
-func computeStartAndEndDateForPortfolio(portfolio *Portfolio) (time.Time, time.Time, error) {
+func computeStartAndEndDateForPortfolio(portfolio *Portfolio, proxies Proxies) (time.Time, time.Time, error) {
 	ny, err := time.LoadLocation("America/New_York")
 	if err != nil {
 		return time.Time{}, time.Time{}, err
@@ -347,7 +480,7 @@ func computeStartAndEndDateForPortfolio(portfolio *Portfolio) (time.Time, time.T
 	startDate := earliest
 
 	for _, symbol := range portfolio.Symbols {
-		start, err := computeStartDateForAsset(earliest, now, symbol)
+		start, err := proxies.startDate(earliest, now, symbol)
 		if err != nil {
 			return time.Time{}, time.Time{}, err
 		}
@@ -391,14 +524,18 @@ func computeCAGR(startDate time.Time, endDate time.Time, initialBalance float64,
 	return cagr
 }
 
-func computeMonthlyBalancesForAsset(symbol string, startDate time.Time, endDate time.Time) ([]finance.ChartBar, error) {
-	monthly := make([]finance.ChartBar, 0)
+// computeBarsForAsset returns the bars of a symbol between the two dates. It is a variable so that tests can
+// backtest bars of their own.
+var computeBarsForAsset = fetchBarsForAsset
+
+func fetchBarsForAsset(symbol string, startDate time.Time, endDate time.Time, interval datetime.Interval) ([]finance.ChartBar, error) {
+	bars := make([]finance.ChartBar, 0)
 
 	p := &chart.Params{
 		Symbol:   symbol,
 		Start:    datetime.New(&startDate),
 		End:      datetime.New(&endDate),
-		Interval: datetime.OneMonth,
+		Interval: interval,
 	}
 
 	iter := chart.Get(p)
@@ -408,47 +545,260 @@ func computeMonthlyBalancesForAsset(symbol string, startDate time.Time, endDate
 
 	for iter.Next() {
 		b := iter.Bar()
-		monthly = append(monthly, *b)
+		bars = append(bars, *b)
 	}
 
-	return monthly, nil
+	return bars, nil
 }
 
-func computeMonthlyBalances(portfolio *Portfolio, startDate time.Time, endDate time.Time, initialBalance float64) ([]Historic, error) {
-	var monthly []Historic
+// computeMonthlyBalances buys the targets at the open of the first month, then applies the flows and the
+// rebalances at the close of every month. The monthly balances measure the growth of the initial balance,
+// while the dollar balances include the flows.
+func computeMonthlyBalances(portfolio *Portfolio, options backtestOptions) (*backtest, error) {
+	startDate, endDate, initialBalance, daily := options.startDate, options.endDate, options.initialBalance, options.daily
 
-	for _, symbol := range portfolio.Symbols {
-		holding := portfolio.Holdings[symbol]
-		allocation := portfolio.TargetAllocation[symbol]
+	if len(portfolio.Symbols) == 0 {
+		return nil, errors.New("Portfolio has no holdings to backtest")
+	}
+
+	name := "monthly"
+	if daily {
+		name = "daily"
+	}
+
+	bars := make(map[string][]finance.ChartBar)
+	periods := -1
+	simulated := make([]*SimulatedPeriod, 0)
 
-		monthlyForAsset, err := computeMonthlyBalancesForAsset(symbol, startDate, endDate)
+	for _, symbol := range portfolio.Symbols {
+		barsForAsset, simulatedForAsset, err := options.proxies.bars(symbol, startDate, endDate, daily)
 		if err != nil {
 			return nil, err
 		}
 
-		initialQuote, _ := monthlyForAsset[0].Open.Float64()
-		holding.Quantity = (initialBalance * (allocation / 100)) / initialQuote
+		// A merged portfolio may list a symbol held in several portfolios more than once
+		if _, ok := bars[symbol]; !ok && simulatedForAsset != nil {
+			simulated = append(simulated, simulatedForAsset)
+		}
+
+		if len(barsForAsset) == 0 {
+			return nil, fmt.Errorf("No %s data for %s", name, symbol)
+		}
+
+		if periods >= 0 && periods != len(barsForAsset) {
+			return nil, fmt.Errorf("Mismatch length between the %s data of the holdings", name)
+		}
+
+		bars[symbol] = barsForAsset
+		periods = len(barsForAsset)
+	}
+
+	// The holdings are bought at the open adjusted for dividends like the closes, so that the balances start
+	// at the initial balance
+	quantities := make(map[string]float64)
+	for _, symbol := range portfolio.Symbols {
+		initialQuote := adjustedOpen(bars[symbol][0])
+		quantities[symbol] = (initialBalance * (portfolio.TargetAllocation[symbol] / 100)) / initialQuote
+		portfolio.Holdings[symbol].Quantity = quantities[symbol]
+	}
+
+	// Units scale the dollar quantities to the time-weighted quantities of the initial balance. Every flow
+	// buys or sells units at their current value, so it does not count as growth.
+	units := 1.0
+
+	allocations := []allocation{{Date: startDate, Quantities: quantities}}
+	monthly := make([]Historic, periods)
+	balances := make([]Historic, periods)
+	flows := []CashFlow{{Date: startDate, Amount: -initialBalance}}
+	assets := make(map[string][]Historic)
+	var rebalances int
+	var turnover float64
+
+	// A strategy decides the targets at the end of every month from the month-end quotes so far
+	targets := portfolio.TargetAllocation
+	history := make(map[string][]Historic)
+	opens := make(map[string]float64)
+
+	for i := 0; i < periods; i++ {
+		closes := make(map[string]float64)
+
+		for _, symbol := range portfolio.Symbols {
+			bar := bars[symbol][i]
+
+			open := adjustedOpen(bar)
+			balances[i].Open += open * quantities[symbol]
+
+			close, _ := bar.AdjClose.Float64()
+			balances[i].Close += close * quantities[symbol]
+			closes[symbol] = close
+
+			if _, ok := opens[symbol]; !ok {
+				opens[symbol] = open
+			}
+
+			balances[i].Date = time.Unix(int64(bar.Timestamp), 0).In(startDate.Location())
+
+			// A merged portfolio may list a symbol held in several portfolios more than once
+			if len(assets[symbol]) == i {
+				assets[symbol] = append(assets[symbol], Historic{Open: open, Close: close, Date: balances[i].Date})
+			}
+		}
+
+		monthly[i] = Historic{
+			Date:  balances[i].Date,
+			Open:  balances[i].Open * units,
+			Close: balances[i].Close * units,
+		}
+
+		if i == periods-1 || balances[i].Close == 0 {
+			continue
+		}
+
+		next := time.Unix(int64(bars[portfolio.Symbols[0]][i+1].Timestamp), 0).In(startDate.Location())
+		traded := false
+
+		// Flows and rebalances happen at the close of the last day of the month
+		if daily && sameMonth(balances[i].Date, next) {
+			continue
+		}
+
+		if portfolio.Strategy != nil {
+			for symbol, close := range closes {
+				history[symbol] = append(history[symbol], Historic{Open: opens[symbol], Close: close, Date: balances[i].Date})
+			}
+			targets = strategyTargets(portfolio, balances[i].Date, history)
+		}
+		opens = make(map[string]float64)
+
+		flow := portfolio.Contribution.amount(balances[i].Date, startDate, options.cpi)
+		if flow != 0 {
+			var added float64
+			quantities, added = applyFlow(portfolio, flow, quantities, closes, targets)
+
+			before := balances[i].Close
+			balances[i].Close += added
+			if balances[i].Close > 0 {
+				units *= before / balances[i].Close
+			}
 
-		if monthly == nil {
-			monthly = make([]Historic, len(monthlyForAsset))
+			flows = append(flows, CashFlow{Date: next, Amount: -added})
+			traded = true
+
+			if balances[i].Close == 0 {
+				continue
+			}
 		}
 
-		if len(monthly) != len(monthlyForAsset) {
-			return nil, errors.New("Mismatch length between monthly and monthlyForAsset")
+		if rebalanceDue(portfolio, balances[i].Date, balances[i].Close, quantities, closes, targets) {
+			var fraction float64
+			quantities, fraction = rebalance(portfolio, balances[i].Close, quantities, closes, targets)
+			turnover += fraction
+			rebalances++
+			traded = true
 		}
 
-		for i := range monthlyForAsset {
-			open, _ := monthlyForAsset[i].Open.Float64()
-			monthly[i].Open += open * holding.Quantity
+		if traded {
+			unitQuantities := make(map[string]float64)
+			for symbol, quantity := range quantities {
+				unitQuantities[symbol] = quantity * units
+			}
+			allocations = append(allocations, allocation{Date: next, Quantities: unitQuantities})
+		}
+	}
 
-			close, _ := monthlyForAsset[i].AdjClose.Float64()
-			monthly[i].Close += close * holding.Quantity
+	result := &backtest{
+		Monthly:     monthly,
+		Balances:    balances,
+		Flows:       flows,
+		Assets:      assets,
+		Allocations: allocations,
+		Rebalances:  rebalances,
+		Turnover:    turnover,
+		Simulated:   simulated,
+	}
 
-			monthly[i].Date = time.Unix(int64(monthlyForAsset[i].Timestamp), 0).In(startDate.Location())
+	if daily {
+		result.Daily = monthly
+		result.Monthly = computeMonthlyHistoric(monthly)
+		result.Balances = computeMonthlyHistoric(balances)
+		for symbol, quotes := range assets {
+			result.Assets[symbol] = computeMonthlyHistoric(quotes)
 		}
 	}
 
-	return monthly, nil
+	return result, nil
+}
+
+// applyFlow splits a contribution or a withdrawal between the holdings by the contribution policy, and trades
+// them at their closes. It returns the new quantities and the amount actually added to the holdings.
+func applyFlow(portfolio *Portfolio, flow float64, quantities map[string]float64, closes map[string]float64, targets map[string]float64) (map[string]float64, float64) {
+	values := make(map[string]float64)
+	for _, symbol := range portfolio.Symbols {
+		values[symbol] = closes[symbol] * quantities[symbol]
+	}
+
+	flowed := make(map[string]float64)
+	for symbol, quantity := range quantities {
+		flowed[symbol] = quantity
+	}
+
+	var added float64
+	for symbol, amount := range portfolio.Contribution.allocate(flow, values, targets) {
+		if closes[symbol] > 0 {
+			flowed[symbol] += amount / closes[symbol]
+			added += amount
+		}
+	}
+
+	return flowed, added
+}
+
+// rebalanceDue tells whether the holdings are traded back to the targets at the close of the given date. A
+// strategy without a rebalancing policy trades at the end of every month.
+func rebalanceDue(portfolio *Portfolio, date time.Time, balance float64, quantities map[string]float64, closes map[string]float64, targets map[string]float64) bool {
+	if portfolio.Strategy != nil && (portfolio.Rebalance == nil || portfolio.Rebalance.Strategy == RebalanceNone) {
+		return true
+	}
+
+	weights := make(map[string]float64)
+	for _, symbol := range portfolio.Symbols {
+		weights[symbol] = closes[symbol] * quantities[symbol] / balance * 100
+	}
+
+	return portfolio.Rebalance.due(date, weights, targets)
+}
+
+// rebalance trades the holdings to the targets at their closes. It returns the new quantities and the
+// turnover of the trades as a fraction of the balance.
+func rebalance(portfolio *Portfolio, balance float64, quantities map[string]float64, closes map[string]float64, targets map[string]float64) (map[string]float64, float64) {
+	rebalanced := make(map[string]float64)
+	var volume float64
+
+	for _, symbol := range portfolio.Symbols {
+		if closes[symbol] == 0 {
+			rebalanced[symbol] = quantities[symbol]
+			continue
+		}
+
+		target := balance * targets[symbol] / 100
+		rebalanced[symbol] = target / closes[symbol]
+		volume += math.Abs(target - closes[symbol]*quantities[symbol])
+	}
+
+	// Every dollar sold is a dollar bought, so only half of the traded amount turns over
+	return rebalanced, volume / 2 / balance
+}
+
+// adjustedOpen returns the open of the bar adjusted for dividends and splits by the ratio of its adjusted
+// close to its close, since only the closes of a symbol are adjusted
+func adjustedOpen(bar finance.ChartBar) float64 {
+	open, _ := bar.Open.Float64()
+	close, _ := bar.Close.Float64()
+	adjusted, _ := bar.AdjClose.Float64()
+	if close > 0 {
+		open *= adjusted / close
+	}
+	return open
 }
 
 func computeStandardDeviation(monthlyReturns []float64) float64 {
@@ -504,7 +854,10 @@ func computeYearlyReturns(historic []Historic, startDate time.Time, endDate time
 		}
 	}
 
-	returns[years-1] = ((historic[len(historic)-1].Close - lastClose) / lastClose) * 100
+	// The last year is partial, unless the backtest ends in December
+	if curr < years {
+		returns[years-1] = ((historic[len(historic)-1].Close - lastClose) / lastClose) * 100
+	}
 
 	return returns
 }
@@ -522,18 +875,6 @@ func computeBestAndWorstYears(yearlyReturns []float64) (float64, float64) {
 	return best, worst
 }
 
-func computeMaxDrawdown(monthlyReturns []float64) float64 {
-	var maxDrawdown float64
-
-	for _, monthly := range monthlyReturns {
-		if monthly < maxDrawdown {
-			maxDrawdown = monthly
-		}
-	}
-
-	return maxDrawdown
-}
-
 func computeRiskFreeReturn() (float64, error) {
 	// We use the yield of the 13-week treasury bill as the risk-free return
 	quote, err := quote.Get("^IRX")
@@ -544,13 +885,8 @@ func computeRiskFreeReturn() (float64, error) {
 	return quote.RegularMarketPrice, nil
 }
 
-func computeSharpeRatio(cagr float64, stdev float64) (float64, error) {
-	riskFree, err := computeRiskFreeReturn()
-	if err != nil {
-		return 0, err
-	}
-
+func computeSharpeRatio(cagr float64, stdev float64, riskFree float64) float64 {
 	sharpe := (cagr - riskFree) / stdev
 
-	return sharpe, nil
+	return sharpe
 }
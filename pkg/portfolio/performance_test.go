@@ -0,0 +1,201 @@
+package portfolio
+
+import (
+	"math"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/piquette/finance-go"
+	"github.com/piquette/finance-go/datetime"
+	"github.com/shopspring/decimal"
+)
+
+func TestRebalance(t *testing.T) {
+	// 6 VTI at 100 and 40 BND at 10 are worth 600 and 400, so 100 of each is traded back to 500 of each
+	portfolio := newTestPortfolio(map[string]float64{"VTI": 50, "BND": 50})
+	quantities := map[string]float64{"VTI": 6, "BND": 40}
+	closes := map[string]float64{"VTI": 100, "BND": 10}
+
+	rebalanced, turnover := rebalance(portfolio, 1000, quantities, closes, portfolio.TargetAllocation)
+	if rebalanced["VTI"] != 5 || rebalanced["BND"] != 50 {
+		t.Errorf("Rebalanced quantities = %v, want 5 VTI and 50 BND", rebalanced)
+	}
+	if math.Abs(turnover-0.1) > 1e-12 {
+		t.Errorf("Turnover = %v, want 0.1", turnover)
+	}
+
+	tests := []struct {
+		name   string
+		policy *Rebalance
+		due    bool
+	}{
+		{name: "no policy", policy: nil, due: false},
+		{name: "within the band", policy: &Rebalance{Strategy: RebalanceAbsoluteBand, Threshold: 15}, due: false},
+		{name: "outside the band", policy: &Rebalance{Strategy: RebalanceAbsoluteBand, Threshold: 5}, due: true},
+	}
+
+	for _, test := range tests {
+		portfolio.Rebalance = test.policy
+		if due := rebalanceDue(portfolio, month(0), 1000, quantities, closes, portfolio.TargetAllocation); due != test.due {
+			t.Errorf("%s: rebalance due = %v, want %v", test.name, due, test.due)
+		}
+	}
+}
+
+func TestApplyFlow(t *testing.T) {
+	// A contribution of 100 buys 50 of VTI at 100 and 50 of BND at 10
+	portfolio := newTestPortfolio(map[string]float64{"VTI": 50, "BND": 50})
+	portfolio.Contribution = &Contribution{Amount: 100, Frequency: ContributeMonthly, Allocation: AllocateTarget}
+	quantities := map[string]float64{"VTI": 6, "BND": 40}
+	closes := map[string]float64{"VTI": 100, "BND": 10}
+
+	flowed, added := applyFlow(portfolio, 100, quantities, closes, portfolio.TargetAllocation)
+	if flowed["VTI"] != 6.5 || flowed["BND"] != 45 {
+		t.Errorf("Quantities after the flow = %v, want 6.5 VTI and 45 BND", flowed)
+	}
+	if added != 100 {
+		t.Errorf("Added %v, want 100", added)
+	}
+	if quantities["VTI"] != 6 {
+		t.Errorf("The flow changed the quantities before it: %v", quantities)
+	}
+}
+
+func TestComputeMonthlyReturns(t *testing.T) {
+	// The first month returns from its open, and the later ones from the previous close
+	historic := []Historic{
+		{Date: month(0), Open: 100, Close: 110},
+		{Date: month(1), Open: 110, Close: 99},
+		{Date: month(2), Open: 99, Close: 108.9},
+	}
+
+	returns := computeMonthlyReturns(historic)
+	want := []float64{10, -10, 10}
+	for i := range returns {
+		if math.Abs(returns[i]-want[i]) > 1e-9 {
+			t.Errorf("Monthly returns = %v, want %v", returns, want)
+			break
+		}
+	}
+
+	// An annualized deviation of the returns of 10, -10 and 10: the population deviation of 9.43 times sqrt(12)
+	if want := math.Sqrt(800.0/9) * math.Sqrt(12); math.Abs(computeStandardDeviation(returns)-want) > 1e-9 {
+		t.Errorf("Standard deviation = %v, want %v", computeStandardDeviation(returns), want)
+	}
+}
+
+func TestComputeCAGR(t *testing.T) {
+	tests := []struct {
+		name  string
+		days  int
+		final float64
+		want  float64
+	}{
+		{name: "one year", days: 365, final: 110, want: 10},
+		{name: "two years", days: 730, final: 121, want: 10},
+		{name: "loss", days: 730, final: 81, want: -10},
+	}
+
+	for _, test := range tests {
+		got := computeCAGR(day(0), day(test.days), 100, test.final)
+		if math.Abs(got-test.want) > 1e-9 {
+			t.Errorf("%s: CAGR = %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestContributionRebalances(t *testing.T) {
+	restore := setTestBars(map[string][]finance.ChartBar{
+		"VTI": {testBar(0, 100, 110, 110), testBar(1, 110, 120, 120), testBar(2, 120, 100, 100), testBar(3, 100, 130, 130)},
+		"BND": {testBar(0, 10, 10, 10), testBar(1, 10, 11, 11), testBar(2, 11, 11, 11), testBar(3, 11, 10, 10)},
+	})
+	defer restore()
+
+	tests := []struct {
+		name       string
+		rebalance  *Rebalance
+		rebalances int
+	}{
+		{name: "no rebalancing", rebalance: nil, rebalances: 0},
+		{name: "monthly rebalancing", rebalance: &Rebalance{Strategy: RebalanceMonthly}, rebalances: 3},
+	}
+
+	for _, test := range tests {
+		// A contribution at the end of every month trades the holdings without rebalancing them
+		portfolio := newTestPortfolio(map[string]float64{"VTI": 60, "BND": 40})
+		portfolio.Contribution = &Contribution{Amount: 100, Frequency: ContributeMonthly, Allocation: AllocateTarget}
+		portfolio.Rebalance = test.rebalance
+
+		result, err := computeResult(portfolio, backtestOptions{startDate: month(0), endDate: month(3), initialBalance: 10000})
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+
+		if result.Rebalances != test.rebalances {
+			t.Errorf("%s: %d rebalances, want %d", test.name, result.Rebalances, test.rebalances)
+		}
+		if len(result.Contributions.Flows) != 4 {
+			t.Errorf("%s: %d flows, want the initial balance and 3 contributions", test.name, len(result.Contributions.Flows))
+		}
+	}
+}
+
+// TestBacktestAdjustedOpen checks that a dividend adjustment of the first bar is not mistaken for a drawdown
+func TestBacktestAdjustedOpen(t *testing.T) {
+	// The price stays at 100 while a dividend of 5 is paid, so the adjusted closes rise from 90 to 95
+	restore := setTestBars(map[string][]finance.ChartBar{
+		"VTI": {testBar(0, 100, 100, 90), testBar(1, 100, 100, 95)},
+	})
+	defer restore()
+
+	portfolio := newTestPortfolio(map[string]float64{"VTI": 100})
+	backtest, err := computeMonthlyBalances(portfolio, backtestOptions{startDate: month(0), endDate: month(1), initialBalance: 10000})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := backtest.Monthly[0].Open; math.Abs(got-10000) > 1e-6 {
+		t.Errorf("Opening balance = %v, want 10000", got)
+	}
+	if got := backtest.Monthly[1].Close; math.Abs(got-10000*95.0/90) > 1e-6 {
+		t.Errorf("Final balance = %v, want %v", got, 10000*95.0/90)
+	}
+	if drawdowns := computeDrawdowns(backtest.Monthly, maxDrawdowns); len(drawdowns) != 0 {
+		t.Errorf("Drawdowns = %+v, want none", drawdowns[0])
+	}
+}
+
+func testBar(i int, open float64, close float64, adjusted float64) finance.ChartBar {
+	return finance.ChartBar{
+		Open:      decimal.NewFromFloat(open),
+		Close:     decimal.NewFromFloat(close),
+		AdjClose:  decimal.NewFromFloat(adjusted),
+		Timestamp: int(month(i).Unix()),
+	}
+}
+
+// setTestBars replaces the quotes of the backtests with the given bars, and returns a function to restore them
+func setTestBars(bars map[string][]finance.ChartBar) func() {
+	fetch := computeBarsForAsset
+	computeBarsForAsset = func(symbol string, startDate time.Time, endDate time.Time, interval datetime.Interval) ([]finance.ChartBar, error) {
+		return bars[symbol], nil
+	}
+	return func() {
+		computeBarsForAsset = fetch
+	}
+}
+
+func newTestPortfolio(targets map[string]float64) *Portfolio {
+	portfolio := NewPortfolio()
+	portfolio.Name = "Test"
+
+	for symbol, target := range targets {
+		portfolio.Symbols = append(portfolio.Symbols, symbol)
+		portfolio.Holdings[symbol] = NewHolding(symbol, 0, 0, 0)
+		portfolio.TargetAllocation[symbol] = target
+	}
+	sort.Strings(portfolio.Symbols)
+
+	return portfolio
+}
@@ -0,0 +1,150 @@
+package portfolio
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Period selects the dates and the granularity of a backtest, which includes both the start and the end date.
+// A zero start date starts the backtest when the youngest holding started trading, and a zero end date ends
+// it now. Daily backtests are more precise for short windows: they start and end on the exact dates, and
+// compute the trailing returns from daily balances, while flows and rebalances still happen at the close
+// of every month.
+type Period struct {
+	Start time.Time
+	End   time.Time
+	Daily bool
+}
+
+// ParsePeriod parses the start and the end dates of a backtest, formatted as "2006-01-02" or "2006-01".
+// A month starts the backtest at the start of the month, or ends it at the end of the month.
+func ParsePeriod(start string, end string, daily bool) (*Period, error) {
+	period := &Period{Daily: daily}
+
+	var err error
+	if strings.TrimSpace(start) != "" {
+		period.Start, err = parsePeriodDate(start, false)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if strings.TrimSpace(end) != "" {
+		period.End, err = parsePeriodDate(end, true)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if !period.Start.IsZero() && !period.End.IsZero() && period.Start.After(period.End) {
+		return nil, errors.New("Backtest start date should be before its end date")
+	}
+
+	return period, nil
+}
+
+// String describes the period, e.g. "2020-02-01 to 2020-12-31 (daily)"
+func (period *Period) String() string {
+	if period == nil {
+		return "All (monthly)"
+	}
+
+	start, end := "inception", "now"
+	if !period.Start.IsZero() {
+		start = period.Start.Format("2006-01-02")
+	}
+	if !period.End.IsZero() {
+		end = period.End.Format("2006-01-02")
+	}
+
+	interval := "monthly"
+	if period.Daily {
+		interval = "daily"
+	}
+
+	return fmt.Sprintf("%s to %s (%s)", start, end, interval)
+}
+
+// dates narrows the full range of the backtest down to the period
+func (period *Period) dates(earliest time.Time, latest time.Time) (time.Time, time.Time, error) {
+	if period == nil {
+		return earliest, latest, nil
+	}
+
+	startDate, endDate := earliest, latest
+
+	if !period.Start.IsZero() {
+		start := inLocation(period.Start, earliest.Location())
+		if start.Before(earliest.AddDate(0, 0, -1)) {
+			return time.Time{}, time.Time{}, fmt.Errorf("The backtest cannot start before %s, when every holding has data", earliest.Format("2006-01-02"))
+		}
+		if start.After(earliest) {
+			startDate = start
+		}
+	}
+
+	if !period.End.IsZero() {
+		end := inLocation(period.End, latest.Location()).AddDate(0, 0, 1).Add(-time.Second)
+		if end.Before(latest) {
+			endDate = end
+		}
+	}
+
+	if !startDate.Before(endDate) {
+		return time.Time{}, time.Time{}, errors.New("Backtest start date should be before its end date")
+	}
+
+	return startDate, endDate, nil
+}
+
+func (period *Period) daily() bool {
+	return period != nil && period.Daily
+}
+
+func parsePeriodDate(value string, end bool) (time.Time, error) {
+	value = strings.TrimSpace(value)
+
+	date, err := time.Parse("2006-01-02", value)
+	if err == nil {
+		return date, nil
+	}
+
+	date, err = time.Parse("2006-01", value)
+	if err == nil {
+		if end {
+			date = date.AddDate(0, 1, -1)
+		}
+		return date, nil
+	}
+
+	return time.Time{}, fmt.Errorf("Invalid date: %s (expected YYYY-MM-DD or YYYY-MM)", value)
+}
+
+// inLocation returns the same calendar date at midnight in the given location
+func inLocation(date time.Time, location *time.Location) time.Time {
+	return time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, location)
+}
+
+// computeMonthlyHistoric aggregates daily balances or quotes into monthly ones, which open at the open of the
+// first day of every month and close at the close of its last day
+func computeMonthlyHistoric(daily []Historic) []Historic {
+	monthly := make([]Historic, 0)
+
+	for _, day := range daily {
+		last := len(monthly) - 1
+		if last >= 0 && sameMonth(monthly[last].Date, day.Date) {
+			monthly[last].Close = day.Close
+			continue
+		}
+
+		monthly = append(monthly, day)
+	}
+
+	return monthly
+}
+
+func sameMonth(a time.Time, b time.Time) bool {
+	return a.Year() == b.Year() && a.Month() == b.Month()
+}
@@ -0,0 +1,167 @@
+package portfolio
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParsePeriodDate(t *testing.T) {
+	tests := []struct {
+		value string
+		end   bool
+		want  string
+	}{
+		{"2020-02-15", false, "2020-02-15"},
+		{"2020-02-15", true, "2020-02-15"},
+		{" 2020-02 ", false, "2020-02-01"},
+		{"2020-02", true, "2020-02-29"},
+		{"2021-12", true, "2021-12-31"},
+		{"2020/02/15", false, ""},
+		{"2020-13", false, ""},
+	}
+
+	for _, test := range tests {
+		date, err := parsePeriodDate(test.value, test.end)
+		if test.want == "" {
+			if err == nil {
+				t.Errorf("Parsed %q as %v, want an error", test.value, date)
+			}
+			continue
+		}
+
+		if err != nil || date.Format("2006-01-02") != test.want {
+			t.Errorf("Parsed %q (end %v) as %v with %v, want %s", test.value, test.end, date, err, test.want)
+		}
+	}
+}
+
+func TestParsePeriod(t *testing.T) {
+	period, err := ParsePeriod("2020-03", "2020-06", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := period.String(); got != "2020-03-01 to 2020-06-30 (daily)" {
+		t.Errorf("Period = %s, want 2020-03-01 to 2020-06-30 (daily)", got)
+	}
+
+	period, err = ParsePeriod("", " ", false)
+	if err != nil || !period.Start.IsZero() || !period.End.IsZero() {
+		t.Errorf("Parsed an empty period as %v with %v, want an open one", period, err)
+	}
+	if got := period.String(); got != "inception to now (monthly)" {
+		t.Errorf("Period = %s, want inception to now (monthly)", got)
+	}
+
+	// A backtest can be a single day, but cannot end before it starts
+	if _, err = ParsePeriod("2020-06-30", "2020-06", false); err != nil {
+		t.Errorf("Rejected a period of a single day: %v", err)
+	}
+	if _, err = ParsePeriod("2020-07", "2020-06", false); err == nil {
+		t.Error("Parsed a period that starts after it ends")
+	}
+	if _, err = ParsePeriod("2020-07", "June", false); err == nil {
+		t.Error("Parsed a period with an invalid end date")
+	}
+}
+
+func TestPeriodDates(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	earliest := time.Date(2020, time.January, 2, 0, 0, 0, 0, ny)
+	latest := time.Date(2021, time.June, 15, 0, 0, 0, 0, ny)
+
+	tests := []struct {
+		name  string
+		start string
+		end   string
+		want  [2]time.Time
+		err   bool
+	}{
+		{
+			name:  "months",
+			start: "2020-03",
+			end:   "2020-06",
+			want:  [2]time.Time{time.Date(2020, time.March, 1, 0, 0, 0, 0, ny), time.Date(2020, time.June, 30, 23, 59, 59, 0, ny)},
+		},
+		{
+			// A backtest may start on the day before the first bar of the youngest holding
+			name:  "start a day before the data",
+			start: "2020-01-01",
+			want:  [2]time.Time{earliest, latest},
+		},
+		{
+			name:  "start before the data",
+			start: "2019-12",
+			err:   true,
+		},
+		{
+			name: "end after the data",
+			end:  "2022-01",
+			want: [2]time.Time{earliest, latest},
+		},
+		{
+			name:  "start after the data",
+			start: "2021-07",
+			err:   true,
+		},
+	}
+
+	for _, test := range tests {
+		period, err := ParsePeriod(test.start, test.end, false)
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+
+		startDate, endDate, err := period.dates(earliest, latest)
+		if test.err {
+			if err == nil {
+				t.Errorf("%s: dates %v to %v, want an error", test.name, startDate, endDate)
+			}
+			continue
+		}
+
+		if err != nil || !startDate.Equal(test.want[0]) || !endDate.Equal(test.want[1]) {
+			t.Errorf("%s: dates %v to %v with %v, want %v to %v", test.name, startDate, endDate, err, test.want[0], test.want[1])
+		}
+	}
+
+	// Without a period, the backtest covers all the data
+	var period *Period
+	if startDate, endDate, err := period.dates(earliest, latest); err != nil || !startDate.Equal(earliest) || !endDate.Equal(latest) {
+		t.Errorf("No period: dates %v to %v with %v, want %v to %v", startDate, endDate, err, earliest, latest)
+	}
+}
+
+func TestComputeMonthlyHistoric(t *testing.T) {
+	daily := []Historic{
+		{Date: day(30), Open: 100, Close: 101},
+		{Date: day(31), Open: 102, Close: 103},
+		{Date: day(32), Open: 103, Close: 104},
+		{Date: day(58), Open: 105, Close: 106},
+		{Date: day(59), Open: 107, Close: 108},
+	}
+
+	// January closes on its 31st, February opens on its 1st and closes on its 28th, and March has a single day
+	want := []Historic{
+		{Date: day(30), Open: 100, Close: 101},
+		{Date: day(31), Open: 102, Close: 106},
+		{Date: day(59), Open: 107, Close: 108},
+	}
+
+	monthly := computeMonthlyHistoric(daily)
+	if len(monthly) != len(want) {
+		t.Fatalf("%d months, want %d", len(monthly), len(want))
+	}
+	for i := range want {
+		if monthly[i] != want[i] {
+			t.Errorf("Month %d = %+v, want %+v", i, monthly[i], want[i])
+		}
+	}
+
+	if monthly := computeMonthlyHistoric(nil); len(monthly) != 0 {
+		t.Errorf("%d months from no days, want none", len(monthly))
+	}
+}
@@ -2,6 +2,7 @@ package portfolio
 
 import (
 	"errors"
+	"fmt"
 
 	"github.com/piquette/finance-go/quote"
 )
@@ -15,11 +16,17 @@ const (
 type Portfolio struct {
 	Name             string
 	CostBasis        float64
+	Cash             float64
 	Symbols          []string
 	Holdings         map[string]*Holding
 	TargetAllocation map[string]float64
+	Transactions     []*Transaction
+	Rebalance        *Rebalance
+	Strategy         Strategy
+	Contribution     *Contribution
 	Status           *Status
 	Performance      *Performance
+	MoneyWeighted    *MoneyWeighted
 }
 
 // Status defines the real-time status of the entire portfolio
@@ -33,17 +40,25 @@ type Status struct {
 }
 
 type holdingConfig struct {
-	Symbol           string  `yaml:"symbol"`
-	TargetAllocation float64 `yaml:"allocation"`
-	Quantity         float64 `yaml:"quantity"`
-	CostBasis        float64 `yaml:"basis"`
-	Watch            float64 `yaml:"watch"`
+	Symbol           string      `yaml:"symbol"`
+	CUSIP            string      `yaml:"cusip,omitempty"`
+	TargetAllocation float64     `yaml:"allocation"`
+	Quantity         float64     `yaml:"quantity"`
+	CostBasis        float64     `yaml:"basis"`
+	Watch            float64     `yaml:"watch,omitempty"`
+	Tags             []string    `yaml:"tags,omitempty"`
+	Lots             []lotConfig `yaml:"lots,omitempty"`
 }
 
 type portfolioConfig struct {
-	Name             string          `yaml:"portfolio"`
-	TargetAllocation float64         `yaml:"allocation"`
-	Holdings         []holdingConfig `yaml:"holdings"`
+	Name             string              `yaml:"portfolio"`
+	TargetAllocation float64             `yaml:"allocation"`
+	Cash             float64             `yaml:"cash,omitempty"`
+	Rebalance        *rebalanceConfig    `yaml:"rebalance,omitempty"`
+	Strategy         *strategyConfig     `yaml:"strategy,omitempty"`
+	Contribution     *contributionConfig `yaml:"contribution,omitempty"`
+	Holdings         []holdingConfig     `yaml:"holdings"`
+	Transactions     []transactionConfig `yaml:"transactions,omitempty"`
 }
 
 // NewPortfolio returns an empty portfolio of asset holdings
@@ -52,12 +67,15 @@ func NewPortfolio() *Portfolio {
 		Symbols:          make([]string, 0),
 		Holdings:         make(map[string]*Holding),
 		TargetAllocation: make(map[string]float64),
+		Transactions:     make([]*Transaction, 0),
+		Rebalance:        NewRebalance(),
 		Status:           &Status{},
 	}
 
 	performance := NewPerformance(&portfolio, benchmark, initialBalance)
 
 	portfolio.Performance = performance
+	portfolio.MoneyWeighted = NewMoneyWeighted(&portfolio, 0)
 
 	return &portfolio
 }
@@ -66,16 +84,42 @@ func NewPortfolio() *Portfolio {
 func (portfolio *Portfolio) Load(config portfolioConfig) error {
 
 	portfolio.Name = config.Name
+	portfolio.Cash = config.Cash
+	portfolio.CostBasis = config.Cash
+
+	rebalance, err := newRebalance(config.Rebalance)
+	if err != nil {
+		return err
+	}
+	portfolio.Rebalance = rebalance
+
+	contribution, err := newContribution(config.Contribution)
+	if err != nil {
+		return err
+	}
+	portfolio.Contribution = contribution
 
 	totalAllocation := 0.0
 
 	for _, holdingConfig := range config.Holdings {
-		portfolio.Symbols = append(portfolio.Symbols, holdingConfig.Symbol)
-		portfolio.Holdings[holdingConfig.Symbol] = NewHolding(
+		holding := NewHolding(
 			holdingConfig.Symbol,
 			holdingConfig.Quantity,
 			holdingConfig.CostBasis,
 			holdingConfig.Watch)
+		holding.CUSIP = holdingConfig.CUSIP
+		holding.Tags = holdingConfig.Tags
+
+		for _, lotConfig := range holdingConfig.Lots {
+			lot, err := newLot(lotConfig)
+			if err != nil {
+				return err
+			}
+			holding.Lots = append(holding.Lots, lot)
+		}
+
+		portfolio.Symbols = append(portfolio.Symbols, holdingConfig.Symbol)
+		portfolio.Holdings[holdingConfig.Symbol] = holding
 		portfolio.TargetAllocation[holdingConfig.Symbol] = holdingConfig.TargetAllocation
 		totalAllocation += holdingConfig.TargetAllocation
 		portfolio.CostBasis += holdingConfig.CostBasis
@@ -85,6 +129,173 @@ func (portfolio *Portfolio) Load(config portfolioConfig) error {
 		return errors.New("Total allocation should be either 0% (ignored) or 100%")
 	}
 
+	strategy, err := newStrategy(config.Strategy, portfolio.Symbols)
+	if err != nil {
+		return err
+	}
+	portfolio.Strategy = strategy
+
+	for _, transactionConfig := range config.Transactions {
+		transaction, err := newTransaction(transactionConfig)
+		if err != nil {
+			return err
+		}
+		portfolio.Transactions = append(portfolio.Transactions, transaction)
+	}
+
+	sortTransactions(portfolio.Transactions)
+
+	return nil
+}
+
+func (portfolio *Portfolio) config(targetAllocation float64) portfolioConfig {
+	config := portfolioConfig{
+		Name:             portfolio.Name,
+		TargetAllocation: targetAllocation,
+		Cash:             portfolio.Cash,
+		Rebalance:        portfolio.Rebalance.config(),
+		Strategy:         strategyConfigOf(portfolio.Strategy),
+		Contribution:     portfolio.Contribution.config(),
+		Holdings:         make([]holdingConfig, 0),
+	}
+
+	for _, symbol := range portfolio.Symbols {
+		holding := portfolio.Holdings[symbol]
+
+		holdingConfig := holdingConfig{
+			Symbol:           symbol,
+			CUSIP:            holding.CUSIP,
+			TargetAllocation: portfolio.TargetAllocation[symbol],
+			Quantity:         holding.Quantity,
+			CostBasis:        holding.CostBasis,
+			Watch:            holding.Watch,
+			Tags:             holding.Tags,
+		}
+
+		for _, lot := range holding.Lots {
+			holdingConfig.Lots = append(holdingConfig.Lots, lot.config())
+		}
+
+		config.Holdings = append(config.Holdings, holdingConfig)
+	}
+
+	for _, transaction := range portfolio.Transactions {
+		config.Transactions = append(config.Transactions, transaction.config())
+	}
+
+	return config
+}
+
+// Import merges the positions, cash and transactions of a statement into the portfolio.
+// Securities are matched to holdings by CUSIP first and by symbol otherwise. Positions replace
+// the quantity and cost basis of existing holdings, or derive the basis from the lots if they
+// do not report one, and transactions already in the ledger are skipped, so importing the same
+// statement twice is a no-op.
+func (portfolio *Portfolio) Import(statement *Statement) error {
+	statement.reconcile(portfolio)
+
+	unpriced := make(map[string]bool)
+
+	if len(statement.Positions) > 0 {
+		reported := make(map[string]bool)
+
+		for _, position := range statement.Positions {
+			if position.Symbol == "" {
+				return fmt.Errorf("Missing symbol for position: %s", position.Description)
+			}
+
+			reported[position.Symbol] = true
+
+			holding, ok := portfolio.Holdings[position.Symbol]
+			if !ok {
+				holding = NewHolding(position.Symbol, 0, 0, 0)
+				portfolio.Symbols = append(portfolio.Symbols, position.Symbol)
+				portfolio.Holdings[position.Symbol] = holding
+				portfolio.TargetAllocation[position.Symbol] = 0
+			}
+
+			holding.Quantity = position.Quantity
+			if position.CostBasis != 0 {
+				holding.CostBasis = position.CostBasis
+			} else {
+				unpriced[position.Symbol] = true
+			}
+		}
+
+		// A statement lists every position in the account, so anything missing has been sold
+		for symbol, holding := range portfolio.Holdings {
+			if !reported[symbol] {
+				holding.Quantity = 0
+				holding.CostBasis = 0
+			}
+		}
+	}
+
+	if statement.HasCash {
+		portfolio.Cash = statement.Cash
+	}
+
+	known := make(map[string]bool)
+	for _, transaction := range portfolio.Transactions {
+		known[transaction.ID] = true
+	}
+
+	traded := make(map[string]bool)
+	for _, transaction := range statement.Transactions {
+		if known[transaction.ID] {
+			continue
+		}
+
+		known[transaction.ID] = true
+		portfolio.Transactions = append(portfolio.Transactions, transaction)
+
+		if transaction.Type == TransactionBuy || transaction.Type == TransactionSell {
+			traded[transaction.Symbol] = true
+		}
+	}
+
+	sortTransactions(portfolio.Transactions)
+
+	for symbol := range traded {
+		lots := computeLots(portfolio.Transactions, symbol)
+
+		holding, ok := portfolio.Holdings[symbol]
+		if !ok {
+			if len(lots) == 0 {
+				continue
+			}
+
+			// The symbol only shows up in the transaction history, so derive the holding from its lots
+			holding = NewHolding(symbol, 0, 0, 0)
+			for _, lot := range lots {
+				holding.Quantity += lot.Quantity
+				holding.CostBasis += lot.CostBasis
+			}
+			portfolio.Symbols = append(portfolio.Symbols, symbol)
+			portfolio.Holdings[symbol] = holding
+			portfolio.TargetAllocation[symbol] = 0
+		}
+
+		holding.Lots = lots
+	}
+
+	// Positions without a basis, e.g. those of OFX statements, take it from the lots of the holding
+	for symbol := range unpriced {
+		holding := portfolio.Holdings[symbol]
+		holding.CostBasis = computeLotsBasis(holding.Lots, holding.Quantity, holding.CostBasis)
+	}
+
+	for symbol, cusip := range statement.CUSIPs {
+		if holding, ok := portfolio.Holdings[symbol]; ok {
+			holding.CUSIP = cusip
+		}
+	}
+
+	portfolio.CostBasis = portfolio.Cash
+	for _, holding := range portfolio.Holdings {
+		portfolio.CostBasis += holding.CostBasis
+	}
+
 	return nil
 }
 
@@ -115,6 +326,8 @@ func (portfolio *Portfolio) RefreshStatus() {
 		Allocation: make(map[string]float64),
 	}
 
+	status.Value = portfolio.Cash
+
 	for _, holding := range portfolio.Holdings {
 		status.Value += holding.Status.Value
 		status.RegularMarketChange += holding.Quote.RegularMarketChange * holding.Quantity
@@ -146,9 +359,14 @@ func (portfolio *Portfolio) RefreshStatus() {
 // Clone makes a copy of the Portfolio
 func (portfolio *Portfolio) Clone() *Portfolio {
 	port := Portfolio{
-		Name:      portfolio.Name,
-		CostBasis: portfolio.CostBasis,
-		Status:    &Status{},
+		Name:         portfolio.Name,
+		CostBasis:    portfolio.CostBasis,
+		Cash:         portfolio.Cash,
+		Transactions: portfolio.Transactions,
+		Rebalance:    portfolio.Rebalance,
+		Strategy:     portfolio.Strategy,
+		Contribution: portfolio.Contribution,
+		Status:       &Status{},
 	}
 
 	symbols := make([]string, len(portfolio.Symbols))
@@ -3,6 +3,7 @@ package portfolio
 import (
 	"errors"
 	"io/ioutil"
+	"os"
 
 	"gopkg.in/yaml.v2"
 )
@@ -16,6 +17,15 @@ type Profile struct {
 	Portfolios       []*Portfolio
 	TargetAllocation map[string]float64
 	MergedPortfolio  *Portfolio
+	MoneyWeighted    *MoneyWeighted
+	ConfidenceLevels []float64
+	Projection       *Projection
+	Withdrawal       *Withdrawal
+	CPI              *CPI
+	Period           *Period
+	Proxies          Proxies
+	StressScenarios  []*StressScenario
+	Optimizer        *Optimizer
 	Status           *ProfileStatus
 }
 
@@ -30,8 +40,15 @@ type ProfileStatus struct {
 }
 
 type profileConfig struct {
-	Cash       cashConfig        `yaml:"cash"`
-	Portfolios []portfolioConfig `yaml:"portfolios"`
+	Cash       cashConfig          `yaml:"cash"`
+	Risk       riskConfig          `yaml:"risk,omitempty"`
+	Inflation  inflationConfig     `yaml:"inflation,omitempty"`
+	Projection *projectionConfig   `yaml:"projection,omitempty"`
+	Withdrawal *withdrawalConfig   `yaml:"withdrawal,omitempty"`
+	Proxies    map[string][]string `yaml:"proxies,omitempty"`
+	Stress     []stressConfig      `yaml:"stress,omitempty"`
+	Optimizer  *optimizerConfig    `yaml:"optimizer,omitempty"`
+	Portfolios []portfolioConfig   `yaml:"portfolios"`
 }
 
 type cashConfig struct {
@@ -46,6 +63,11 @@ func NewProfile(name string) *Profile {
 		Market:           NewMarket(),
 		Portfolios:       make([]*Portfolio, 0),
 		TargetAllocation: make(map[string]float64),
+		ConfidenceLevels: defaultConfidenceLevels,
+		Projection:       NewProjection(0, defaultProjectionYears),
+		Withdrawal:       NewWithdrawal(0),
+		StressScenarios:  append([]*StressScenario{}, HistoricalCrises...),
+		Optimizer:        NewOptimizer(),
 	}
 }
 
@@ -63,6 +85,48 @@ func (profile *Profile) Load(name string) error {
 		return err
 	}
 
+	confidenceLevels, err := newConfidenceLevels(profileConfig.Risk)
+	if err != nil {
+		return err
+	}
+	profile.ConfidenceLevels = confidenceLevels
+
+	projection, err := newProjection(profileConfig.Projection)
+	if err != nil {
+		return err
+	}
+	profile.Projection = projection
+
+	withdrawal, err := newWithdrawal(profileConfig.Withdrawal)
+	if err != nil {
+		return err
+	}
+	profile.Withdrawal = withdrawal
+
+	cpi, err := newCPI(profileConfig.Inflation, name)
+	if err != nil {
+		return err
+	}
+	profile.CPI = cpi
+
+	proxies, err := newProxies(profileConfig.Proxies, name)
+	if err != nil {
+		return err
+	}
+	profile.Proxies = proxies
+
+	stressScenarios, err := newStressScenarios(profileConfig.Stress)
+	if err != nil {
+		return err
+	}
+	profile.StressScenarios = stressScenarios
+
+	optimizer, err := newOptimizer(profileConfig.Optimizer)
+	if err != nil {
+		return err
+	}
+	profile.Optimizer = optimizer
+
 	profile.Cash = profileConfig.Cash.Value
 	profile.CostBasis = profileConfig.Cash.Value
 	profile.TargetAllocation["cash"] = profileConfig.Cash.TargetAllocation
@@ -86,7 +150,95 @@ func (profile *Profile) Load(name string) error {
 		return errors.New("Total allocation should be either 0% (ignored) or 100%")
 	}
 
-	profile.MergedPortfolio = profile.mergePortfolios()
+	profile.merge()
+
+	return nil
+}
+
+// Save writes the profile to the given file, and keeps the previous version of the file with a .bak extension
+func (profile *Profile) Save(name string) error {
+	config := profileConfig{
+		Cash: cashConfig{
+			Value:            profile.Cash,
+			TargetAllocation: profile.TargetAllocation["cash"],
+		},
+		Projection: profile.Projection.config(),
+		Withdrawal: profile.Withdrawal.config(),
+		Proxies:    profile.Proxies.config(),
+		Stress:     stressConfigs(profile.StressScenarios),
+		Optimizer:  profile.Optimizer.config(),
+		Portfolios: make([]portfolioConfig, 0),
+	}
+
+	if !equalFloats(profile.ConfidenceLevels, defaultConfidenceLevels) {
+		config.Risk.Confidence = profile.ConfidenceLevels
+	}
+
+	if profile.CPI != nil {
+		config.Inflation.CPI = profile.CPI.File
+	}
+
+	for _, portfolio := range profile.Portfolios {
+		config.Portfolios = append(config.Portfolios, portfolio.config(profile.TargetAllocation[portfolio.Name]))
+	}
+
+	file, err := yaml.Marshal(&config)
+	if err != nil {
+		return err
+	}
+
+	// Comments and formatting of the file are lost on save, so the previous version is kept next to it
+	previous, err := ioutil.ReadFile(name)
+	if err == nil {
+		err = ioutil.WriteFile(name+".bak", previous, 0644)
+		if err != nil {
+			return err
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	return ioutil.WriteFile(name, file, 0644)
+}
+
+// LoadOrCreate loads a profile from the given file, or leaves the profile empty if the file does not exist
+func (profile *Profile) LoadOrCreate(name string) error {
+	_, err := os.Stat(name)
+	if os.IsNotExist(err) {
+		profile.merge()
+		return nil
+	}
+
+	return profile.Load(name)
+}
+
+// Import merges a statement into the named portfolio, creating the portfolio if needed
+func (profile *Profile) Import(name string, statement *Statement) error {
+	var portfolio *Portfolio
+
+	for _, port := range profile.Portfolios {
+		if port.Name == name {
+			portfolio = port
+			break
+		}
+	}
+
+	if portfolio == nil {
+		portfolio = NewPortfolio()
+		portfolio.Name = name
+		profile.Portfolios = append(profile.Portfolios, portfolio)
+		profile.TargetAllocation[name] = 0
+	}
+
+	costBasis := portfolio.CostBasis
+
+	err := portfolio.Import(statement)
+	if err != nil {
+		return err
+	}
+
+	profile.CostBasis += portfolio.CostBasis - costBasis
+	profile.merge()
 
 	return nil
 }
@@ -148,7 +300,55 @@ func (profile *Profile) RefreshStatus() {
 	profile.Status = &status
 }
 
-// MergePortfolios merges all portfolios in the profile into a single portfolio
+// merge rebuilds the merged portfolio and the money-weighted return of the entire profile, and
+// applies the profile settings to the performance of every portfolio
+func (profile *Profile) merge() {
+	profile.MergedPortfolio = profile.mergePortfolios()
+	profile.MoneyWeighted = NewMoneyWeighted(profile.MergedPortfolio, profile.Cash)
+
+	profile.MergedPortfolio.Performance.ConfidenceLevels = profile.ConfidenceLevels
+	profile.MergedPortfolio.Performance.CPI = profile.CPI
+	profile.MergedPortfolio.Performance.Period = profile.Period
+	profile.MergedPortfolio.Performance.Proxies = profile.Proxies
+	for _, portfolio := range profile.Portfolios {
+		portfolio.Performance.ConfidenceLevels = profile.ConfidenceLevels
+		portfolio.Performance.CPI = profile.CPI
+		portfolio.Performance.Period = profile.Period
+		portfolio.Performance.Proxies = profile.Proxies
+	}
+}
+
+// SetPeriod selects the dates and the granularity of the backtests of the profile and of every portfolio.
+// The backtests need to be computed again.
+func (profile *Profile) SetPeriod(period *Period) {
+	profile.Period = period
+
+	if profile.MergedPortfolio != nil {
+		profile.MergedPortfolio.Performance.Period = period
+		profile.MergedPortfolio.Performance.Ready = false
+	}
+	for _, portfolio := range profile.Portfolios {
+		portfolio.Performance.Period = period
+		portfolio.Performance.Ready = false
+	}
+}
+
+func equalFloats(a []float64, b []float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// MergePortfolios merges all portfolios in the profile into a single portfolio. A symbol held in several
+// portfolios is merged into a single holding of their total quantity, which shares their quote.
 func (profile *Profile) mergePortfolios() *Portfolio {
 	portfolio := NewPortfolio()
 
@@ -156,12 +356,32 @@ func (profile *Profile) mergePortfolios() *Portfolio {
 
 	for _, port := range profile.Portfolios {
 		portfolio.CostBasis += port.CostBasis
+		portfolio.Cash += port.Cash
 		portfolio.Symbols = append(portfolio.Symbols, port.Symbols...)
+		portfolio.Transactions = append(portfolio.Transactions, port.Transactions...)
 
 		for symbol, holding := range port.Holdings {
-			portfolio.Holdings[symbol] = holding
+			merged, ok := portfolio.Holdings[symbol]
+			if !ok {
+				merged = holding.Clone()
+				if holding.Quote != nil {
+					merged.Quote = holding.Quote
+				}
+				portfolio.Holdings[symbol] = merged
+				continue
+			}
+
+			merged.Quantity += holding.Quantity
+			merged.CostBasis += holding.CostBasis
+			merged.Lots = append(merged.Lots, holding.Clone().Lots...)
 		}
 	}
 
+	for _, holding := range portfolio.Holdings {
+		holding.RefreshStatus()
+	}
+
+	sortTransactions(portfolio.Transactions)
+
 	return portfolio
 }
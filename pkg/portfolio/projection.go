@@ -0,0 +1,248 @@
+package portfolio
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// ProjectionModel defines how the future monthly returns of a projection are drawn
+type ProjectionModel string
+
+const (
+	// ProjectionBootstrap draws blocks of consecutive historical monthly returns, which keeps the
+	// momentum and volatility clustering of the history
+	ProjectionBootstrap ProjectionModel = "bootstrap"
+	// ProjectionParametric draws monthly returns from a log-normal distribution with the same mean
+	// and standard deviation of log returns as the history
+	ProjectionParametric ProjectionModel = "parametric"
+)
+
+// ProjectionPercentiles are the percentiles of the balance reported by a projection
+var ProjectionPercentiles = []float64{10, 25, 50, 75, 90}
+
+// Projection simulates future paths of the balance of a portfolio from its backtested monthly
+// returns. The contribution is added at the end of every month. The same seed always produces
+// the same paths.
+type Projection struct {
+	Model          ProjectionModel
+	Years          int
+	Paths          int
+	BlockSize      int
+	InitialBalance float64
+	Contribution   float64
+	Goal           float64
+	Seed           int64
+	Bands          [][]float64
+	Ending         []float64
+	Contributed    float64
+	Probability    float64
+}
+
+type projectionConfig struct {
+	Model        ProjectionModel `yaml:"model,omitempty"`
+	Years        int             `yaml:"years,omitempty"`
+	Paths        int             `yaml:"paths,omitempty"`
+	BlockSize    int             `yaml:"block,omitempty"`
+	Contribution float64         `yaml:"contribution,omitempty"`
+	Goal         float64         `yaml:"goal,omitempty"`
+	Seed         *int64          `yaml:"seed,omitempty"`
+}
+
+const (
+	defaultProjectionYears = 20
+	defaultProjectionPaths = 10000
+	defaultBlockSize       = 12
+	defaultProjectionSeed  = 1
+)
+
+// NewProjection returns a projection of the balance over the given years with the default settings
+func NewProjection(initialBalance float64, years int) *Projection {
+	return &Projection{
+		Model:          ProjectionBootstrap,
+		Years:          years,
+		Paths:          defaultProjectionPaths,
+		BlockSize:      defaultBlockSize,
+		InitialBalance: initialBalance,
+		Seed:           defaultProjectionSeed,
+	}
+}
+
+// newProjection returns the projection settings of a profile, using the defaults for the missing ones
+func newProjection(config *projectionConfig) (*Projection, error) {
+	projection := NewProjection(0, defaultProjectionYears)
+	if config == nil {
+		return projection, nil
+	}
+
+	if config.Model != "" {
+		projection.Model = config.Model
+	}
+	if config.Years != 0 {
+		projection.Years = config.Years
+	}
+	if config.Paths != 0 {
+		projection.Paths = config.Paths
+	}
+	if config.BlockSize != 0 {
+		projection.BlockSize = config.BlockSize
+	}
+	if config.Seed != nil {
+		projection.Seed = *config.Seed
+	}
+	projection.Contribution = config.Contribution
+	projection.Goal = config.Goal
+
+	switch {
+	case projection.Model != ProjectionBootstrap && projection.Model != ProjectionParametric:
+		return nil, fmt.Errorf("Unknown projection model: %s", projection.Model)
+	case projection.Years < 0 || projection.Paths < 0 || projection.BlockSize < 0:
+		return nil, errors.New("Projection years, paths and block size should be positive")
+	case projection.Goal < 0:
+		return nil, errors.New("Projection goal should not be negative")
+	}
+
+	return projection, nil
+}
+
+// config returns the projection settings that differ from the defaults, or nil if none do
+func (projection *Projection) config() *projectionConfig {
+	config := projectionConfig{
+		Contribution: projection.Contribution,
+		Goal:         projection.Goal,
+	}
+
+	if projection.Model != ProjectionBootstrap {
+		config.Model = projection.Model
+	}
+	if projection.Years != defaultProjectionYears {
+		config.Years = projection.Years
+	}
+	if projection.Paths != defaultProjectionPaths {
+		config.Paths = projection.Paths
+	}
+	if projection.BlockSize != defaultBlockSize {
+		config.BlockSize = projection.BlockSize
+	}
+	if projection.Seed != defaultProjectionSeed {
+		seed := projection.Seed
+		config.Seed = &seed
+	}
+
+	if config == (projectionConfig{}) {
+		return nil
+	}
+
+	return &config
+}
+
+// Run simulates the paths from the monthly returns of the result. The bands contain the balance at
+// every percentile at the end of every month, starting with the initial balance.
+func (projection *Projection) Run(result *PerformanceResult) error {
+	if projection.Years <= 0 || projection.Paths <= 0 {
+		return errors.New("Projection requires a positive number of years and paths")
+	}
+
+	returns := computeMonthlyReturns(result.Historic)
+	if len(returns) == 0 {
+		return errors.New("No historic returns to project from")
+	}
+
+	var draw func(rng *rand.Rand, path []float64)
+
+	switch projection.Model {
+	case ProjectionBootstrap:
+		if projection.BlockSize <= 0 {
+			return errors.New("Block size should be positive")
+		}
+		draw = func(rng *rand.Rand, path []float64) {
+			drawBootstrap(rng, returns, projection.BlockSize, path)
+		}
+
+	case ProjectionParametric:
+		logReturns := make([]float64, len(returns))
+		for i, r := range returns {
+			logReturns[i] = math.Log(1 + r/100)
+		}
+		mean, sd := computeMeanAndDeviation(logReturns)
+		draw = func(rng *rand.Rand, path []float64) {
+			for i := range path {
+				path[i] = (math.Exp(mean+sd*rng.NormFloat64()) - 1) * 100
+			}
+		}
+
+	default:
+		return fmt.Errorf("Unknown projection model: %s", projection.Model)
+	}
+
+	months := projection.Years * 12
+	rng := rand.New(rand.NewSource(projection.Seed))
+
+	// balances[m][p] is the balance of path p at the end of month m
+	balances := make([][]float64, months+1)
+	for m := range balances {
+		balances[m] = make([]float64, projection.Paths)
+	}
+
+	path := make([]float64, months)
+	reached := 0
+
+	for p := 0; p < projection.Paths; p++ {
+		draw(rng, path)
+
+		balance := projection.InitialBalance
+		balances[0][p] = balance
+
+		for m, r := range path {
+			balance = balance*(1+r/100) + projection.Contribution
+			if balance < 0 {
+				balance = 0
+			}
+			balances[m+1][p] = balance
+		}
+
+		if projection.Goal > 0 && balance >= projection.Goal {
+			reached++
+		}
+	}
+
+	projection.Bands = make([][]float64, len(ProjectionPercentiles))
+	for i := range projection.Bands {
+		projection.Bands[i] = make([]float64, months+1)
+	}
+
+	for m := range balances {
+		sort.Float64s(balances[m])
+		for i, percentile := range ProjectionPercentiles {
+			projection.Bands[i][m] = computeQuantile(balances[m], percentile/100)
+		}
+	}
+
+	projection.Ending = make([]float64, len(ProjectionPercentiles))
+	for i := range ProjectionPercentiles {
+		projection.Ending[i] = projection.Bands[i][months]
+	}
+
+	projection.Contributed = projection.InitialBalance + projection.Contribution*float64(months)
+
+	projection.Probability = math.NaN()
+	if projection.Goal > 0 {
+		projection.Probability = float64(reached) / float64(projection.Paths) * 100
+	}
+
+	return nil
+}
+
+// drawBootstrap fills the path with blocks of consecutive returns starting at random months,
+// wrapping around the end of the history
+func drawBootstrap(rng *rand.Rand, returns []float64, blockSize int, path []float64) {
+	for i := 0; i < len(path); {
+		start := rng.Intn(len(returns))
+		for j := 0; j < blockSize && i < len(path); j++ {
+			path[i] = returns[(start+j)%len(returns)]
+			i++
+		}
+	}
+}
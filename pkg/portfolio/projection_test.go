@@ -0,0 +1,116 @@
+package portfolio
+
+import (
+	"math"
+	"testing"
+
+	"gopkg.in/yaml.v2"
+)
+
+func TestProjectionSeed(t *testing.T) {
+	result := &PerformanceResult{Historic: testHistoric(100, 3, -2, 5, 1, -4, 2, 0.5, -1, 6, -3, 2, 1)}
+
+	run := func(seed int64) *Projection {
+		projection := NewProjection(10000, 5)
+		projection.Paths = 500
+		projection.BlockSize = 3
+		projection.Seed = seed
+		if err := projection.Run(result); err != nil {
+			t.Fatal(err)
+		}
+		return projection
+	}
+
+	for _, seed := range []int64{0, 1, 42} {
+		first, second := run(seed), run(seed)
+		for i := range ProjectionPercentiles {
+			for m := range first.Bands[i] {
+				if first.Bands[i][m] != second.Bands[i][m] {
+					t.Fatalf("Seed %d: percentile %v of month %d is %v, then %v", seed, ProjectionPercentiles[i], m, first.Bands[i][m], second.Bands[i][m])
+				}
+			}
+		}
+	}
+
+	if run(0).Ending[2] == run(1).Ending[2] {
+		t.Errorf("Seeds 0 and 1 should draw different paths")
+	}
+}
+
+func TestProjectionSeedConfig(t *testing.T) {
+	tests := []struct {
+		yaml string
+		want int64
+	}{
+		{"years: 10", defaultProjectionSeed},
+		{"seed: 0", 0},
+		{"seed: 7", 7},
+	}
+
+	for _, test := range tests {
+		var config projectionConfig
+		if err := yaml.Unmarshal([]byte(test.yaml), &config); err != nil {
+			t.Fatal(err)
+		}
+
+		projection, err := newProjection(&config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if projection.Seed != test.want {
+			t.Errorf("%q: seed = %d, want %d", test.yaml, projection.Seed, test.want)
+		}
+
+		// The seed survives saving the profile and loading it again
+		saved := projection.config()
+		loaded, err := newProjection(saved)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if loaded.Seed != test.want {
+			t.Errorf("%q: seed = %d after saving, want %d", test.yaml, loaded.Seed, test.want)
+		}
+	}
+}
+
+func TestProjectionConstantReturns(t *testing.T) {
+	// Every month returns 1%, so every path, percentile and model gives the same balances
+	result := &PerformanceResult{Historic: testHistoric(100, 1, 1, 1, 1, 1, 1)}
+
+	for _, model := range []ProjectionModel{ProjectionBootstrap, ProjectionParametric} {
+		projection := NewProjection(10000, 1)
+		projection.Model = model
+		projection.Paths = 100
+		projection.Contribution = 100
+		projection.Goal = 11000
+
+		if err := projection.Run(result); err != nil {
+			t.Fatal(err)
+		}
+
+		// The future value of 10000 and of a monthly annuity of 100 at 1% a month
+		want := 10000*math.Pow(1.01, 12) + 100*(math.Pow(1.01, 12)-1)/0.01
+		for i, ending := range projection.Ending {
+			if math.Abs(ending-want) > 1e-6 {
+				t.Errorf("%s: percentile %v ends at %v, want %v", model, ProjectionPercentiles[i], ending, want)
+			}
+		}
+
+		if projection.Contributed != 11200 {
+			t.Errorf("%s: contributed %v, want 11200", model, projection.Contributed)
+		}
+		if projection.Probability != 100 {
+			t.Errorf("%s: probability of the goal = %v, want 100", model, projection.Probability)
+		}
+	}
+}
+
+// testHistoric returns monthly balances starting at the given balance with the given monthly returns in percent
+func testHistoric(balance float64, returns ...float64) []Historic {
+	historic := make([]Historic, len(returns))
+	for i, r := range returns {
+		historic[i] = Historic{Date: month(i), Open: balance, Close: balance * (1 + r/100)}
+		balance = historic[i].Close
+	}
+	return historic
+}
@@ -0,0 +1,365 @@
+package portfolio
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/piquette/finance-go"
+	"github.com/piquette/finance-go/datetime"
+	"github.com/shopspring/decimal"
+)
+
+// Proxy is a series that stands in for a symbol before its inception: either another symbol, e.g. the mutual
+// fund share class of an ETF, or a local CSV file of index levels or returns
+type Proxy struct {
+	Symbol   string
+	File     string
+	Historic []Historic
+}
+
+// Proxies map symbols to the proxies that backfill their history before their inception. Every proxy
+// backfills the history before the previous one, so the proxies of a symbol go from the youngest to the oldest.
+type Proxies map[string][]*Proxy
+
+// SimulatedPeriod is a period of a backtest in which a holding is replaced by its proxies. The proxies are
+// scaled to the first price of the holding, so that the spliced series is continuous.
+type SimulatedPeriod struct {
+	Symbol  string
+	Proxies []string
+	Start   time.Time
+	End     time.Time
+}
+
+// LoadProxy reads the history of a proxy from a CSV file of dates and index levels, e.g. "2000-01-31,1394.46".
+// If the header of the value column contains "return", the values are returns in percent instead.
+func LoadProxy(name string) ([]Historic, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	historic, err := ParseProxy(file)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to load proxy from %s: %v", name, err)
+	}
+
+	return historic, nil
+}
+
+// ParseProxy reads the history of a proxy in the format supported by LoadProxy
+func ParseProxy(reader io.Reader) ([]Historic, error) {
+	r := csv.NewReader(reader)
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[time.Time]float64)
+	returns := false
+
+	for _, record := range records {
+		if isBlankRecord(record) || len(record) < 2 {
+			continue
+		}
+
+		date, err := parseDate(record[0], "2006-01-02|2006-01|01/02/2006")
+		if err != nil {
+			// The header tells whether the values are levels or returns
+			returns = returns || strings.Contains(strings.ToLower(record[1]), "return")
+			continue
+		}
+
+		value, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(record[1]), "%"), 64)
+		if err != nil {
+			continue
+		}
+
+		values[date] = value
+	}
+
+	if len(values) == 0 {
+		return nil, errors.New("No proxy values found")
+	}
+
+	dates := make([]time.Time, 0, len(values))
+	for date := range values {
+		dates = append(dates, date)
+	}
+	sort.Slice(dates, func(i, j int) bool {
+		return dates[i].Before(dates[j])
+	})
+
+	historic := make([]Historic, len(dates))
+	level := 100.0
+
+	for i, date := range dates {
+		open := level
+		if returns {
+			level *= 1 + values[date]/100
+		} else {
+			level = values[date]
+			if i == 0 {
+				open = level
+			}
+		}
+
+		if level <= 0 {
+			return nil, fmt.Errorf("Invalid proxy value on %s", date.Format("2006-01-02"))
+		}
+
+		historic[i] = Historic{Date: date, Open: open, Close: level}
+	}
+
+	return historic, nil
+}
+
+// newProxies loads the proxies configured in the profile. Sources ending in ".csv" are files relative to the
+// directory of the profile, and the other sources are symbols.
+func newProxies(config map[string][]string, profileFile string) (Proxies, error) {
+	if len(config) == 0 {
+		return nil, nil
+	}
+
+	proxies := make(Proxies)
+
+	for symbol, sources := range config {
+		if len(sources) == 0 {
+			return nil, fmt.Errorf("No proxies for %s", symbol)
+		}
+
+		for _, source := range sources {
+			if !strings.HasSuffix(strings.ToLower(source), ".csv") {
+				proxies[symbol] = append(proxies[symbol], &Proxy{Symbol: source})
+				continue
+			}
+
+			name := source
+			if !filepath.IsAbs(name) {
+				name = filepath.Join(filepath.Dir(profileFile), name)
+			}
+
+			historic, err := LoadProxy(name)
+			if err != nil {
+				return nil, err
+			}
+
+			proxies[symbol] = append(proxies[symbol], &Proxy{File: source, Historic: historic})
+		}
+	}
+
+	return proxies, nil
+}
+
+func (proxies Proxies) config() map[string][]string {
+	if len(proxies) == 0 {
+		return nil
+	}
+
+	config := make(map[string][]string)
+	for symbol, list := range proxies {
+		for _, proxy := range list {
+			config[symbol] = append(config[symbol], proxy.Name())
+		}
+	}
+
+	return config
+}
+
+// Name returns the symbol or the file of the proxy
+func (proxy *Proxy) Name() string {
+	if proxy.File != "" {
+		return proxy.File
+	}
+	return proxy.Symbol
+}
+
+// startDate returns the earliest date with data for the symbol or any of its proxies
+func (proxies Proxies) startDate(earliest time.Time, endDate time.Time, symbol string) (time.Time, error) {
+	startDate, err := computeStartDateForAsset(earliest, endDate, symbol)
+	if err != nil && len(proxies[symbol]) == 0 {
+		return time.Time{}, err
+	}
+	if err != nil {
+		// A symbol may have no data at all yet, as long as its proxies do
+		startDate = endDate
+	}
+
+	for _, proxy := range proxies[symbol] {
+		var start time.Time
+
+		if proxy.File != "" {
+			date := proxy.Historic[0].Date
+			start = time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, earliest.Location())
+		} else {
+			start, err = computeStartDateForAsset(earliest, endDate, proxy.Symbol)
+			if err != nil {
+				return time.Time{}, err
+			}
+		}
+
+		if start.Before(startDate) {
+			startDate = start
+		}
+	}
+
+	return startDate, nil
+}
+
+// bars returns the daily or monthly bars of the symbol, preceded by the bars of its proxies before its
+// inception, so that backtests can start before the symbol did
+func (proxies Proxies) bars(symbol string, startDate time.Time, endDate time.Time, daily bool) ([]finance.ChartBar, *SimulatedPeriod, error) {
+	interval := datetime.OneMonth
+	if daily {
+		interval = datetime.OneDay
+	}
+
+	bars, err := computeBarsForAsset(symbol, startDate, endDate, interval)
+	if len(proxies[symbol]) == 0 {
+		return bars, nil, err
+	}
+	if err != nil {
+		bars = nil
+	}
+
+	// The bars of the proxies are spliced in before the first bar of the symbol, or the end of the backtest
+	first := endDate
+	scale := 1.0
+	if len(bars) > 0 {
+		first = barDate(bars[0], startDate.Location())
+		scale = adjustedOpen(bars[0])
+	}
+
+	simulated := &SimulatedPeriod{Symbol: symbol, End: first}
+	spliced := make([]Historic, 0)
+
+	for _, proxy := range proxies[symbol] {
+		historic, err := proxy.history(startDate, first, daily)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		// Only the bars before the months or the days already covered are spliced in
+		for len(historic) > 0 && !before(historic[len(historic)-1].Date, first, daily) {
+			historic = historic[:len(historic)-1]
+		}
+		if len(historic) == 0 {
+			continue
+		}
+
+		// Scale the proxy so that it closes at the first price of the series it is spliced to, adjusted like its closes
+		if len(bars) > 0 || len(spliced) > 0 {
+			scale /= historic[len(historic)-1].Close
+		} else {
+			scale = 1
+		}
+
+		for i := range historic {
+			historic[i].Open *= scale
+			historic[i].Close *= scale
+		}
+
+		spliced = append(historic, spliced...)
+		simulated.Proxies = append(simulated.Proxies, proxy.Name())
+		first = historic[0].Date
+		scale = historic[0].Open
+	}
+
+	if len(spliced) == 0 {
+		return bars, nil, nil
+	}
+	simulated.Start = spliced[0].Date
+
+	backfilled := make([]finance.ChartBar, 0, len(spliced)+len(bars))
+	for _, bar := range spliced {
+		backfilled = append(backfilled, finance.ChartBar{
+			Open:      decimal.NewFromFloat(bar.Open),
+			Close:     decimal.NewFromFloat(bar.Close),
+			AdjClose:  decimal.NewFromFloat(bar.Close),
+			Timestamp: int(bar.Date.Unix()),
+		})
+	}
+
+	return append(backfilled, bars...), simulated, nil
+}
+
+// history returns the history of the proxy between the two dates, with monthly bars unless daily is set. The
+// open of every bar is the close of the previous one, since the open of a symbol is not adjusted for dividends.
+func (proxy *Proxy) history(startDate time.Time, endDate time.Time, daily bool) ([]Historic, error) {
+	historic := make([]Historic, 0)
+
+	if proxy.File != "" {
+		for _, bar := range proxy.Historic {
+			date := time.Date(bar.Date.Year(), bar.Date.Month(), bar.Date.Day(), 0, 0, 0, 0, startDate.Location())
+			if before(date, startDate, daily) || !date.Before(endDate) {
+				continue
+			}
+			historic = append(historic, Historic{Date: date, Open: bar.Open, Close: bar.Close})
+		}
+
+		if !daily {
+			historic = computeMonthlyHistoric(historic)
+			for i := range historic {
+				historic[i].Date = time.Date(historic[i].Date.Year(), historic[i].Date.Month(), 1, 0, 0, 0, 0, startDate.Location())
+			}
+		}
+
+		return historic, nil
+	}
+
+	interval := datetime.OneMonth
+	if daily {
+		interval = datetime.OneDay
+	}
+
+	bars, err := computeBarsForAsset(proxy.Symbol, startDate, endDate, interval)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, bar := range bars {
+		close, _ := bar.AdjClose.Float64()
+
+		open := adjustedOpen(bar)
+		if i > 0 {
+			open = historic[i-1].Close
+		}
+
+		historic = append(historic, Historic{Date: barDate(bar, startDate.Location()), Open: open, Close: close})
+	}
+
+	return historic, nil
+}
+
+// simulatedOn tells whether any holding is replaced by its proxies on the given date
+func simulatedOn(simulated []*SimulatedPeriod, date time.Time) bool {
+	for _, period := range simulated {
+		if date.Before(period.End) {
+			return true
+		}
+	}
+	return false
+}
+
+// before compares two dates by month, or by day for daily bars
+func before(a time.Time, b time.Time, daily bool) bool {
+	if daily {
+		return inLocation(a, b.Location()).Before(inLocation(b, b.Location()))
+	}
+	return a.Year() < b.Year() || (a.Year() == b.Year() && a.Month() < b.Month())
+}
+
+func barDate(bar finance.ChartBar, location *time.Location) time.Time {
+	return time.Unix(int64(bar.Timestamp), 0).In(location)
+}
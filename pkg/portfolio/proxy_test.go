@@ -0,0 +1,47 @@
+package portfolio
+
+import (
+	"math"
+	"testing"
+
+	"github.com/piquette/finance-go"
+)
+
+func TestProxyBars(t *testing.T) {
+	// VXUS pays a dividend of half its price before the end of the backtest, so its first bar opens at an
+	// adjusted 50. The first bar of the proxy is adjusted the same way, from an open of 8 to 4.
+	restore := setTestBars(map[string][]finance.ChartBar{
+		"VXUS":  {testBar(2, 100, 100, 50), testBar(3, 100, 110, 55)},
+		"VGTSX": {testBar(0, 8, 10, 5), testBar(1, 10, 10, 10)},
+	})
+	defer restore()
+
+	proxies := Proxies{"VXUS": {{Symbol: "VGTSX"}}}
+	bars, simulated, err := proxies.bars("VXUS", month(0), month(3), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The proxy is scaled by 50 / 10 so that it closes at the adjusted open of VXUS
+	want := []Historic{
+		{Date: month(0), Open: 20, Close: 25},
+		{Date: month(1), Open: 25, Close: 50},
+		{Date: month(2), Open: 50, Close: 50},
+		{Date: month(3), Open: 50, Close: 55},
+	}
+
+	if len(bars) != len(want) {
+		t.Fatalf("%d bars, want %d", len(bars), len(want))
+	}
+	for i, bar := range bars {
+		close, _ := bar.AdjClose.Float64()
+		got := Historic{Date: barDate(bar, month(0).Location()), Open: adjustedOpen(bar), Close: close}
+		if !got.Date.Equal(want[i].Date) || math.Abs(got.Open-want[i].Open) > 1e-9 || math.Abs(got.Close-want[i].Close) > 1e-9 {
+			t.Errorf("Bar %d = %+v, want %+v", i, got, want[i])
+		}
+	}
+
+	if simulated == nil || !simulated.Start.Equal(month(0)) || !simulated.End.Equal(month(2)) {
+		t.Errorf("Simulated period = %+v, want %v to %v", simulated, month(0), month(2))
+	}
+}
@@ -0,0 +1,110 @@
+package portfolio
+
+import (
+	"math"
+	"time"
+)
+
+// RealResult contains the performance of a portfolio adjusted for inflation. Balances are in
+// dollars of the start of the backtest, and the final balance is its purchasing power.
+type RealResult struct {
+	Historic     []Historic
+	FinalBalance float64
+	CAGR         float64
+	Stdev        float64
+	BestYear     float64
+	WorstYear    float64
+	MaxDrawdown  float64
+	Drawdowns    []*Drawdown
+	SharpeRatio  float64
+	Inflation    float64
+	Return       *Return
+}
+
+// computeRealResult deflates the monthly balances and the returns of the result by the CPI, or
+// returns nil if the CPI series does not cover the start of the backtest. The Sharpe ratio is
+// measured against the risk-free return deflated by the average inflation of the backtest.
+func computeRealResult(result *PerformanceResult, cpi *CPI, riskFree float64, startDate time.Time, endDate time.Time) *RealResult {
+	if cpi == nil || len(result.Historic) == 0 {
+		return nil
+	}
+
+	base, ok := cpi.Index(startDate)
+	if !ok {
+		return nil
+	}
+
+	inflation, _ := cpi.Inflation(startDate, endDate)
+
+	real := &RealResult{
+		Historic:  make([]Historic, len(result.Historic)),
+		Inflation: (inflation - 1) * 100,
+	}
+
+	previous := 1.0
+	for i, month := range result.Historic {
+		index, _ := cpi.Index(month.Date)
+		deflator := index / base
+
+		real.Historic[i] = Historic{
+			Date:  month.Date,
+			Open:  month.Open / previous,
+			Close: month.Close / deflator,
+		}
+		previous = deflator
+	}
+
+	real.FinalBalance = result.FinalBalance / inflation
+	real.CAGR = computeCAGR(startDate, endDate, real.Historic[0].Open, real.FinalBalance)
+
+	real.Stdev = computeStandardDeviation(computeMonthlyReturns(real.Historic))
+
+	yearly := computeYearlyReturns(real.Historic, startDate, endDate)
+	real.BestYear, real.WorstYear = computeBestAndWorstYears(yearly)
+
+	real.Drawdowns = computeDrawdowns(real.Historic, maxDrawdowns)
+	if len(real.Drawdowns) > 0 {
+		real.MaxDrawdown = real.Drawdowns[0].Depth
+	}
+
+	realRiskFree := riskFree
+	if years := endDate.Sub(startDate).Hours() / 24 / 365; years > 0 {
+		realRiskFree = ((1+riskFree/100)/math.Pow(inflation, 1/years) - 1) * 100
+	}
+	real.SharpeRatio = computeSharpeRatio(real.CAGR, real.Stdev, realRiskFree)
+
+	real.Return = computeRealReturn(result.Return, cpi, real.CAGR, startDate, endDate)
+
+	return real
+}
+
+// computeRealReturn deflates the trailing returns by the inflation over the same periods. Periods
+// longer than the backtest fall back to the real CAGR, like their nominal counterparts.
+func computeRealReturn(nominal *Return, cpi *CPI, cagr float64, startDate time.Time, endDate time.Time) *Return {
+	real := NewReturn()
+	real.Max = cagr
+
+	deflate := func(value float64, start time.Time, years float64) float64 {
+		if start.Before(startDate) {
+			return cagr
+		}
+
+		inflation, _ := cpi.Inflation(start, endDate)
+		if years > 0 {
+			inflation = math.Pow(inflation, 1/years)
+		}
+
+		return ((1+value/100)/inflation - 1) * 100
+	}
+
+	real.OneMonth = deflate(nominal.OneMonth, endDate.AddDate(0, -1, 0), 0)
+	real.ThreeMonth = deflate(nominal.ThreeMonth, endDate.AddDate(0, -3, 0), 0)
+	real.SixMonth = deflate(nominal.SixMonth, endDate.AddDate(0, -6, 0), 0)
+	real.YTD = deflate(nominal.YTD, time.Date(endDate.Year(), time.January, 1, 0, 0, 0, 0, endDate.Location()), 0)
+	real.OneYear = deflate(nominal.OneYear, endDate.AddDate(-1, 0, 0), 1)
+	real.ThreeYear = deflate(nominal.ThreeYear, endDate.AddDate(-3, 0, 0), 3)
+	real.FiveYear = deflate(nominal.FiveYear, endDate.AddDate(-5, 0, 0), 5)
+	real.TenYear = deflate(nominal.TenYear, endDate.AddDate(-10, 0, 0), 10)
+
+	return real
+}
@@ -0,0 +1,84 @@
+package portfolio
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestComputeRealResult(t *testing.T) {
+	// Prices rise by 10% a month, so the balance of each month is deflated by 1, 1.1 and 1.21
+	cpi, err := ParseCPI(strings.NewReader("2020-01-01,100\n2020-02-01,110\n2020-03-01,121\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		returns     []float64
+		stdev       float64
+		maxDrawdown float64
+		length      int
+	}{
+		{
+			// Real balances of 100, 90.91 and 82.64: monthly returns of 0, -9.09% and -9.09%
+			name:        "flat",
+			returns:     []float64{0, 0, 0},
+			stdev:       14.8454,
+			maxDrawdown: -17.3554,
+			length:      2,
+		},
+		{
+			name:    "growing with inflation",
+			returns: []float64{0, 10, 10},
+		},
+	}
+
+	for _, test := range tests {
+		result := NewPerformanceResult()
+		result.Historic = testHistoric(100, test.returns...)
+		result.FinalBalance = result.Historic[len(result.Historic)-1].Close
+		result.Return = NewReturn()
+
+		real := computeRealResult(result, cpi, 2, month(0), month(2))
+		if real == nil {
+			t.Fatalf("%s: no real result", test.name)
+		}
+
+		if math.Abs(real.Stdev-test.stdev) > 1e-4 {
+			t.Errorf("%s: real stdev = %v, want %v", test.name, real.Stdev, test.stdev)
+		}
+		if math.Abs(real.MaxDrawdown-test.maxDrawdown) > 1e-4 {
+			t.Errorf("%s: real max drawdown = %v, want %v", test.name, real.MaxDrawdown, test.maxDrawdown)
+		}
+		if test.length == 0 && len(real.Drawdowns) != 0 {
+			t.Errorf("%s: %d real drawdowns, want none", test.name, len(real.Drawdowns))
+		}
+		if test.length > 0 && (len(real.Drawdowns) == 0 || real.Drawdowns[0].Length != test.length) {
+			t.Errorf("%s: real drawdowns = %+v, want a length of %d", test.name, real.Drawdowns, test.length)
+		}
+	}
+}
+
+func TestComputeRealSharpeRatio(t *testing.T) {
+	cpi, err := ParseCPI(strings.NewReader("2020-01-01,100\n2020-02-01,110\n2020-03-01,121\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result := NewPerformanceResult()
+	result.Historic = testHistoric(100, 0, 0, 0)
+	result.FinalBalance = 100
+	result.Return = NewReturn()
+
+	real := computeRealResult(result, cpi, 2, month(0), month(2))
+
+	// The risk-free return of 2% is deflated by the inflation of 21% over the 60 days of the backtest
+	years := 60.0 / 365
+	riskFree := (1.02/math.Pow(1.21, 1/years) - 1) * 100
+	sharpe := (real.CAGR - riskFree) / real.Stdev
+
+	if math.Abs(real.SharpeRatio-sharpe) > 1e-9 {
+		t.Errorf("Real Sharpe ratio = %v, want %v", real.SharpeRatio, sharpe)
+	}
+}
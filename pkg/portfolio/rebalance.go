@@ -0,0 +1,142 @@
+package portfolio
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// RebalanceStrategy defines when the backtest trades the holdings back to their target allocation
+type RebalanceStrategy string
+
+const (
+	// RebalanceNone buys the target allocation once and lets it drift
+	RebalanceNone RebalanceStrategy = "none"
+	// RebalanceMonthly rebalances at the end of every month
+	RebalanceMonthly RebalanceStrategy = "monthly"
+	// RebalanceQuarterly rebalances at the end of every quarter
+	RebalanceQuarterly RebalanceStrategy = "quarterly"
+	// RebalanceAnnual rebalances at the end of every year
+	RebalanceAnnual RebalanceStrategy = "annual"
+	// RebalanceAbsoluteBand rebalances at the end of a month when an allocation is off its target by more
+	// than the threshold in percentage points, e.g. 5 rebalances a 60% target outside of 55% to 65%
+	RebalanceAbsoluteBand RebalanceStrategy = "absolute"
+	// RebalanceRelativeBand rebalances at the end of a month when an allocation is off its target by more
+	// than the threshold in percent of the target, e.g. 25 rebalances a 20% target outside of 15% to 25%
+	RebalanceRelativeBand RebalanceStrategy = "relative"
+)
+
+// Rebalance defines the rebalancing policy of a portfolio
+type Rebalance struct {
+	Strategy  RebalanceStrategy
+	Threshold float64
+}
+
+type rebalanceConfig struct {
+	Strategy  string  `yaml:"strategy"`
+	Threshold float64 `yaml:"threshold,omitempty"`
+}
+
+// allocation records the quantities held by the backtest from a date on
+type allocation struct {
+	Date       time.Time
+	Quantities map[string]float64
+}
+
+// NewRebalance returns a rebalancing policy that never rebalances
+func NewRebalance() *Rebalance {
+	return &Rebalance{
+		Strategy: RebalanceNone,
+	}
+}
+
+func newRebalance(config *rebalanceConfig) (*Rebalance, error) {
+	rebalance := NewRebalance()
+	if config == nil {
+		return rebalance, nil
+	}
+
+	switch RebalanceStrategy(config.Strategy) {
+	case RebalanceNone, RebalanceMonthly, RebalanceQuarterly, RebalanceAnnual:
+		if config.Threshold != 0 {
+			return nil, fmt.Errorf("Rebalance strategy %s does not take a threshold", config.Strategy)
+		}
+
+	case RebalanceAbsoluteBand, RebalanceRelativeBand:
+		if config.Threshold <= 0 {
+			return nil, fmt.Errorf("Rebalance strategy %s requires a positive threshold", config.Strategy)
+		}
+
+	case "":
+		return nil, errors.New("Missing rebalance strategy")
+
+	default:
+		return nil, fmt.Errorf("Unknown rebalance strategy: %s", config.Strategy)
+	}
+
+	rebalance.Strategy = RebalanceStrategy(config.Strategy)
+	rebalance.Threshold = config.Threshold
+
+	return rebalance, nil
+}
+
+func (rebalance *Rebalance) config() *rebalanceConfig {
+	if rebalance == nil || rebalance.Strategy == RebalanceNone {
+		return nil
+	}
+
+	return &rebalanceConfig{
+		Strategy:  string(rebalance.Strategy),
+		Threshold: rebalance.Threshold,
+	}
+}
+
+// due returns true if the holdings should be rebalanced at the close of the month, given their
+// current weights and the target allocation, both in percent
+func (rebalance *Rebalance) due(month time.Time, weights map[string]float64, targets map[string]float64) bool {
+	if rebalance == nil {
+		return false
+	}
+
+	switch rebalance.Strategy {
+	case RebalanceMonthly:
+		return true
+
+	case RebalanceQuarterly:
+		return month.Month()%3 == 0
+
+	case RebalanceAnnual:
+		return month.Month() == time.December
+
+	case RebalanceAbsoluteBand:
+		for symbol, target := range targets {
+			if math.Abs(weights[symbol]-target) > rebalance.Threshold {
+				return true
+			}
+		}
+
+	case RebalanceRelativeBand:
+		for symbol, target := range targets {
+			if target > 0 && math.Abs(weights[symbol]-target)/target*100 > rebalance.Threshold {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// quantitiesForDate returns the quantities held by the backtest on the given date
+func quantitiesForDate(allocations []allocation, date time.Time) map[string]float64 {
+	quantities := allocations[0].Quantities
+
+	for _, allocation := range allocations {
+		if allocation.Date.After(date) {
+			break
+		}
+		quantities = allocation.Quantities
+	}
+
+	return quantities
+}
@@ -0,0 +1,101 @@
+package portfolio
+
+import (
+	"math"
+)
+
+// minDeviation is the standard deviation of monthly returns in percent below which a series is constant, since
+// the returns of a constant series are only equal up to rounding
+const minDeviation = 1e-9
+
+// RelativeStatistics compares the monthly returns of a portfolio against those of its benchmark over
+// the same months. Alpha, tracking error and the information ratio are annualized percentages, and
+// the capture ratios are the percentages of the benchmark's up and down months the portfolio captured.
+type RelativeStatistics struct {
+	Beta             float64
+	Alpha            float64
+	Correlation      float64
+	RSquared         float64
+	TrackingError    float64
+	InformationRatio float64
+	UpsideCapture    float64
+	DownsideCapture  float64
+}
+
+// computeRelativeStatistics computes the statistics of the portfolio relative to the benchmark.
+// The risk-free return is annual.
+func computeRelativeStatistics(result *PerformanceResult, benchmark *PerformanceResult, riskFree float64) *RelativeStatistics {
+	portfolioReturns := computeMonthlyReturns(result.Historic)
+	benchmarkReturns := computeMonthlyReturns(benchmark.Historic)
+
+	// Both results are computed over identical dates, but be defensive about the length
+	n := len(portfolioReturns)
+	if len(benchmarkReturns) < n {
+		n = len(benchmarkReturns)
+	}
+	portfolioReturns = portfolioReturns[:n]
+	benchmarkReturns = benchmarkReturns[:n]
+
+	relative := &RelativeStatistics{}
+
+	portfolioMean, portfolioDeviation := computeMeanAndDeviation(portfolioReturns)
+	benchmarkMean, benchmarkDeviation := computeMeanAndDeviation(benchmarkReturns)
+	if portfolioDeviation < minDeviation {
+		portfolioDeviation = 0
+	}
+	if benchmarkDeviation < minDeviation {
+		benchmarkDeviation = 0
+	}
+
+	var covariance float64
+	differences := make([]float64, n)
+	for i := 0; i < n; i++ {
+		covariance += (portfolioReturns[i] - portfolioMean) * (benchmarkReturns[i] - benchmarkMean)
+		differences[i] = portfolioReturns[i] - benchmarkReturns[i]
+	}
+	if n > 0 {
+		covariance /= float64(n)
+	}
+
+	relative.Beta = ratio(covariance, benchmarkDeviation*benchmarkDeviation)
+	relative.Correlation = ratio(covariance, portfolioDeviation*benchmarkDeviation)
+	relative.RSquared = relative.Correlation * relative.Correlation * 100
+
+	// Jensen's alpha is the return in excess of what the CAPM expects for the portfolio's beta
+	relative.Alpha = result.CAGR - (riskFree + relative.Beta*(benchmark.CAGR-riskFree))
+
+	_, trackingError := computeMeanAndDeviation(differences)
+	relative.TrackingError = trackingError * math.Sqrt(12)
+	relative.InformationRatio = ratio(result.CAGR-benchmark.CAGR, relative.TrackingError)
+
+	relative.UpsideCapture = computeCaptureRatio(portfolioReturns, benchmarkReturns, func(r float64) bool { return r > 0 })
+	relative.DownsideCapture = computeCaptureRatio(portfolioReturns, benchmarkReturns, func(r float64) bool { return r < 0 })
+
+	return relative
+}
+
+// computeCaptureRatio compares the geometric average monthly return of the portfolio with that of
+// the benchmark over the months selected by the benchmark's return
+func computeCaptureRatio(portfolioReturns []float64, benchmarkReturns []float64, selected func(float64) bool) float64 {
+	portfolioGrowth, benchmarkGrowth := 1.0, 1.0
+	months := 0
+
+	for i, r := range benchmarkReturns {
+		if !selected(r) {
+			continue
+		}
+
+		portfolioGrowth *= 1 + portfolioReturns[i]/100
+		benchmarkGrowth *= 1 + r/100
+		months++
+	}
+
+	if months == 0 {
+		return math.NaN()
+	}
+
+	portfolioAverage := math.Pow(portfolioGrowth, 1/float64(months)) - 1
+	benchmarkAverage := math.Pow(benchmarkGrowth, 1/float64(months)) - 1
+
+	return ratio(portfolioAverage, benchmarkAverage) * 100
+}
@@ -0,0 +1,112 @@
+package portfolio
+
+import (
+	"math"
+	"testing"
+)
+
+func TestComputeRelativeStatistics(t *testing.T) {
+	tests := []struct {
+		name      string
+		portfolio []float64
+		benchmark []float64
+		want      RelativeStatistics
+	}{
+		{
+			// The portfolio moves twice as much as the benchmark, with a CAGR of 5% against 3% and a risk-free
+			// return of 1%, so it has no alpha. The differences of ±10% a month are the tracking error.
+			name:      "leveraged",
+			portfolio: []float64{20, -20, 20, -20},
+			benchmark: []float64{10, -10, 10, -10},
+			want: RelativeStatistics{
+				Beta:             2,
+				Alpha:            0,
+				Correlation:      1,
+				RSquared:         100,
+				TrackingError:    10 * math.Sqrt(12),
+				InformationRatio: 2 / (10 * math.Sqrt(12)),
+				UpsideCapture:    200,
+				DownsideCapture:  200,
+			},
+		},
+		{
+			// A benchmark without variance has no beta or correlation, and no down months to capture. The portfolio
+			// grows by 1.02 * 1.02 over the four up months, a geometric average of 0.995% against 1%.
+			name:      "constant benchmark",
+			portfolio: []float64{2, 0, 2, 0},
+			benchmark: []float64{1, 1, 1, 1},
+			want: RelativeStatistics{
+				Beta:             math.NaN(),
+				Alpha:            math.NaN(),
+				Correlation:      math.NaN(),
+				RSquared:         math.NaN(),
+				TrackingError:    math.Sqrt(12),
+				InformationRatio: 2 / math.Sqrt(12),
+				UpsideCapture:    (math.Pow(1.0404, 0.25) - 1) / 0.01 * 100,
+				DownsideCapture:  math.NaN(),
+			},
+		},
+	}
+
+	for _, test := range tests {
+		result := NewPerformanceResult()
+		result.Historic = testHistoric(100, test.portfolio...)
+		result.CAGR = 5
+
+		benchmark := NewPerformanceResult()
+		benchmark.Historic = testHistoric(100, test.benchmark...)
+		benchmark.CAGR = 3
+
+		got := computeRelativeStatistics(result, benchmark, 1)
+
+		values := []struct {
+			name      string
+			got, want float64
+		}{
+			{"beta", got.Beta, test.want.Beta},
+			{"alpha", got.Alpha, test.want.Alpha},
+			{"correlation", got.Correlation, test.want.Correlation},
+			{"R²", got.RSquared, test.want.RSquared},
+			{"tracking error", got.TrackingError, test.want.TrackingError},
+			{"information ratio", got.InformationRatio, test.want.InformationRatio},
+			{"upside capture", got.UpsideCapture, test.want.UpsideCapture},
+			{"downside capture", got.DownsideCapture, test.want.DownsideCapture},
+		}
+		for _, value := range values {
+			if !equalOrNaN(value.got, value.want, 1e-9) {
+				t.Errorf("%s: %s = %v, want %v", test.name, value.name, value.got, value.want)
+			}
+		}
+	}
+}
+
+func TestComputeCaptureRatio(t *testing.T) {
+	up := func(r float64) bool { return r > 0 }
+
+	tests := []struct {
+		name      string
+		portfolio []float64
+		benchmark []float64
+		want      float64
+	}{
+		// Over the two up months the portfolio averages 5% and the benchmark 10%
+		{"half", []float64{5, 5, -20}, []float64{10, 10, -10}, 50},
+		{"no up months", []float64{5, 5}, []float64{-10, 0}, math.NaN()},
+		{"empty", nil, nil, math.NaN()},
+	}
+
+	for _, test := range tests {
+		got := computeCaptureRatio(test.portfolio, test.benchmark, up)
+		if !equalOrNaN(got, test.want, 1e-9) {
+			t.Errorf("%s: upside capture = %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+// equalOrNaN tells whether two values are within the tolerance of each other, or both NaN
+func equalOrNaN(a float64, b float64, tolerance float64) bool {
+	if math.IsNaN(a) || math.IsNaN(b) {
+		return math.IsNaN(a) && math.IsNaN(b)
+	}
+	return math.Abs(a-b) <= tolerance
+}
@@ -0,0 +1,207 @@
+package portfolio
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// defaultConfidenceLevels are the confidence levels of the Value-at-Risk unless configured otherwise
+var defaultConfidenceLevels = []float64{95, 99}
+
+// RiskMetrics contains the risk-adjusted metrics of a portfolio. Ratios use annualized returns and
+// deviations, while the Value-at-Risk describes the loss of a single month.
+type RiskMetrics struct {
+	DownsideDeviation float64
+	SortinoRatio      float64
+	CalmarRatio       float64
+	UlcerIndex        float64
+	UlcerPerformance  float64
+	Skewness          float64
+	Kurtosis          float64
+	ValueAtRisk       []*ValueAtRisk
+}
+
+// ValueAtRisk contains the monthly return that is only underperformed with the given probability,
+// and the expected return of the months that do (Conditional VaR), both as negative percentages.
+// Historical values come from the actual monthly returns, parametric values from a normal
+// distribution with the same mean and standard deviation.
+type ValueAtRisk struct {
+	Confidence     float64
+	Historical     float64
+	HistoricalCVaR float64
+	Parametric     float64
+	ParametricCVaR float64
+}
+
+type riskConfig struct {
+	Confidence []float64 `yaml:"confidence,omitempty"`
+}
+
+func newConfidenceLevels(config riskConfig) ([]float64, error) {
+	if len(config.Confidence) == 0 {
+		return defaultConfidenceLevels, nil
+	}
+
+	for _, confidence := range config.Confidence {
+		if confidence <= 50 || confidence >= 100 {
+			return nil, fmt.Errorf("Confidence level should be between 50%% and 100%%: %.2f", confidence)
+		}
+	}
+
+	return config.Confidence, nil
+}
+
+// computeRiskMetrics computes the risk metrics from the monthly returns and the underwater curve,
+// all in percent. The risk-free return is annual.
+func computeRiskMetrics(result *PerformanceResult, monthlyReturns []float64, riskFree float64, confidenceLevels []float64) *RiskMetrics {
+	risk := &RiskMetrics{
+		ValueAtRisk: make([]*ValueAtRisk, 0),
+	}
+
+	excess := result.CAGR - riskFree
+
+	risk.DownsideDeviation = computeDownsideDeviation(monthlyReturns, riskFree/12)
+	risk.SortinoRatio = ratio(excess, risk.DownsideDeviation)
+	risk.CalmarRatio = ratio(result.CAGR, math.Abs(result.MaxDrawdown))
+
+	risk.UlcerIndex = computeUlcerIndex(result.Underwater)
+	risk.UlcerPerformance = ratio(excess, risk.UlcerIndex)
+
+	risk.Skewness, risk.Kurtosis = computeSkewnessAndKurtosis(monthlyReturns)
+
+	for _, confidence := range confidenceLevels {
+		risk.ValueAtRisk = append(risk.ValueAtRisk, computeValueAtRisk(monthlyReturns, confidence))
+	}
+
+	return risk
+}
+
+// computeDownsideDeviation returns the annualized deviation of the monthly returns below the target return
+func computeDownsideDeviation(monthlyReturns []float64, target float64) float64 {
+	if len(monthlyReturns) == 0 {
+		return 0
+	}
+
+	var sum float64
+	for _, r := range monthlyReturns {
+		if r < target {
+			sum += math.Pow(r-target, 2)
+		}
+	}
+
+	return math.Sqrt(sum/float64(len(monthlyReturns))) * math.Sqrt(12)
+}
+
+// computeUlcerIndex returns the root mean square of the drawdowns, which penalizes both deep and long drawdowns
+func computeUlcerIndex(underwater []float64) float64 {
+	if len(underwater) == 0 {
+		return 0
+	}
+
+	var sum float64
+	for _, drawdown := range underwater {
+		sum += drawdown * drawdown
+	}
+
+	return math.Sqrt(sum / float64(len(underwater)))
+}
+
+// computeSkewnessAndKurtosis returns the skewness and the excess kurtosis of the returns
+func computeSkewnessAndKurtosis(returns []float64) (float64, float64) {
+	n := float64(len(returns))
+	if n == 0 {
+		return 0, 0
+	}
+
+	mean, sd := computeMeanAndDeviation(returns)
+	if sd == 0 {
+		return 0, 0
+	}
+
+	var m3, m4 float64
+	for _, r := range returns {
+		z := (r - mean) / sd
+		m3 += math.Pow(z, 3)
+		m4 += math.Pow(z, 4)
+	}
+
+	return m3 / n, m4/n - 3
+}
+
+func computeValueAtRisk(returns []float64, confidence float64) *ValueAtRisk {
+	tail := 1 - confidence/100
+
+	sorted := make([]float64, len(returns))
+	copy(sorted, returns)
+	sort.Float64s(sorted)
+
+	valueAtRisk := &ValueAtRisk{
+		Confidence: confidence,
+		Historical: computeQuantile(sorted, tail),
+	}
+
+	var sum float64
+	var count int
+	for _, r := range sorted {
+		if r > valueAtRisk.Historical {
+			break
+		}
+		sum += r
+		count++
+	}
+	valueAtRisk.HistoricalCVaR = valueAtRisk.Historical
+	if count > 0 {
+		valueAtRisk.HistoricalCVaR = sum / float64(count)
+	}
+
+	mean, sd := computeMeanAndDeviation(returns)
+	z := math.Sqrt2 * math.Erfinv(2*tail-1)
+	density := math.Exp(-z*z/2) / math.Sqrt(2*math.Pi)
+
+	valueAtRisk.Parametric = mean + z*sd
+	valueAtRisk.ParametricCVaR = mean - sd*density/tail
+
+	return valueAtRisk
+}
+
+// computeQuantile returns the linearly interpolated quantile of sorted values
+func computeQuantile(sorted []float64, q float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+
+	position := q * float64(len(sorted)-1)
+	lower := int(math.Floor(position))
+	upper := int(math.Ceil(position))
+
+	return sorted[lower] + (position-float64(lower))*(sorted[upper]-sorted[lower])
+}
+
+// computeMeanAndDeviation returns the mean and the population standard deviation of the values
+func computeMeanAndDeviation(values []float64) (float64, float64) {
+	n := float64(len(values))
+	if n == 0 {
+		return 0, 0
+	}
+
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	mean := sum / n
+
+	var variance float64
+	for _, v := range values {
+		variance += math.Pow(v-mean, 2)
+	}
+
+	return mean, math.Sqrt(variance / n)
+}
+
+func ratio(numerator float64, denominator float64) float64 {
+	if denominator == 0 {
+		return math.NaN()
+	}
+	return numerator / denominator
+}
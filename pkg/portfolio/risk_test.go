@@ -0,0 +1,87 @@
+package portfolio
+
+import (
+	"math"
+	"testing"
+)
+
+func TestComputeValueAtRisk(t *testing.T) {
+	// The returns have a mean of 0 and a population standard deviation of sqrt(50)
+	returns := []float64{5, -10, 0, 10, -5}
+	sd := math.Sqrt(50)
+
+	tests := []struct {
+		confidence     float64
+		historical     float64
+		historicalCVaR float64
+		parametric     float64
+		parametricCVaR float64
+	}{
+		{
+			// The 25% quantile is the second of the five sorted returns, and the tail averages -10 and -5.
+			// The normal quantile is -0.67449, with a density of 0.31778.
+			confidence:     75,
+			historical:     -5,
+			historicalCVaR: -7.5,
+			parametric:     -0.6744897501960817 * sd,
+			parametricCVaR: -sd * 0.3177765727 / 0.25,
+		},
+		{
+			// The 5% quantile is a fifth of the way from -10 to -5, and only -10 is below it. The normal
+			// quantile is -1.64485, with a density of 0.10314.
+			confidence:     95,
+			historical:     -9,
+			historicalCVaR: -10,
+			parametric:     -1.6448536269514726 * sd,
+			parametricCVaR: -sd * 0.1031356447 / 0.05,
+		},
+	}
+
+	for _, test := range tests {
+		got := computeValueAtRisk(returns, test.confidence)
+
+		values := []struct {
+			name      string
+			got, want float64
+		}{
+			{"historical VaR", got.Historical, test.historical},
+			{"historical CVaR", got.HistoricalCVaR, test.historicalCVaR},
+			{"parametric VaR", got.Parametric, test.parametric},
+			{"parametric CVaR", got.ParametricCVaR, test.parametricCVaR},
+		}
+		for _, value := range values {
+			if math.Abs(value.got-value.want) > 1e-6 {
+				t.Errorf("%v%% %s = %v, want %v", test.confidence, value.name, value.got, value.want)
+			}
+		}
+	}
+}
+
+func TestComputeRiskMetrics(t *testing.T) {
+	result := NewPerformanceResult()
+	result.CAGR = 12
+	result.MaxDrawdown = -4
+	result.Underwater = []float64{0, -3, -4}
+
+	risk := computeRiskMetrics(result, []float64{5, -10, 0, 10, -5}, 0, []float64{95})
+
+	// Only -10 and -5 are below the target of 0: sqrt((100 + 25) / 5) = 5 a month
+	if want := 5 * math.Sqrt(12); math.Abs(risk.DownsideDeviation-want) > 1e-9 {
+		t.Errorf("Downside deviation = %v, want %v", risk.DownsideDeviation, want)
+	}
+	if want := 12 / (5 * math.Sqrt(12)); math.Abs(risk.SortinoRatio-want) > 1e-9 {
+		t.Errorf("Sortino ratio = %v, want %v", risk.SortinoRatio, want)
+	}
+	if math.Abs(risk.CalmarRatio-3) > 1e-9 {
+		t.Errorf("Calmar ratio = %v, want 3", risk.CalmarRatio)
+	}
+	if want := math.Sqrt(25.0 / 3); math.Abs(risk.UlcerIndex-want) > 1e-9 {
+		t.Errorf("Ulcer index = %v, want %v", risk.UlcerIndex, want)
+	}
+	if math.Abs(risk.Skewness) > 1e-9 {
+		t.Errorf("Skewness of symmetric returns = %v, want 0", risk.Skewness)
+	}
+	if len(risk.ValueAtRisk) != 1 || risk.ValueAtRisk[0].Confidence != 95 {
+		t.Errorf("Value-at-Risk = %+v, want one at 95%%", risk.ValueAtRisk)
+	}
+}
@@ -0,0 +1,225 @@
+package portfolio
+
+import (
+	"errors"
+	"math"
+	"sort"
+)
+
+const (
+	riskParityIterations = 1000
+	riskParityTolerance  = 1e-12
+)
+
+// RiskContributions breaks the volatility of the target allocation of a portfolio down by holding and by
+// asset class, from the covariance of the monthly returns of the holdings, and compares it with the
+// equal-risk-contribution (risk-parity) weights. Weights, volatilities and shares are in percent.
+type RiskContributions struct {
+	Months             int
+	Volatility         float64
+	ParityVolatility   float64
+	EffectiveBets      float64
+	ClassEffectiveBets float64
+	Holdings           []*RiskContribution
+	Classes            []*RiskContribution
+}
+
+// RiskContribution is the contribution of a holding or of an asset class to the volatility of a portfolio.
+// The contributions add up to the volatility of the portfolio, and the shares of the risk to 100%.
+type RiskContribution struct {
+	Name         string
+	Class        AssetClass
+	Weight       float64
+	Volatility   float64
+	Contribution float64
+	Share        float64
+	Parity       float64
+}
+
+// ComputeRiskContributions computes the contribution of every holding and asset class of the backtest to the
+// volatility of its target allocation, and the weights at which every holding contributes the same risk
+func (result *PerformanceResult) ComputeRiskContributions() (*RiskContributions, error) {
+	symbols, returns, err := result.holdingReturns()
+	if err != nil {
+		return nil, err
+	}
+
+	_, cov := computeCovariance(returns)
+
+	weights := make([]float64, len(symbols))
+	var total float64
+	for i, symbol := range symbols {
+		weights[i] = result.Portfolio.TargetAllocation[symbol] / 100
+		total += weights[i]
+	}
+	if total <= 0 {
+		return nil, errors.New("Portfolio has no target allocation")
+	}
+	for i := range weights {
+		weights[i] /= total
+	}
+
+	parity, err := computeRiskParity(cov)
+	if err != nil {
+		return nil, err
+	}
+
+	risk := &RiskContributions{
+		Months:           len(returns[0]),
+		Volatility:       math.Sqrt(quadratic(cov, weights)) * 100,
+		ParityVolatility: math.Sqrt(quadratic(cov, parity)) * 100,
+		Holdings:         make([]*RiskContribution, 0, len(symbols)),
+		Classes:          make([]*RiskContribution, 0),
+	}
+
+	contributions := computeRiskContributions(cov, weights)
+
+	for i, symbol := range symbols {
+		holding := &RiskContribution{
+			Name:         symbol,
+			Class:        result.Portfolio.Holdings[symbol].Asset.Class,
+			Weight:       weights[i] * 100,
+			Volatility:   math.Sqrt(cov[i][i]) * 100,
+			Contribution: contributions[i] * 100,
+			Parity:       parity[i] * 100,
+		}
+		if risk.Volatility > 0 {
+			holding.Share = holding.Contribution / risk.Volatility * 100
+		}
+		risk.Holdings = append(risk.Holdings, holding)
+
+		class := findRiskContribution(risk.Classes, string(holding.Class))
+		if class == nil {
+			class = &RiskContribution{Name: string(holding.Class), Class: holding.Class}
+			risk.Classes = append(risk.Classes, class)
+		}
+		class.Weight += holding.Weight
+		class.Contribution += holding.Contribution
+		class.Share += holding.Share
+		class.Parity += holding.Parity
+	}
+
+	// The volatility of an asset class is that of its holdings at their weights within the class
+	for _, class := range risk.Classes {
+		within := make([]float64, len(symbols))
+		for i, holding := range risk.Holdings {
+			if holding.Class == class.Class && class.Weight > 0 {
+				within[i] = holding.Weight / class.Weight
+			}
+		}
+		class.Volatility = math.Sqrt(quadratic(cov, within)) * 100
+	}
+
+	sortRiskContributions(risk.Holdings)
+	sortRiskContributions(risk.Classes)
+
+	risk.EffectiveBets = computeEffectiveBets(risk.Holdings)
+	risk.ClassEffectiveBets = computeEffectiveBets(risk.Classes)
+
+	return risk, nil
+}
+
+// computeRiskParity returns the weights at which every asset contributes the same risk. It minimizes
+// y'Σy/2 - Σ ln(y)/n by cyclical coordinate descent, whose solution normalized to a sum of 1 has equal
+// risk contributions.
+func computeRiskParity(cov [][]float64) ([]float64, error) {
+	n := len(cov)
+	budget := 1 / float64(n)
+
+	y := make([]float64, n)
+	for i := range y {
+		if cov[i][i] <= 0 {
+			return nil, errors.New("Risk parity needs every holding to have some volatility")
+		}
+		y[i] = 1 / math.Sqrt(cov[i][i])
+	}
+
+	for iteration := 0; iteration < riskParityIterations; iteration++ {
+		var change float64
+		for i := range y {
+			var c float64
+			for j := range y {
+				if j != i {
+					c += cov[i][j] * y[j]
+				}
+			}
+
+			next := (-c + math.Sqrt(c*c+4*cov[i][i]*budget)) / (2 * cov[i][i])
+			change = math.Max(change, math.Abs(next-y[i])/next)
+			y[i] = next
+		}
+
+		if change < riskParityTolerance {
+			break
+		}
+	}
+
+	var total float64
+	for _, value := range y {
+		total += value
+	}
+	for i := range y {
+		y[i] /= total
+	}
+
+	return y, nil
+}
+
+// computeRiskContributions returns the contribution of every asset to the volatility of the weights, which
+// is its weight times its marginal contribution (Σw)/σ
+func computeRiskContributions(cov [][]float64, weights []float64) []float64 {
+	contributions := make([]float64, len(weights))
+
+	volatility := math.Sqrt(quadratic(cov, weights))
+	if volatility == 0 {
+		return contributions
+	}
+
+	for i := range weights {
+		var marginal float64
+		for j := range weights {
+			marginal += cov[i][j] * weights[j]
+		}
+		contributions[i] = weights[i] * marginal / volatility
+	}
+
+	return contributions
+}
+
+// computeEffectiveBets returns the exponential of the entropy of the risk shares, which is the number of
+// holdings or classes if they share the risk equally, and approaches 1 as one of them dominates
+func computeEffectiveBets(contributions []*RiskContribution) float64 {
+	var entropy float64
+	for _, contribution := range contributions {
+		share := contribution.Share / 100
+		if share > 0 {
+			entropy -= share * math.Log(share)
+		}
+	}
+	return math.Exp(entropy)
+}
+
+func findRiskContribution(contributions []*RiskContribution, name string) *RiskContribution {
+	for _, contribution := range contributions {
+		if contribution.Name == name {
+			return contribution
+		}
+	}
+	return nil
+}
+
+func sortRiskContributions(contributions []*RiskContribution) {
+	sort.SliceStable(contributions, func(i, j int) bool {
+		return contributions[i].Share > contributions[j].Share
+	})
+}
+
+func quadratic(cov [][]float64, weights []float64) float64 {
+	var value float64
+	for i := range weights {
+		for j := range weights {
+			value += weights[i] * cov[i][j] * weights[j]
+		}
+	}
+	return value
+}
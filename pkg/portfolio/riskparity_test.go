@@ -0,0 +1,100 @@
+package portfolio
+
+import (
+	"math"
+	"testing"
+)
+
+func TestComputeRiskParity(t *testing.T) {
+	tests := []struct {
+		name string
+		cov  [][]float64
+		want []float64
+	}{
+		{
+			// Uncorrelated holdings with volatilities of 20% and 10% get weights proportional to 1/20 and 1/10
+			name: "uncorrelated",
+			cov:  [][]float64{{0.04, 0}, {0, 0.01}},
+			want: []float64{1.0 / 3, 2.0 / 3},
+		},
+		{
+			name: "three uncorrelated",
+			cov:  [][]float64{{0.04, 0, 0}, {0, 0.01, 0}, {0, 0, 0.0025}},
+			want: []float64{1.0 / 7, 2.0 / 7, 4.0 / 7},
+		},
+		{
+			name: "symmetric",
+			cov:  [][]float64{{0.04, 0.02}, {0.02, 0.04}},
+			want: []float64{0.5, 0.5},
+		},
+	}
+
+	for _, test := range tests {
+		weights, err := computeRiskParity(test.cov)
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+
+		for i := range weights {
+			if math.Abs(weights[i]-test.want[i]) > 1e-9 {
+				t.Errorf("%s: weights = %v, want %v", test.name, weights, test.want)
+				break
+			}
+		}
+
+		// Every holding contributes the same risk at the risk-parity weights
+		contributions := computeRiskContributions(test.cov, weights)
+		for i := range contributions {
+			if math.Abs(contributions[i]-contributions[0]) > 1e-9 {
+				t.Errorf("%s: risk contributions = %v, want equal contributions", test.name, contributions)
+				break
+			}
+		}
+	}
+
+	_, err := computeRiskParity([][]float64{{0.04, 0}, {0, 0}})
+	if err == nil {
+		t.Errorf("A holding without volatility should fail")
+	}
+}
+
+func TestComputeRiskContributions(t *testing.T) {
+	// An equal split of holdings with variances of 4% and 1% has a variance of 1.25%, and the contributions
+	// are 0.5 * 0.5 * 4% / σ and 0.5 * 0.5 * 1% / σ
+	volatility := math.Sqrt(0.0125)
+	contributions := computeRiskContributions([][]float64{{0.04, 0}, {0, 0.01}}, []float64{0.5, 0.5})
+
+	want := []float64{0.01 / volatility, 0.0025 / volatility}
+	for i := range contributions {
+		if math.Abs(contributions[i]-want[i]) > 1e-12 {
+			t.Errorf("Risk contributions = %v, want %v", contributions, want)
+			break
+		}
+	}
+	if math.Abs(contributions[0]+contributions[1]-volatility) > 1e-12 {
+		t.Errorf("Risk contributions add up to %v, want the volatility of %v", contributions[0]+contributions[1], volatility)
+	}
+}
+
+func TestComputeEffectiveBets(t *testing.T) {
+	tests := []struct {
+		shares []float64
+		want   float64
+	}{
+		{[]float64{50, 50}, 2},
+		{[]float64{100, 0}, 1},
+		{[]float64{25, 25, 25, 25}, 4},
+		{[]float64{80, 20}, math.Exp(-0.8*math.Log(0.8) - 0.2*math.Log(0.2))},
+	}
+
+	for _, test := range tests {
+		contributions := make([]*RiskContribution, len(test.shares))
+		for i, share := range test.shares {
+			contributions[i] = &RiskContribution{Share: share}
+		}
+
+		if got := computeEffectiveBets(contributions); math.Abs(got-test.want) > 1e-12 {
+			t.Errorf("Effective bets of %v = %v, want %v", test.shares, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package portfolio
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// rollingWindows are the lengths in years of the rolling windows over the backtest history
+var rollingWindows = []int{1, 3, 5, 10}
+
+// Rolling contains the annualized return and volatility of every window of a given length over the
+// backtest history, ending at the close of each month, and the distribution of both. It is empty if
+// the history is shorter than the window.
+type Rolling struct {
+	Years      int
+	Dates      []time.Time
+	Returns    []float64
+	Volatility []float64
+	Return     *Distribution
+	Risk       *Distribution
+}
+
+// Distribution summarizes a series of values
+type Distribution struct {
+	Min          float64
+	Percentile10 float64
+	Median       float64
+	Percentile90 float64
+	Max          float64
+}
+
+// computeRolling computes the rolling windows of every length from the monthly balances
+func computeRolling(historic []Historic) []*Rolling {
+	rolling := make([]*Rolling, 0)
+	if len(historic) == 0 {
+		return rolling
+	}
+
+	// The balance before the first month is its opening balance
+	balances := make([]float64, len(historic)+1)
+	balances[0] = historic[0].Open
+	for i, month := range historic {
+		balances[i+1] = month.Close
+	}
+
+	monthlyReturns := computeMonthlyReturns(historic)
+
+	for _, years := range rollingWindows {
+		window := &Rolling{
+			Years:      years,
+			Dates:      make([]time.Time, 0),
+			Returns:    make([]float64, 0),
+			Volatility: make([]float64, 0),
+		}
+
+		months := years * 12
+		for end := months; end < len(balances); end++ {
+			start := end - months
+			if balances[start] <= 0 {
+				continue
+			}
+
+			_, sd := computeMeanAndDeviation(monthlyReturns[start:end])
+
+			window.Dates = append(window.Dates, historic[end-1].Date)
+			window.Returns = append(window.Returns, (math.Pow(balances[end]/balances[start], 1/float64(years))-1)*100)
+			window.Volatility = append(window.Volatility, sd*math.Sqrt(12))
+		}
+
+		window.Return = computeDistribution(window.Returns)
+		window.Risk = computeDistribution(window.Volatility)
+
+		rolling = append(rolling, window)
+	}
+
+	return rolling
+}
+
+// computeDistribution returns the summary of the values, or nil if there are none
+func computeDistribution(values []float64) *Distribution {
+	if len(values) == 0 {
+		return nil
+	}
+
+	sorted := make([]float64, len(values))
+	copy(sorted, values)
+	sort.Float64s(sorted)
+
+	return &Distribution{
+		Min:          sorted[0],
+		Percentile10: computeQuantile(sorted, 0.1),
+		Median:       computeQuantile(sorted, 0.5),
+		Percentile90: computeQuantile(sorted, 0.9),
+		Max:          sorted[len(sorted)-1],
+	}
+}
@@ -0,0 +1,109 @@
+package portfolio
+
+import (
+	"math"
+	"testing"
+)
+
+func TestComputeRolling(t *testing.T) {
+	alternating := make([]float64, 12)
+	for i := range alternating {
+		alternating[i] = 2
+		if i%2 == 1 {
+			alternating[i] = -2
+		}
+	}
+
+	tests := []struct {
+		name       string
+		returns    []float64
+		years      int
+		windows    int
+		annual     float64
+		volatility float64
+	}{
+		{
+			name:    "longer than the history",
+			returns: repeat(1, 11),
+			years:   1,
+			windows: 0,
+		},
+		{
+			// A window of the whole history, whose constant returns have no volatility
+			name:    "equal to the history",
+			returns: repeat(1, 12),
+			years:   1,
+			windows: 1,
+			annual:  (math.Pow(1.01, 12) - 1) * 100,
+		},
+		{
+			// Monthly returns of ±2% have a deviation of 2% a month, or 2 * sqrt(12) a year
+			name:       "annualized volatility",
+			returns:    alternating,
+			years:      1,
+			windows:    1,
+			annual:     (math.Pow(1.02*0.98, 6) - 1) * 100,
+			volatility: 2 * math.Sqrt(12),
+		},
+		{
+			// 36 months of 1% grow by 1.01^36, which is 1.01^12 a year
+			name:    "annualized return",
+			returns: repeat(1, 36),
+			years:   3,
+			windows: 1,
+			annual:  (math.Pow(1.01, 12) - 1) * 100,
+		},
+	}
+
+	for _, test := range tests {
+		var window *Rolling
+		for _, rolling := range computeRolling(testHistoric(100, test.returns...)) {
+			if rolling.Years == test.years {
+				window = rolling
+			}
+		}
+		if window == nil {
+			t.Fatalf("%s: no %d-year window", test.name, test.years)
+		}
+
+		if len(window.Returns) != test.windows {
+			t.Errorf("%s: %d windows, want %d", test.name, len(window.Returns), test.windows)
+			continue
+		}
+		if test.windows == 0 {
+			if window.Return != nil || window.Risk != nil {
+				t.Errorf("%s: distributions of no windows = %+v and %+v, want nil", test.name, window.Return, window.Risk)
+			}
+			continue
+		}
+
+		if math.Abs(window.Returns[0]-test.annual) > 1e-9 {
+			t.Errorf("%s: return = %v, want %v", test.name, window.Returns[0], test.annual)
+		}
+		if math.Abs(window.Volatility[0]-test.volatility) > 1e-9 {
+			t.Errorf("%s: volatility = %v, want %v", test.name, window.Volatility[0], test.volatility)
+		}
+		if !window.Dates[0].Equal(month(len(test.returns) - 1)) {
+			t.Errorf("%s: window ends on %v, want %v", test.name, window.Dates[0], month(len(test.returns)-1))
+		}
+	}
+}
+
+func TestComputeDistribution(t *testing.T) {
+	// The percentiles interpolate between the sorted values 1 to 11
+	distribution := computeDistribution([]float64{11, 1, 10, 2, 9, 3, 8, 4, 7, 5, 6})
+	want := Distribution{Min: 1, Percentile10: 2, Median: 6, Percentile90: 10, Max: 11}
+
+	if *distribution != want {
+		t.Errorf("Distribution = %+v, want %+v", *distribution, want)
+	}
+}
+
+// repeat returns n copies of the value
+func repeat(value float64, n int) []float64 {
+	values := make([]float64, n)
+	for i := range values {
+		values[i] = value
+	}
+	return values
+}
@@ -0,0 +1,391 @@
+package portfolio
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// ShockUSD is the key of a shock to the US dollar. A stronger dollar lowers the value of the holdings that
+// are not US assets, on top of their own shock.
+const ShockUSD = "usd"
+
+// shockGroups are the broad groups of asset classes that shocks can be keyed by
+var shockGroups = map[string][]AssetClass{
+	"equities":    {AssetClassUSStock, AssetClassInternationalStock, AssetClassChinaStock},
+	"stocks":      {AssetClassUSStock, AssetClassInternationalStock, AssetClassChinaStock},
+	"bonds":       {AssetClassUSBond, AssetClassUSTreasury},
+	"real estate": {AssetClassUSRealEstate, AssetClassInternationalRealEstate},
+	"commodities": {AssetClassCommodity},
+	"gold":        {AssetClassGold},
+	"crypto":      {AssetClassCrypto},
+}
+
+// foreignClasses are the asset classes priced in other currencies than the US dollar
+var foreignClasses = map[AssetClass]bool{
+	AssetClassInternationalStock:      true,
+	AssetClassEmergingMarketStock:     true,
+	AssetClassChinaStock:              true,
+	AssetClassInternationalRealEstate: true,
+}
+
+// ShockScenario is a set of immediate price changes in percent, keyed by symbol, tag, asset subclass, asset
+// class or group of asset classes, case-insensitive. The most specific key applies to a holding, in that order.
+type ShockScenario struct {
+	Name   string
+	Shocks map[string]float64
+}
+
+// ShockScenarios is a file of named shock scenarios
+type ShockScenarios struct {
+	Scenarios []*ShockScenario
+}
+
+type shockScenariosConfig struct {
+	Scenarios []shockScenarioConfig `yaml:"scenarios"`
+}
+
+type shockScenarioConfig struct {
+	Name   string             `yaml:"name"`
+	Shocks map[string]float64 `yaml:"shocks"`
+}
+
+// ShockResult is the immediate effect of a shock scenario on the current holdings of a profile, and the
+// allocation of the profile to its portfolios and cash after the shock
+type ShockResult struct {
+	Scenario   *ShockScenario
+	Value      float64
+	Shocked    float64
+	Change     float64
+	Percent    float64
+	Portfolios []*ShockedPortfolio
+	Allocation []*ShockedAllocation
+}
+
+// ShockedPortfolio is the effect of a shock scenario on a portfolio, and the allocation of the portfolio to
+// its holdings and cash after the shock
+type ShockedPortfolio struct {
+	Name       string
+	Value      float64
+	Shocked    float64
+	Change     float64
+	Percent    float64
+	Allocation []*ShockedAllocation
+}
+
+// ShockedAllocation is the value and the weight of a holding, a portfolio or cash before and after a shock,
+// and its target weight
+type ShockedAllocation struct {
+	Name    string
+	Shock   float64
+	Value   float64
+	Shocked float64
+	Before  float64
+	After   float64
+	Target  float64
+}
+
+// ShockScenariosFile returns the default shock scenarios file of a profile, e.g. "profile.scenarios.yml"
+// for "profile.yml"
+func ShockScenariosFile(profileFile string) string {
+	return strings.TrimSuffix(profileFile, filepath.Ext(profileFile)) + ".scenarios.yml"
+}
+
+// ParseShocks parses shocks written as "equities -30%, bonds +5%, gold +10%, USD +8%", or with "=" or ":"
+// between the keys and the changes
+func ParseShocks(text string) (map[string]float64, error) {
+	shocks := make(map[string]float64)
+
+	for _, item := range strings.Split(text, ",") {
+		item = strings.TrimSpace(strings.NewReplacer("=", " ", ":", " ").Replace(item))
+		if item == "" {
+			continue
+		}
+
+		split := strings.LastIndex(item, " ")
+		if split < 0 {
+			return nil, fmt.Errorf("Invalid shock: %s (expected a name and a change, e.g. \"bonds +5%%\")", item)
+		}
+
+		change, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(item[split+1:]), "%"), 64)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid shock: %s (expected a name and a change, e.g. \"bonds +5%%\")", item)
+		}
+
+		shocks[strings.ToLower(strings.TrimSpace(item[:split]))] = change
+	}
+
+	if len(shocks) == 0 {
+		return nil, errors.New("No shocks found")
+	}
+
+	return shocks, nil
+}
+
+// NewShockScenarios returns an empty set of shock scenarios
+func NewShockScenarios() *ShockScenarios {
+	return &ShockScenarios{
+		Scenarios: make([]*ShockScenario, 0),
+	}
+}
+
+// Load reads the shock scenarios from the given file. A missing file has no scenarios.
+func (scenarios *ShockScenarios) Load(name string) error {
+	file, err := ioutil.ReadFile(name)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	config := shockScenariosConfig{}
+
+	err = yaml.Unmarshal(file, &config)
+	if err != nil {
+		return err
+	}
+
+	for _, scenarioConfig := range config.Scenarios {
+		if scenarioConfig.Name == "" {
+			return errors.New("Shock scenario should have a name")
+		}
+		if len(scenarioConfig.Shocks) == 0 {
+			return fmt.Errorf("No shocks in scenario %s", scenarioConfig.Name)
+		}
+
+		shocks := make(map[string]float64)
+		for key, change := range scenarioConfig.Shocks {
+			shocks[strings.ToLower(key)] = change
+		}
+
+		scenarios.Scenarios = append(scenarios.Scenarios, &ShockScenario{Name: scenarioConfig.Name, Shocks: shocks})
+	}
+
+	return nil
+}
+
+// Save writes the shock scenarios to the given file
+func (scenarios *ShockScenarios) Save(name string) error {
+	config := shockScenariosConfig{
+		Scenarios: make([]shockScenarioConfig, 0),
+	}
+
+	for _, scenario := range scenarios.Scenarios {
+		config.Scenarios = append(config.Scenarios, shockScenarioConfig{Name: scenario.Name, Shocks: scenario.Shocks})
+	}
+
+	file, err := yaml.Marshal(&config)
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(name, file, 0644)
+}
+
+// Find returns the scenario with the given name, case-insensitive, or nil if there is none
+func (scenarios *ShockScenarios) Find(name string) *ShockScenario {
+	for _, scenario := range scenarios.Scenarios {
+		if strings.EqualFold(scenario.Name, name) {
+			return scenario
+		}
+	}
+	return nil
+}
+
+// Set adds the scenario, or replaces the scenario with the same name
+func (scenarios *ShockScenarios) Set(scenario *ShockScenario) {
+	for i, existing := range scenarios.Scenarios {
+		if strings.EqualFold(existing.Name, scenario.Name) {
+			scenarios.Scenarios[i] = scenario
+			return
+		}
+	}
+
+	scenarios.Scenarios = append(scenarios.Scenarios, scenario)
+}
+
+// Shock applies the scenario to the current holdings of the profile, which must have been refreshed. Cash keeps
+// its value. Every key of the scenario must match a group of asset classes, an asset class, or a holding.
+func (profile *Profile) Shock(scenario *ShockScenario) (*ShockResult, error) {
+	err := profile.validateShocks(scenario)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ShockResult{
+		Scenario:   scenario,
+		Value:      profile.Cash,
+		Shocked:    profile.Cash,
+		Portfolios: make([]*ShockedPortfolio, 0),
+		Allocation: make([]*ShockedAllocation, 0),
+	}
+
+	for _, portfolio := range profile.Portfolios {
+		shocked := &ShockedPortfolio{
+			Name:       portfolio.Name,
+			Value:      portfolio.Cash,
+			Shocked:    portfolio.Cash,
+			Allocation: make([]*ShockedAllocation, 0),
+		}
+
+		for _, symbol := range portfolio.Symbols {
+			holding := portfolio.Holdings[symbol]
+			if _, seen := findShockedAllocation(shocked.Allocation, symbol); seen {
+				continue
+			}
+
+			change := scenario.change(holding)
+			allocation := &ShockedAllocation{
+				Name:    symbol,
+				Shock:   change,
+				Value:   holding.Status.Value,
+				Shocked: holding.Status.Value * (1 + change/100),
+				Target:  portfolio.TargetAllocation[symbol],
+			}
+
+			shocked.Allocation = append(shocked.Allocation, allocation)
+			shocked.Value += allocation.Value
+			shocked.Shocked += allocation.Shocked
+		}
+
+		if portfolio.Cash != 0 {
+			shocked.Allocation = append(shocked.Allocation, &ShockedAllocation{Name: "Cash", Value: portfolio.Cash, Shocked: portfolio.Cash})
+		}
+
+		computeShockedWeights(shocked.Allocation, shocked.Value, shocked.Shocked)
+		shocked.Change, shocked.Percent = shockChange(shocked.Value, shocked.Shocked)
+
+		result.Portfolios = append(result.Portfolios, shocked)
+		result.Allocation = append(result.Allocation, &ShockedAllocation{
+			Name:    portfolio.Name,
+			Shock:   shocked.Percent,
+			Value:   shocked.Value,
+			Shocked: shocked.Shocked,
+			Target:  profile.TargetAllocation[portfolio.Name],
+		})
+		result.Value += shocked.Value
+		result.Shocked += shocked.Shocked
+	}
+
+	result.Allocation = append(result.Allocation, &ShockedAllocation{
+		Name:    "Cash",
+		Value:   profile.Cash,
+		Shocked: profile.Cash,
+		Target:  profile.TargetAllocation["cash"],
+	})
+
+	computeShockedWeights(result.Allocation, result.Value, result.Shocked)
+	result.Change, result.Percent = shockChange(result.Value, result.Shocked)
+
+	return result, nil
+}
+
+// change returns the change of the price of the holding in percent, which compounds the most specific shock
+// of the holding with the shock to the US dollar for foreign assets
+func (scenario *ShockScenario) change(holding *Holding) float64 {
+	asset := holding.Asset
+	growth := 1.0
+
+	keys := []string{asset.Symbol}
+	keys = append(keys, holding.Tags...)
+	keys = append(keys, string(asset.Subclass), string(asset.Class))
+	for _, group := range sortedShockGroups() {
+		for _, class := range shockGroups[group] {
+			if class == asset.Class || class == asset.Subclass {
+				keys = append(keys, group)
+			}
+		}
+	}
+
+	for _, key := range keys {
+		if change, ok := scenario.Shocks[strings.ToLower(key)]; ok {
+			growth = 1 + change/100
+			break
+		}
+	}
+
+	if usd, ok := scenario.Shocks[ShockUSD]; ok && (foreignClasses[asset.Class] || foreignClasses[asset.Subclass]) {
+		growth /= 1 + usd/100
+	}
+
+	return (growth - 1) * 100
+}
+
+// validateShocks rejects keys that match nothing, which are most likely typos
+func (profile *Profile) validateShocks(scenario *ShockScenario) error {
+	known := map[string]bool{ShockUSD: true}
+
+	for group := range shockGroups {
+		known[group] = true
+	}
+	for _, asset := range AssetDB() {
+		known[strings.ToLower(string(asset.Class))] = true
+		known[strings.ToLower(string(asset.Subclass))] = true
+	}
+	for _, portfolio := range profile.Portfolios {
+		for symbol, holding := range portfolio.Holdings {
+			known[strings.ToLower(symbol)] = true
+			known[strings.ToLower(string(holding.Asset.Class))] = true
+			known[strings.ToLower(string(holding.Asset.Subclass))] = true
+			for _, tag := range holding.Tags {
+				known[strings.ToLower(tag)] = true
+			}
+		}
+	}
+
+	for key := range scenario.Shocks {
+		if !known[key] {
+			return fmt.Errorf("Unknown shock in scenario %s: %s", scenario.Name, key)
+		}
+	}
+
+	return nil
+}
+
+func computeShockedWeights(allocation []*ShockedAllocation, value float64, shocked float64) {
+	for _, item := range allocation {
+		if value != 0 {
+			item.Before = item.Value / value * 100
+		}
+		if shocked != 0 {
+			item.After = item.Shocked / shocked * 100
+		}
+		if item.Shock == 0 && item.Value != 0 {
+			item.Shock = (item.Shocked/item.Value - 1) * 100
+		}
+	}
+}
+
+func findShockedAllocation(allocation []*ShockedAllocation, name string) (*ShockedAllocation, bool) {
+	for _, item := range allocation {
+		if item.Name == name {
+			return item, true
+		}
+	}
+	return nil, false
+}
+
+func shockChange(value float64, shocked float64) (float64, float64) {
+	if value == 0 {
+		return shocked - value, 0
+	}
+	return shocked - value, (shocked/value - 1) * 100
+}
+
+// sortedShockGroups returns the groups in a stable order, since a holding may belong to several of them
+func sortedShockGroups() []string {
+	groups := make([]string, 0, len(shockGroups))
+	for group := range shockGroups {
+		groups = append(groups, group)
+	}
+	sort.Strings(groups)
+	return groups
+}
@@ -0,0 +1,147 @@
+package portfolio
+
+import (
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseShocks(t *testing.T) {
+	shocks, err := ParseShocks("equities -30%, bonds +5%, gold=+10, USD: 8%, US Stock Large -20%,")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]float64{"equities": -30, "bonds": 5, "gold": 10, "usd": 8, "us stock large": -20}
+	if !reflect.DeepEqual(shocks, want) {
+		t.Errorf("Shocks = %v, want %v", shocks, want)
+	}
+
+	for _, text := range []string{"", " , ", "equities", "equities a lot"} {
+		if _, err := ParseShocks(text); err == nil {
+			t.Errorf("Parsed %q, want an error", text)
+		}
+	}
+}
+
+func TestShockScenarioChange(t *testing.T) {
+	// VTI is a large US stock, VXUS an international stock, and BND a US bond
+	vti := NewHolding("VTI", 10, 1000, 0)
+	vti.Tags = []string{"Core"}
+	vxus := NewHolding("VXUS", 10, 1000, 0)
+	bnd := NewHolding("BND", 10, 1000, 0)
+
+	tests := []struct {
+		name    string
+		holding *Holding
+		shocks  map[string]float64
+		want    float64
+	}{
+		{"symbol", vti, map[string]float64{"vti": -10, "core": -20, "us stock large": -30, "us stock": -40, "equities": -50}, -10},
+		{"tag", vti, map[string]float64{"core": -20, "us stock large": -30, "us stock": -40, "equities": -50}, -20},
+		{"subclass", vti, map[string]float64{"us stock large": -30, "us stock": -40, "equities": -50}, -30},
+		{"class", vti, map[string]float64{"us stock": -40, "equities": -50}, -40},
+		{"group", vti, map[string]float64{"equities": -50}, -50},
+		{"no shock", bnd, map[string]float64{"equities": -50}, 0},
+		{"US asset with a stronger dollar", vti, map[string]float64{"equities": -20, "usd": 25}, -20},
+		{"foreign asset with a stronger dollar", vxus, map[string]float64{"equities": -20, "usd": 25}, -36},
+		{"foreign asset with only a weaker dollar", vxus, map[string]float64{"usd": -20}, 25},
+	}
+
+	for _, test := range tests {
+		scenario := &ShockScenario{Name: test.name, Shocks: test.shocks}
+		if got := scenario.change(test.holding); math.Abs(got-test.want) > 1e-9 {
+			t.Errorf("%s: change = %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestValidateShocks(t *testing.T) {
+	portfolio := newTestPortfolio(map[string]float64{"VTI": 60, "BND": 40})
+	portfolio.Holdings["VTI"].Tags = []string{"Core"}
+
+	profile := NewProfile("Test")
+	profile.Portfolios = append(profile.Portfolios, portfolio)
+
+	// Groups, classes and subclasses are known even if no holding is in them
+	known := &ShockScenario{Name: "Known", Shocks: map[string]float64{
+		"equities": -30, "usd": 5, "vti": -10, "core": -20, "us bond": 2, "us stock large": -25, "silver": 10,
+	}}
+	if err := profile.validateShocks(known); err != nil {
+		t.Errorf("Known shocks rejected: %v", err)
+	}
+
+	for _, key := range []string{"equitys", "vxus", "satellite"} {
+		unknown := &ShockScenario{Name: "Unknown", Shocks: map[string]float64{"equities": -30, key: -10}}
+		if err := profile.validateShocks(unknown); err == nil {
+			t.Errorf("Unknown shock %s accepted", key)
+		}
+	}
+}
+
+func TestShockScenariosLoadSave(t *testing.T) {
+	dir, err := ioutil.TempDir("", "portfolio")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// A missing file has no scenarios
+	name := filepath.Join(dir, "profile.scenarios.yml")
+	scenarios := NewShockScenarios()
+	if err = scenarios.Load(name); err != nil || len(scenarios.Scenarios) != 0 {
+		t.Fatalf("Loaded %d scenarios from a missing file with %v, want none", len(scenarios.Scenarios), err)
+	}
+
+	scenarios.Set(&ShockScenario{Name: "Crash", Shocks: map[string]float64{"equities": -30, "bonds": 5}})
+	scenarios.Set(&ShockScenario{Name: "Dollar", Shocks: map[string]float64{"usd": 10}})
+	scenarios.Set(&ShockScenario{Name: "crash", Shocks: map[string]float64{"equities": -40, "gold": 10}})
+
+	if err = scenarios.Save(name); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded := NewShockScenarios()
+	if err = loaded.Load(name); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(loaded.Scenarios, scenarios.Scenarios) {
+		t.Errorf("Loaded %+v, want %+v", loaded.Scenarios, scenarios.Scenarios)
+	}
+	if scenario := loaded.Find("CRASH"); scenario == nil || scenario.Shocks["equities"] != -40 {
+		t.Errorf("Found %+v, want the replaced crash", scenario)
+	}
+
+	// The keys are case-insensitive, and every scenario needs a name and shocks
+	tests := []struct {
+		name   string
+		config string
+		shocks map[string]float64
+	}{
+		{"mixed case", "scenarios:\n- name: Crash\n  shocks:\n    Equities: -30\n    USD: 5\n", map[string]float64{"equities": -30, "usd": 5}},
+		{"no name", "scenarios:\n- shocks:\n    equities: -30\n", nil},
+		{"no shocks", "scenarios:\n- name: Crash\n", nil},
+	}
+
+	for _, test := range tests {
+		if err = ioutil.WriteFile(name, []byte(test.config), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		loaded := NewShockScenarios()
+		err = loaded.Load(name)
+		if test.shocks == nil {
+			if err == nil {
+				t.Errorf("%s: loaded %+v, want an error", test.name, loaded.Scenarios)
+			}
+			continue
+		}
+
+		if err != nil || len(loaded.Scenarios) != 1 || !reflect.DeepEqual(loaded.Scenarios[0].Shocks, test.shocks) {
+			t.Errorf("%s: loaded %+v with %v, want shocks %v", test.name, loaded.Scenarios, err, test.shocks)
+		}
+	}
+}
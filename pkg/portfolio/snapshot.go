@@ -0,0 +1,318 @@
+package portfolio
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Snapshot records the end-of-day value of a portfolio, or of the entire profile if the
+// portfolio name is empty. The flow is the net amount deposited since the previous snapshot.
+type Snapshot struct {
+	Date      time.Time
+	Portfolio string
+	Value     float64
+	CostBasis float64
+	Cash      float64
+	Flow      float64
+	Holdings  map[string]float64
+}
+
+type snapshotRecord struct {
+	Date      string             `json:"date"`
+	Portfolio string             `json:"portfolio,omitempty"`
+	Value     float64            `json:"value"`
+	CostBasis float64            `json:"basis"`
+	Cash      float64            `json:"cash,omitempty"`
+	Flow      float64            `json:"flow,omitempty"`
+	Holdings  map[string]float64 `json:"holdings,omitempty"`
+}
+
+// SnapshotStore keeps the daily snapshots of a profile in a JSON lines file
+type SnapshotStore struct {
+	File      string
+	snapshots map[string][]*Snapshot
+	mutex     sync.Mutex
+}
+
+// NewSnapshotStore returns a snapshot store backed by the given file
+func NewSnapshotStore(file string) *SnapshotStore {
+	return &SnapshotStore{
+		File:      file,
+		snapshots: make(map[string][]*Snapshot),
+	}
+}
+
+// SnapshotFile returns the default snapshot file of a profile, e.g. "profile.snapshots.jsonl" for "profile.yml"
+func SnapshotFile(profileFile string) string {
+	return strings.TrimSuffix(profileFile, filepath.Ext(profileFile)) + ".snapshots.jsonl"
+}
+
+// Load reads all snapshots from the file. A missing file is an empty store.
+func (store *SnapshotStore) Load() error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	store.snapshots = make(map[string][]*Snapshot)
+
+	file, err := os.Open(store.File)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		record := snapshotRecord{}
+		err = json.Unmarshal([]byte(line), &record)
+		if err != nil {
+			return err
+		}
+
+		date, err := time.Parse(dateFormat, record.Date)
+		if err != nil {
+			return err
+		}
+
+		store.add(&Snapshot{
+			Date:      date,
+			Portfolio: record.Portfolio,
+			Value:     record.Value,
+			CostBasis: record.CostBasis,
+			Cash:      record.Cash,
+			Flow:      record.Flow,
+			Holdings:  record.Holdings,
+		})
+	}
+
+	return scanner.Err()
+}
+
+// Record appends the snapshots of the profile and its portfolios for the given day, unless
+// that day has been recorded already. The profile must have been refreshed.
+func (store *SnapshotStore) Record(profile *Profile, date time.Time) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	day := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+
+	if profile.Status == nil {
+		return errors.New("Profile has not been refreshed")
+	}
+
+	if store.recorded("", day) {
+		return nil
+	}
+
+	snapshots := make([]*Snapshot, 0)
+
+	profileSnapshot := &Snapshot{
+		Date:      day,
+		Value:     profile.Status.Value,
+		CostBasis: profile.CostBasis,
+		Cash:      profile.Cash,
+		Holdings:  make(map[string]float64),
+	}
+
+	for _, portfolio := range profile.Portfolios {
+		snapshot := &Snapshot{
+			Date:      day,
+			Portfolio: portfolio.Name,
+			Value:     portfolio.Status.Value,
+			CostBasis: portfolio.CostBasis,
+			Cash:      portfolio.Cash,
+			Flow:      store.computeFlow(portfolio, day),
+			Holdings:  make(map[string]float64),
+		}
+
+		for symbol, holding := range portfolio.Holdings {
+			snapshot.Holdings[symbol] = holding.Quantity
+			profileSnapshot.Holdings[symbol] += holding.Quantity
+		}
+
+		profileSnapshot.Flow += snapshot.Flow
+		snapshots = append(snapshots, snapshot)
+	}
+
+	snapshots = append(snapshots, profileSnapshot)
+
+	file, err := os.OpenFile(store.File, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	for _, snapshot := range snapshots {
+		line, err := json.Marshal(snapshotRecord{
+			Date:      snapshot.Date.Format(dateFormat),
+			Portfolio: snapshot.Portfolio,
+			Value:     snapshot.Value,
+			CostBasis: snapshot.CostBasis,
+			Cash:      snapshot.Cash,
+			Flow:      snapshot.Flow,
+			Holdings:  snapshot.Holdings,
+		})
+		if err != nil {
+			return err
+		}
+
+		_, err = file.Write(append(line, '\n'))
+		if err != nil {
+			return err
+		}
+
+		store.add(snapshot)
+	}
+
+	return nil
+}
+
+// History returns the snapshots of a portfolio, or of the profile if the name is empty, ordered by date
+func (store *SnapshotStore) History(portfolio string) []*Snapshot {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	history := make([]*Snapshot, len(store.snapshots[portfolio]))
+	copy(history, store.snapshots[portfolio])
+
+	return history
+}
+
+// Recorded returns true if the profile has been recorded for the day of the given date
+func (store *SnapshotStore) Recorded(date time.Time) bool {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	return store.recorded("", time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC))
+}
+
+func (store *SnapshotStore) add(snapshot *Snapshot) {
+	history := store.snapshots[snapshot.Portfolio]
+	history = append(history, snapshot)
+
+	sort.SliceStable(history, func(i, j int) bool {
+		return history[i].Date.Before(history[j].Date)
+	})
+
+	store.snapshots[snapshot.Portfolio] = history
+}
+
+func (store *SnapshotStore) recorded(portfolio string, day time.Time) bool {
+	history := store.snapshots[portfolio]
+	return len(history) > 0 && !history[len(history)-1].Date.Before(day)
+}
+
+// computeFlow sums the deposits and withdrawals of the portfolio since its last snapshot
+func (store *SnapshotStore) computeFlow(portfolio *Portfolio, day time.Time) float64 {
+	var last time.Time
+	history := store.snapshots[portfolio.Name]
+	if len(history) > 0 {
+		last = history[len(history)-1].Date
+	}
+
+	var flow float64
+	for _, transaction := range portfolio.Transactions {
+		if !transaction.IsExternal() {
+			continue
+		}
+
+		// The first snapshot is the starting point, so earlier flows are already part of its value
+		if last.IsZero() || !transaction.Date.After(last) || transaction.Date.After(day) {
+			continue
+		}
+
+		flow += transaction.Amount
+	}
+
+	return flow
+}
+
+// ComputeTimeWeighted computes the actual trailing time-weighted returns from daily snapshots.
+// Each day's return excludes the money deposited or withdrawn that day. Returns of periods
+// shorter than a year are cumulative, longer ones are annualized. Periods starting before the
+// first snapshot fall back to the return since inception.
+func ComputeTimeWeighted(snapshots []*Snapshot) (*Return, error) {
+	if len(snapshots) < 2 {
+		return nil, errors.New("Not enough snapshots")
+	}
+
+	first := snapshots[0].Date
+	end := snapshots[len(snapshots)-1].Date
+
+	twr := NewReturn()
+	twr.Max = computeTimeWeightedReturn(snapshots, first, true)
+
+	periods := []struct {
+		result     *float64
+		start      time.Time
+		annualized bool
+	}{
+		{&twr.OneMonth, end.AddDate(0, -1, 0), false},
+		{&twr.ThreeMonth, end.AddDate(0, -3, 0), false},
+		{&twr.SixMonth, end.AddDate(0, -6, 0), false},
+		{&twr.YTD, time.Date(end.Year(), time.January, 1, 0, 0, 0, 0, end.Location()).AddDate(0, 0, -1), false},
+		{&twr.OneYear, end.AddDate(-1, 0, 0), true},
+		{&twr.ThreeYear, end.AddDate(-3, 0, 0), true},
+		{&twr.FiveYear, end.AddDate(-5, 0, 0), true},
+		{&twr.TenYear, end.AddDate(-10, 0, 0), true},
+	}
+
+	for _, period := range periods {
+		if period.start.Before(first) {
+			*period.result = twr.Max
+			continue
+		}
+
+		*period.result = computeTimeWeightedReturn(snapshots, period.start, period.annualized)
+	}
+
+	return twr, nil
+}
+
+// computeTimeWeightedReturn chains the daily returns after the last snapshot on or before the start date
+func computeTimeWeightedReturn(snapshots []*Snapshot, start time.Time, annualized bool) float64 {
+	base := 0
+	for i, snapshot := range snapshots {
+		if snapshot.Date.After(start) {
+			break
+		}
+		base = i
+	}
+
+	growth := 1.0
+	for i := base + 1; i < len(snapshots); i++ {
+		previous := snapshots[i-1].Value
+		if previous == 0 {
+			continue
+		}
+		growth *= (snapshots[i].Value - snapshots[i].Flow) / previous
+	}
+
+	if !annualized {
+		return (growth - 1) * 100
+	}
+
+	years := snapshots[len(snapshots)-1].Date.Sub(snapshots[base].Date).Hours() / 24 / daysPerYear
+	if years <= 0 {
+		return math.NaN()
+	}
+
+	return (math.Pow(growth, 1/years) - 1) * 100
+}
@@ -0,0 +1,98 @@
+package portfolio
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestComputeTimeWeightedReturn(t *testing.T) {
+	tests := []struct {
+		name       string
+		snapshots  []*Snapshot
+		start      time.Time
+		annualized bool
+		want       float64
+	}{
+		{
+			// Daily returns of 10%, (2200 - 1000) / 1100 - 1 = 9.09% and -10%, which chain to 1.1 * 1.2 / 1.1 * 0.9 = 1.08
+			name:      "deposit",
+			snapshots: testSnapshots(day(0), 1000, 0, 1100, 0, 2200, 1000, 1980, 0),
+			start:     day(0),
+			want:      8,
+		},
+		{
+			name:      "after the start",
+			snapshots: testSnapshots(day(0), 1000, 0, 1100, 0, 2200, 1000, 1980, 0),
+			start:     day(1),
+			want:      (12.0/11*0.9 - 1) * 100,
+		},
+		{
+			// The start falls between two snapshots, so the earlier one is the base
+			name:      "between snapshots",
+			snapshots: testSnapshots(day(0), 1000, 0, 1100, 0, 2200, 1000, 1980, 0),
+			start:     day(1).Add(12 * time.Hour),
+			want:      (12.0/11*0.9 - 1) * 100,
+		},
+		{
+			// A withdrawal of 500 at the end of the second year, after a growth of 1.1 * 1.1 = 1.21 over 730 days
+			name: "annualized",
+			snapshots: []*Snapshot{
+				{Date: day(0), Value: 1000},
+				{Date: day(365), Value: 1100},
+				{Date: day(730), Value: 710, Flow: -500},
+			},
+			start:      day(0),
+			annualized: true,
+			want:       10,
+		},
+	}
+
+	for _, test := range tests {
+		got := computeTimeWeightedReturn(test.snapshots, test.start, test.annualized)
+		if math.Abs(got-test.want) > 1e-9 {
+			t.Errorf("%s: time-weighted return = %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestComputeTimeWeighted(t *testing.T) {
+	_, err := ComputeTimeWeighted(testSnapshots(day(0), 1000, 0))
+	if err == nil {
+		t.Errorf("A single snapshot should fail")
+	}
+
+	snapshots := []*Snapshot{
+		{Date: day(0), Value: 1000},
+		{Date: day(365), Value: 1100},
+		{Date: day(730), Value: 1210},
+	}
+
+	twr, err := ComputeTimeWeighted(snapshots)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The one-year and six-month returns start at the second snapshot, the last one on or before their
+	// start, and the longer periods fall back to the return since inception
+	if math.Abs(twr.Max-10) > 1e-9 || math.Abs(twr.OneYear-10) > 1e-9 || twr.FiveYear != twr.Max {
+		t.Errorf("Time-weighted returns = %+v, want 10%% a year", twr)
+	}
+	if math.Abs(twr.SixMonth-10) > 1e-9 {
+		t.Errorf("Six-month return = %v, want 10", twr.SixMonth)
+	}
+}
+
+// day returns the date the given number of days after January 1, 2021
+func day(i int) time.Time {
+	return time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC).AddDate(0, 0, i)
+}
+
+// testSnapshots returns daily snapshots of the profile from pairs of values and flows
+func testSnapshots(start time.Time, values ...float64) []*Snapshot {
+	snapshots := make([]*Snapshot, 0, len(values)/2)
+	for i := 0; i+1 < len(values); i += 2 {
+		snapshots = append(snapshots, &Snapshot{Date: start.AddDate(0, 0, i/2), Value: values[i], Flow: values[i+1]})
+	}
+	return snapshots
+}
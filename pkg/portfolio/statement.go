@@ -0,0 +1,128 @@
+package portfolio
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Statement contains the positions, cash and transactions of an account as reported by a broker
+type Statement struct {
+	Positions    []*Position
+	Cash         float64
+	HasCash      bool
+	Transactions []*Transaction
+	CUSIPs       map[string]string
+}
+
+// Position defines a holding as reported in a statement
+type Position struct {
+	Symbol      string
+	Description string
+	Quantity    float64
+	Price       float64
+	Value       float64
+	CostBasis   float64
+}
+
+// NewStatement returns an empty statement
+func NewStatement() *Statement {
+	return &Statement{
+		Positions:    make([]*Position, 0),
+		Transactions: make([]*Transaction, 0),
+		CUSIPs:       make(map[string]string),
+	}
+}
+
+// assignTransactionIDs derives a stable ID for every transaction without one from its content,
+// so that the same transaction gets the same ID every time the statement is imported. Identical
+// transactions in one statement are told apart by their order of appearance.
+func (statement *Statement) assignTransactionIDs() {
+	seen := make(map[string]int)
+
+	for _, transaction := range statement.Transactions {
+		if transaction.ID != "" {
+			continue
+		}
+
+		key := fmt.Sprintf("%s|%s|%s|%.6f|%.6f|%.2f|%s",
+			transaction.Date.Format(dateFormat),
+			transaction.Type,
+			transaction.Symbol,
+			transaction.Quantity,
+			transaction.Price,
+			transaction.Amount,
+			transaction.Description)
+
+		occurrence := seen[key]
+		seen[key]++
+
+		sum := sha1.Sum([]byte(fmt.Sprintf("%s#%d", key, occurrence)))
+		transaction.ID = fmt.Sprintf("%x", sum[:6])
+	}
+}
+
+// reconcile renames the securities of the statement to the symbols already used by the
+// holdings of the portfolio, matching them by CUSIP
+func (statement *Statement) reconcile(portfolio *Portfolio) {
+	symbols := make(map[string]string)
+	for symbol, holding := range portfolio.Holdings {
+		if holding.CUSIP != "" {
+			symbols[holding.CUSIP] = symbol
+		}
+	}
+
+	aliases := make(map[string]string)
+	for symbol, cusip := range statement.CUSIPs {
+		existing, ok := symbols[cusip]
+		if ok && existing != symbol {
+			aliases[symbol] = existing
+		}
+	}
+
+	for symbol, alias := range aliases {
+		statement.CUSIPs[alias] = statement.CUSIPs[symbol]
+		delete(statement.CUSIPs, symbol)
+	}
+
+	for _, position := range statement.Positions {
+		if alias, ok := aliases[position.Symbol]; ok {
+			position.Symbol = alias
+		}
+	}
+
+	for _, transaction := range statement.Transactions {
+		if alias, ok := aliases[transaction.Symbol]; ok {
+			transaction.Symbol = alias
+		}
+	}
+}
+
+// parseAmount parses numbers the way brokers print them, e.g. "$1,234.56", "+1.5" or "(12.00)"
+func parseAmount(value string) (float64, bool) {
+	value = strings.TrimSpace(value)
+
+	negative := false
+	if strings.HasPrefix(value, "(") && strings.HasSuffix(value, ")") {
+		negative = true
+		value = strings.Trim(value, "()")
+	}
+
+	value = strings.NewReplacer("$", "", ",", "", "+", "", " ", "").Replace(value)
+	if value == "" || value == "--" || strings.EqualFold(value, "n/a") {
+		return 0, false
+	}
+
+	amount, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, false
+	}
+
+	if negative {
+		amount = -math.Abs(amount)
+	}
+
+	return amount, true
+}
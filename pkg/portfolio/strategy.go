@@ -0,0 +1,318 @@
+package portfolio
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// StrategyType names a built-in tactical strategy
+type StrategyType string
+
+const (
+	// StrategyMovingAverage holds every holding at its target weight while its price is above its moving
+	// average, and moves its weight to the safe holding otherwise
+	StrategyMovingAverage StrategyType = "moving-average"
+	// StrategyDualMomentum holds the holdings with the highest trailing returns, as long as they beat the
+	// trailing return of the safe holding, and the safe holding otherwise
+	StrategyDualMomentum StrategyType = "dual-momentum"
+	// StrategyRelativeStrength rotates into the holdings with the highest trailing returns
+	StrategyRelativeStrength StrategyType = "relative-strength"
+)
+
+const (
+	defaultMovingAverageMonths    = 10
+	defaultDualMomentumMonths     = 12
+	defaultRelativeStrengthMonths = 6
+	defaultStrategyTop            = 1
+)
+
+// Strategy decides the target weights of the holdings of a backtest at every rebalance date, instead of the
+// target allocation. History holds the month-end quotes of every holding up to and including the date, and
+// targets the target allocation of the portfolio. Weights are in percent.
+type Strategy interface {
+	Name() string
+	Weights(date time.Time, history map[string][]Historic, targets map[string]float64) map[string]float64
+}
+
+// MovingAverageStrategy times every holding other than the safe one with its moving average of month-end
+// closes over the given number of months
+type MovingAverageStrategy struct {
+	Months int
+	Safe   string
+}
+
+// DualMomentumStrategy ranks the holdings other than the safe one by their return over the given number of
+// months, and splits the portfolio equally between the top ones. A slot whose holding does not beat the
+// return of the safe holding goes to the safe holding instead.
+type DualMomentumStrategy struct {
+	Months int
+	Top    int
+	Safe   string
+}
+
+// RelativeStrengthStrategy ranks the holdings by their return over the given number of months, and splits
+// the portfolio equally between the top ones
+type RelativeStrengthStrategy struct {
+	Months int
+	Top    int
+}
+
+type strategyConfig struct {
+	Type   string `yaml:"type"`
+	Months int    `yaml:"months,omitempty"`
+	Top    int    `yaml:"top,omitempty"`
+	Safe   string `yaml:"safe,omitempty"`
+}
+
+func newStrategy(config *strategyConfig, symbols []string) (Strategy, error) {
+	if config == nil {
+		return nil, nil
+	}
+
+	if config.Months < 0 || config.Top < 0 {
+		return nil, errors.New("Strategy months and top should not be negative")
+	}
+
+	strategyType := StrategyType(config.Type)
+
+	switch strategyType {
+	case StrategyMovingAverage, StrategyDualMomentum:
+		if config.Safe == "" {
+			return nil, fmt.Errorf("Strategy %s requires a safe holding", config.Type)
+		}
+		if !containsString(symbols, config.Safe) {
+			return nil, fmt.Errorf("Safe holding of strategy %s is not in the portfolio: %s", config.Type, config.Safe)
+		}
+
+	case StrategyRelativeStrength:
+		if config.Safe != "" {
+			return nil, fmt.Errorf("Strategy %s does not take a safe holding", config.Type)
+		}
+
+	case "":
+		return nil, errors.New("Missing strategy type")
+
+	default:
+		return nil, fmt.Errorf("Unknown strategy: %s", config.Type)
+	}
+
+	if strategyType == StrategyMovingAverage && config.Top != 0 {
+		return nil, fmt.Errorf("Strategy %s does not take a top", config.Type)
+	}
+
+	months := config.Months
+	top := config.Top
+	if top == 0 {
+		top = defaultStrategyTop
+	}
+
+	switch strategyType {
+	case StrategyMovingAverage:
+		if months == 0 {
+			months = defaultMovingAverageMonths
+		}
+		return &MovingAverageStrategy{Months: months, Safe: config.Safe}, nil
+
+	case StrategyDualMomentum:
+		if months == 0 {
+			months = defaultDualMomentumMonths
+		}
+		return &DualMomentumStrategy{Months: months, Top: top, Safe: config.Safe}, nil
+
+	default:
+		if months == 0 {
+			months = defaultRelativeStrengthMonths
+		}
+		return &RelativeStrengthStrategy{Months: months, Top: top}, nil
+	}
+}
+
+// strategyConfigOf returns the config of a built-in strategy, or nil for no strategy or a custom one
+func strategyConfigOf(strategy Strategy) *strategyConfig {
+	switch s := strategy.(type) {
+	case *MovingAverageStrategy:
+		return &strategyConfig{Type: string(StrategyMovingAverage), Months: s.Months, Safe: s.Safe}
+	case *DualMomentumStrategy:
+		return &strategyConfig{Type: string(StrategyDualMomentum), Months: s.Months, Top: s.Top, Safe: s.Safe}
+	case *RelativeStrengthStrategy:
+		return &strategyConfig{Type: string(StrategyRelativeStrength), Months: s.Months, Top: s.Top}
+	}
+	return nil
+}
+
+// Name returns the name of the strategy
+func (strategy *MovingAverageStrategy) Name() string {
+	return fmt.Sprintf("%d-Month Moving Average", strategy.Months)
+}
+
+// Weights keeps the target weight of every holding trading above its moving average, and gives the weights of
+// the others to the safe holding. It keeps the target allocation until the moving averages have enough history.
+func (strategy *MovingAverageStrategy) Weights(date time.Time, history map[string][]Historic, targets map[string]float64) map[string]float64 {
+	weights := make(map[string]float64)
+
+	for symbol, target := range targets {
+		historic := history[symbol]
+		if len(historic) < strategy.Months {
+			return targets
+		}
+
+		if symbol == strategy.Safe {
+			weights[symbol] += target
+			continue
+		}
+
+		var sum float64
+		for _, month := range historic[len(historic)-strategy.Months:] {
+			sum += month.Close
+		}
+
+		if historic[len(historic)-1].Close > sum/float64(strategy.Months) {
+			weights[symbol] += target
+		} else {
+			weights[strategy.Safe] += target
+		}
+	}
+
+	return weights
+}
+
+// Name returns the name of the strategy
+func (strategy *DualMomentumStrategy) Name() string {
+	return fmt.Sprintf("%d-Month Dual Momentum, Top %d", strategy.Months, strategy.Top)
+}
+
+// Weights splits the portfolio between the top holdings by trailing return, or the safe holding for those that
+// do not beat it. It keeps the target allocation until the trailing returns have enough history.
+func (strategy *DualMomentumStrategy) Weights(date time.Time, history map[string][]Historic, targets map[string]float64) map[string]float64 {
+	momentum, ok := computeMomentum(history, targets, strategy.Months)
+	if !ok {
+		return targets
+	}
+
+	symbols := rankMomentum(momentum, strategy.Safe)
+	slots := strategy.Top
+	if slots > len(symbols) {
+		slots = len(symbols)
+	}
+	if slots == 0 {
+		return map[string]float64{strategy.Safe: 100}
+	}
+
+	weights := make(map[string]float64)
+	for _, symbol := range symbols[:slots] {
+		if momentum[symbol] > momentum[strategy.Safe] {
+			weights[symbol] += 100 / float64(slots)
+		} else {
+			weights[strategy.Safe] += 100 / float64(slots)
+		}
+	}
+
+	return weights
+}
+
+// Name returns the name of the strategy
+func (strategy *RelativeStrengthStrategy) Name() string {
+	return fmt.Sprintf("%d-Month Relative Strength, Top %d", strategy.Months, strategy.Top)
+}
+
+// Weights splits the portfolio equally between the top holdings by trailing return. It keeps the target
+// allocation until the trailing returns have enough history.
+func (strategy *RelativeStrengthStrategy) Weights(date time.Time, history map[string][]Historic, targets map[string]float64) map[string]float64 {
+	momentum, ok := computeMomentum(history, targets, strategy.Months)
+	if !ok {
+		return targets
+	}
+
+	symbols := rankMomentum(momentum, "")
+	slots := strategy.Top
+	if slots > len(symbols) {
+		slots = len(symbols)
+	}
+
+	weights := make(map[string]float64)
+	for _, symbol := range symbols[:slots] {
+		weights[symbol] = 100 / float64(slots)
+	}
+
+	return weights
+}
+
+// computeMomentum returns the return of every holding over the given number of months, in percent, and false
+// if a holding does not have enough history yet
+func computeMomentum(history map[string][]Historic, targets map[string]float64, months int) (map[string]float64, bool) {
+	momentum := make(map[string]float64)
+
+	for symbol := range targets {
+		historic := history[symbol]
+		if len(historic) <= months {
+			return nil, false
+		}
+
+		start := historic[len(historic)-1-months].Close
+		if start <= 0 {
+			return nil, false
+		}
+		momentum[symbol] = (historic[len(historic)-1].Close/start - 1) * 100
+	}
+
+	return momentum, true
+}
+
+// rankMomentum returns the holdings other than the excluded one from the highest to the lowest momentum
+func rankMomentum(momentum map[string]float64, excluded string) []string {
+	symbols := make([]string, 0, len(momentum))
+	for symbol := range momentum {
+		if symbol != excluded {
+			symbols = append(symbols, symbol)
+		}
+	}
+
+	sort.Slice(symbols, func(i, j int) bool {
+		if momentum[symbols[i]] == momentum[symbols[j]] {
+			return symbols[i] < symbols[j]
+		}
+		return momentum[symbols[i]] > momentum[symbols[j]]
+	})
+
+	return symbols
+}
+
+// strategyTargets returns the weights of the strategy of the portfolio for its holdings, scaled to add up to
+// 100%, or the target allocation if the strategy holds none of them. A symbol listed twice is weighed once.
+// A backtest trades to these targets instead of the target allocation.
+func strategyTargets(portfolio *Portfolio, date time.Time, history map[string][]Historic) map[string]float64 {
+	weights := portfolio.Strategy.Weights(date, history, portfolio.TargetAllocation)
+
+	symbols := make([]string, 0, len(portfolio.Symbols))
+	for _, symbol := range portfolio.Symbols {
+		if !containsString(symbols, symbol) {
+			symbols = append(symbols, symbol)
+		}
+	}
+
+	var total float64
+	for _, symbol := range symbols {
+		total += weights[symbol]
+	}
+
+	if total <= 0 {
+		return portfolio.TargetAllocation
+	}
+
+	targets := make(map[string]float64)
+	for _, symbol := range symbols {
+		targets[symbol] = weights[symbol] / total * 100
+	}
+
+	return targets
+}
+
+// strategyName returns the name of the strategy of the portfolio, or an empty string for its target allocation
+func strategyName(portfolio *Portfolio) string {
+	if portfolio.Strategy == nil {
+		return ""
+	}
+	return portfolio.Strategy.Name()
+}
@@ -0,0 +1,196 @@
+package portfolio
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestMovingAverageWeights(t *testing.T) {
+	strategy := &MovingAverageStrategy{Months: 3, Safe: "BND"}
+	targets := map[string]float64{"VTI": 60, "VXUS": 20, "BND": 20}
+
+	// VTI closes above its moving average of 11, and VXUS below it
+	history := map[string][]Historic{
+		"VTI":  testCloses(10, 11, 12),
+		"VXUS": testCloses(12, 11, 10),
+		"BND":  testCloses(10, 10, 10),
+	}
+
+	weights := strategy.Weights(month(2), history, targets)
+	if want := map[string]float64{"VTI": 60, "BND": 40}; !reflect.DeepEqual(weights, want) {
+		t.Errorf("Weights = %v, want %v", weights, want)
+	}
+
+	// The moving average of VTI needs three months
+	history["VTI"] = testCloses(11, 12)
+	if weights = strategy.Weights(month(1), history, targets); !reflect.DeepEqual(weights, targets) {
+		t.Errorf("Weights without enough history = %v, want the targets %v", weights, targets)
+	}
+}
+
+func TestDualMomentumWeights(t *testing.T) {
+	targets := map[string]float64{"VTI": 25, "VXUS": 25, "GLD": 25, "BND": 25}
+
+	// Over two months, VTI returns 10%, VXUS 2%, GLD -5% and BND 3%
+	history := map[string][]Historic{
+		"VTI":  testCloses(100, 105, 110),
+		"VXUS": testCloses(100, 100, 102),
+		"GLD":  testCloses(100, 90, 95),
+		"BND":  testCloses(100, 101, 103),
+	}
+
+	tests := []struct {
+		name     string
+		strategy *DualMomentumStrategy
+		safe     []float64
+		want     map[string]float64
+	}{
+		{
+			// VXUS is second but does not beat BND, so its slot goes to BND
+			name:     "top two",
+			strategy: &DualMomentumStrategy{Months: 2, Top: 2, Safe: "BND"},
+			want:     map[string]float64{"VTI": 50, "BND": 50},
+		},
+		{
+			name:     "top one",
+			strategy: &DualMomentumStrategy{Months: 2, Top: 1, Safe: "BND"},
+			want:     map[string]float64{"VTI": 100},
+		},
+		{
+			name:     "safe holding wins",
+			strategy: &DualMomentumStrategy{Months: 2, Top: 2, Safe: "BND"},
+			safe:     []float64{100, 110, 120},
+			want:     map[string]float64{"BND": 100},
+		},
+		{
+			name:     "not enough history",
+			strategy: &DualMomentumStrategy{Months: 3, Top: 2, Safe: "BND"},
+			want:     targets,
+		},
+	}
+
+	for _, test := range tests {
+		history["BND"] = testCloses(100, 101, 103)
+		if test.safe != nil {
+			history["BND"] = testCloses(test.safe...)
+		}
+
+		weights := test.strategy.Weights(month(2), history, targets)
+		if !reflect.DeepEqual(weights, test.want) {
+			t.Errorf("%s: weights = %v, want %v", test.name, weights, test.want)
+		}
+	}
+}
+
+func TestRelativeStrengthWeights(t *testing.T) {
+	targets := map[string]float64{"VTI": 25, "VXUS": 25, "GLD": 25, "BND": 25}
+	history := map[string][]Historic{
+		"VTI":  testCloses(100, 105, 110),
+		"VXUS": testCloses(100, 100, 102),
+		"GLD":  testCloses(100, 90, 95),
+		"BND":  testCloses(100, 101, 103),
+	}
+
+	tests := []struct {
+		name     string
+		strategy *RelativeStrengthStrategy
+		want     map[string]float64
+	}{
+		{"top two", &RelativeStrengthStrategy{Months: 2, Top: 2}, map[string]float64{"VTI": 50, "BND": 50}},
+		{"top more than the holdings", &RelativeStrengthStrategy{Months: 2, Top: 10}, map[string]float64{"VTI": 25, "VXUS": 25, "GLD": 25, "BND": 25}},
+		{"not enough history", &RelativeStrengthStrategy{Months: 6, Top: 2}, targets},
+	}
+
+	for _, test := range tests {
+		weights := test.strategy.Weights(month(2), history, targets)
+		if !reflect.DeepEqual(weights, test.want) {
+			t.Errorf("%s: weights = %v, want %v", test.name, weights, test.want)
+		}
+	}
+}
+
+func TestComputeMomentum(t *testing.T) {
+	targets := map[string]float64{"VTI": 50, "BND": 50}
+	history := map[string][]Historic{
+		"VTI": testCloses(100, 90, 80, 120),
+		"BND": testCloses(100, 100, 104, 102),
+	}
+
+	momentum, ok := computeMomentum(history, targets, 2)
+	if !ok {
+		t.Fatal("Not enough history for a momentum of 2 months")
+	}
+
+	want := map[string]float64{"VTI": 120.0/90*100 - 100, "BND": 2}
+	for symbol := range want {
+		if math.Abs(momentum[symbol]-want[symbol]) > 1e-9 {
+			t.Errorf("Momentum of %s = %v, want %v", symbol, momentum[symbol], want[symbol])
+		}
+	}
+
+	// The momentum needs a close before the period, and a positive one
+	if _, ok = computeMomentum(history, targets, 4); ok {
+		t.Error("Momentum of 4 months from 4 closes, want not enough history")
+	}
+	history["BND"] = testCloses(100, 0, 104, 102)
+	if _, ok = computeMomentum(history, targets, 2); ok {
+		t.Error("Momentum from a close of 0, want none")
+	}
+}
+
+func TestStrategyTargets(t *testing.T) {
+	portfolio := newTestPortfolio(map[string]float64{"VTI": 60, "BND": 40})
+
+	tests := []struct {
+		name    string
+		symbols []string
+		weights map[string]float64
+		want    map[string]float64
+	}{
+		{"scaled", []string{"BND", "VTI"}, map[string]float64{"VTI": 30, "BND": 10}, map[string]float64{"VTI": 75, "BND": 25}},
+		{"symbol listed twice", []string{"BND", "VTI", "VTI"}, map[string]float64{"VTI": 60, "BND": 40}, map[string]float64{"VTI": 60, "BND": 40}},
+		{"no holding", []string{"BND", "VTI"}, map[string]float64{"VXUS": 100}, map[string]float64{"VTI": 60, "BND": 40}},
+	}
+
+	for _, test := range tests {
+		portfolio.Symbols = test.symbols
+		portfolio.Strategy = fixedStrategy(test.weights)
+
+		targets := strategyTargets(portfolio, month(0), nil)
+
+		var total float64
+		for symbol, want := range test.want {
+			if math.Abs(targets[symbol]-want) > 1e-9 {
+				t.Errorf("%s: target of %s = %v, want %v", test.name, symbol, targets[symbol], want)
+			}
+		}
+		for _, target := range targets {
+			total += target
+		}
+		if math.Abs(total-100) > 1e-9 {
+			t.Errorf("%s: targets add up to %v, want 100", test.name, total)
+		}
+	}
+}
+
+// fixedStrategy always returns the same weights
+type fixedStrategy map[string]float64
+
+func (strategy fixedStrategy) Name() string {
+	return "Fixed"
+}
+
+func (strategy fixedStrategy) Weights(date time.Time, history map[string][]Historic, targets map[string]float64) map[string]float64 {
+	return strategy
+}
+
+// testCloses returns the month-end quotes of a holding from January 2020
+func testCloses(closes ...float64) []Historic {
+	historic := make([]Historic, len(closes))
+	for i, close := range closes {
+		historic[i] = Historic{Date: month(i), Open: close, Close: close}
+	}
+	return historic
+}
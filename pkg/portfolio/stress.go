@@ -0,0 +1,335 @@
+package portfolio
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/piquette/finance-go"
+)
+
+// StressScenario is a historical crisis, or any other range of dates, through which the current holdings
+// of a portfolio are replayed. The end date is included.
+type StressScenario struct {
+	Name   string
+	Start  time.Time
+	End    time.Time
+	custom bool
+}
+
+// HistoricalCrises are the stress scenarios available in every profile
+var HistoricalCrises = []*StressScenario{
+	{Name: "Dot-com Crash", Start: stressDate(2000, time.February, 1), End: stressDate(2002, time.October, 31)},
+	{Name: "Global Financial Crisis", Start: stressDate(2007, time.October, 1), End: stressDate(2009, time.March, 31)},
+	{Name: "COVID Crash", Start: stressDate(2020, time.February, 1), End: stressDate(2020, time.March, 31)},
+	{Name: "2022 Rate Shock", Start: stressDate(2022, time.January, 1), End: stressDate(2022, time.October, 31)},
+}
+
+// StressResult is the replay of the current holdings of a portfolio through a stress scenario. The loss of
+// the portfolio is its deepest peak-to-trough decline during the scenario, and the losses of the holdings are
+// taken between the same dates, so that they add up to it. The benchmark replays the value of the portfolio
+// on its own, with its own peak and trough. Cash keeps its value, and so do holdings before their data starts.
+type StressResult struct {
+	Scenario  *StressScenario
+	Value     float64
+	Cash      float64
+	Loss      *StressLoss
+	Holdings  []*StressLoss
+	Benchmark *StressLoss
+	Simulated []*SimulatedPeriod
+	Missing   []string
+}
+
+// StressLoss is the decline of a holding, a portfolio or a benchmark from a peak to a trough. The value is
+// the current value, and the loss is negative, in dollars and in percent of the value at the peak.
+type StressLoss struct {
+	Symbol  string
+	Value   float64
+	Peak    time.Time
+	Trough  time.Time
+	Loss    float64
+	Percent float64
+}
+
+type stressConfig struct {
+	Name  string `yaml:"name"`
+	Start string `yaml:"start"`
+	End   string `yaml:"end"`
+}
+
+// NewStressScenario returns a user-defined stress scenario between two dates formatted as "2006-01-02" or
+// "2006-01"
+func NewStressScenario(name string, start string, end string) (*StressScenario, error) {
+	if strings.TrimSpace(start) == "" || strings.TrimSpace(end) == "" {
+		return nil, errors.New("Stress scenario needs both a start and an end date")
+	}
+
+	startDate, err := parsePeriodDate(start, false)
+	if err != nil {
+		return nil, err
+	}
+
+	endDate, err := parsePeriodDate(end, true)
+	if err != nil {
+		return nil, err
+	}
+
+	if !startDate.Before(endDate) {
+		return nil, errors.New("Stress scenario should start before it ends")
+	}
+
+	if name == "" {
+		name = fmt.Sprintf("%s to %s", startDate.Format("2006-01-02"), endDate.Format("2006-01-02"))
+	}
+
+	return &StressScenario{Name: name, Start: startDate, End: endDate, custom: true}, nil
+}
+
+// newStressScenarios returns the historical crises followed by the scenarios defined in the profile
+func newStressScenarios(config []stressConfig) ([]*StressScenario, error) {
+	scenarios := append([]*StressScenario{}, HistoricalCrises...)
+
+	for _, scenarioConfig := range config {
+		if scenarioConfig.Name == "" {
+			return nil, errors.New("Stress scenario should have a name")
+		}
+
+		scenario, err := NewStressScenario(scenarioConfig.Name, scenarioConfig.Start, scenarioConfig.End)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid stress scenario %s: %v", scenarioConfig.Name, err)
+		}
+
+		scenarios = append(scenarios, scenario)
+	}
+
+	return scenarios, nil
+}
+
+func stressConfigs(scenarios []*StressScenario) []stressConfig {
+	config := make([]stressConfig, 0)
+
+	for _, scenario := range scenarios {
+		if scenario.custom {
+			config = append(config, stressConfig{
+				Name:  scenario.Name,
+				Start: scenario.Start.Format("2006-01-02"),
+				End:   scenario.End.Format("2006-01-02"),
+			})
+		}
+	}
+
+	if len(config) == 0 {
+		return nil
+	}
+	return config
+}
+
+// Stress replays the current holdings of the portfolio through the scenario on daily bars, with the proxies
+// and the benchmark of its performance
+func (portfolio *Portfolio) Stress(scenario *StressScenario) (*StressResult, error) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		return nil, err
+	}
+
+	startDate := inLocation(scenario.Start, ny)
+	endDate := inLocation(scenario.End, ny).AddDate(0, 0, 1).Add(-time.Second)
+
+	proxies := portfolio.Performance.Proxies
+
+	result := &StressResult{
+		Scenario:  scenario,
+		Cash:      portfolio.Cash,
+		Holdings:  make([]*StressLoss, 0),
+		Simulated: make([]*SimulatedPeriod, 0),
+		Missing:   make([]string, 0),
+	}
+
+	values := make(map[string]float64)
+	paths := make(map[string]map[string]float64)
+	symbols := make([]string, 0)
+
+	for _, symbol := range portfolio.Symbols {
+		holding := portfolio.Holdings[symbol]
+		if _, seen := values[symbol]; seen || holding.Status.Value == 0 {
+			continue
+		}
+
+		values[symbol] = holding.Status.Value
+		symbols = append(symbols, symbol)
+
+		bars, simulated, err := proxies.bars(symbol, startDate, endDate, true)
+		if simulated != nil {
+			result.Simulated = append(result.Simulated, simulated)
+		}
+
+		path := computeStressPath(bars, ny)
+		if err != nil || len(path) == 0 || barDate(bars[0], ny).After(startDate.AddDate(0, 0, 7)) {
+			result.Missing = append(result.Missing, symbol)
+		}
+		paths[symbol] = path
+	}
+
+	if len(symbols) == 0 {
+		return nil, errors.New("Portfolio has no holdings to stress")
+	}
+
+	dates := stressDates(paths)
+	if len(dates) == 0 {
+		return nil, fmt.Errorf("No data for the holdings during %s", scenario.Name)
+	}
+
+	series := make(map[string][]float64)
+	total := make([]float64, len(dates)+1)
+	total[0] = result.Cash
+
+	for _, symbol := range symbols {
+		series[symbol] = replayStressPath(paths[symbol], dates, values[symbol])
+		for i, value := range series[symbol] {
+			total[i] += value
+		}
+	}
+	for i := 1; i < len(total); i++ {
+		total[i] += result.Cash
+	}
+
+	var peak, trough int
+	result.Value = total[0]
+	result.Loss, peak, trough = computeStressLoss(portfolio.Name, total, dates, startDate)
+
+	for _, symbol := range symbols {
+		loss := &StressLoss{
+			Symbol: symbol,
+			Value:  values[symbol],
+			Peak:   result.Loss.Peak,
+			Trough: result.Loss.Trough,
+			Loss:   series[symbol][trough] - series[symbol][peak],
+		}
+		if series[symbol][peak] > 0 {
+			loss.Percent = loss.Loss / series[symbol][peak] * 100
+		}
+		result.Holdings = append(result.Holdings, loss)
+	}
+
+	sort.SliceStable(result.Holdings, func(i, j int) bool {
+		return result.Holdings[i].Loss < result.Holdings[j].Loss
+	})
+
+	benchmark := portfolio.Performance.BenchmarkSymbol
+	bars, _, err := proxies.bars(benchmark, startDate, endDate, true)
+	if err != nil {
+		return nil, err
+	}
+
+	path := computeStressPath(bars, ny)
+	benchmarkDates := stressDates(map[string]map[string]float64{benchmark: path})
+	if len(benchmarkDates) == 0 {
+		return nil, fmt.Errorf("No data for %s during %s", benchmark, scenario.Name)
+	}
+
+	result.Benchmark, _, _ = computeStressLoss(benchmark, replayStressPath(path, benchmarkDates, result.Value), benchmarkDates, startDate)
+
+	return result, nil
+}
+
+// computeStressPath returns the closes of the bars by day, relative to the price at the start of the first
+// bar. The open of the first bar is adjusted for dividends like the closes.
+func computeStressPath(bars []finance.ChartBar, location *time.Location) map[string]float64 {
+	path := make(map[string]float64)
+	if len(bars) == 0 {
+		return path
+	}
+
+	open, _ := bars[0].Open.Float64()
+	unadjusted, _ := bars[0].Close.Float64()
+	adjusted, _ := bars[0].AdjClose.Float64()
+	if unadjusted > 0 {
+		open *= adjusted / unadjusted
+	}
+	if open <= 0 {
+		return path
+	}
+
+	for _, bar := range bars {
+		close, _ := bar.AdjClose.Float64()
+		path[barDate(bar, location).Format("2006-01-02")] = close / open
+	}
+
+	return path
+}
+
+// stressDates returns every day with a close of any holding, in order
+func stressDates(paths map[string]map[string]float64) []string {
+	seen := make(map[string]bool)
+	dates := make([]string, 0)
+
+	for _, path := range paths {
+		for date := range path {
+			if !seen[date] {
+				seen[date] = true
+				dates = append(dates, date)
+			}
+		}
+	}
+
+	sort.Strings(dates)
+
+	return dates
+}
+
+// replayStressPath values the holding at the start and at the close of every day, keeping the last known
+// value on the days without a close
+func replayStressPath(path map[string]float64, dates []string, value float64) []float64 {
+	series := make([]float64, len(dates)+1)
+	series[0] = value
+
+	growth := 1.0
+	for i, date := range dates {
+		if g, ok := path[date]; ok {
+			growth = g
+		}
+		series[i+1] = value * growth
+	}
+
+	return series
+}
+
+// computeStressLoss finds the deepest peak-to-trough decline of a series that starts at the start date and
+// closes on the given dates, and returns the points of its peak and its trough
+func computeStressLoss(symbol string, series []float64, dates []string, startDate time.Time) (*StressLoss, int, int) {
+	loss := &StressLoss{Symbol: symbol, Value: series[0], Peak: startDate, Trough: startDate}
+
+	peak, worstPeak, worstTrough := 0, 0, 0
+	for i := range series {
+		if series[i] > series[peak] {
+			peak = i
+		}
+
+		if series[peak] > 0 && series[i]-series[peak] < loss.Loss {
+			loss.Loss = series[i] - series[peak]
+			loss.Percent = loss.Loss / series[peak] * 100
+			worstPeak, worstTrough = peak, i
+		}
+	}
+
+	loss.Peak = stressDateAt(dates, startDate, worstPeak)
+	loss.Trough = stressDateAt(dates, startDate, worstTrough)
+
+	return loss, worstPeak, worstTrough
+}
+
+// stressDateAt returns the date of the given point of a series, where the first point is the start date
+func stressDateAt(dates []string, startDate time.Time, i int) time.Time {
+	if i == 0 {
+		return startDate
+	}
+
+	date, _ := time.ParseInLocation("2006-01-02", dates[i-1], startDate.Location())
+	return date
+}
+
+func stressDate(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
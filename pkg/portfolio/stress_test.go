@@ -0,0 +1,179 @@
+package portfolio
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/piquette/finance-go"
+	"github.com/shopspring/decimal"
+)
+
+func TestComputeStressLoss(t *testing.T) {
+	// The series starts on the Sunday before its first close
+	startDate := time.Date(2020, time.March, 1, 0, 0, 0, 0, time.UTC)
+	dates := []string{"2020-03-02", "2020-03-03", "2020-03-04", "2020-03-05"}
+
+	tests := []struct {
+		name    string
+		series  []float64
+		loss    float64
+		percent float64
+		peak    string
+		trough  string
+	}{
+		{
+			// The later decline of 20 is not as deep as the first one of 22
+			name:    "deepest decline",
+			series:  []float64{100, 110, 88, 120, 100},
+			loss:    -22,
+			percent: -20,
+			peak:    "2020-03-02",
+			trough:  "2020-03-03",
+		},
+		{
+			name:    "decline from the start",
+			series:  []float64{100, 90, 80, 95, 99},
+			loss:    -20,
+			percent: -20,
+			peak:    "2020-03-01",
+			trough:  "2020-03-03",
+		},
+		{
+			name:   "no decline",
+			series: []float64{100, 101, 102, 103, 104},
+			peak:   "2020-03-01",
+			trough: "2020-03-01",
+		},
+	}
+
+	for _, test := range tests {
+		loss, peak, trough := computeStressLoss("Test", test.series, dates, startDate)
+
+		if math.Abs(loss.Loss-test.loss) > 1e-9 || math.Abs(loss.Percent-test.percent) > 1e-9 {
+			t.Errorf("%s: loss = %v (%v%%), want %v (%v%%)", test.name, loss.Loss, loss.Percent, test.loss, test.percent)
+		}
+		if loss.Peak.Format("2006-01-02") != test.peak || loss.Trough.Format("2006-01-02") != test.trough {
+			t.Errorf("%s: from %s to %s, want from %s to %s", test.name, loss.Peak.Format("2006-01-02"),
+				loss.Trough.Format("2006-01-02"), test.peak, test.trough)
+		}
+		if loss.Loss != 0 && test.series[trough]-test.series[peak] != loss.Loss {
+			t.Errorf("%s: points %d and %d do not match the loss of %v", test.name, peak, trough, loss.Loss)
+		}
+	}
+}
+
+func TestReplayStressPath(t *testing.T) {
+	// The holding has no close on 2020-03-03 and keeps its value of the day before
+	path := map[string]float64{"2020-03-02": 1.1, "2020-03-04": 0.9}
+	dates := []string{"2020-03-02", "2020-03-03", "2020-03-04"}
+
+	series := replayStressPath(path, dates, 100)
+	want := []float64{100, 110, 110, 90}
+
+	if len(series) != len(want) {
+		t.Fatalf("Replayed %d values, want %d", len(series), len(want))
+	}
+	for i := range want {
+		if math.Abs(series[i]-want[i]) > 1e-9 {
+			t.Errorf("Value %d = %v, want %v", i, series[i], want[i])
+		}
+	}
+}
+
+func TestPortfolioStress(t *testing.T) {
+	// BND has no close on 2020-03-04, and GLD has no data at all
+	restore := setTestBars(map[string][]finance.ChartBar{
+		"VTI": {
+			testDailyBar(2, 100, 102), testDailyBar(3, 0, 96), testDailyBar(4, 0, 90),
+			testDailyBar(5, 0, 95), testDailyBar(6, 0, 99),
+		},
+		"BND": {
+			testDailyBar(2, 50, 50), testDailyBar(3, 0, 50.5), testDailyBar(5, 0, 51), testDailyBar(6, 0, 50),
+		},
+		"SPY": {
+			testDailyBar(2, 200, 200), testDailyBar(3, 0, 190), testDailyBar(4, 0, 180),
+			testDailyBar(5, 0, 200), testDailyBar(6, 0, 210),
+		},
+	})
+	defer restore()
+
+	portfolio := newTestPortfolio(map[string]float64{"VTI": 50, "BND": 30, "GLD": 10, "VXUS": 10})
+	portfolio.Performance = NewPerformance(portfolio, "SPY", 10000)
+	portfolio.Cash = 100
+	portfolio.Holdings["VTI"].Status.Value = 600
+	portfolio.Holdings["BND"].Status.Value = 400
+	portfolio.Holdings["GLD"].Status.Value = 100
+
+	scenario, err := NewStressScenario("Test", "2020-03-02", "2020-03-06")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := portfolio.Stress(scenario)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The portfolio is worth 1200, 1212, 1180, 1144, 1178 and 1194: it peaks on 2020-03-02 and bottoms on 2020-03-04
+	if result.Value != 1200 {
+		t.Errorf("Value = %v, want 1200", result.Value)
+	}
+	checkStressLoss(t, "Portfolio", result.Loss, -68, -68.0/1212*100, "2020-03-02", "2020-03-04")
+
+	// The holdings lose between the peak and the trough of the portfolio, and VXUS, which is not held, is left out
+	want := []struct {
+		symbol  string
+		loss    float64
+		percent float64
+	}{
+		{"VTI", -72, -72.0 / 612 * 100},
+		{"GLD", 0, 0},
+		{"BND", 4, 1},
+	}
+
+	if len(result.Holdings) != len(want) {
+		t.Fatalf("%d holdings, want %d", len(result.Holdings), len(want))
+	}
+
+	var total float64
+	for i, holding := range want {
+		checkStressLoss(t, holding.symbol, result.Holdings[i], holding.loss, holding.percent, "2020-03-02", "2020-03-04")
+		if result.Holdings[i].Symbol != holding.symbol {
+			t.Errorf("Holding %d = %s, want %s", i, result.Holdings[i].Symbol, holding.symbol)
+		}
+		total += result.Holdings[i].Loss
+	}
+	if math.Abs(total-result.Loss.Loss) > 1e-9 {
+		t.Errorf("Holdings lose %v, want the loss of the portfolio of %v", total, result.Loss.Loss)
+	}
+
+	if len(result.Missing) != 1 || result.Missing[0] != "GLD" {
+		t.Errorf("Missing = %v, want [GLD]", result.Missing)
+	}
+
+	// The same 1200 in the benchmark is worth 1200, 1140, 1080, 1200 and 1260 at the closes
+	checkStressLoss(t, "Benchmark", result.Benchmark, -120, -10, "2020-03-02", "2020-03-04")
+}
+
+func checkStressLoss(t *testing.T, name string, loss *StressLoss, want float64, percent float64, peak string, trough string) {
+	if math.Abs(loss.Loss-want) > 1e-9 || math.Abs(loss.Percent-percent) > 1e-9 {
+		t.Errorf("%s: loss = %v (%v%%), want %v (%v%%)", name, loss.Loss, loss.Percent, want, percent)
+	}
+	if loss.Peak.Format("2006-01-02") != peak || loss.Trough.Format("2006-01-02") != trough {
+		t.Errorf("%s: from %s to %s, want from %s to %s", name, loss.Peak.Format("2006-01-02"),
+			loss.Trough.Format("2006-01-02"), peak, trough)
+	}
+}
+
+// testDailyBar returns the bar of a day in March 2020, closing in New York. Only the first bar of a path needs an open.
+func testDailyBar(day int, open float64, close float64) finance.ChartBar {
+	ny, _ := time.LoadLocation("America/New_York")
+
+	return finance.ChartBar{
+		Open:      decimal.NewFromFloat(open),
+		Close:     decimal.NewFromFloat(close),
+		AdjClose:  decimal.NewFromFloat(close),
+		Timestamp: int(time.Date(2020, time.March, day, 16, 0, 0, 0, ny).Unix()),
+	}
+}
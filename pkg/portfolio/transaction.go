@@ -0,0 +1,188 @@
+package portfolio
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// TransactionType defines the type of ledger transactions
+type TransactionType string
+
+// Transaction type definitions
+const (
+	TransactionBuy        TransactionType = "buy"
+	TransactionSell       TransactionType = "sell"
+	TransactionDividend   TransactionType = "dividend"
+	TransactionInterest   TransactionType = "interest"
+	TransactionDeposit    TransactionType = "deposit"
+	TransactionWithdrawal TransactionType = "withdrawal"
+	TransactionFee        TransactionType = "fee"
+	TransactionOther      TransactionType = "other"
+)
+
+const dateFormat = "2006-01-02"
+
+// Transaction defines an entry in the ledger of a portfolio
+type Transaction struct {
+	ID          string
+	Date        time.Time
+	Type        TransactionType
+	Symbol      string
+	Quantity    float64
+	Price       float64
+	Amount      float64
+	Description string
+}
+
+// Lot defines a tax lot of a holding
+type Lot struct {
+	Date      time.Time
+	Quantity  float64
+	CostBasis float64
+}
+
+type transactionConfig struct {
+	ID          string  `yaml:"id"`
+	Date        string  `yaml:"date"`
+	Type        string  `yaml:"type"`
+	Symbol      string  `yaml:"symbol,omitempty"`
+	Quantity    float64 `yaml:"quantity,omitempty"`
+	Price       float64 `yaml:"price,omitempty"`
+	Amount      float64 `yaml:"amount"`
+	Description string  `yaml:"description,omitempty"`
+}
+
+type lotConfig struct {
+	Date      string  `yaml:"date"`
+	Quantity  float64 `yaml:"quantity"`
+	CostBasis float64 `yaml:"basis"`
+}
+
+func newTransaction(config transactionConfig) (*Transaction, error) {
+	date, err := time.Parse(dateFormat, config.Date)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Transaction{
+		ID:          config.ID,
+		Date:        date,
+		Type:        TransactionType(config.Type),
+		Symbol:      config.Symbol,
+		Quantity:    config.Quantity,
+		Price:       config.Price,
+		Amount:      config.Amount,
+		Description: config.Description,
+	}, nil
+}
+
+func (transaction *Transaction) config() transactionConfig {
+	return transactionConfig{
+		ID:          transaction.ID,
+		Date:        transaction.Date.Format(dateFormat),
+		Type:        string(transaction.Type),
+		Symbol:      transaction.Symbol,
+		Quantity:    transaction.Quantity,
+		Price:       transaction.Price,
+		Amount:      transaction.Amount,
+		Description: transaction.Description,
+	}
+}
+
+func newLot(config lotConfig) (*Lot, error) {
+	date, err := time.Parse(dateFormat, config.Date)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Lot{
+		Date:      date,
+		Quantity:  config.Quantity,
+		CostBasis: config.CostBasis,
+	}, nil
+}
+
+func (lot *Lot) config() lotConfig {
+	return lotConfig{
+		Date:      lot.Date.Format(dateFormat),
+		Quantity:  lot.Quantity,
+		CostBasis: lot.CostBasis,
+	}
+}
+
+// IsExternal returns true if the transaction moves money into or out of the portfolio
+func (transaction *Transaction) IsExternal() bool {
+	return transaction.Type == TransactionDeposit || transaction.Type == TransactionWithdrawal
+}
+
+func sortTransactions(transactions []*Transaction) {
+	sort.SliceStable(transactions, func(i, j int) bool {
+		return transactions[i].Date.Before(transactions[j].Date)
+	})
+}
+
+// computeLots replays the buys and sells of a symbol in first-in, first-out order
+func computeLots(transactions []*Transaction, symbol string) []*Lot {
+	lots := make([]*Lot, 0)
+
+	for _, transaction := range transactions {
+		if transaction.Symbol != symbol {
+			continue
+		}
+
+		switch transaction.Type {
+		case TransactionBuy:
+			basis := math.Abs(transaction.Amount)
+			if basis == 0 {
+				basis = transaction.Quantity * transaction.Price
+			}
+			lots = append(lots, &Lot{
+				Date:      transaction.Date,
+				Quantity:  transaction.Quantity,
+				CostBasis: basis,
+			})
+
+		case TransactionSell:
+			remaining := transaction.Quantity
+			for len(lots) > 0 && remaining > 0 {
+				lot := lots[0]
+				if lot.Quantity > remaining {
+					lot.CostBasis -= lot.CostBasis * (remaining / lot.Quantity)
+					lot.Quantity -= remaining
+					remaining = 0
+				} else {
+					remaining -= lot.Quantity
+					lots = lots[1:]
+				}
+			}
+		}
+	}
+
+	return lots
+}
+
+// computeLotsBasis returns the cost basis of the given quantity of a holding from its lots. If the lots only
+// cover part of the quantity, the basis of the rest is estimated at their average cost, unless the holding
+// already has a basis. Without lots the existing basis is kept.
+func computeLotsBasis(lots []*Lot, quantity float64, existing float64) float64 {
+	var lotsQuantity, lotsBasis float64
+	for _, lot := range lots {
+		lotsQuantity += lot.Quantity
+		lotsBasis += lot.CostBasis
+	}
+
+	if lotsQuantity <= 0 || quantity <= 0 {
+		return existing
+	}
+
+	if math.Abs(lotsQuantity-quantity) < 1e-6 {
+		return lotsBasis
+	}
+
+	if existing != 0 {
+		return existing
+	}
+
+	return lotsBasis / lotsQuantity * quantity
+}
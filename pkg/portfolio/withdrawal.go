@@ -0,0 +1,333 @@
+package portfolio
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// WithdrawalRule defines how much is withdrawn from a portfolio in retirement
+type WithdrawalRule string
+
+const (
+	// WithdrawalFixed withdraws a fixed percentage of the starting balance every year, adjusted for inflation
+	WithdrawalFixed WithdrawalRule = "fixed"
+	// WithdrawalPercent withdraws a fixed percentage of the current balance every year
+	WithdrawalPercent WithdrawalRule = "percent"
+	// WithdrawalGuardrails adjusts the inflation-adjusted withdrawal of the fixed rule every year, cutting it
+	// when the current withdrawal rate rises above the upper guardrail and raising it when the rate falls
+	// below the lower guardrail
+	WithdrawalGuardrails WithdrawalRule = "guardrails"
+	// WithdrawalVPW withdraws the amortized balance over the remaining years at an expected real return
+	// (variable percentage withdrawal), which spends the entire balance by the end of the horizon
+	WithdrawalVPW WithdrawalRule = "vpw"
+)
+
+// WithdrawalRules are all the supported withdrawal rules
+var WithdrawalRules = []WithdrawalRule{WithdrawalFixed, WithdrawalPercent, WithdrawalGuardrails, WithdrawalVPW}
+
+// Withdrawal replays the withdrawals from a portfolio over every historical period of the given horizon,
+// starting every year of the backtest. Amounts are in real dollars of the start of each period when a CPI
+// series is available, and in nominal dollars otherwise.
+type Withdrawal struct {
+	Rule           WithdrawalRule
+	Rate           float64
+	Years          int
+	InitialBalance float64
+	Guardrail      float64
+	Adjustment     float64
+	ExpectedReturn float64
+	Periods        []*WithdrawalPeriod
+	Real           bool
+	SuccessRate    float64
+	WorstEnding    float64
+	MedianEnding   float64
+	SafeRate       float64
+}
+
+// WithdrawalPeriod is the outcome of a retirement starting at a given month of the backtest
+type WithdrawalPeriod struct {
+	Start         time.Time
+	Ending        float64
+	Withdrawn     float64
+	MinWithdrawal float64
+	Depleted      bool
+	DepletedDate  time.Time
+}
+
+type withdrawalConfig struct {
+	Rule           WithdrawalRule `yaml:"rule,omitempty"`
+	Rate           float64        `yaml:"rate,omitempty"`
+	Years          int            `yaml:"years,omitempty"`
+	Balance        float64        `yaml:"balance,omitempty"`
+	Guardrail      float64        `yaml:"guardrail,omitempty"`
+	Adjustment     float64        `yaml:"adjustment,omitempty"`
+	ExpectedReturn float64        `yaml:"return,omitempty"`
+}
+
+const (
+	defaultWithdrawalRate   = 4
+	defaultWithdrawalYears  = 30
+	defaultGuardrail        = 20
+	defaultAdjustment       = 10
+	defaultExpectedReturn   = 5
+	safeWithdrawalPrecision = 0.01
+)
+
+// NewWithdrawal returns a withdrawal simulation of the given starting balance with the default settings
+func NewWithdrawal(initialBalance float64) *Withdrawal {
+	return &Withdrawal{
+		Rule:           WithdrawalFixed,
+		Rate:           defaultWithdrawalRate,
+		Years:          defaultWithdrawalYears,
+		InitialBalance: initialBalance,
+		Guardrail:      defaultGuardrail,
+		Adjustment:     defaultAdjustment,
+		ExpectedReturn: defaultExpectedReturn,
+	}
+}
+
+// newWithdrawal returns the withdrawal settings of a profile, using the defaults for the missing ones
+func newWithdrawal(config *withdrawalConfig) (*Withdrawal, error) {
+	withdrawal := NewWithdrawal(0)
+	if config == nil {
+		return withdrawal, nil
+	}
+
+	if config.Rule != "" {
+		withdrawal.Rule = config.Rule
+	}
+	if config.Rate != 0 {
+		withdrawal.Rate = config.Rate
+	}
+	if config.Years != 0 {
+		withdrawal.Years = config.Years
+	}
+	if config.Guardrail != 0 {
+		withdrawal.Guardrail = config.Guardrail
+	}
+	if config.Adjustment != 0 {
+		withdrawal.Adjustment = config.Adjustment
+	}
+	if config.ExpectedReturn != 0 {
+		withdrawal.ExpectedReturn = config.ExpectedReturn
+	}
+	withdrawal.InitialBalance = config.Balance
+
+	switch {
+	case !validWithdrawalRule(withdrawal.Rule):
+		return nil, fmt.Errorf("Unknown withdrawal rule: %s", withdrawal.Rule)
+	case withdrawal.Rate < 0 || withdrawal.Rate > 100:
+		return nil, fmt.Errorf("Withdrawal rate should be between 0%% and 100%%: %.2f", withdrawal.Rate)
+	case withdrawal.Years < 0 || withdrawal.InitialBalance < 0:
+		return nil, errors.New("Withdrawal years and balance should be positive")
+	case withdrawal.Guardrail < 0 || withdrawal.Adjustment < 0 || withdrawal.Adjustment >= 100:
+		return nil, errors.New("Withdrawal guardrail and adjustment should be between 0% and 100%")
+	}
+
+	return withdrawal, nil
+}
+
+// config returns the withdrawal settings that differ from the defaults, or nil if none do
+func (withdrawal *Withdrawal) config() *withdrawalConfig {
+	config := withdrawalConfig{
+		Balance: withdrawal.InitialBalance,
+	}
+
+	if withdrawal.Rule != WithdrawalFixed {
+		config.Rule = withdrawal.Rule
+	}
+	if withdrawal.Rate != defaultWithdrawalRate {
+		config.Rate = withdrawal.Rate
+	}
+	if withdrawal.Years != defaultWithdrawalYears {
+		config.Years = withdrawal.Years
+	}
+	if withdrawal.Guardrail != defaultGuardrail {
+		config.Guardrail = withdrawal.Guardrail
+	}
+	if withdrawal.Adjustment != defaultAdjustment {
+		config.Adjustment = withdrawal.Adjustment
+	}
+	if withdrawal.ExpectedReturn != defaultExpectedReturn {
+		config.ExpectedReturn = withdrawal.ExpectedReturn
+	}
+
+	if config == (withdrawalConfig{}) {
+		return nil
+	}
+
+	return &config
+}
+
+// Run replays the withdrawals over every period of the backtest that is at least as long as the horizon,
+// and finds the highest rate of the fixed rule that never depleted the portfolio
+func (withdrawal *Withdrawal) Run(result *PerformanceResult, cpi *CPI) error {
+	if withdrawal.Years <= 0 {
+		return errors.New("Withdrawal requires a positive number of years")
+	}
+	if withdrawal.InitialBalance <= 0 {
+		return errors.New("Withdrawal requires a positive initial balance")
+	}
+	if !validWithdrawalRule(withdrawal.Rule) {
+		return fmt.Errorf("Unknown withdrawal rule: %s", withdrawal.Rule)
+	}
+
+	months := withdrawal.Years * 12
+	if len(result.Historic) < months {
+		return fmt.Errorf("The backtest covers %d months, which is shorter than the %d-year horizon", len(result.Historic), withdrawal.Years)
+	}
+
+	returns, real := computeRealReturns(result.Historic, cpi)
+	withdrawal.Real = real
+
+	withdrawal.Periods = make([]*WithdrawalPeriod, 0)
+	succeeded := 0
+
+	for start := 0; start+months <= len(returns); start += 12 {
+		period := withdrawal.simulate(withdrawal.Rule, withdrawal.Rate, returns[start:start+months], result.Historic[start].Date)
+		withdrawal.Periods = append(withdrawal.Periods, period)
+		if !period.Depleted {
+			succeeded++
+		}
+	}
+
+	endings := make([]float64, len(withdrawal.Periods))
+	for i, period := range withdrawal.Periods {
+		endings[i] = period.Ending
+	}
+	sort.Float64s(endings)
+
+	withdrawal.SuccessRate = float64(succeeded) / float64(len(withdrawal.Periods)) * 100
+	withdrawal.WorstEnding = endings[0]
+	withdrawal.MedianEnding = computeQuantile(endings, 0.5)
+	withdrawal.SafeRate = withdrawal.computeSafeRate(returns, months)
+
+	return nil
+}
+
+// simulate withdraws from the balance at the start of every month and then applies the real return of the month
+func (withdrawal *Withdrawal) simulate(rule WithdrawalRule, rate float64, returns []float64, start time.Time) *WithdrawalPeriod {
+	period := &WithdrawalPeriod{
+		Start:         start,
+		MinWithdrawal: math.Inf(1),
+	}
+
+	balance := withdrawal.InitialBalance
+	annual := withdrawal.InitialBalance * rate / 100
+	withdrawn := 0.0
+
+	for m, r := range returns {
+		if m%12 == 0 {
+			if m > 0 {
+				period.MinWithdrawal = math.Min(period.MinWithdrawal, withdrawn)
+				withdrawn = 0
+			}
+
+			if rule == WithdrawalGuardrails && m > 0 && balance > 0 {
+				current := annual / balance * 100
+				if current > rate*(1+withdrawal.Guardrail/100) {
+					annual *= 1 - withdrawal.Adjustment/100
+				} else if current < rate*(1-withdrawal.Guardrail/100) {
+					annual *= 1 + withdrawal.Adjustment/100
+				}
+			}
+		}
+
+		var amount float64
+		switch rule {
+		case WithdrawalFixed, WithdrawalGuardrails:
+			amount = annual / 12
+		case WithdrawalPercent:
+			amount = balance * rate / 100 / 12
+		case WithdrawalVPW:
+			amount = balance * computeAmortization(withdrawal.ExpectedReturn, len(returns)-m)
+		}
+
+		if amount > balance*(1+1e-9) && !period.Depleted {
+			period.Depleted = true
+			period.DepletedDate = start.AddDate(0, m, 0)
+			amount = balance
+		}
+		amount = math.Min(amount, balance)
+
+		balance -= amount
+		withdrawn += amount
+		period.Withdrawn += amount
+
+		balance *= 1 + r/100
+	}
+
+	period.MinWithdrawal = math.Min(period.MinWithdrawal, withdrawn)
+	period.Ending = balance
+
+	return period
+}
+
+// computeSafeRate finds the highest rate of the fixed rule that did not deplete the portfolio in any period
+func (withdrawal *Withdrawal) computeSafeRate(returns []float64, months int) float64 {
+	succeeds := func(rate float64) bool {
+		for start := 0; start+months <= len(returns); start += 12 {
+			if withdrawal.simulate(WithdrawalFixed, rate, returns[start:start+months], time.Time{}).Depleted {
+				return false
+			}
+		}
+		return true
+	}
+
+	low, high := 0.0, 100.0
+	for high-low > safeWithdrawalPrecision {
+		rate := (low + high) / 2
+		if succeeds(rate) {
+			low = rate
+		} else {
+			high = rate
+		}
+	}
+
+	return low
+}
+
+// computeAmortization returns the fraction of the balance that is withdrawn in the current month so that
+// the balance lasts exactly the remaining months at the expected annual return
+func computeAmortization(expectedReturn float64, remaining int) float64 {
+	r := math.Pow(1+expectedReturn/100, 1.0/12) - 1
+	if r == 0 {
+		return 1 / float64(remaining)
+	}
+
+	// Withdrawals are taken at the start of every month, so the first one is not discounted
+	return r / (1 + r) / (1 - math.Pow(1+r, -float64(remaining)))
+}
+
+// computeRealReturns returns the monthly returns of the backtest deflated by the CPI, or the nominal returns
+// if the CPI series does not cover the entire backtest
+func computeRealReturns(historic []Historic, cpi *CPI) ([]float64, bool) {
+	returns := computeMonthlyReturns(historic)
+	if cpi == nil || len(historic) == 0 {
+		return returns, false
+	}
+
+	real := make([]float64, len(returns))
+	for i, month := range historic {
+		// The CPI of the month before the backtest may be missing, in which case the first month is nominal
+		inflation, ok := cpi.Inflation(month.Date.AddDate(0, -1, 0), month.Date)
+		if !ok && i > 0 {
+			return returns, false
+		}
+		real[i] = ((1+returns[i]/100)/inflation - 1) * 100
+	}
+
+	return real, true
+}
+
+func validWithdrawalRule(rule WithdrawalRule) bool {
+	for _, r := range WithdrawalRules {
+		if r == rule {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,94 @@
+package portfolio
+
+import (
+	"math"
+	"testing"
+)
+
+func TestWithdrawal(t *testing.T) {
+	// Two years of flat returns, so the balance only goes down by the withdrawals
+	flat := &PerformanceResult{Historic: testHistoric(100, make([]float64, 24)...)}
+
+	tests := []struct {
+		name     string
+		rule     WithdrawalRule
+		rate     float64
+		depleted bool
+		ending   float64
+		minimum  float64
+	}{
+		{"fixed", WithdrawalFixed, 40, false, 2000, 4000},
+		{"fixed to zero", WithdrawalFixed, 50, false, 0, 5000},
+		{"fixed depleted", WithdrawalFixed, 60, true, 0, 4000},
+		// 50% a year is 1/24 of the balance a month, which decays by (23/24)^24
+		{"percent", WithdrawalPercent, 50, false, 10000 * math.Pow(23.0/24, 24), 10000 * (1 - math.Pow(23.0/24, 12)) * math.Pow(23.0/24, 12)},
+		// Without an expected return, the amortized withdrawal spends 1/24 of the initial balance every month
+		{"vpw", WithdrawalVPW, 0, false, 0, 5000},
+	}
+
+	for _, test := range tests {
+		withdrawal := NewWithdrawal(10000)
+		withdrawal.Rule = test.rule
+		withdrawal.Rate = test.rate
+		withdrawal.Years = 2
+		withdrawal.ExpectedReturn = 0
+
+		err := withdrawal.Run(flat, nil)
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+
+		if len(withdrawal.Periods) != 1 {
+			t.Fatalf("%s: %d periods, want 1", test.name, len(withdrawal.Periods))
+		}
+		period := withdrawal.Periods[0]
+
+		if period.Depleted != test.depleted {
+			t.Errorf("%s: depleted = %v, want %v", test.name, period.Depleted, test.depleted)
+		}
+		if math.Abs(period.Ending-test.ending) > 1e-6 {
+			t.Errorf("%s: ending = %v, want %v", test.name, period.Ending, test.ending)
+		}
+		if math.Abs(period.MinWithdrawal-test.minimum) > 1e-6 {
+			t.Errorf("%s: minimum yearly withdrawal = %v, want %v", test.name, period.MinWithdrawal, test.minimum)
+		}
+
+		wantSuccess := 100.0
+		if test.depleted {
+			wantSuccess = 0
+		}
+		if withdrawal.SuccessRate != wantSuccess {
+			t.Errorf("%s: success rate = %v, want %v", test.name, withdrawal.SuccessRate, wantSuccess)
+		}
+
+		// Withdrawing half of the initial balance a year lasts exactly two years
+		if math.Abs(withdrawal.SafeRate-50) > safeWithdrawalPrecision {
+			t.Errorf("%s: safe rate = %v, want 50", test.name, withdrawal.SafeRate)
+		}
+	}
+}
+
+func TestWithdrawalErrors(t *testing.T) {
+	result := &PerformanceResult{Historic: testHistoric(100, make([]float64, 24)...)}
+
+	tests := []struct {
+		name   string
+		change func(withdrawal *Withdrawal)
+	}{
+		{"zero balance", func(withdrawal *Withdrawal) { withdrawal.InitialBalance = 0 }},
+		{"negative balance", func(withdrawal *Withdrawal) { withdrawal.InitialBalance = -1 }},
+		{"zero years", func(withdrawal *Withdrawal) { withdrawal.Years = 0 }},
+		{"longer than the backtest", func(withdrawal *Withdrawal) { withdrawal.Years = 3 }},
+		{"unknown rule", func(withdrawal *Withdrawal) { withdrawal.Rule = "unknown" }},
+	}
+
+	for _, test := range tests {
+		withdrawal := NewWithdrawal(10000)
+		withdrawal.Years = 2
+		test.change(withdrawal)
+
+		if err := withdrawal.Run(result, nil); err == nil {
+			t.Errorf("%s: want an error", test.name)
+		}
+	}
+}
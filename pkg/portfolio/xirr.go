@@ -0,0 +1,317 @@
+package portfolio
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/piquette/finance-go/chart"
+	"github.com/piquette/finance-go/datetime"
+)
+
+const (
+	xirrMaxIterations = 100
+	xirrPrecision     = 1e-9
+	daysPerYear       = 365.0
+)
+
+// CashFlow defines a dated flow of money from the investor's point of view: contributions
+// are negative, withdrawals and the ending value are positive
+type CashFlow struct {
+	Date   time.Time
+	Amount float64
+}
+
+// MoneyWeighted analyzes the money-weighted return (XIRR) of a portfolio from the deposits and
+// withdrawals in its ledger and its current value. Returns of periods shorter than a year are
+// cumulative, longer ones are annualized, just like the trailing returns of the backtest. The return
+// since inception is NaN if the portfolio has neither transactions nor lots.
+type MoneyWeighted struct {
+	Portfolio *Portfolio
+	Cash      float64
+	StartDate time.Time
+	EndDate   time.Time
+	Return    *Return
+	Ready     bool
+	Err       error
+}
+
+// NewMoneyWeighted creates a new analysis of the money-weighted return of a portfolio. The
+// cash is held on top of the portfolio during the entire period, e.g. the cash of a profile.
+func NewMoneyWeighted(portfolio *Portfolio, cash float64) *MoneyWeighted {
+	return &MoneyWeighted{
+		Portfolio: portfolio,
+		Cash:      cash,
+		Ready:     false,
+	}
+}
+
+// Compute generates the money-weighted returns for the trailing periods. A failure is kept in Err, so that
+// viewers can show it in place of the returns.
+func (mw *MoneyWeighted) Compute() error {
+	mw.Err = mw.compute()
+	return mw.Err
+}
+
+func (mw *MoneyWeighted) compute() error {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		return err
+	}
+
+	mw.EndDate = time.Now().In(ny)
+	mw.StartDate = mw.inceptionDate()
+
+	endValue := mw.Portfolio.Status.Value + mw.Cash
+	end := mw.EndDate
+
+	mwReturn := NewReturn()
+
+	mwReturn.Max = math.NaN()
+	if !mw.StartDate.IsZero() {
+		mwReturn.Max, err = mw.computeReturn(mw.StartDate, endValue, true)
+		if err != nil {
+			return err
+		}
+	}
+
+	periods := []struct {
+		result     *float64
+		start      time.Time
+		annualized bool
+	}{
+		{&mwReturn.OneMonth, end.AddDate(0, -1, 0), false},
+		{&mwReturn.ThreeMonth, end.AddDate(0, -3, 0), false},
+		{&mwReturn.SixMonth, end.AddDate(0, -6, 0), false},
+		{&mwReturn.YTD, time.Date(end.Year(), time.January, 1, 0, 0, 0, 0, end.Location()), false},
+		{&mwReturn.OneYear, end.AddDate(-1, 0, 0), true},
+		{&mwReturn.ThreeYear, end.AddDate(-3, 0, 0), true},
+		{&mwReturn.FiveYear, end.AddDate(-5, 0, 0), true},
+		{&mwReturn.TenYear, end.AddDate(-10, 0, 0), true},
+	}
+
+	for _, period := range periods {
+		if !mw.StartDate.IsZero() && period.start.Before(mw.StartDate) {
+			*period.result = mwReturn.Max
+			continue
+		}
+
+		*period.result, err = mw.computeReturn(period.start, endValue, period.annualized)
+		if err != nil {
+			return err
+		}
+	}
+
+	mw.Return = mwReturn
+	mw.Ready = true
+
+	return nil
+}
+
+// inceptionDate returns the day before the first transaction or lot of the portfolio
+func (mw *MoneyWeighted) inceptionDate() time.Time {
+	var earliest time.Time
+
+	for _, transaction := range mw.Portfolio.Transactions {
+		if earliest.IsZero() || transaction.Date.Before(earliest) {
+			earliest = transaction.Date
+		}
+	}
+
+	for _, holding := range mw.Portfolio.Holdings {
+		for _, lot := range holding.Lots {
+			if earliest.IsZero() || lot.Date.Before(earliest) {
+				earliest = lot.Date
+			}
+		}
+	}
+
+	if earliest.IsZero() {
+		return earliest
+	}
+
+	return earliest.AddDate(0, 0, -1).In(mw.EndDate.Location())
+}
+
+// computeReturn computes the money-weighted return between the start date and now, starting
+// with the value of the portfolio on the start date as the initial contribution
+func (mw *MoneyWeighted) computeReturn(startDate time.Time, endValue float64, annualized bool) (float64, error) {
+	startValue, err := mw.computeValueForDate(startDate)
+	if err != nil {
+		return 0, err
+	}
+
+	flows := []CashFlow{{startDate, -startValue}}
+
+	for _, transaction := range mw.Portfolio.Transactions {
+		if transaction.IsExternal() && transaction.Date.After(startDate) && !transaction.Date.After(mw.EndDate) {
+			flows = append(flows, CashFlow{transaction.Date, -transaction.Amount})
+		}
+	}
+
+	flows = append(flows, CashFlow{mw.EndDate, endValue})
+
+	rate, err := computeXIRR(flows)
+	if err != nil {
+		return 0, fmt.Errorf("Failed to compute the money-weighted return since %s: %v", startDate.Format("2006-01-02"), err)
+	}
+
+	if !annualized {
+		years := mw.EndDate.Sub(startDate).Hours() / 24 / daysPerYear
+		return (math.Pow(1+rate/100, years) - 1) * 100, nil
+	}
+
+	return rate, nil
+}
+
+// computeValueForDate reconstructs the value of the portfolio on a past date by undoing every
+// transaction recorded since then
+func (mw *MoneyWeighted) computeValueForDate(date time.Time) (float64, error) {
+	portfolio := mw.Portfolio
+	cash := portfolio.Cash + mw.Cash
+
+	quantities := make(map[string]float64)
+	for symbol, holding := range portfolio.Holdings {
+		quantities[symbol] = holding.Quantity
+	}
+
+	for _, transaction := range portfolio.Transactions {
+		if !transaction.Date.After(date) {
+			continue
+		}
+
+		cash -= transaction.Amount
+
+		switch transaction.Type {
+		case TransactionBuy:
+			quantities[transaction.Symbol] -= transaction.Quantity
+		case TransactionSell:
+			quantities[transaction.Symbol] += transaction.Quantity
+		}
+	}
+
+	value := cash
+
+	for symbol, quantity := range quantities {
+		if quantity <= 0 {
+			continue
+		}
+
+		price, err := computeAssetPriceForDate(date, mw.EndDate, symbol)
+		if err != nil {
+			return 0, err
+		}
+
+		value += price * quantity
+	}
+
+	return value, nil
+}
+
+// computeAssetPriceForDate returns the closing price of the first trading day on or after the date. It is a
+// variable so that tests can use prices of their own.
+var computeAssetPriceForDate = fetchAssetPriceForDate
+
+func fetchAssetPriceForDate(date time.Time, endDate time.Time, symbol string) (float64, error) {
+	p := &chart.Params{
+		Symbol:   symbol,
+		Start:    datetime.New(&date),
+		End:      datetime.New(&endDate),
+		Interval: datetime.OneDay,
+	}
+
+	iter := chart.Get(p)
+	for iter.Next() {
+		b := iter.Bar()
+		value, _ := b.Close.Float64()
+		return value, nil
+	}
+
+	return 0, iter.Err()
+}
+
+// computeXIRR returns the annualized rate of return in percent that discounts the cash flows to zero
+func computeXIRR(flows []CashFlow) (float64, error) {
+	if len(flows) < 2 {
+		return 0, errors.New("At least two cash flows are required")
+	}
+
+	var positive, negative bool
+	for _, flow := range flows {
+		if flow.Amount > 0 {
+			positive = true
+		} else if flow.Amount < 0 {
+			negative = true
+		}
+	}
+
+	if !positive || !negative {
+		return 0, errors.New("Cash flows must contain both contributions and withdrawals")
+	}
+
+	start := flows[0].Date
+	years := make([]float64, len(flows))
+	for i, flow := range flows {
+		years[i] = flow.Date.Sub(start).Hours() / 24 / daysPerYear
+	}
+
+	npv := func(rate float64) (float64, float64) {
+		var value, derivative float64
+		for i, flow := range flows {
+			discount := math.Pow(1+rate, years[i])
+			value += flow.Amount / discount
+			derivative -= years[i] * flow.Amount / (discount * (1 + rate))
+		}
+		return value, derivative
+	}
+
+	// Newton's method converges quickly for well-behaved flows
+	rate := 0.1
+	for i := 0; i < xirrMaxIterations; i++ {
+		value, derivative := npv(rate)
+		if derivative == 0 {
+			break
+		}
+
+		next := rate - value/derivative
+		if math.Abs(next-rate) < xirrPrecision {
+			return next * 100, nil
+		}
+
+		if next <= -1 || math.IsNaN(next) || math.IsInf(next, 0) {
+			break
+		}
+		rate = next
+	}
+
+	// Otherwise fall back to bisection over a wide bracket
+	low, high := -0.999999, 1.0
+	lowValue, _ := npv(low)
+	highValue, _ := npv(high)
+	for lowValue*highValue > 0 && high < 1e6 {
+		high *= 10
+		highValue, _ = npv(high)
+	}
+
+	if lowValue*highValue > 0 {
+		return 0, errors.New("XIRR does not converge")
+	}
+
+	for i := 0; i < xirrMaxIterations*10; i++ {
+		mid := (low + high) / 2
+		midValue, _ := npv(mid)
+
+		if math.Abs(midValue) < xirrPrecision || (high-low)/2 < xirrPrecision {
+			return mid * 100, nil
+		}
+
+		if midValue*lowValue > 0 {
+			low, lowValue = mid, midValue
+		} else {
+			high = mid
+		}
+	}
+
+	return ((low + high) / 2) * 100, nil
+}
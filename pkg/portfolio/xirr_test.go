@@ -0,0 +1,121 @@
+package portfolio
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestComputeXIRR(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	day := func(days int) time.Time {
+		return start.AddDate(0, 0, days)
+	}
+
+	tests := []struct {
+		name  string
+		flows []CashFlow
+		want  float64
+	}{
+		{"one year", []CashFlow{{day(0), -1000}, {day(365), 1100}}, 10},
+		{"two years", []CashFlow{{day(0), -1000}, {day(730), 1210}}, 10},
+		// 1000 grows to 1210 and the second 1000 to 1100
+		{"two contributions", []CashFlow{{day(0), -1000}, {day(365), -1000}, {day(730), 2310}}, 10},
+		// 1100 after a year, of which 550 are withdrawn, and the other 550 grow to 605
+		{"withdrawal", []CashFlow{{day(0), -1000}, {day(365), 550}, {day(730), 605}}, 10},
+		{"loss", []CashFlow{{day(0), -1000}, {day(365), 900}}, -10},
+		{"half year", []CashFlow{{day(0), -1000}, {day(365 / 2), 1000 * math.Pow(1.21, 182.0/365)}}, 21},
+		{"extreme gain", []CashFlow{{day(0), -1}, {day(365), 1000}}, 99900},
+	}
+
+	for _, test := range tests {
+		got, err := computeXIRR(test.flows)
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+
+		if math.Abs(got-test.want) > 1e-4*math.Max(1, math.Abs(test.want)) {
+			t.Errorf("%s: XIRR = %v%%, want %v%%", test.name, got, test.want)
+		}
+	}
+}
+
+func TestComputeXIRRErrors(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		flows []CashFlow
+	}{
+		{"no flows", nil},
+		{"one flow", []CashFlow{{start, -1000}}},
+		{"only contributions", []CashFlow{{start, -1000}, {start.AddDate(1, 0, 0), -1000}}},
+		{"only withdrawals", []CashFlow{{start, 1000}, {start.AddDate(1, 0, 0), 1000}}},
+	}
+
+	for _, test := range tests {
+		if _, err := computeXIRR(test.flows); err == nil {
+			t.Errorf("%s: want an error", test.name)
+		}
+	}
+}
+
+func TestMergedValueForDate(t *testing.T) {
+	fetch := computeAssetPriceForDate
+	computeAssetPriceForDate = func(date time.Time, endDate time.Time, symbol string) (float64, error) {
+		return 100, nil
+	}
+	defer func() {
+		computeAssetPriceForDate = fetch
+	}()
+
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// 10 VTI in the taxable account, and 5 VTI in the Roth IRA bought with a deposit of 600 after the start
+	taxable := NewPortfolio()
+	taxable.Name = "Taxable"
+	addExportHolding(taxable, "VTI", 10, 1000, 100)
+
+	roth := NewPortfolio()
+	roth.Name = "Roth IRA"
+	roth.Cash = 100
+	addExportHolding(roth, "VTI", 5, 500, 100)
+	roth.Transactions = []*Transaction{
+		{ID: "1", Date: start.AddDate(0, 1, 0), Type: TransactionDeposit, Amount: 600},
+		{ID: "2", Date: start.AddDate(0, 1, 0), Type: TransactionBuy, Symbol: "VTI", Quantity: 5, Price: 100, Amount: -500},
+	}
+
+	profile := NewProfile("Main")
+	profile.Portfolios = []*Portfolio{taxable, roth}
+	profile.merge()
+
+	if quantity := profile.MergedPortfolio.Holdings["VTI"].Quantity; quantity != 15 {
+		t.Errorf("Merged quantity of VTI = %v, want 15", quantity)
+	}
+	if value := profile.MergedPortfolio.Holdings["VTI"].Status.Value; value != 1500 {
+		t.Errorf("Merged value of VTI = %v, want 1500", value)
+	}
+
+	// Before the deposit, the profile held the 10 VTI of the taxable account and no cash
+	mw := profile.MoneyWeighted
+	mw.EndDate = start.AddDate(1, 0, 0)
+	value, err := mw.computeValueForDate(start)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(value-1000) > 1e-9 {
+		t.Errorf("Value of the profile at the start = %v, want 1000", value)
+	}
+}
+
+func TestComputeReturnError(t *testing.T) {
+	// An empty portfolio has no value to compute a return on
+	mw := NewMoneyWeighted(NewPortfolio(), 0)
+	mw.EndDate = time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	_, err := mw.computeReturn(mw.EndDate.AddDate(-1, 0, 0), 0, true)
+	if err == nil {
+		t.Errorf("A return without cash flows should fail")
+	}
+}
@@ -0,0 +1,166 @@
+package terminal
+
+import (
+	"fmt"
+
+	"github.com/cimomo/portfolio-go/pkg/portfolio"
+	"github.com/gdamore/tcell"
+	"github.com/rivo/tview"
+)
+
+// AttributionViewer drills down into a trailing return of a portfolio: the contributions of its holdings and
+// of its asset classes, and the allocation and selection effects of the asset classes against the benchmark
+type AttributionViewer struct {
+	performance *portfolio.Performance
+	period      int
+	table       *tview.Table
+}
+
+// NewAttributionViewer returns a new viewer for the return attribution of a portfolio
+func NewAttributionViewer(performance *portfolio.Performance) *AttributionViewer {
+	table := tview.NewTable().SetBorders(false).SetFixed(1, 1)
+	table.SetTitle("Attribution (<Up>/<Down> to scroll)").SetBorder(true).SetBorderPadding(0, 0, 1, 1)
+
+	return &AttributionViewer{
+		performance: performance,
+		period:      8,
+		table:       table,
+	}
+}
+
+// Reload updates the performance data object and selects a trailing period, from 0 for 1-Month to 8 for Max
+func (viewer *AttributionViewer) Reload(performance *portfolio.Performance, period int) {
+	viewer.performance = performance
+	viewer.period = period
+	viewer.table.SetOffset(0, 0)
+}
+
+// HandleKey selects the previous or next trailing period with the arrow keys, or scrolls the tables
+func (viewer *AttributionViewer) HandleKey(event *tcell.EventKey) bool {
+	switch event.Key() {
+	case tcell.KeyLeft:
+		viewer.period--
+	case tcell.KeyRight:
+		viewer.period++
+	default:
+		return scrollTable(viewer.table, event)
+	}
+
+	viewer.Draw()
+	return true
+}
+
+// Draw refreshes the attribution of the selected trailing period
+func (viewer *AttributionViewer) Draw() {
+	viewer.table.Clear()
+
+	if !viewer.performance.Ready {
+		setString(viewer.table, "Computing ...", 0, 0, tcell.ColorWhite, tview.AlignLeft)
+		return
+	}
+
+	attributions := viewer.performance.ComputeAttribution()
+	viewer.period = (viewer.period + len(attributions)) % len(attributions)
+	attribution := attributions[viewer.period]
+
+	viewer.drawPeriods(attributions)
+	r := viewer.drawSummary(attribution, 2)
+	r = viewer.drawHoldings(attribution, r+1)
+	r = viewer.drawClasses(attribution, r+1)
+	viewer.drawSubclasses(attribution, r+1)
+}
+
+func (viewer *AttributionViewer) drawPeriods(attributions []*portfolio.Attribution) {
+	setString(viewer.table, "Period (<Left>/<Right>)", 0, 0, tcell.ColorWhite, tview.AlignLeft)
+
+	for i, attribution := range attributions {
+		color := tcell.ColorGray
+		if i == viewer.period {
+			color = tcell.ColorYellow
+		}
+		setString(viewer.table, attribution.Period, 0, i+1, color, tview.AlignRight)
+	}
+}
+
+func (viewer *AttributionViewer) drawSummary(attribution *portfolio.Attribution, r int) int {
+	title := fmt.Sprintf("Since %s (%d months, cumulative)", attribution.Start.Format("2006-01"), attribution.Months)
+	viewer.drawHeader([]string{title, "Return", "Benchmark", "Excess"}, r)
+
+	setString(viewer.table, viewer.performance.Result.Portfolio.Name, r+1, 0, tcell.ColorWhite, tview.AlignLeft)
+	setPercentChange(viewer.table, attribution.Return, r+1, 1)
+	setPercentChange(viewer.table, attribution.Benchmark, r+1, 2)
+	setPercentChange(viewer.table, attribution.Return-attribution.Benchmark, r+1, 3)
+
+	return r + 2
+}
+
+func (viewer *AttributionViewer) drawHoldings(attribution *portfolio.Attribution, r int) int {
+	viewer.drawHeader([]string{"Holding", "Weight", "Return", "Contribution", "Class"}, r)
+
+	for i, holding := range attribution.Holdings {
+		setString(viewer.table, holding.Name, r+i+1, 0, tcell.ColorWhite, tview.AlignLeft)
+		viewer.drawItem(holding, r+i+1)
+		setString(viewer.table, string(holding.Class), r+i+1, 4, tcell.ColorWhite, tview.AlignRight)
+	}
+
+	return r + len(attribution.Holdings) + 1
+}
+
+func (viewer *AttributionViewer) drawClasses(attribution *portfolio.Attribution, r int) int {
+	viewer.drawHeader([]string{"Asset Class", "Weight", "Return", "Contribution", "Allocation", "Selection", "Interaction"}, r)
+
+	var allocation, selection, interaction float64
+
+	for i, class := range attribution.Classes {
+		setString(viewer.table, class.Name, r+i+1, 0, tcell.ColorWhite, tview.AlignLeft)
+		viewer.drawItem(class, r+i+1)
+		setPercentChange(viewer.table, class.Allocation, r+i+1, 4)
+		setPercentChange(viewer.table, class.Selection, r+i+1, 5)
+		setPercentChange(viewer.table, class.Interaction, r+i+1, 6)
+
+		allocation += class.Allocation
+		selection += class.Selection
+		interaction += class.Interaction
+	}
+
+	r += len(attribution.Classes) + 1
+	setString(viewer.table, "Total", r, 0, tcell.ColorYellow, tview.AlignLeft)
+	setPercentChange(viewer.table, attribution.Return, r, 3)
+	setPercentChange(viewer.table, allocation, r, 4)
+	setPercentChange(viewer.table, selection, r, 5)
+	setPercentChange(viewer.table, interaction, r, 6)
+
+	return r + 1
+}
+
+func (viewer *AttributionViewer) drawSubclasses(attribution *portfolio.Attribution, r int) int {
+	viewer.drawHeader([]string{"Subclass", "Weight", "Return", "Contribution", "Class"}, r)
+
+	for i, subclass := range attribution.Subclasses {
+		setString(viewer.table, subclass.Name, r+i+1, 0, tcell.ColorWhite, tview.AlignLeft)
+		viewer.drawItem(subclass, r+i+1)
+		setString(viewer.table, string(subclass.Class), r+i+1, 4, tcell.ColorWhite, tview.AlignRight)
+	}
+
+	return r + len(attribution.Subclasses) + 1
+}
+
+func (viewer *AttributionViewer) drawItem(item *portfolio.AttributionItem, r int) {
+	setPercent(viewer.table, item.Weight, r, 1, tcell.ColorWhite)
+	setPercentChange(viewer.table, item.Return, r, 2)
+	setPercentChange(viewer.table, item.Contribution, r, 3)
+}
+
+func (viewer *AttributionViewer) drawHeader(header []string, r int) {
+	var cell *tview.TableCell
+
+	for c := 0; c < len(header); c++ {
+		cell = tview.NewTableCell(header[c]).SetTextColor(tcell.ColorYellow).SetAttributes(tcell.AttrBold).SetExpansion(1)
+		if c < 1 {
+			cell.SetAlign(tview.AlignLeft)
+		} else {
+			cell.SetAlign((tview.AlignRight))
+		}
+		viewer.table.SetCell(r, c, cell)
+	}
+}
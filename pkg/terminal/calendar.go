@@ -0,0 +1,114 @@
+package terminal
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/cimomo/portfolio-go/pkg/portfolio"
+	"github.com/gdamore/tcell"
+	"github.com/rivo/tview"
+)
+
+// CalendarViewer displays the monthly and the annual returns of a portfolio by calendar year as a heat map,
+// with the annual return of the benchmark and the excess return over it
+type CalendarViewer struct {
+	performance *portfolio.Performance
+	table       *tview.Table
+}
+
+// NewCalendarViewer returns a new viewer for the calendar returns of a portfolio
+func NewCalendarViewer(performance *portfolio.Performance) *CalendarViewer {
+	table := tview.NewTable().SetBorders(false).SetFixed(1, 1)
+	table.SetTitle("Calendar Returns (<Up>/<Down> to scroll)").SetBorder(true).SetBorderPadding(0, 0, 1, 1)
+
+	return &CalendarViewer{
+		performance: performance,
+		table:       table,
+	}
+}
+
+// Reload updates the performance data object
+func (viewer *CalendarViewer) Reload(performance *portfolio.Performance) {
+	viewer.performance = performance
+	viewer.table.SetOffset(0, 0)
+}
+
+// HandleKey scrolls the years with the arrow and page keys
+func (viewer *CalendarViewer) HandleKey(event *tcell.EventKey) bool {
+	return scrollTable(viewer.table, event)
+}
+
+// Draw refreshes the heat map, with the most recent year first
+func (viewer *CalendarViewer) Draw() {
+	viewer.table.Clear()
+	viewer.drawHeader()
+
+	if !viewer.performance.Ready {
+		setString(viewer.table, "Computing ...", 1, 0, tcell.ColorWhite, tview.AlignLeft)
+		return
+	}
+
+	calendar := viewer.performance.Result.Calendar
+	benchmark := viewer.performance.Benchmark.Calendar
+	excess := calendar.Excess(benchmark)
+
+	for i := len(calendar.Years) - 1; i >= 0; i-- {
+		r := len(calendar.Years) - i
+
+		setString(viewer.table, strconv.Itoa(calendar.Years[i]), r, 0, tcell.ColorYellow, tview.AlignLeft)
+
+		for m, value := range calendar.Monthly[i] {
+			setHeat(viewer.table, value, 3, r, m+1)
+		}
+
+		setHeat(viewer.table, calendar.Annual[i], 10, r, 13)
+		setHeat(viewer.table, benchmark.Return(calendar.Years[i]), 10, r, 14)
+		setHeat(viewer.table, excess[i], 5, r, 15)
+	}
+}
+
+func (viewer *CalendarViewer) drawHeader() {
+	var cell *tview.TableCell
+	header := []string{"Year", "Jan", "Feb", "Mar", "Apr", "May", "Jun", "Jul", "Aug", "Sep", "Oct", "Nov", "Dec", "Year", "Benchmark", "Excess"}
+
+	for c := 0; c < len(header); c++ {
+		cell = tview.NewTableCell(header[c]).SetTextColor(tcell.ColorYellow).SetAttributes(tcell.AttrBold).SetExpansion(1)
+		if c < 1 {
+			cell.SetAlign(tview.AlignLeft)
+		} else {
+			cell.SetAlign((tview.AlignRight))
+		}
+		viewer.table.SetCell(0, c, cell)
+	}
+}
+
+// setHeat shows a return on a background colored by its size, in steps of the given number of percentage points
+func setHeat(table *tview.Table, value float64, step float64, r int, c int) {
+	if math.IsNaN(value) {
+		setString(table, "", r, c, tcell.ColorWhite, tview.AlignRight)
+		return
+	}
+
+	cell := tview.NewTableCell(fmt.Sprintf("%.1f%%", value)).SetTextColor(tcell.ColorBlack).
+		SetBackgroundColor(returnColor(value, step)).SetAlign(tview.AlignRight).SetExpansion(1)
+	table.SetCell(r, c, cell)
+}
+
+// returnColor maps a return to a heat map color, from red for losses to green for gains
+func returnColor(value float64, step float64) tcell.Color {
+	switch {
+	case value <= -2*step:
+		return tcell.ColorRed
+	case value <= -step:
+		return tcell.ColorLightCoral
+	case value < 0:
+		return tcell.ColorPink
+	case value < step:
+		return tcell.ColorPaleGreen
+	case value < 2*step:
+		return tcell.ColorLightGreen
+	default:
+		return tcell.ColorGreen
+	}
+}
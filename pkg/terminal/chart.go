@@ -0,0 +1,377 @@
+package terminal
+
+import (
+	"math"
+
+	"github.com/gdamore/tcell"
+	"github.com/rivo/tview"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+// Each braille character is a grid of 2x4 dots, which quadruples the vertical resolution of a line chart
+const (
+	brailleBase   = 0x2800
+	brailleWidth  = 2
+	brailleHeight = 4
+	yLabelWidth   = 14
+)
+
+var brailleDots = [brailleWidth][brailleHeight]rune{
+	{0x01, 0x02, 0x04, 0x40},
+	{0x08, 0x10, 0x20, 0x80},
+}
+
+// ChartSeries defines a line of a chart, or a set of unconnected dots if it is a scatter series
+type ChartSeries struct {
+	Name    string
+	Color   tcell.Color
+	X       []float64
+	Y       []float64
+	Scatter bool
+}
+
+// ChartBand defines a shaded area between two lines of a chart sharing the same x values
+type ChartBand struct {
+	Color tcell.Color
+	X     []float64
+	Lower []float64
+	Upper []float64
+}
+
+// ChartMarker defines a symbol shown on the x axis of a chart
+type ChartMarker struct {
+	X      float64
+	Symbol rune
+	Color  tcell.Color
+}
+
+// ChartPoint defines a symbol plotted at a point of a chart, on top of the lines
+type ChartPoint struct {
+	X      float64
+	Y      float64
+	Symbol rune
+	Color  tcell.Color
+}
+
+// Chart is a primitive that draws line charts with braille characters
+type Chart struct {
+	*tview.Box
+	series  []*ChartSeries
+	bands   []*ChartBand
+	markers []*ChartMarker
+	points  []*ChartPoint
+	formatX func(float64) string
+	formatY func(float64) string
+}
+
+// NewChart returns a new empty chart
+func NewChart() *Chart {
+	return &Chart{
+		Box:     tview.NewBox(),
+		series:  make([]*ChartSeries, 0),
+		bands:   make([]*ChartBand, 0),
+		markers: make([]*ChartMarker, 0),
+		points:  make([]*ChartPoint, 0),
+		formatX: formatNumber,
+		formatY: formatNumber,
+	}
+}
+
+// SetFormatters sets the functions that format the labels of the x and y axes
+func (chart *Chart) SetFormatters(formatX func(float64) string, formatY func(float64) string) *Chart {
+	chart.formatX = formatX
+	chart.formatY = formatY
+	return chart
+}
+
+// Clear removes all the data from the chart
+func (chart *Chart) Clear() *Chart {
+	chart.series = make([]*ChartSeries, 0)
+	chart.bands = make([]*ChartBand, 0)
+	chart.markers = make([]*ChartMarker, 0)
+	chart.points = make([]*ChartPoint, 0)
+	return chart
+}
+
+// AddSeries adds a line to the chart. Lines added later are drawn on top.
+func (chart *Chart) AddSeries(series *ChartSeries) *Chart {
+	chart.series = append(chart.series, series)
+	return chart
+}
+
+// AddBand adds a shaded area to the chart, drawn underneath the lines
+func (chart *Chart) AddBand(band *ChartBand) *Chart {
+	chart.bands = append(chart.bands, band)
+	return chart
+}
+
+// AddMarker adds a symbol on the x axis of the chart
+func (chart *Chart) AddMarker(marker *ChartMarker) *Chart {
+	chart.markers = append(chart.markers, marker)
+	return chart
+}
+
+// AddPoint adds a symbol at a point of the chart
+func (chart *Chart) AddPoint(point *ChartPoint) *Chart {
+	chart.points = append(chart.points, point)
+	return chart
+}
+
+// Draw draws the chart onto the screen
+func (chart *Chart) Draw(screen tcell.Screen) {
+	chart.Box.Draw(screen)
+
+	x, y, width, height := chart.GetInnerRect()
+
+	// Leave room for the labels of the y axis on the left, and for the x axis and its labels at the bottom
+	plotX, plotWidth := x+yLabelWidth, width-yLabelWidth
+	plotY, plotHeight := y, height-2
+	if plotWidth < 2 || plotHeight < 2 {
+		return
+	}
+
+	minX, maxX, minY, maxY, ok := chart.bounds()
+	if !ok {
+		tview.Print(screen, "No data", x, y+height/2, width, tview.AlignCenter, tcell.ColorWhite)
+		return
+	}
+
+	dotsX := plotWidth * brailleWidth
+	dotsY := plotHeight * brailleHeight
+
+	toDotX := func(value float64) int {
+		return int(math.Round((value - minX) / (maxX - minX) * float64(dotsX-1)))
+	}
+	toDotY := func(value float64) int {
+		return dotsY - 1 - int(math.Round((value-minY)/(maxY-minY)*float64(dotsY-1)))
+	}
+
+	// Bands shade the background of every cell between their lower and upper lines
+	background := make([][]tcell.Color, plotWidth)
+	for i := range background {
+		background[i] = make([]tcell.Color, plotHeight)
+		for j := range background[i] {
+			background[i][j] = tcell.ColorDefault
+		}
+	}
+
+	for _, band := range chart.bands {
+		for col := 0; col < plotWidth; col++ {
+			value := minX + (float64(col*brailleWidth)+0.5)/float64(dotsX-1)*(maxX-minX)
+			lower, lok := interpolate(band.X, band.Lower, value)
+			upper, uok := interpolate(band.X, band.Upper, value)
+			if !lok || !uok {
+				continue
+			}
+
+			top, bottom := toDotY(math.Max(lower, upper))/brailleHeight, toDotY(math.Min(lower, upper))/brailleHeight
+			for row := top; row <= bottom; row++ {
+				background[col][row] = band.Color
+			}
+		}
+	}
+
+	// Lines set the dots of the braille characters, and the color of a cell is the last line through it
+	dots := make([][]rune, plotWidth)
+	colors := make([][]tcell.Color, plotWidth)
+	for i := range dots {
+		dots[i] = make([]rune, plotHeight)
+		colors[i] = make([]tcell.Color, plotHeight)
+	}
+
+	setDot := func(dx int, dy int, color tcell.Color) {
+		if dx < 0 || dx >= dotsX || dy < 0 || dy >= dotsY {
+			return
+		}
+		col, row := dx/brailleWidth, dy/brailleHeight
+		dots[col][row] |= brailleDots[dx%brailleWidth][dy%brailleHeight]
+		colors[col][row] = color
+	}
+
+	for _, series := range chart.series {
+		for i := range series.X {
+			if math.IsNaN(series.Y[i]) {
+				continue
+			}
+
+			x1, y1 := toDotX(series.X[i]), toDotY(series.Y[i])
+			if series.Scatter || i == 0 || math.IsNaN(series.Y[i-1]) {
+				setDot(x1, y1, series.Color)
+				continue
+			}
+
+			x0, y0 := toDotX(series.X[i-1]), toDotY(series.Y[i-1])
+			drawLine(x0, y0, x1, y1, func(dx int, dy int) {
+				setDot(dx, dy, series.Color)
+			})
+		}
+	}
+
+	for col := 0; col < plotWidth; col++ {
+		for row := 0; row < plotHeight; row++ {
+			style := tcell.StyleDefault.Background(chart.GetBackgroundColor())
+			if background[col][row] != tcell.ColorDefault {
+				style = style.Background(background[col][row])
+			}
+
+			char := ' '
+			if dots[col][row] != 0 {
+				char = brailleBase + dots[col][row]
+				style = style.Foreground(colors[col][row])
+			}
+
+			screen.SetContent(plotX+col, plotY+row, char, nil, style)
+		}
+	}
+
+	for _, point := range chart.points {
+		col, row := toDotX(point.X)/brailleWidth, toDotY(point.Y)/brailleHeight
+		screen.SetContent(plotX+col, plotY+row, point.Symbol, nil, tcell.StyleDefault.Foreground(point.Color).Background(chart.GetBackgroundColor()))
+	}
+
+	chart.drawAxes(screen, x, plotX, plotY, plotWidth, plotHeight, minX, maxX, minY, maxY)
+
+	axis := plotY + plotHeight
+	for _, marker := range chart.markers {
+		if marker.X < minX || marker.X > maxX {
+			continue
+		}
+		col := toDotX(marker.X) / brailleWidth
+		screen.SetContent(plotX+col, axis, marker.Symbol, nil, tcell.StyleDefault.Foreground(marker.Color).Background(chart.GetBackgroundColor()))
+	}
+}
+
+func (chart *Chart) drawAxes(screen tcell.Screen, x int, plotX int, plotY int, plotWidth int, plotHeight int, minX float64, maxX float64, minY float64, maxY float64) {
+	axis := plotY + plotHeight
+
+	for col := 0; col < plotWidth; col++ {
+		screen.SetContent(plotX+col, axis, tview.BoxDrawingsLightHorizontal, nil, tcell.StyleDefault.Foreground(tcell.ColorGray).Background(chart.GetBackgroundColor()))
+	}
+
+	// Label the top, middle and bottom of the y axis
+	for _, row := range []int{0, (plotHeight - 1) / 2, plotHeight - 1} {
+		value := maxY - float64(row)/float64(plotHeight-1)*(maxY-minY)
+		tview.Print(screen, chart.formatY(value)+" ", x, plotY+row, yLabelWidth, tview.AlignRight, tcell.ColorYellow)
+	}
+
+	// Label both ends and the middle of the x axis
+	left := chart.formatX(minX)
+	middle := chart.formatX((minX + maxX) / 2)
+	right := chart.formatX(maxX)
+
+	tview.Print(screen, left, plotX, axis+1, plotWidth, tview.AlignLeft, tcell.ColorYellow)
+	if plotWidth > len(left)+len(middle)+len(right)+4 {
+		tview.Print(screen, middle, plotX, axis+1, plotWidth, tview.AlignCenter, tcell.ColorYellow)
+	}
+	tview.Print(screen, right, plotX, axis+1, plotWidth, tview.AlignRight, tcell.ColorYellow)
+}
+
+// bounds returns the range of all the data in the chart, widened if it is a single point
+func (chart *Chart) bounds() (float64, float64, float64, float64, bool) {
+	minX, maxX := math.Inf(1), math.Inf(-1)
+	minY, maxY := math.Inf(1), math.Inf(-1)
+
+	update := func(xs []float64, ys []float64) {
+		for i := range xs {
+			if math.IsNaN(ys[i]) {
+				continue
+			}
+			minX, maxX = math.Min(minX, xs[i]), math.Max(maxX, xs[i])
+			minY, maxY = math.Min(minY, ys[i]), math.Max(maxY, ys[i])
+		}
+	}
+
+	for _, series := range chart.series {
+		update(series.X, series.Y)
+	}
+
+	for _, band := range chart.bands {
+		update(band.X, band.Lower)
+		update(band.X, band.Upper)
+	}
+
+	for _, point := range chart.points {
+		update([]float64{point.X}, []float64{point.Y})
+	}
+
+	if math.IsInf(minX, 1) {
+		return 0, 0, 0, 0, false
+	}
+
+	if maxX == minX {
+		minX, maxX = minX-1, maxX+1
+	}
+
+	if maxY == minY {
+		margin := math.Max(math.Abs(minY)*0.01, 1)
+		minY, maxY = minY-margin, maxY+margin
+	}
+
+	return minX, maxX, minY, maxY, true
+}
+
+// drawLine visits the dots on the line between two dots using Bresenham's algorithm
+func drawLine(x0 int, y0 int, x1 int, y1 int, plot func(int, int)) {
+	dx := abs(x1 - x0)
+	dy := -abs(y1 - y0)
+
+	sx, sy := 1, 1
+	if x0 > x1 {
+		sx = -1
+	}
+	if y0 > y1 {
+		sy = -1
+	}
+
+	err := dx + dy
+	for {
+		plot(x0, y0)
+		if x0 == x1 && y0 == y1 {
+			return
+		}
+
+		e2 := 2 * err
+		if e2 >= dy {
+			err += dy
+			x0 += sx
+		}
+		if e2 <= dx {
+			err += dx
+			y0 += sy
+		}
+	}
+}
+
+// interpolate returns the linearly interpolated y value at x, if x is within the range of xs
+func interpolate(xs []float64, ys []float64, x float64) (float64, bool) {
+	for i := 1; i < len(xs); i++ {
+		if x < xs[i-1] || x > xs[i] {
+			continue
+		}
+
+		if xs[i] == xs[i-1] {
+			return ys[i], true
+		}
+
+		ratio := (x - xs[i-1]) / (xs[i] - xs[i-1])
+		return ys[i-1] + ratio*(ys[i]-ys[i-1]), true
+	}
+
+	if len(xs) == 1 && xs[0] == x {
+		return ys[0], true
+	}
+
+	return 0, false
+}
+
+func abs(value int) int {
+	if value < 0 {
+		return -value
+	}
+	return value
+}
+
+func formatNumber(value float64) string {
+	return message.NewPrinter(language.English).Sprintf("%.2f", value)
+}
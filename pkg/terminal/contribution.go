@@ -0,0 +1,143 @@
+package terminal
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/cimomo/portfolio-go/pkg/portfolio"
+	"github.com/gdamore/tcell"
+	"github.com/rivo/tview"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+// ContributionViewer displays the dollar balances of a backtest with recurring contributions or withdrawals,
+// and compares its time-weighted and money-weighted returns with those of the benchmark
+type ContributionViewer struct {
+	performance *portfolio.Performance
+	table       *tview.Table
+	note        *tview.TextView
+	chart       *Chart
+	layout      *tview.Flex
+}
+
+// NewContributionViewer returns a new viewer for the contributions to a portfolio
+func NewContributionViewer(performance *portfolio.Performance) *ContributionViewer {
+	viewer := &ContributionViewer{
+		performance: performance,
+		table:       tview.NewTable().SetBorders(false),
+		note:        tview.NewTextView().SetTextColor(tcell.ColorGray),
+		chart:       NewChart().SetFormatters(formatChartDate, formatChartDollars),
+	}
+
+	viewer.layout = tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(viewer.table, 4, 0, false).
+		AddItem(viewer.note, 2, 0, false).
+		AddItem(viewer.chart, 0, 1, false)
+	viewer.layout.SetTitle("Contributions").SetBorder(true).SetBorderPadding(0, 0, 1, 1)
+
+	return viewer
+}
+
+// Reload updates the performance data object
+func (viewer *ContributionViewer) Reload(performance *portfolio.Performance) {
+	viewer.performance = performance
+}
+
+// Draw refreshes the balances and the returns of the portfolio and its benchmark
+func (viewer *ContributionViewer) Draw() {
+	viewer.table.Clear()
+	viewer.note.Clear()
+	viewer.chart.Clear()
+	viewer.drawHeader()
+
+	if !viewer.performance.Ready {
+		viewer.note.SetText("Computing ...")
+		return
+	}
+
+	result := viewer.performance.Result
+	if result.Contributions == nil {
+		viewer.note.SetText("No contributions configured (add a contribution section to the portfolio)")
+		return
+	}
+
+	viewer.drawResult(viewer.performance.Benchmark, tcell.ColorAqua, 2)
+	viewer.drawResult(result, tcell.ColorGreen, 1)
+	viewer.drawInvested(result.Contributions)
+
+	viewer.note.SetText(fmt.Sprintf("Schedule: %s. Other pages are time-weighted and ignore the flows.",
+		formatContribution(result.Portfolio.Contribution)))
+}
+
+func (viewer *ContributionViewer) drawHeader() {
+	var cell *tview.TableCell
+	header := []string{"Portfolio", "Initial Balance", "Contributed", "Withdrawn", "Ending Balance", "Time-Weighted", "Money-Weighted"}
+
+	for c := 0; c < len(header); c++ {
+		cell = tview.NewTableCell(header[c]).SetTextColor(tcell.ColorYellow).SetAttributes(tcell.AttrBold).SetExpansion(1)
+		if c < 1 {
+			cell.SetAlign(tview.AlignLeft)
+		} else {
+			cell.SetAlign((tview.AlignRight))
+		}
+		viewer.table.SetCell(0, c, cell)
+	}
+}
+
+func (viewer *ContributionViewer) drawResult(result *portfolio.PerformanceResult, color tcell.Color, r int) {
+	contributions := result.Contributions
+	if contributions == nil || len(contributions.Balances) == 0 {
+		return
+	}
+
+	setString(viewer.table, result.Portfolio.Name, r, 0, color, tview.AlignLeft)
+	setDollarAmount(viewer.table, contributions.Balances[0].Open, r, 1, tcell.ColorWhite)
+	setDollarAmount(viewer.table, contributions.Contributed, r, 2, tcell.ColorWhite)
+	setDollarAmount(viewer.table, contributions.Withdrawn, r, 3, tcell.ColorWhite)
+	setDollarAmount(viewer.table, contributions.EndingBalance, r, 4, tcell.ColorWhite)
+	setReturn(viewer.table, contributions.TimeWeighted, r, 5)
+	setReturn(viewer.table, contributions.MoneyWeighted, r, 6)
+
+	series := &ChartSeries{Name: result.Portfolio.Name, Color: color}
+	for _, month := range contributions.Balances {
+		series.X = append(series.X, chartDate(month.Date))
+		series.Y = append(series.Y, month.Close)
+	}
+	viewer.chart.AddSeries(series)
+}
+
+// drawInvested adds the cumulative net amount invested in the portfolio, which starts at the initial balance
+func (viewer *ContributionViewer) drawInvested(contributions *portfolio.ContributionResult) {
+	series := &ChartSeries{Name: "Net Invested", Color: tcell.ColorGray}
+
+	invested := contributions.Balances[0].Open
+	series.X = append(series.X, chartDate(contributions.Balances[0].Date))
+	series.Y = append(series.Y, invested)
+
+	for _, flow := range contributions.Flows {
+		// Flows are from the investor's point of view, so contributions are negative
+		invested -= flow.Amount
+		series.X = append(series.X, chartDate(flow.Date))
+		series.Y = append(series.Y, invested)
+	}
+
+	viewer.chart.AddSeries(series)
+}
+
+// formatContribution describes a contribution schedule, e.g. "contribute $1,000 monthly, allocated by target"
+func formatContribution(contribution *portfolio.Contribution) string {
+	printer := message.NewPrinter(language.English)
+
+	kind := "contribute"
+	if contribution.Amount < 0 {
+		kind = "withdraw"
+	}
+
+	text := printer.Sprintf("%s $%.0f %s", kind, math.Abs(contribution.Amount), contribution.Frequency)
+	if contribution.Indexed {
+		text += ", indexed to inflation"
+	}
+
+	return fmt.Sprintf("%s, allocated by %s", text, contribution.Allocation)
+}
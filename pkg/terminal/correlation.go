@@ -0,0 +1,145 @@
+package terminal
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/cimomo/portfolio-go/pkg/portfolio"
+	"github.com/gdamore/tcell"
+	"github.com/rivo/tview"
+)
+
+var correlationLookbacks = []struct {
+	name   string
+	months int
+}{
+	{"1Y", 12}, {"3Y", 36}, {"5Y", 60}, {"10Y", 120}, {"Max", 0},
+}
+
+// CorrelationViewer displays the correlation of the monthly returns between the holdings of a portfolio as a heat map
+type CorrelationViewer struct {
+	performance *portfolio.Performance
+	lookback    int
+	table       *tview.Table
+}
+
+// NewCorrelationViewer returns a new viewer for the correlation matrix of a portfolio
+func NewCorrelationViewer(performance *portfolio.Performance) *CorrelationViewer {
+	table := tview.NewTable().SetBorders(false).SetFixed(2, 1)
+	table.SetTitle("Correlation").SetBorder(true).SetBorderPadding(0, 0, 1, 1)
+
+	return &CorrelationViewer{
+		performance: performance,
+		lookback:    len(correlationLookbacks) - 1,
+		table:       table,
+	}
+}
+
+// Reload updates the performance data object
+func (viewer *CorrelationViewer) Reload(performance *portfolio.Performance) {
+	viewer.performance = performance
+}
+
+// HandleKey selects the previous or next lookback period with the arrow keys
+func (viewer *CorrelationViewer) HandleKey(event *tcell.EventKey) bool {
+	switch event.Key() {
+	case tcell.KeyLeft:
+		viewer.lookback = (viewer.lookback + len(correlationLookbacks) - 1) % len(correlationLookbacks)
+	case tcell.KeyRight:
+		viewer.lookback = (viewer.lookback + 1) % len(correlationLookbacks)
+	default:
+		return false
+	}
+
+	viewer.Draw()
+	return true
+}
+
+// Draw refreshes the heat map and the redundant pairs
+func (viewer *CorrelationViewer) Draw() {
+	viewer.table.Clear()
+	viewer.drawLookbacks()
+
+	if !viewer.performance.Ready {
+		setString(viewer.table, "Computing ...", 2, 0, tcell.ColorWhite, tview.AlignLeft)
+		return
+	}
+
+	matrix := viewer.performance.Result.ComputeCorrelation(correlationLookbacks[viewer.lookback].months)
+
+	viewer.drawMatrix(matrix)
+	viewer.drawRedundant(matrix, len(matrix.Symbols)+3)
+}
+
+func (viewer *CorrelationViewer) drawLookbacks() {
+	setString(viewer.table, "Lookback (<Left>/<Right>)", 0, 0, tcell.ColorWhite, tview.AlignLeft)
+
+	for i, lookback := range correlationLookbacks {
+		color := tcell.ColorGray
+		if i == viewer.lookback {
+			color = tcell.ColorYellow
+		}
+		setString(viewer.table, lookback.name, 0, i+1, color, tview.AlignRight)
+	}
+}
+
+func (viewer *CorrelationViewer) drawMatrix(matrix *portfolio.CorrelationMatrix) {
+	title := fmt.Sprintf("%d Months", matrix.Months)
+	cell := tview.NewTableCell(title).SetTextColor(tcell.ColorYellow).SetAttributes(tcell.AttrBold).SetExpansion(1)
+	viewer.table.SetCell(1, 0, cell)
+
+	for i, symbol := range matrix.Symbols {
+		cell = tview.NewTableCell(symbol).SetTextColor(tcell.ColorYellow).SetAttributes(tcell.AttrBold).SetExpansion(1).SetAlign(tview.AlignRight)
+		viewer.table.SetCell(1, i+1, cell)
+
+		setString(viewer.table, symbol, i+2, 0, tcell.ColorYellow, tview.AlignLeft)
+
+		for j := range matrix.Symbols {
+			value := matrix.Values[i][j]
+
+			text := "-"
+			if !math.IsNaN(value) {
+				text = fmt.Sprintf("%.2f", value)
+			}
+
+			cell = tview.NewTableCell(text).SetTextColor(tcell.ColorBlack).SetBackgroundColor(correlationColor(value)).
+				SetAlign(tview.AlignRight).SetExpansion(1)
+			viewer.table.SetCell(i+2, j+1, cell)
+		}
+	}
+}
+
+func (viewer *CorrelationViewer) drawRedundant(matrix *portfolio.CorrelationMatrix, r int) {
+	title := fmt.Sprintf("Redundant Pairs (Correlation >= %.2f)", portfolio.RedundantCorrelation)
+	setString(viewer.table, title, r, 0, tcell.ColorYellow, tview.AlignLeft)
+
+	if len(matrix.Redundant) == 0 {
+		setString(viewer.table, "None", r+1, 0, tcell.ColorWhite, tview.AlignLeft)
+		return
+	}
+
+	for i, pair := range matrix.Redundant {
+		setString(viewer.table, pair.First+" / "+pair.Second, r+i+1, 0, tcell.ColorOrange, tview.AlignLeft)
+		setString(viewer.table, fmt.Sprintf("%.2f", pair.Correlation), r+i+1, 1, tcell.ColorOrange, tview.AlignRight)
+	}
+}
+
+// correlationColor maps a correlation to a heat map color, from blue for negative to red for redundant
+func correlationColor(value float64) tcell.Color {
+	switch {
+	case math.IsNaN(value):
+		return tcell.ColorGray
+	case value >= portfolio.RedundantCorrelation:
+		return tcell.ColorRed
+	case value >= 0.8:
+		return tcell.ColorOrange
+	case value >= 0.5:
+		return tcell.ColorYellow
+	case value >= 0.2:
+		return tcell.ColorLightGreen
+	case value >= -0.2:
+		return tcell.ColorLightSkyBlue
+	default:
+		return tcell.ColorDodgerBlue
+	}
+}
@@ -0,0 +1,132 @@
+package terminal
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/cimomo/portfolio-go/pkg/portfolio"
+	"github.com/gdamore/tcell"
+	"github.com/rivo/tview"
+)
+
+// DrawdownViewer displays the worst drawdowns of a portfolio and its benchmark, and their underwater curves
+type DrawdownViewer struct {
+	performance *portfolio.Performance
+	table       *tview.Table
+	chart       *Chart
+	layout      *tview.Flex
+}
+
+// NewDrawdownViewer returns a new viewer for the drawdowns of a portfolio
+func NewDrawdownViewer(performance *portfolio.Performance) *DrawdownViewer {
+	viewer := &DrawdownViewer{
+		performance: performance,
+		table:       tview.NewTable().SetBorders(false),
+		chart:       NewChart().SetFormatters(formatChartDate, formatChartPercent),
+	}
+
+	viewer.layout = tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(viewer.table, 0, 1, false).
+		AddItem(viewer.chart, 0, 1, false)
+	viewer.layout.SetTitle("Drawdowns").SetBorder(true).SetBorderPadding(0, 0, 1, 1)
+
+	return viewer
+}
+
+// Reload updates the performance data object
+func (viewer *DrawdownViewer) Reload(performance *portfolio.Performance) {
+	viewer.performance = performance
+}
+
+// Draw refreshes the drawdown table and the underwater chart
+func (viewer *DrawdownViewer) Draw() {
+	viewer.table.Clear()
+	viewer.chart.Clear()
+	viewer.layout.SetTitle("Drawdowns")
+	viewer.drawHeader()
+
+	if !viewer.performance.Ready {
+		setString(viewer.table, "Computing ...", 1, 0, tcell.ColorWhite, tview.AlignLeft)
+		return
+	}
+
+	r := viewer.drawResult(viewer.performance.Result, tcell.ColorGreen, 1)
+	viewer.drawResult(viewer.performance.Benchmark, tcell.ColorAqua, r)
+	viewer.drawSimulated(viewer.performance.Result)
+}
+
+func (viewer *DrawdownViewer) drawHeader() {
+	var cell *tview.TableCell
+	header := []string{"Portfolio", "Depth", "Peak", "Trough", "Recovery", "Decline", "Length"}
+
+	for c := 0; c < len(header); c++ {
+		cell = tview.NewTableCell(header[c]).SetTextColor(tcell.ColorYellow).SetAttributes(tcell.AttrBold).SetExpansion(1)
+		if c < 1 {
+			cell.SetAlign(tview.AlignLeft)
+		} else {
+			cell.SetAlign((tview.AlignRight))
+		}
+		viewer.table.SetCell(0, c, cell)
+	}
+}
+
+func (viewer *DrawdownViewer) drawResult(result *portfolio.PerformanceResult, color tcell.Color, r int) int {
+	series := &ChartSeries{Name: result.Portfolio.Name, Color: color}
+	for i, month := range result.Historic {
+		series.X = append(series.X, chartDate(month.Date))
+		series.Y = append(series.Y, result.Underwater[i])
+	}
+	viewer.chart.AddSeries(series)
+
+	if len(result.Drawdowns) == 0 {
+		setString(viewer.table, result.Portfolio.Name, r, 0, color, tview.AlignLeft)
+		setString(viewer.table, "No drawdowns", r, 1, tcell.ColorWhite, tview.AlignRight)
+		return r + 1
+	}
+
+	for _, drawdown := range result.Drawdowns {
+		setString(viewer.table, result.Portfolio.Name, r, 0, color, tview.AlignLeft)
+		setPercentChange(viewer.table, drawdown.Depth, r, 1)
+		setString(viewer.table, drawdown.PeakDate.Format("2006-01"), r, 2, tcell.ColorWhite, tview.AlignRight)
+		setString(viewer.table, drawdown.TroughDate.Format("2006-01"), r, 3, tcell.ColorWhite, tview.AlignRight)
+
+		if drawdown.Recovered {
+			setString(viewer.table, drawdown.RecoveryDate.Format("2006-01"), r, 4, tcell.ColorWhite, tview.AlignRight)
+		} else {
+			setString(viewer.table, "Ongoing", r, 4, tcell.ColorOrange, tview.AlignRight)
+		}
+
+		setString(viewer.table, formatMonths(drawdown.Decline, true), r, 5, tcell.ColorWhite, tview.AlignRight)
+		setString(viewer.table, formatMonths(drawdown.Length, drawdown.Recovered), r, 6, tcell.ColorWhite, tview.AlignRight)
+		r++
+	}
+
+	return r
+}
+
+// drawSimulated marks the inception of every holding simulated by proxies before it on the time axis
+func (viewer *DrawdownViewer) drawSimulated(result *portfolio.PerformanceResult) {
+	if len(result.Simulated) == 0 {
+		return
+	}
+
+	inceptions := make([]string, 0)
+	for _, simulated := range result.Simulated {
+		viewer.chart.AddMarker(&ChartMarker{X: chartDate(simulated.End), Symbol: '◆', Color: tcell.ColorOrange})
+		inceptions = append(inceptions, simulated.Symbol+" "+simulated.End.Format("2006-01"))
+	}
+
+	viewer.layout.SetTitle(fmt.Sprintf("Drawdowns (◆ simulated before %s)", strings.Join(inceptions, ", ")))
+}
+
+// formatMonths formats a duration in months, marking durations that are still running with a "+"
+func formatMonths(months int, complete bool) string {
+	if complete {
+		return fmt.Sprintf("%d mo", months)
+	}
+	return fmt.Sprintf("%d+ mo", months)
+}
+
+func formatChartPercent(value float64) string {
+	return fmt.Sprintf("%.1f%%", value)
+}
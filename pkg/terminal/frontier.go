@@ -0,0 +1,169 @@
+package terminal
+
+import (
+	"fmt"
+
+	"github.com/cimomo/portfolio-go/pkg/portfolio"
+	"github.com/gdamore/tcell"
+	"github.com/rivo/tview"
+)
+
+// FrontierViewer displays the efficient frontier of the holdings of a portfolio, and compares the weights of
+// the minimum-variance, maximum-Sharpe and target-return portfolios with the target allocation
+type FrontierViewer struct {
+	performance *portfolio.Performance
+	optimizer   *portfolio.Optimizer
+	result      *portfolio.PerformanceResult
+	frontier    *portfolio.Frontier
+	err         error
+	table       *tview.Table
+	chart       *Chart
+	layout      *tview.Flex
+}
+
+// NewFrontierViewer returns a new viewer for the efficient frontier of a portfolio
+func NewFrontierViewer(performance *portfolio.Performance, optimizer *portfolio.Optimizer) *FrontierViewer {
+	viewer := &FrontierViewer{
+		performance: performance,
+		optimizer:   optimizer,
+		table:       tview.NewTable().SetBorders(false).SetFixed(0, 1),
+		chart:       NewChart().SetFormatters(formatChartPercent, formatChartPercent),
+	}
+
+	viewer.layout = tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(viewer.table, 0, 1, false).
+		AddItem(viewer.chart, 0, 2, false)
+	viewer.layout.SetTitle("Efficient Frontier").SetBorder(true).SetBorderPadding(0, 0, 1, 1)
+
+	return viewer
+}
+
+// Reload updates the performance data object and the optimizer
+func (viewer *FrontierViewer) Reload(performance *portfolio.Performance, optimizer *portfolio.Optimizer) {
+	viewer.performance = performance
+	viewer.optimizer = optimizer
+	viewer.result = nil
+	viewer.table.SetOffset(0, 0)
+}
+
+// HandleKey scrolls the table
+func (viewer *FrontierViewer) HandleKey(event *tcell.EventKey) bool {
+	return scrollTable(viewer.table, event)
+}
+
+// Draw refreshes the efficient portfolios and the chart of the frontier
+func (viewer *FrontierViewer) Draw() {
+	viewer.table.Clear()
+	viewer.chart.Clear()
+
+	if !viewer.performance.Ready {
+		setString(viewer.table, "Computing ...", 0, 0, tcell.ColorWhite, tview.AlignLeft)
+		return
+	}
+
+	// The frontier is only computed again once the backtest changes
+	if viewer.result != viewer.performance.Result {
+		viewer.result = viewer.performance.Result
+		viewer.frontier, viewer.err = viewer.result.ComputeFrontier(viewer.optimizer, viewer.performance.RiskFree)
+	}
+
+	if viewer.err != nil {
+		setString(viewer.table, viewer.err.Error(), 0, 0, tcell.ColorRed, tview.AlignLeft)
+		viewer.layout.ResizeItem(viewer.table, 1, 0)
+		return
+	}
+
+	frontier := viewer.frontier
+	header := []string{"Portfolio", "Return", "Volatility", "Sharpe"}
+	header = append(header, frontier.Symbols...)
+	viewer.drawHeader(header, 0)
+
+	r := 1
+	r = viewer.drawPortfolio("Current (target allocation)", frontier.Current, tcell.ColorRed, r)
+	r = viewer.drawPortfolio("Minimum Variance", frontier.MinVariance, tcell.ColorAqua, r)
+	r = viewer.drawPortfolio("Maximum Sharpe", frontier.MaxSharpe, tcell.ColorYellow, r)
+
+	if frontier.Target != nil {
+		name := fmt.Sprintf("Target Return %.2f%%", viewer.optimizer.Target)
+		if !frontier.Reachable {
+			name = fmt.Sprintf("Maximum Return (%.2f%% not reachable)", viewer.optimizer.Target)
+		}
+		r = viewer.drawPortfolio(name, frontier.Target, tcell.ColorFuchsia, r)
+	}
+
+	setString(viewer.table, "Holding Return / Volatility", r, 0, tcell.ColorWhite, tview.AlignLeft)
+	for i := range frontier.Symbols {
+		setString(viewer.table, fmt.Sprintf("%.1f%% / %.1f%%", frontier.Returns[i], frontier.Volatility[i]), r, i+4, tcell.ColorGray, tview.AlignRight)
+	}
+	r++
+
+	summary := fmt.Sprintf("Chart: return against volatility, annualized from %d months, risk-free %.2f%% (", frontier.Months, frontier.RiskFree)
+	summary += "[green]frontier[white], [gray]holdings[white], [red]● current[white], [aqua]◆ min variance[white], [yellow]★ max Sharpe[white]"
+	if frontier.Target != nil {
+		summary += ", [fuchsia]▲ target[white]"
+	}
+	setString(viewer.table, summary+")", r+1, 0, tcell.ColorWhite, tview.AlignLeft)
+
+	viewer.drawChart(frontier)
+
+	viewer.layout.ResizeItem(viewer.table, r+2, 0)
+}
+
+func (viewer *FrontierViewer) drawHeader(header []string, r int) {
+	for c := 0; c < len(header); c++ {
+		cell := tview.NewTableCell(header[c]).SetTextColor(tcell.ColorYellow).SetAttributes(tcell.AttrBold).SetExpansion(1)
+		if c == 0 {
+			cell.SetAlign(tview.AlignLeft)
+		} else {
+			cell.SetAlign((tview.AlignRight))
+		}
+		viewer.table.SetCell(r, c, cell)
+	}
+}
+
+func (viewer *FrontierViewer) drawPortfolio(name string, efficient *portfolio.EfficientPortfolio, color tcell.Color, r int) int {
+	if efficient == nil {
+		return r
+	}
+
+	setString(viewer.table, name, r, 0, color, tview.AlignLeft)
+	setPercentChange(viewer.table, efficient.Return, r, 1)
+	setPercent(viewer.table, efficient.Volatility, r, 2, tcell.ColorWhite)
+	setFloat64(viewer.table, efficient.Sharpe, "%.2f", r, 3, tcell.ColorWhite, tview.AlignRight)
+
+	for i, symbol := range viewer.frontier.Symbols {
+		setPercent(viewer.table, efficient.Weights[symbol], r, i+4, tcell.ColorWhite)
+	}
+
+	return r + 1
+}
+
+func (viewer *FrontierViewer) drawChart(frontier *portfolio.Frontier) {
+	holdings := &ChartSeries{Name: "Holdings", Color: tcell.ColorGray, X: frontier.Volatility, Y: frontier.Returns, Scatter: true}
+	viewer.chart.AddSeries(holdings)
+
+	efficient := &ChartSeries{Name: "Frontier", Color: tcell.ColorGreen, Scatter: true}
+	for _, point := range frontier.Points {
+		efficient.X = append(efficient.X, point.Volatility)
+		efficient.Y = append(efficient.Y, point.Return)
+	}
+	viewer.chart.AddSeries(efficient)
+
+	points := []struct {
+		efficient *portfolio.EfficientPortfolio
+		symbol    rune
+		color     tcell.Color
+	}{
+		{frontier.MinVariance, '◆', tcell.ColorAqua},
+		{frontier.MaxSharpe, '★', tcell.ColorYellow},
+		{frontier.Target, '▲', tcell.ColorFuchsia},
+		{frontier.Current, '●', tcell.ColorRed},
+	}
+
+	for _, point := range points {
+		if point.efficient == nil {
+			continue
+		}
+		viewer.chart.AddPoint(&ChartPoint{X: point.efficient.Volatility, Y: point.efficient.Return, Symbol: point.symbol, Color: point.color})
+	}
+}
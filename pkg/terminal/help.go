@@ -19,6 +19,24 @@ func NewHelpViewer() *HelpViewer {
 			"<0>/<m>":        "Switch to home page",
 			"<1>...<9>":      "Switch to portfolio",
 			"<r>":            "Reload profile",
+			"<t>":            "Toggle backtest/actual returns",
+			"<i>":            "Toggle nominal/real returns",
+			"<c>":            "Net worth chart",
+			"<d>":            "Drawdowns",
+			"<k>":            "Risk metrics",
+			"<l>":            "Rolling returns and volatility",
+			"<x>":            "Correlation between holdings",
+			"<p>":            "Monte Carlo projection",
+			"<w>":            "Retirement withdrawals",
+			"<f>":            "Contributions",
+			"<g>":            "Backtest period",
+			"<y>":            "Calendar returns",
+			"<Left>/<Right>": "Select trailing return",
+			"<Enter>":        "Attribution of selected return",
+			"<s>":            "Stress scenarios",
+			"<e>":            "Efficient frontier",
+			"<b>":            "Risk contributions and risk parity",
+			"<Esc>":          "Close help or detail page",
 			"<q>/<Ctrl>+<c>": "Exit",
 		},
 		table: tview.NewTable().SetBorders(false),
@@ -0,0 +1,217 @@
+package terminal
+
+import (
+	"math"
+	"time"
+
+	"github.com/cimomo/portfolio-go/pkg/portfolio"
+	"github.com/gdamore/tcell"
+	"github.com/rivo/tview"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+const secondsPerDay = 24 * 60 * 60
+
+var chartRanges = []string{"1M", "6M", "YTD", "1Y", "5Y", "All"}
+
+var chartColors = []tcell.Color{
+	tcell.ColorAqua, tcell.ColorFuchsia, tcell.ColorOrange, tcell.ColorYellow,
+	tcell.ColorLightSkyBlue, tcell.ColorViolet, tcell.ColorLightCoral, tcell.ColorLime,
+}
+
+// NetWorthViewer charts the recorded net worth of the profile and the value of each portfolio over time
+type NetWorthViewer struct {
+	profile    *portfolio.Profile
+	snapshots  *portfolio.SnapshotStore
+	rangeIndex int
+	table      *tview.Table
+	chart      *Chart
+	layout     *tview.Flex
+}
+
+// NewNetWorthViewer returns a new viewer for the net worth history of a profile
+func NewNetWorthViewer(profile *portfolio.Profile, snapshots *portfolio.SnapshotStore) *NetWorthViewer {
+	viewer := &NetWorthViewer{
+		profile:    profile,
+		snapshots:  snapshots,
+		rangeIndex: len(chartRanges) - 1,
+		table:      tview.NewTable().SetBorders(false),
+		chart:      NewChart().SetFormatters(formatChartDate, formatChartDollars),
+	}
+
+	viewer.layout = tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(viewer.table, len(profile.Portfolios)+4, 0, false).
+		AddItem(viewer.chart, 0, 1, false)
+	viewer.layout.SetTitle("Net Worth").SetBorder(true).SetBorderPadding(0, 0, 1, 1)
+
+	return viewer
+}
+
+// Reload updates the profile data object
+func (viewer *NetWorthViewer) Reload(profile *portfolio.Profile) {
+	viewer.profile = profile
+	viewer.layout.ResizeItem(viewer.table, len(profile.Portfolios)+4, 0)
+}
+
+// HandleKey selects the previous or next time range with the arrow keys
+func (viewer *NetWorthViewer) HandleKey(event *tcell.EventKey) bool {
+	switch event.Key() {
+	case tcell.KeyLeft:
+		viewer.rangeIndex = (viewer.rangeIndex + len(chartRanges) - 1) % len(chartRanges)
+	case tcell.KeyRight:
+		viewer.rangeIndex = (viewer.rangeIndex + 1) % len(chartRanges)
+	default:
+		return false
+	}
+
+	viewer.Draw()
+	return true
+}
+
+// Draw refreshes the chart and its legend for the selected time range
+func (viewer *NetWorthViewer) Draw() {
+	viewer.table.Clear()
+	viewer.chart.Clear()
+
+	viewer.drawRanges()
+	viewer.drawHeader()
+
+	history := viewer.window(viewer.snapshots.History(""))
+	viewer.drawProfile(history)
+
+	for i, port := range viewer.profile.Portfolios {
+		color := chartColors[i%len(chartColors)]
+		viewer.drawSeries(port.Name, viewer.window(viewer.snapshots.History(port.Name)), color, i+3)
+	}
+
+	// The net worth is drawn last so that it stays on top of the portfolios
+	viewer.drawSeries(viewer.profile.Name, history, tcell.ColorGreen, 2)
+}
+
+func (viewer *NetWorthViewer) drawRanges() {
+	setString(viewer.table, "Range (<Left>/<Right>)", 0, 0, tcell.ColorWhite, tview.AlignLeft)
+
+	for i, name := range chartRanges {
+		color := tcell.ColorGray
+		if i == viewer.rangeIndex {
+			color = tcell.ColorYellow
+		}
+		setString(viewer.table, name, 0, i+1, color, tview.AlignRight)
+	}
+}
+
+func (viewer *NetWorthViewer) drawHeader() {
+	var cell *tview.TableCell
+	header := []string{"Series", "Start Value", "End Value", "Net Flows", "Change", "Unrealized Gain"}
+
+	for c := 0; c < len(header); c++ {
+		cell = tview.NewTableCell(header[c]).SetTextColor(tcell.ColorYellow).SetAttributes(tcell.AttrBold).SetExpansion(1)
+		if c < 1 {
+			cell.SetAlign(tview.AlignLeft)
+		} else {
+			cell.SetAlign((tview.AlignRight))
+		}
+		viewer.table.SetCell(1, c, cell)
+	}
+}
+
+// drawProfile adds the unrealized gain band and the markers of the deposits and withdrawals of the profile
+func (viewer *NetWorthViewer) drawProfile(history []*portfolio.Snapshot) {
+	band := &ChartBand{Color: tcell.ColorDarkGreen}
+
+	for i, snapshot := range history {
+		x := chartDate(snapshot.Date)
+		band.X = append(band.X, x)
+		band.Lower = append(band.Lower, snapshot.CostBasis)
+		band.Upper = append(band.Upper, snapshot.Value)
+
+		// The flow of the first snapshot in the window happened before the window
+		if i == 0 || snapshot.Flow == 0 {
+			continue
+		}
+
+		if snapshot.Flow > 0 {
+			viewer.chart.AddMarker(&ChartMarker{X: x, Symbol: '▲', Color: tcell.ColorGreen})
+		} else {
+			viewer.chart.AddMarker(&ChartMarker{X: x, Symbol: '▼', Color: tcell.ColorRed})
+		}
+	}
+
+	viewer.chart.AddBand(band)
+}
+
+func (viewer *NetWorthViewer) drawSeries(name string, history []*portfolio.Snapshot, color tcell.Color, r int) {
+	setString(viewer.table, name, r, 0, color, tview.AlignLeft)
+
+	if len(history) == 0 {
+		setString(viewer.table, "No snapshots yet", r, 1, tcell.ColorWhite, tview.AlignRight)
+		return
+	}
+
+	series := &ChartSeries{Name: name, Color: color}
+	var flows float64
+
+	for i, snapshot := range history {
+		series.X = append(series.X, chartDate(snapshot.Date))
+		series.Y = append(series.Y, snapshot.Value)
+		if i > 0 {
+			flows += snapshot.Flow
+		}
+	}
+
+	viewer.chart.AddSeries(series)
+
+	first, last := history[0], history[len(history)-1]
+
+	setDollarAmount(viewer.table, first.Value, r, 1, tcell.ColorWhite)
+	setDollarAmount(viewer.table, last.Value, r, 2, tcell.ColorWhite)
+	setNonZeroDollarAmount(viewer.table, flows, r, 3, tcell.ColorWhite)
+	setDollarChange(viewer.table, last.Value-first.Value-flows, r, 4)
+	setDollarChange(viewer.table, last.Value-last.CostBasis, r, 5)
+}
+
+// window returns the snapshots within the selected time range, ending with the latest snapshot
+func (viewer *NetWorthViewer) window(history []*portfolio.Snapshot) []*portfolio.Snapshot {
+	if len(history) == 0 {
+		return history
+	}
+
+	end := history[len(history)-1].Date
+
+	var start time.Time
+	switch chartRanges[viewer.rangeIndex] {
+	case "1M":
+		start = end.AddDate(0, -1, 0)
+	case "6M":
+		start = end.AddDate(0, -6, 0)
+	case "YTD":
+		start = time.Date(end.Year(), time.January, 1, 0, 0, 0, 0, end.Location()).AddDate(0, 0, -1)
+	case "1Y":
+		start = end.AddDate(-1, 0, 0)
+	case "5Y":
+		start = end.AddDate(-5, 0, 0)
+	default:
+		return history
+	}
+
+	for i, snapshot := range history {
+		if !snapshot.Date.Before(start) {
+			return history[i:]
+		}
+	}
+
+	return history
+}
+
+func chartDate(date time.Time) float64 {
+	return float64(date.Unix()) / secondsPerDay
+}
+
+func formatChartDate(value float64) string {
+	return time.Unix(int64(math.Round(value*secondsPerDay)), 0).UTC().Format("2006-01-02")
+}
+
+func formatChartDollars(value float64) string {
+	return message.NewPrinter(language.English).Sprintf("$%.0f", value)
+}
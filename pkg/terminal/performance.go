@@ -1,14 +1,19 @@
 package terminal
 
 import (
+	"fmt"
+	"strconv"
+	"time"
+
 	"github.com/cimomo/portfolio-go/pkg/portfolio"
 	"github.com/gdamore/tcell"
 	"github.com/rivo/tview"
 )
 
-// PerformanceViewer displays the historic performance of a portfolio
+// PerformanceViewer displays the historic performance of a portfolio, in nominal or in real terms
 type PerformanceViewer struct {
 	performance *portfolio.Performance
+	real        bool
 	table       *tview.Table
 }
 
@@ -25,6 +30,11 @@ func (viewer *PerformanceViewer) Reload(performance *portfolio.Performance) {
 	viewer.performance = performance
 }
 
+// ToggleReal switches between the nominal and the inflation-adjusted performance
+func (viewer *PerformanceViewer) ToggleReal() {
+	viewer.real = !viewer.real
+}
+
 // Draw calculates the portfolio performance and refreshes the viewer
 func (viewer *PerformanceViewer) Draw() {
 	viewer.table.Clear()
@@ -35,9 +45,19 @@ func (viewer *PerformanceViewer) Draw() {
 func (viewer *PerformanceViewer) drawHeader() {
 	var cell *tview.TableCell
 	header := []string{
-		"Portfolio", "Start Date", "Initial Balance", "Final Balance",
+		"Portfolio", "Dates", "Initial Balance", "Final Balance",
 		"CAGR", "Stdev",
-		"Best Year", "Worst Year", "Max Drawdown", "Sharpe Ratio",
+		"Best Year", "Worst Year", "Max Drawdown", "Drawdown Length", "Sharpe Ratio",
+		"Rebalances", "Turnover",
+	}
+
+	if viewer.real {
+		header = []string{
+			"Portfolio (Real)", "Dates", "Initial Balance", "Final Balance", "Purchasing Power",
+			"Real CAGR", "Stdev",
+			"Real Best Year", "Real Worst Year", "Max Drawdown", "Drawdown Length", "Sharpe Ratio",
+			"Inflation",
+		}
 	}
 
 	for c := 0; c < len(header); c++ {
@@ -58,8 +78,14 @@ func (viewer *PerformanceViewer) drawPerformance() {
 		return
 	}
 
-	setString(viewer.table, viewer.performance.Portfolio.Name, 1, 0, tcell.ColorWhite, tview.AlignLeft)
-	setString(viewer.table, viewer.performance.StartDate.Format("2006-01-02"), 1, 1, tcell.ColorWhite, tview.AlignRight)
+	if viewer.real {
+		viewer.drawReal(viewer.performance.Result, 1)
+		viewer.drawReal(viewer.performance.Benchmark, 2)
+		return
+	}
+
+	setString(viewer.table, backtestName(viewer.performance.Result), 1, 0, tcell.ColorWhite, tview.AlignLeft)
+	setBacktestDates(viewer.table, viewer.performance, viewer.performance.Result, 1, 1)
 	setDollarAmount(viewer.table, viewer.performance.InitialBalance, 1, 2, tcell.ColorWhite)
 	setDollarAmount(viewer.table, viewer.performance.Result.FinalBalance, 1, 3, tcell.ColorWhite)
 	setPercentChange(viewer.table, viewer.performance.Result.CAGR, 1, 4)
@@ -67,10 +93,13 @@ func (viewer *PerformanceViewer) drawPerformance() {
 	setPercentChange(viewer.table, viewer.performance.Result.BestYear, 1, 6)
 	setPercentChange(viewer.table, viewer.performance.Result.WorstYear, 1, 7)
 	setPercentChange(viewer.table, viewer.performance.Result.MaxDrawdown, 1, 8)
-	setQuantity(viewer.table, viewer.performance.Result.SharpeRatio, 1, 9, tview.AlignRight)
+	setDrawdownLength(viewer.table, viewer.performance.Result.Drawdowns, 1, 9)
+	setQuantity(viewer.table, viewer.performance.Result.SharpeRatio, 1, 10, tview.AlignRight)
+	setString(viewer.table, strconv.Itoa(viewer.performance.Result.Rebalances), 1, 11, tcell.ColorWhite, tview.AlignRight)
+	setPercent(viewer.table, viewer.performance.Result.Turnover, 1, 12, tcell.ColorWhite)
 
 	setString(viewer.table, viewer.performance.Benchmark.Portfolio.Name, 2, 0, tcell.ColorWhite, tview.AlignLeft)
-	setString(viewer.table, viewer.performance.StartDate.Format("2006-01-02"), 2, 1, tcell.ColorWhite, tview.AlignRight)
+	setBacktestDates(viewer.table, viewer.performance, viewer.performance.Benchmark, 2, 1)
 	setDollarAmount(viewer.table, viewer.performance.InitialBalance, 2, 2, tcell.ColorWhite)
 	setDollarAmount(viewer.table, viewer.performance.Benchmark.FinalBalance, 2, 3, tcell.ColorWhite)
 	setPercentChange(viewer.table, viewer.performance.Benchmark.CAGR, 2, 4)
@@ -78,5 +107,79 @@ func (viewer *PerformanceViewer) drawPerformance() {
 	setPercentChange(viewer.table, viewer.performance.Benchmark.BestYear, 2, 6)
 	setPercentChange(viewer.table, viewer.performance.Benchmark.WorstYear, 2, 7)
 	setPercentChange(viewer.table, viewer.performance.Benchmark.MaxDrawdown, 2, 8)
-	setQuantity(viewer.table, viewer.performance.Benchmark.SharpeRatio, 2, 9, tview.AlignRight)
+	setDrawdownLength(viewer.table, viewer.performance.Benchmark.Drawdowns, 2, 9)
+	setQuantity(viewer.table, viewer.performance.Benchmark.SharpeRatio, 2, 10, tview.AlignRight)
+	setString(viewer.table, strconv.Itoa(viewer.performance.Benchmark.Rebalances), 2, 11, tcell.ColorWhite, tview.AlignRight)
+	setPercent(viewer.table, viewer.performance.Benchmark.Turnover, 2, 12, tcell.ColorWhite)
+}
+
+func (viewer *PerformanceViewer) drawReal(result *portfolio.PerformanceResult, r int) {
+	setString(viewer.table, backtestName(result), r, 0, tcell.ColorWhite, tview.AlignLeft)
+
+	if result.Real == nil {
+		setString(viewer.table, "No CPI data", r, 1, tcell.ColorWhite, tview.AlignRight)
+		return
+	}
+
+	setBacktestDates(viewer.table, viewer.performance, result, r, 1)
+	setDollarAmount(viewer.table, viewer.performance.InitialBalance, r, 2, tcell.ColorWhite)
+	setDollarAmount(viewer.table, result.FinalBalance, r, 3, tcell.ColorWhite)
+	setDollarAmount(viewer.table, result.Real.FinalBalance, r, 4, tcell.ColorWhite)
+	setPercentChange(viewer.table, result.Real.CAGR, r, 5)
+	setPercent(viewer.table, result.Real.Stdev, r, 6, tcell.ColorWhite)
+	setPercentChange(viewer.table, result.Real.BestYear, r, 7)
+	setPercentChange(viewer.table, result.Real.WorstYear, r, 8)
+	setPercentChange(viewer.table, result.Real.MaxDrawdown, r, 9)
+	setDrawdownLength(viewer.table, result.Real.Drawdowns, r, 10)
+	setQuantity(viewer.table, result.Real.SharpeRatio, r, 11, tview.AlignRight)
+	setPercent(viewer.table, result.Real.Inflation, r, 12, tcell.ColorWhite)
+}
+
+// setDrawdownLength shows the length of the maximum drawdown, from the peak until the recovery
+func setDrawdownLength(table *tview.Table, drawdowns []*portfolio.Drawdown, r int, c int) {
+	if len(drawdowns) == 0 {
+		setString(table, "-", r, c, tcell.ColorWhite, tview.AlignRight)
+		return
+	}
+
+	setString(table, formatMonths(drawdowns[0].Length, drawdowns[0].Recovered), r, c, tcell.ColorWhite, tview.AlignRight)
+}
+
+// setBacktestDates shows the dates of the backtest, highlighted with a "*" if some of its holdings are
+// simulated by proxies before their inception
+func setBacktestDates(table *tview.Table, performance *portfolio.Performance, result *portfolio.PerformanceResult, r int, c int) {
+	if len(result.Simulated) > 0 {
+		setString(table, formatBacktestDates(performance)+"*", r, c, tcell.ColorOrange, tview.AlignRight)
+		return
+	}
+
+	setString(table, formatBacktestDates(performance), r, c, tcell.ColorWhite, tview.AlignRight)
+}
+
+// formatBacktestDates formats the start date of the backtest, followed by its end date if it ends before now,
+// and marks daily backtests
+func formatBacktestDates(performance *portfolio.Performance) string {
+	dates := performance.StartDate.Format("2006-01-02")
+
+	period := performance.Period
+	if period == nil {
+		return dates
+	}
+
+	if !period.End.IsZero() && performance.EndDate.Before(time.Now().AddDate(0, 0, -1)) {
+		dates += " to " + performance.EndDate.Format("2006-01-02")
+	}
+	if period.Daily {
+		dates += " daily"
+	}
+
+	return dates
+}
+
+// backtestName returns the name of the backtested portfolio, followed by its strategy if it has one
+func backtestName(result *portfolio.PerformanceResult) string {
+	if result.Strategy == "" {
+		return result.Portfolio.Name
+	}
+	return fmt.Sprintf("%s (%s)", result.Portfolio.Name, result.Strategy)
 }
@@ -0,0 +1,90 @@
+package terminal
+
+import (
+	"github.com/cimomo/portfolio-go/pkg/portfolio"
+	"github.com/gdamore/tcell"
+	"github.com/rivo/tview"
+)
+
+const (
+	periodStartLabel = "Start (YYYY-MM-DD)"
+	periodEndLabel   = "End (YYYY-MM-DD)"
+	periodDailyLabel = "Daily bars"
+)
+
+// PeriodViewer prompts for the dates and the granularity of the backtests
+type PeriodViewer struct {
+	form   *tview.Form
+	note   *tview.TextView
+	layout *tview.Flex
+	apply  func(period *portfolio.Period)
+	cancel func()
+}
+
+// NewPeriodViewer returns a new prompt that calls apply with the selected period, or cancel when it is closed
+func NewPeriodViewer(apply func(period *portfolio.Period), cancel func()) *PeriodViewer {
+	viewer := &PeriodViewer{
+		form:   tview.NewForm().SetItemPadding(0),
+		note:   tview.NewTextView().SetTextColor(tcell.ColorRed),
+		apply:  apply,
+		cancel: cancel,
+	}
+
+	viewer.form.AddInputField(periodStartLabel, "", 12, nil, nil).
+		AddInputField(periodEndLabel, "", 12, nil, nil).
+		AddCheckbox(periodDailyLabel, false, nil).
+		AddButton("Apply", viewer.submit).
+		AddButton("Reset", viewer.reset).
+		AddButton("Cancel", viewer.cancel).
+		SetCancelFunc(viewer.cancel)
+
+	viewer.layout = tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(viewer.form, 0, 1, true).
+		AddItem(viewer.note, 1, 0, false)
+	viewer.layout.SetTitle("Backtest Period (leave empty for all)").SetBorder(true).SetBorderPadding(0, 0, 1, 1)
+
+	return viewer
+}
+
+// Reload fills the prompt with the current period
+func (viewer *PeriodViewer) Reload(period *portfolio.Period) {
+	start, end, daily := "", "", false
+	if period != nil {
+		if !period.Start.IsZero() {
+			start = period.Start.Format("2006-01-02")
+		}
+		if !period.End.IsZero() {
+			end = period.End.Format("2006-01-02")
+		}
+		daily = period.Daily
+	}
+
+	viewer.form.GetFormItemByLabel(periodStartLabel).(*tview.InputField).SetText(start)
+	viewer.form.GetFormItemByLabel(periodEndLabel).(*tview.InputField).SetText(end)
+	viewer.form.GetFormItemByLabel(periodDailyLabel).(*tview.Checkbox).SetChecked(daily)
+	viewer.form.SetFocus(0)
+	viewer.note.Clear()
+}
+
+func (viewer *PeriodViewer) submit() {
+	start := viewer.form.GetFormItemByLabel(periodStartLabel).(*tview.InputField).GetText()
+	end := viewer.form.GetFormItemByLabel(periodEndLabel).(*tview.InputField).GetText()
+	daily := viewer.form.GetFormItemByLabel(periodDailyLabel).(*tview.Checkbox).IsChecked()
+
+	if start == "" && end == "" && !daily {
+		viewer.apply(nil)
+		return
+	}
+
+	period, err := portfolio.ParsePeriod(start, end, daily)
+	if err != nil {
+		viewer.note.SetText(err.Error())
+		return
+	}
+
+	viewer.apply(period)
+}
+
+func (viewer *PeriodViewer) reset() {
+	viewer.apply(nil)
+}
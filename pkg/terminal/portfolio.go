@@ -77,6 +77,16 @@ func (viewer *PortfolioViewer) drawPortfolio() {
 		r++
 	}
 
+	if port.Cash != 0 {
+		setString(viewer.table, "Cash", r, 0, tcell.ColorWhite, tview.AlignLeft)
+		setDollarAmount(viewer.table, port.Cash, r, 7, tcell.ColorWhite)
+		if port.Status.Value != 0 {
+			setPercent(viewer.table, (port.Cash/port.Status.Value)*100, r, 11, tcell.ColorWhite)
+		}
+
+		r++
+	}
+
 	setString(viewer.table, "TOTAL", r, 0, tcell.ColorYellow, tview.AlignLeft)
 	setPercentChange(viewer.table, port.Status.RegularMarketChangePercent, r, 6)
 	setDollarAmount(viewer.table, port.Status.Value, r, 7, tcell.ColorYellow)
@@ -0,0 +1,173 @@
+package terminal
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/cimomo/portfolio-go/pkg/portfolio"
+	"github.com/gdamore/tcell"
+	"github.com/rivo/tview"
+)
+
+// ProjectionViewer displays a Monte Carlo projection of the value of a portfolio, with the percentiles of the
+// ending balance and a chart of the percentile bands over time
+type ProjectionViewer struct {
+	performance *portfolio.Performance
+	settings    *portfolio.Projection
+	balance     float64
+	years       int
+	model       portfolio.ProjectionModel
+	table       *tview.Table
+	chart       *Chart
+	layout      *tview.Flex
+}
+
+// NewProjectionViewer returns a new viewer for the projection of a portfolio with the given settings
+func NewProjectionViewer(performance *portfolio.Performance, settings *portfolio.Projection) *ProjectionViewer {
+	viewer := &ProjectionViewer{
+		performance: performance,
+		settings:    settings,
+		years:       settings.Years,
+		model:       settings.Model,
+		table:       tview.NewTable().SetBorders(false),
+		chart:       NewChart().SetFormatters(formatChartDate, formatChartDollars),
+	}
+
+	viewer.layout = tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(viewer.table, 4, 0, false).
+		AddItem(viewer.chart, 0, 1, false)
+	viewer.layout.SetTitle("Projection").SetBorder(true).SetBorderPadding(0, 0, 1, 1)
+
+	return viewer
+}
+
+// Reload updates the performance data object and the current value of the portfolio
+func (viewer *ProjectionViewer) Reload(performance *portfolio.Performance, balance float64) {
+	viewer.performance = performance
+	viewer.balance = balance
+}
+
+// HandleKey shortens or extends the horizon by five years with the left and right keys, and switches
+// between the bootstrap and the parametric models with the up and down keys
+func (viewer *ProjectionViewer) HandleKey(event *tcell.EventKey) bool {
+	switch event.Key() {
+	case tcell.KeyLeft:
+		if viewer.years <= 5 {
+			return true
+		}
+		viewer.years -= 5
+	case tcell.KeyRight:
+		viewer.years += 5
+	case tcell.KeyUp, tcell.KeyDown:
+		if viewer.model == portfolio.ProjectionBootstrap {
+			viewer.model = portfolio.ProjectionParametric
+		} else {
+			viewer.model = portfolio.ProjectionBootstrap
+		}
+	default:
+		return false
+	}
+
+	viewer.Draw()
+	return true
+}
+
+// Draw runs the projection and refreshes the table and the chart
+func (viewer *ProjectionViewer) Draw() {
+	viewer.table.Clear()
+	viewer.chart.Clear()
+	viewer.drawHeader()
+
+	if !viewer.performance.Ready {
+		setString(viewer.table, "Computing ...", 2, 0, tcell.ColorWhite, tview.AlignLeft)
+		return
+	}
+
+	projection := *viewer.settings
+	projection.InitialBalance = viewer.balance
+	projection.Years = viewer.years
+	projection.Model = viewer.model
+
+	err := projection.Run(viewer.performance.Result)
+	if err != nil {
+		setString(viewer.table, err.Error(), 2, 0, tcell.ColorRed, tview.AlignLeft)
+		return
+	}
+
+	viewer.drawProjection(&projection)
+	viewer.drawChart(&projection)
+}
+
+func (viewer *ProjectionViewer) drawHeader() {
+	var cell *tview.TableCell
+	header := []string{"Initial Balance", "Contribution", "Total Invested"}
+	for _, percentile := range portfolio.ProjectionPercentiles {
+		header = append(header, fmt.Sprintf("%.0fth", percentile))
+	}
+	header = append(header, "Goal", "Probability")
+
+	for c := 0; c < len(header); c++ {
+		cell = tview.NewTableCell(header[c]).SetTextColor(tcell.ColorYellow).SetAttributes(tcell.AttrBold).SetExpansion(1)
+		cell.SetAlign((tview.AlignRight))
+		viewer.table.SetCell(1, c, cell)
+	}
+
+	setString(viewer.table, "Ending Balance", 0, 3, tcell.ColorYellow, tview.AlignRight)
+}
+
+func (viewer *ProjectionViewer) drawProjection(projection *portfolio.Projection) {
+	title := fmt.Sprintf("Projection: %s over %d years, %s model (<Left>/<Right> years, <Up>/<Down> model)",
+		viewer.performance.Portfolio.Name, projection.Years, projection.Model)
+	viewer.layout.SetTitle(title)
+
+	setDollarAmount(viewer.table, projection.InitialBalance, 2, 0, tcell.ColorWhite)
+	setDollarAmount(viewer.table, projection.Contribution, 2, 1, tcell.ColorWhite)
+	setDollarAmount(viewer.table, projection.Contributed, 2, 2, tcell.ColorWhite)
+
+	c := 3
+	for _, ending := range projection.Ending {
+		setDollarAmount(viewer.table, ending, 2, c, tcell.ColorWhite)
+		c++
+	}
+
+	if math.IsNaN(projection.Probability) {
+		setString(viewer.table, "-", 2, c, tcell.ColorWhite, tview.AlignRight)
+	} else {
+		setDollarAmount(viewer.table, projection.Goal, 2, c, tcell.ColorWhite)
+	}
+	setOptionalPercent(viewer.table, projection.Probability, 2, c+1)
+}
+
+func (viewer *ProjectionViewer) drawChart(projection *portfolio.Projection) {
+	start := time.Now()
+	months := len(projection.Bands[0])
+
+	x := make([]float64, months)
+	for m := range x {
+		x[m] = chartDate(start.AddDate(0, m, 0))
+	}
+
+	// The bands are the 10th to 90th and the 25th to 75th percentiles, drawn from the outermost
+	last := len(projection.Bands) - 1
+	colors := []tcell.Color{tcell.ColorDarkSlateGray, tcell.ColorDarkGreen}
+
+	for i := 0; i < last-i; i++ {
+		viewer.chart.AddBand(&ChartBand{
+			Color: colors[i%len(colors)],
+			X:     x,
+			Lower: projection.Bands[i],
+			Upper: projection.Bands[last-i],
+		})
+	}
+
+	viewer.chart.AddSeries(&ChartSeries{Name: "Median", Color: tcell.ColorGreen, X: x, Y: projection.Bands[last/2]})
+
+	if projection.Goal > 0 {
+		goal := make([]float64, months)
+		for m := range goal {
+			goal[m] = projection.Goal
+		}
+		viewer.chart.AddSeries(&ChartSeries{Name: "Goal", Color: tcell.ColorYellow, X: x, Y: goal})
+	}
+}
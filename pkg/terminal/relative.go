@@ -0,0 +1,75 @@
+package terminal
+
+import (
+	"github.com/cimomo/portfolio-go/pkg/portfolio"
+	"github.com/gdamore/tcell"
+	"github.com/rivo/tview"
+)
+
+// RelativeViewer displays the statistics of a portfolio relative to its benchmark
+type RelativeViewer struct {
+	performance *portfolio.Performance
+	table       *tview.Table
+}
+
+// NewRelativeViewer returns a new viewer for the benchmark-relative statistics of a portfolio
+func NewRelativeViewer(performance *portfolio.Performance) *RelativeViewer {
+	return &RelativeViewer{
+		performance: performance,
+		table:       tview.NewTable().SetBorders(true),
+	}
+}
+
+// Reload updates the performance data object
+func (viewer *RelativeViewer) Reload(performance *portfolio.Performance) {
+	viewer.performance = performance
+}
+
+// Draw refreshes the viewer
+func (viewer *RelativeViewer) Draw() {
+	viewer.table.Clear()
+	viewer.drawHeader()
+	viewer.drawRelative()
+}
+
+func (viewer *RelativeViewer) drawHeader() {
+	var cell *tview.TableCell
+	title := "Relative"
+	if viewer.performance.Ready {
+		title = "vs. " + viewer.performance.Benchmark.Portfolio.Name
+	}
+
+	header := []string{
+		title, "Beta", "Alpha", "Correlation", "R-Squared",
+		"Tracking Error", "Information Ratio", "Upside Capture", "Downside Capture",
+	}
+
+	for c := 0; c < len(header); c++ {
+		cell = tview.NewTableCell(header[c]).SetTextColor(tcell.ColorYellow).SetAttributes(tcell.AttrBold).SetExpansion(1)
+		if c < 1 {
+			cell.SetAlign(tview.AlignLeft)
+		} else {
+			cell.SetAlign((tview.AlignRight))
+		}
+		viewer.table.SetCell(0, c, cell)
+	}
+}
+
+func (viewer *RelativeViewer) drawRelative() {
+	if !viewer.performance.Ready {
+		setString(viewer.table, "Computing ...", 1, 0, tcell.ColorWhite, tview.AlignLeft)
+		return
+	}
+
+	relative := viewer.performance.Relative
+
+	setString(viewer.table, viewer.performance.Portfolio.Name, 1, 0, tcell.ColorWhite, tview.AlignLeft)
+	setRatio(viewer.table, relative.Beta, 1, 1)
+	setReturn(viewer.table, relative.Alpha, 1, 2)
+	setRatio(viewer.table, relative.Correlation, 1, 3)
+	setOptionalPercent(viewer.table, relative.RSquared, 1, 4)
+	setPercent(viewer.table, relative.TrackingError, 1, 5, tcell.ColorWhite)
+	setRatio(viewer.table, relative.InformationRatio, 1, 6)
+	setOptionalPercent(viewer.table, relative.UpsideCapture, 1, 7)
+	setOptionalPercent(viewer.table, relative.DownsideCapture, 1, 8)
+}
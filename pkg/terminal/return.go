@@ -6,36 +6,87 @@ import (
 	"github.com/rivo/tview"
 )
 
-// ReturnViewer displays the trailing returns of a portfolio
+// ReturnViewer displays the trailing returns of a portfolio, either backtested from the target
+// allocation or actually achieved according to the recorded snapshots. The backtested returns
+// can be adjusted for inflation. The selected trailing period drills down into its return attribution.
 type ReturnViewer struct {
-	performance *portfolio.Performance
-	table       *tview.Table
+	performance   *portfolio.Performance
+	moneyWeighted []*portfolio.MoneyWeighted
+	snapshots     *portfolio.SnapshotStore
+	snapshotName  string
+	actual        bool
+	real          bool
+	period        int
+	table         *tview.Table
 }
 
-// NewReturnViewer returns a new viewer for the trailing returns of a portfolio
-func NewReturnViewer(performance *portfolio.Performance) *ReturnViewer {
+// NewReturnViewer returns a new viewer for the trailing returns of a portfolio, followed by
+// its actual money-weighted returns
+func NewReturnViewer(performance *portfolio.Performance, moneyWeighted ...*portfolio.MoneyWeighted) *ReturnViewer {
 	return &ReturnViewer{
-		performance: performance,
-		table:       tview.NewTable().SetBorders(true),
+		performance:   performance,
+		moneyWeighted: moneyWeighted,
+		period:        8,
+		table:         tview.NewTable().SetBorders(true),
 	}
 }
 
 // Reload updates the performance data object
-func (viewer *ReturnViewer) Reload(performance *portfolio.Performance) {
+func (viewer *ReturnViewer) Reload(performance *portfolio.Performance, moneyWeighted ...*portfolio.MoneyWeighted) {
 	viewer.performance = performance
+	viewer.moneyWeighted = moneyWeighted
+}
+
+// SetSnapshots sets the snapshots of the portfolio, or of the profile if the name is empty, for the actual returns
+func (viewer *ReturnViewer) SetSnapshots(snapshots *portfolio.SnapshotStore, name string) *ReturnViewer {
+	viewer.snapshots = snapshots
+	viewer.snapshotName = name
+	return viewer
+}
+
+// ToggleActual switches between the backtested and the actual returns
+func (viewer *ReturnViewer) ToggleActual() {
+	viewer.actual = !viewer.actual
+}
+
+// ToggleReal switches between the nominal and the inflation-adjusted backtested returns
+func (viewer *ReturnViewer) ToggleReal() {
+	viewer.real = !viewer.real
+}
+
+// SelectPeriod moves the selected trailing period to the left or to the right
+func (viewer *ReturnViewer) SelectPeriod(delta int) {
+	viewer.period = (viewer.period + delta + 9) % 9
+}
+
+// Period returns the index of the selected trailing period, from 0 for 1-Month to 8 for Max
+func (viewer *ReturnViewer) Period() int {
+	return viewer.period
 }
 
 // Draw calculates the portfolio performance and refreshes the viewer
 func (viewer *ReturnViewer) Draw() {
 	viewer.table.Clear()
 	viewer.drawHeader()
-	viewer.drawPerformance()
+	if viewer.actual {
+		viewer.drawTimeWeighted()
+	} else {
+		viewer.drawPerformance()
+	}
+	viewer.drawMoneyWeighted(3)
 }
 
 func (viewer *ReturnViewer) drawHeader() {
 	var cell *tview.TableCell
+	title := "Backtest"
+	if viewer.actual {
+		title = "Actual"
+	} else if viewer.real {
+		title = "Backtest (Real)"
+	}
+
 	header := []string{
-		"Portfolio", "1-Month", "3-Month", "6-Month", "YTD",
+		title, "1-Month", "3-Month", "6-Month", "YTD",
 		"1-Year", "3-Year", "5-Year", "10-Year", "Max",
 	}
 
@@ -46,6 +97,9 @@ func (viewer *ReturnViewer) drawHeader() {
 		} else {
 			cell.SetAlign((tview.AlignRight))
 		}
+		if c == viewer.period+1 {
+			cell.SetAttributes(tcell.AttrBold | tcell.AttrReverse)
+		}
 		viewer.table.SetCell(0, c, cell)
 	}
 }
@@ -58,15 +112,69 @@ func (viewer *ReturnViewer) drawPerformance() {
 	}
 
 	setString(viewer.table, viewer.performance.Portfolio.Name, 1, 0, tcell.ColorWhite, tview.AlignLeft)
-	setPercentChange(viewer.table, viewer.performance.Result.Return.OneMonth, 1, 1)
-	setPercentChange(viewer.table, viewer.performance.Result.Return.ThreeMonth, 1, 2)
-	setPercentChange(viewer.table, viewer.performance.Result.Return.SixMonth, 1, 3)
-	setPercentChange(viewer.table, viewer.performance.Result.Return.YTD, 1, 4)
-	setPercentChange(viewer.table, viewer.performance.Result.Return.OneYear, 1, 5)
-	setPercentChange(viewer.table, viewer.performance.Result.Return.ThreeYear, 1, 6)
-	setPercentChange(viewer.table, viewer.performance.Result.Return.FiveYear, 1, 7)
-	setPercentChange(viewer.table, viewer.performance.Result.Return.TenYear, 1, 8)
-	setPercentChange(viewer.table, viewer.performance.Result.Return.Max, 1, 9)
+	setString(viewer.table, viewer.performance.Benchmark.Portfolio.Name, 2, 0, tcell.ColorWhite, tview.AlignLeft)
+
+	portfolioReturn := viewer.performance.Result.Return
+	benchmarkReturn := viewer.performance.Benchmark.Return
+
+	if viewer.real {
+		if viewer.performance.Result.Real == nil || viewer.performance.Benchmark.Real == nil {
+			setString(viewer.table, "No CPI data", 1, 1, tcell.ColorWhite, tview.AlignRight)
+			setString(viewer.table, "No CPI data", 2, 1, tcell.ColorWhite, tview.AlignRight)
+			return
+		}
+
+		portfolioReturn = viewer.performance.Result.Real.Return
+		benchmarkReturn = viewer.performance.Benchmark.Real.Return
+	}
+
+	setPercentChange(viewer.table, portfolioReturn.OneMonth, 1, 1)
+	setPercentChange(viewer.table, portfolioReturn.ThreeMonth, 1, 2)
+	setPercentChange(viewer.table, portfolioReturn.SixMonth, 1, 3)
+	setPercentChange(viewer.table, portfolioReturn.YTD, 1, 4)
+	setPercentChange(viewer.table, portfolioReturn.OneYear, 1, 5)
+	setPercentChange(viewer.table, portfolioReturn.ThreeYear, 1, 6)
+	setPercentChange(viewer.table, portfolioReturn.FiveYear, 1, 7)
+	setPercentChange(viewer.table, portfolioReturn.TenYear, 1, 8)
+	setPercentChange(viewer.table, portfolioReturn.Max, 1, 9)
+
+	setPercentChange(viewer.table, benchmarkReturn.OneMonth, 2, 1)
+	setPercentChange(viewer.table, benchmarkReturn.ThreeMonth, 2, 2)
+	setPercentChange(viewer.table, benchmarkReturn.SixMonth, 2, 3)
+	setPercentChange(viewer.table, benchmarkReturn.YTD, 2, 4)
+	setPercentChange(viewer.table, benchmarkReturn.OneYear, 2, 5)
+	setPercentChange(viewer.table, benchmarkReturn.ThreeYear, 2, 6)
+	setPercentChange(viewer.table, benchmarkReturn.FiveYear, 2, 7)
+	setPercentChange(viewer.table, benchmarkReturn.TenYear, 2, 8)
+	setPercentChange(viewer.table, benchmarkReturn.Max, 2, 9)
+}
+
+func (viewer *ReturnViewer) drawTimeWeighted() {
+	name := viewer.performance.Portfolio.Name + " (TWR)"
+	setString(viewer.table, name, 1, 0, tcell.ColorWhite, tview.AlignLeft)
+
+	if viewer.snapshots != nil {
+		twr, err := portfolio.ComputeTimeWeighted(viewer.snapshots.History(viewer.snapshotName))
+		if err == nil {
+			setReturn(viewer.table, twr.OneMonth, 1, 1)
+			setReturn(viewer.table, twr.ThreeMonth, 1, 2)
+			setReturn(viewer.table, twr.SixMonth, 1, 3)
+			setReturn(viewer.table, twr.YTD, 1, 4)
+			setReturn(viewer.table, twr.OneYear, 1, 5)
+			setReturn(viewer.table, twr.ThreeYear, 1, 6)
+			setReturn(viewer.table, twr.FiveYear, 1, 7)
+			setReturn(viewer.table, twr.TenYear, 1, 8)
+			setReturn(viewer.table, twr.Max, 1, 9)
+		} else {
+			setString(viewer.table, "Not enough snapshots yet", 1, 1, tcell.ColorWhite, tview.AlignRight)
+		}
+	}
+
+	// The trailing returns of the benchmark are actual returns too
+	if !viewer.performance.Ready {
+		setString(viewer.table, "Computing ...", 2, 0, tcell.ColorWhite, tview.AlignLeft)
+		return
+	}
 
 	setString(viewer.table, viewer.performance.Benchmark.Portfolio.Name, 2, 0, tcell.ColorWhite, tview.AlignLeft)
 	setPercentChange(viewer.table, viewer.performance.Benchmark.Return.OneMonth, 2, 1)
@@ -79,3 +187,39 @@ func (viewer *ReturnViewer) drawPerformance() {
 	setPercentChange(viewer.table, viewer.performance.Benchmark.Return.TenYear, 2, 8)
 	setPercentChange(viewer.table, viewer.performance.Benchmark.Return.Max, 2, 9)
 }
+
+func (viewer *ReturnViewer) drawMoneyWeighted(r int) {
+	for _, mw := range viewer.moneyWeighted {
+		name := mw.Portfolio.Name + " (XIRR)"
+		if mw.Cash != 0 {
+			name = mw.Portfolio.Name + " + Cash (XIRR)"
+		}
+
+		if mw.Err != nil {
+			setString(viewer.table, name, r, 0, tcell.ColorWhite, tview.AlignLeft)
+			setString(viewer.table, mw.Err.Error(), r, 1, tcell.ColorRed, tview.AlignLeft)
+			r++
+			continue
+		}
+
+		if !mw.Ready {
+			setString(viewer.table, name, r, 0, tcell.ColorWhite, tview.AlignLeft)
+			setString(viewer.table, "Computing ...", r, 1, tcell.ColorWhite, tview.AlignRight)
+			r++
+			continue
+		}
+
+		setString(viewer.table, name, r, 0, tcell.ColorWhite, tview.AlignLeft)
+		setReturn(viewer.table, mw.Return.OneMonth, r, 1)
+		setReturn(viewer.table, mw.Return.ThreeMonth, r, 2)
+		setReturn(viewer.table, mw.Return.SixMonth, r, 3)
+		setReturn(viewer.table, mw.Return.YTD, r, 4)
+		setReturn(viewer.table, mw.Return.OneYear, r, 5)
+		setReturn(viewer.table, mw.Return.ThreeYear, r, 6)
+		setReturn(viewer.table, mw.Return.FiveYear, r, 7)
+		setReturn(viewer.table, mw.Return.TenYear, r, 8)
+		setReturn(viewer.table, mw.Return.Max, r, 9)
+
+		r++
+	}
+}
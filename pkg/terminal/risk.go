@@ -0,0 +1,125 @@
+package terminal
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/cimomo/portfolio-go/pkg/portfolio"
+	"github.com/gdamore/tcell"
+	"github.com/rivo/tview"
+)
+
+// RiskViewer displays the risk-adjusted metrics of a portfolio and its benchmark in a scrollable panel
+type RiskViewer struct {
+	performance *portfolio.Performance
+	table       *tview.Table
+}
+
+// NewRiskViewer returns a new viewer for the risk metrics of a portfolio
+func NewRiskViewer(performance *portfolio.Performance) *RiskViewer {
+	table := tview.NewTable().SetBorders(false).SetFixed(1, 1)
+	table.SetTitle("Risk Metrics (<Up>/<Down> to scroll)").SetBorder(true).SetBorderPadding(0, 0, 1, 1)
+
+	return &RiskViewer{
+		performance: performance,
+		table:       table,
+	}
+}
+
+// Reload updates the performance data object
+func (viewer *RiskViewer) Reload(performance *portfolio.Performance) {
+	viewer.performance = performance
+	viewer.table.SetOffset(0, 0)
+}
+
+// HandleKey scrolls the metrics with the arrow and page keys
+func (viewer *RiskViewer) HandleKey(event *tcell.EventKey) bool {
+	return scrollTable(viewer.table, event)
+}
+
+// Draw refreshes the risk metrics
+func (viewer *RiskViewer) Draw() {
+	viewer.table.Clear()
+
+	if !viewer.performance.Ready {
+		setString(viewer.table, "Computing ...", 0, 0, tcell.ColorWhite, tview.AlignLeft)
+		return
+	}
+
+	result := viewer.performance.Result
+	benchmark := viewer.performance.Benchmark
+
+	viewer.drawHeader(result.Portfolio.Name, benchmark.Portfolio.Name)
+	viewer.drawNames(result.Risk.ValueAtRisk)
+	viewer.drawResult(result, 1)
+	viewer.drawResult(benchmark, 2)
+}
+
+func (viewer *RiskViewer) drawNames(valueAtRisk []*portfolio.ValueAtRisk) {
+	names := []string{
+		"CAGR", "Standard Deviation", "Downside Deviation", "Max Drawdown",
+		"Sharpe Ratio", "Sortino Ratio", "Calmar Ratio", "Ulcer Index", "Ulcer Performance Index",
+		"Skewness", "Excess Kurtosis",
+	}
+
+	for _, v := range valueAtRisk {
+		confidence := fmt.Sprintf("%g%%", v.Confidence)
+		names = append(names,
+			"Historical VaR "+confidence+" (Monthly)",
+			"Historical CVaR "+confidence+" (Monthly)",
+			"Parametric VaR "+confidence+" (Monthly)",
+			"Parametric CVaR "+confidence+" (Monthly)")
+	}
+
+	for r, name := range names {
+		setString(viewer.table, name, r+1, 0, tcell.ColorWhite, tview.AlignLeft)
+	}
+}
+
+func (viewer *RiskViewer) drawResult(result *portfolio.PerformanceResult, c int) {
+	setPercentChange(viewer.table, result.CAGR, 1, c)
+	setPercent(viewer.table, result.Stdev, 2, c, tcell.ColorWhite)
+	setPercent(viewer.table, result.Risk.DownsideDeviation, 3, c, tcell.ColorWhite)
+	setPercentChange(viewer.table, result.MaxDrawdown, 4, c)
+	setRatio(viewer.table, result.SharpeRatio, 5, c)
+	setRatio(viewer.table, result.Risk.SortinoRatio, 6, c)
+	setRatio(viewer.table, result.Risk.CalmarRatio, 7, c)
+	setRatio(viewer.table, result.Risk.UlcerIndex, 8, c)
+	setRatio(viewer.table, result.Risk.UlcerPerformance, 9, c)
+	setRatio(viewer.table, result.Risk.Skewness, 10, c)
+	setRatio(viewer.table, result.Risk.Kurtosis, 11, c)
+
+	r := 12
+	for _, valueAtRisk := range result.Risk.ValueAtRisk {
+		setPercentChange(viewer.table, valueAtRisk.Historical, r, c)
+		setPercentChange(viewer.table, valueAtRisk.HistoricalCVaR, r+1, c)
+		setPercentChange(viewer.table, valueAtRisk.Parametric, r+2, c)
+		setPercentChange(viewer.table, valueAtRisk.ParametricCVaR, r+3, c)
+		r += 4
+	}
+}
+
+func (viewer *RiskViewer) drawHeader(name string, benchmark string) {
+	var cell *tview.TableCell
+	header := []string{"Metric", name, benchmark}
+
+	for c := 0; c < len(header); c++ {
+		cell = tview.NewTableCell(header[c]).SetTextColor(tcell.ColorYellow).SetAttributes(tcell.AttrBold).SetExpansion(1)
+		if c < 1 {
+			cell.SetAlign(tview.AlignLeft)
+		} else {
+			cell.SetAlign((tview.AlignRight))
+		}
+		viewer.table.SetCell(0, c, cell)
+	}
+}
+
+// setRatio shows a ratio with two decimals, or a dash if it is undefined
+func setRatio(table *tview.Table, value float64, r int, c int) {
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		setString(table, "-", r, c, tcell.ColorWhite, tview.AlignRight)
+		return
+	}
+
+	setQuantity(table, value, r, c, tview.AlignRight)
+}
@@ -0,0 +1,115 @@
+package terminal
+
+import (
+	"fmt"
+
+	"github.com/cimomo/portfolio-go/pkg/portfolio"
+	"github.com/gdamore/tcell"
+	"github.com/rivo/tview"
+)
+
+// RiskParityViewer compares the share of the volatility of a portfolio contributed by every holding and
+// asset class with its share of the capital, and suggests the equal-risk-contribution weights
+type RiskParityViewer struct {
+	performance *portfolio.Performance
+	result      *portfolio.PerformanceResult
+	risk        *portfolio.RiskContributions
+	err         error
+	table       *tview.Table
+}
+
+// NewRiskParityViewer returns a new viewer for the risk contributions of a portfolio
+func NewRiskParityViewer(performance *portfolio.Performance) *RiskParityViewer {
+	table := tview.NewTable().SetBorders(false).SetFixed(1, 1)
+	table.SetTitle("Risk Contributions (<Up>/<Down> to scroll)").SetBorder(true).SetBorderPadding(0, 0, 1, 1)
+
+	return &RiskParityViewer{
+		performance: performance,
+		table:       table,
+	}
+}
+
+// Reload updates the performance data object
+func (viewer *RiskParityViewer) Reload(performance *portfolio.Performance) {
+	viewer.performance = performance
+	viewer.result = nil
+	viewer.table.SetOffset(0, 0)
+}
+
+// HandleKey scrolls the tables
+func (viewer *RiskParityViewer) HandleKey(event *tcell.EventKey) bool {
+	return scrollTable(viewer.table, event)
+}
+
+// Draw refreshes the risk contributions of the holdings and of the asset classes
+func (viewer *RiskParityViewer) Draw() {
+	viewer.table.Clear()
+
+	if !viewer.performance.Ready {
+		setString(viewer.table, "Computing ...", 0, 0, tcell.ColorWhite, tview.AlignLeft)
+		return
+	}
+
+	if viewer.result != viewer.performance.Result {
+		viewer.result = viewer.performance.Result
+		viewer.risk, viewer.err = viewer.result.ComputeRiskContributions()
+	}
+
+	if viewer.err != nil {
+		setString(viewer.table, viewer.err.Error(), 0, 0, tcell.ColorRed, tview.AlignLeft)
+		return
+	}
+
+	r := viewer.drawSummary(viewer.risk, 0)
+	r = viewer.drawContributions("Holding", viewer.risk.Holdings, r+1)
+	viewer.drawContributions("Asset Class", viewer.risk.Classes, r+1)
+}
+
+func (viewer *RiskParityViewer) drawSummary(risk *portfolio.RiskContributions, r int) int {
+	title := fmt.Sprintf("Portfolio (%d months)", risk.Months)
+	viewer.drawHeader([]string{title, "Volatility", "Parity Volatility", "Effective Bets", "Class Effective Bets"}, r)
+
+	setString(viewer.table, viewer.result.Portfolio.Name, r+1, 0, tcell.ColorWhite, tview.AlignLeft)
+	setPercent(viewer.table, risk.Volatility, r+1, 1, tcell.ColorWhite)
+	setPercent(viewer.table, risk.ParityVolatility, r+1, 2, tcell.ColorWhite)
+	setString(viewer.table, fmt.Sprintf("%.1f of %d", risk.EffectiveBets, len(risk.Holdings)), r+1, 3, tcell.ColorWhite, tview.AlignRight)
+	setString(viewer.table, fmt.Sprintf("%.1f of %d", risk.ClassEffectiveBets, len(risk.Classes)), r+1, 4, tcell.ColorWhite, tview.AlignRight)
+
+	return r + 2
+}
+
+// drawContributions shows the share of the risk in red where it exceeds the share of the capital by half
+func (viewer *RiskParityViewer) drawContributions(name string, contributions []*portfolio.RiskContribution, r int) int {
+	viewer.drawHeader([]string{name, "Capital", "Volatility", "Contribution", "Risk Share", "Risk - Capital", "Parity Weight"}, r)
+
+	for i, contribution := range contributions {
+		color := tcell.ColorWhite
+		if contribution.Share > 1.5*contribution.Weight {
+			color = tcell.ColorRed
+		}
+
+		setString(viewer.table, contribution.Name, r+i+1, 0, tcell.ColorWhite, tview.AlignLeft)
+		setPercent(viewer.table, contribution.Weight, r+i+1, 1, tcell.ColorWhite)
+		setPercent(viewer.table, contribution.Volatility, r+i+1, 2, tcell.ColorWhite)
+		setPercent(viewer.table, contribution.Contribution, r+i+1, 3, tcell.ColorWhite)
+		setPercent(viewer.table, contribution.Share, r+i+1, 4, color)
+		setFloat64(viewer.table, contribution.Share-contribution.Weight, "%+.2f%%", r+i+1, 5, color, tview.AlignRight)
+		setPercent(viewer.table, contribution.Parity, r+i+1, 6, tcell.ColorWhite)
+	}
+
+	return r + len(contributions) + 1
+}
+
+func (viewer *RiskParityViewer) drawHeader(header []string, r int) {
+	var cell *tview.TableCell
+
+	for c := 0; c < len(header); c++ {
+		cell = tview.NewTableCell(header[c]).SetTextColor(tcell.ColorYellow).SetAttributes(tcell.AttrBold).SetExpansion(1)
+		if c < 1 {
+			cell.SetAlign(tview.AlignLeft)
+		} else {
+			cell.SetAlign((tview.AlignRight))
+		}
+		viewer.table.SetCell(r, c, cell)
+	}
+}
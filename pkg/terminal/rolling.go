@@ -0,0 +1,156 @@
+package terminal
+
+import (
+	"fmt"
+
+	"github.com/cimomo/portfolio-go/pkg/portfolio"
+	"github.com/gdamore/tcell"
+	"github.com/rivo/tview"
+)
+
+// RollingViewer displays the distribution of the rolling returns and volatility of a portfolio and its
+// benchmark, and charts the rolling series of the selected window
+type RollingViewer struct {
+	performance *portfolio.Performance
+	window      int
+	volatility  bool
+	table       *tview.Table
+	chart       *Chart
+	layout      *tview.Flex
+}
+
+// NewRollingViewer returns a new viewer for the rolling windows of a portfolio
+func NewRollingViewer(performance *portfolio.Performance) *RollingViewer {
+	viewer := &RollingViewer{
+		performance: performance,
+		table:       tview.NewTable().SetBorders(false),
+		chart:       NewChart().SetFormatters(formatChartDate, formatChartPercent),
+	}
+
+	viewer.layout = tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(viewer.table, 0, 1, false).
+		AddItem(viewer.chart, 0, 2, false)
+	viewer.layout.SetTitle("Rolling Windows").SetBorder(true).SetBorderPadding(0, 0, 1, 1)
+
+	return viewer
+}
+
+// Reload updates the performance data object
+func (viewer *RollingViewer) Reload(performance *portfolio.Performance) {
+	viewer.performance = performance
+}
+
+// HandleKey selects the charted window with the left and right keys, and switches between the
+// returns and the volatility with the up and down keys
+func (viewer *RollingViewer) HandleKey(event *tcell.EventKey) bool {
+	if !viewer.performance.Ready {
+		return false
+	}
+
+	windows := len(viewer.performance.Result.Rolling)
+
+	switch event.Key() {
+	case tcell.KeyLeft:
+		viewer.window = (viewer.window + windows - 1) % windows
+	case tcell.KeyRight:
+		viewer.window = (viewer.window + 1) % windows
+	case tcell.KeyUp, tcell.KeyDown:
+		viewer.volatility = !viewer.volatility
+	default:
+		return false
+	}
+
+	viewer.Draw()
+	return true
+}
+
+// Draw refreshes the table and the chart
+func (viewer *RollingViewer) Draw() {
+	viewer.table.Clear()
+	viewer.chart.Clear()
+	viewer.drawHeader()
+
+	if !viewer.performance.Ready {
+		setString(viewer.table, "Computing ...", 2, 0, tcell.ColorWhite, tview.AlignLeft)
+		return
+	}
+
+	r := 2
+	for i := range viewer.performance.Result.Rolling {
+		r = viewer.drawWindow(viewer.performance.Result, i, r)
+		r = viewer.drawWindow(viewer.performance.Benchmark, i, r)
+	}
+
+	viewer.drawSeries(viewer.performance.Result, tcell.ColorGreen)
+	viewer.drawSeries(viewer.performance.Benchmark, tcell.ColorAqua)
+
+	window := viewer.performance.Result.Rolling[viewer.window]
+	title := fmt.Sprintf("Chart: %d-Year Rolling Return", window.Years)
+	if viewer.volatility {
+		title = fmt.Sprintf("Chart: %d-Year Rolling Volatility", window.Years)
+	}
+	setString(viewer.table, title+" (<Left>/<Right> window, <Up>/<Down> return/volatility)", r+1, 0, tcell.ColorWhite, tview.AlignLeft)
+
+	viewer.layout.ResizeItem(viewer.table, r+2, 0)
+}
+
+func (viewer *RollingViewer) drawHeader() {
+	var cell *tview.TableCell
+	groups := []string{"", "", "", "Annualized Return", "", "", "", "", "Volatility", "", ""}
+	header := []string{
+		"Window", "Portfolio", "Windows",
+		"Min", "10th", "Median", "90th", "Max",
+		"Min", "Median", "Max",
+	}
+
+	for c := 0; c < len(header); c++ {
+		cell = tview.NewTableCell(header[c]).SetTextColor(tcell.ColorYellow).SetAttributes(tcell.AttrBold).SetExpansion(1)
+		if c < 2 {
+			cell.SetAlign(tview.AlignLeft)
+		} else {
+			cell.SetAlign((tview.AlignRight))
+		}
+		viewer.table.SetCell(1, c, cell)
+
+		setString(viewer.table, groups[c], 0, c, tcell.ColorYellow, tview.AlignRight)
+	}
+}
+
+func (viewer *RollingViewer) drawWindow(result *portfolio.PerformanceResult, index int, r int) int {
+	window := result.Rolling[index]
+
+	setString(viewer.table, fmt.Sprintf("%d-Year", window.Years), r, 0, tcell.ColorWhite, tview.AlignLeft)
+	setString(viewer.table, result.Portfolio.Name, r, 1, tcell.ColorWhite, tview.AlignLeft)
+	setString(viewer.table, fmt.Sprintf("%d", len(window.Returns)), r, 2, tcell.ColorWhite, tview.AlignRight)
+
+	if window.Return == nil {
+		setString(viewer.table, "Not enough history", r, 3, tcell.ColorWhite, tview.AlignRight)
+		return r + 1
+	}
+
+	setPercentChange(viewer.table, window.Return.Min, r, 3)
+	setPercentChange(viewer.table, window.Return.Percentile10, r, 4)
+	setPercentChange(viewer.table, window.Return.Median, r, 5)
+	setPercentChange(viewer.table, window.Return.Percentile90, r, 6)
+	setPercentChange(viewer.table, window.Return.Max, r, 7)
+	setPercent(viewer.table, window.Risk.Min, r, 8, tcell.ColorWhite)
+	setPercent(viewer.table, window.Risk.Median, r, 9, tcell.ColorWhite)
+	setPercent(viewer.table, window.Risk.Max, r, 10, tcell.ColorWhite)
+
+	return r + 1
+}
+
+func (viewer *RollingViewer) drawSeries(result *portfolio.PerformanceResult, color tcell.Color) {
+	window := result.Rolling[viewer.window]
+
+	series := &ChartSeries{Name: result.Portfolio.Name, Color: color, Y: window.Returns}
+	if viewer.volatility {
+		series.Y = window.Volatility
+	}
+
+	for _, date := range window.Dates {
+		series.X = append(series.X, chartDate(date))
+	}
+
+	viewer.chart.AddSeries(series)
+}
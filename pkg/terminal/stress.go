@@ -0,0 +1,177 @@
+package terminal
+
+import (
+	"strings"
+
+	"github.com/cimomo/portfolio-go/pkg/portfolio"
+	"github.com/gdamore/tcell"
+	"github.com/rivo/tview"
+)
+
+// StressViewer displays the peak-to-trough losses of the current holdings of a portfolio replayed through
+// the stress scenarios of the profile, and the losses of every holding in the selected scenario
+type StressViewer struct {
+	portfolio *portfolio.Portfolio
+	scenarios []*portfolio.StressScenario
+	results   []*portfolio.StressResult
+	errors    []error
+	selected  int
+	table     *tview.Table
+}
+
+// NewStressViewer returns a new viewer for the stress scenarios of a portfolio
+func NewStressViewer(p *portfolio.Portfolio, scenarios []*portfolio.StressScenario) *StressViewer {
+	table := tview.NewTable().SetBorders(false).SetFixed(1, 1)
+	table.SetTitle("Stress Scenarios (<Up>/<Down> to scroll)").SetBorder(true).SetBorderPadding(0, 0, 1, 1)
+
+	return &StressViewer{
+		portfolio: p,
+		scenarios: scenarios,
+		table:     table,
+	}
+}
+
+// Reload selects the portfolio and the scenarios, and discards the results of the previous ones
+func (viewer *StressViewer) Reload(p *portfolio.Portfolio, scenarios []*portfolio.StressScenario) {
+	viewer.portfolio = p
+	viewer.scenarios = scenarios
+	viewer.results = nil
+	viewer.errors = nil
+	if viewer.selected >= len(scenarios) {
+		viewer.selected = 0
+	}
+	viewer.table.SetOffset(0, 0)
+}
+
+// Compute replays the portfolio through every scenario, which fetches the daily bars of its holdings
+func (viewer *StressViewer) Compute(p *portfolio.Portfolio, scenarios []*portfolio.StressScenario) ([]*portfolio.StressResult, []error) {
+	results := make([]*portfolio.StressResult, len(scenarios))
+	errors := make([]error, len(scenarios))
+
+	for i, scenario := range scenarios {
+		results[i], errors[i] = p.Stress(scenario)
+	}
+
+	return results, errors
+}
+
+// SetResults shows the results of Compute, unless another portfolio has been selected since
+func (viewer *StressViewer) SetResults(p *portfolio.Portfolio, results []*portfolio.StressResult, errors []error) {
+	if p != viewer.portfolio {
+		return
+	}
+
+	viewer.results = results
+	viewer.errors = errors
+}
+
+// HandleKey selects the previous or next scenario with the arrow keys, or scrolls the tables
+func (viewer *StressViewer) HandleKey(event *tcell.EventKey) bool {
+	switch event.Key() {
+	case tcell.KeyLeft:
+		viewer.selected = (viewer.selected + len(viewer.scenarios) - 1) % len(viewer.scenarios)
+	case tcell.KeyRight:
+		viewer.selected = (viewer.selected + 1) % len(viewer.scenarios)
+	default:
+		return scrollTable(viewer.table, event)
+	}
+
+	viewer.Draw()
+	return true
+}
+
+// Draw refreshes the losses of every scenario and the losses of the holdings in the selected one
+func (viewer *StressViewer) Draw() {
+	viewer.table.Clear()
+	viewer.drawHeader([]string{"Scenario (<Left>/<Right>)", "Start", "End", "Peak", "Trough", "Loss", "Loss %", "Benchmark", "Benchmark %"}, 0)
+
+	if viewer.results == nil {
+		setString(viewer.table, "Computing ...", 1, 0, tcell.ColorWhite, tview.AlignLeft)
+		return
+	}
+
+	for i, scenario := range viewer.scenarios {
+		color := tcell.ColorWhite
+		if i == viewer.selected {
+			color = tcell.ColorYellow
+		}
+
+		setString(viewer.table, scenario.Name, i+1, 0, color, tview.AlignLeft)
+		setString(viewer.table, scenario.Start.Format("2006-01-02"), i+1, 1, color, tview.AlignRight)
+		setString(viewer.table, scenario.End.Format("2006-01-02"), i+1, 2, color, tview.AlignRight)
+
+		if viewer.errors[i] != nil {
+			setString(viewer.table, viewer.errors[i].Error(), i+1, 3, tcell.ColorRed, tview.AlignLeft)
+			continue
+		}
+
+		result := viewer.results[i]
+		setString(viewer.table, result.Loss.Peak.Format("2006-01-02"), i+1, 3, color, tview.AlignRight)
+		setString(viewer.table, result.Loss.Trough.Format("2006-01-02"), i+1, 4, color, tview.AlignRight)
+		viewer.drawLoss(result.Loss, i+1, 5)
+		viewer.drawLoss(result.Benchmark, i+1, 7)
+	}
+
+	if viewer.errors[viewer.selected] == nil {
+		viewer.drawResult(viewer.results[viewer.selected], len(viewer.scenarios)+2)
+	}
+}
+
+func (viewer *StressViewer) drawResult(result *portfolio.StressResult, r int) {
+	viewer.drawHeader([]string{result.Scenario.Name, "Value", "Loss", "Loss %"}, r)
+
+	for i, holding := range result.Holdings {
+		setString(viewer.table, holding.Symbol, r+i+1, 0, tcell.ColorWhite, tview.AlignLeft)
+		setDollarAmount(viewer.table, holding.Value, r+i+1, 1, tcell.ColorWhite)
+		viewer.drawLoss(holding, r+i+1, 2)
+	}
+
+	r += len(result.Holdings) + 1
+	if result.Cash != 0 {
+		setString(viewer.table, "Cash", r, 0, tcell.ColorWhite, tview.AlignLeft)
+		setDollarAmount(viewer.table, result.Cash, r, 1, tcell.ColorWhite)
+		r++
+	}
+
+	setString(viewer.table, result.Loss.Symbol, r, 0, tcell.ColorYellow, tview.AlignLeft)
+	setDollarAmount(viewer.table, result.Value, r, 1, tcell.ColorWhite)
+	viewer.drawLoss(result.Loss, r, 2)
+
+	setString(viewer.table, "Benchmark ("+result.Benchmark.Symbol+")", r+1, 0, tcell.ColorYellow, tview.AlignLeft)
+	setDollarAmount(viewer.table, result.Benchmark.Value, r+1, 1, tcell.ColorWhite)
+	viewer.drawLoss(result.Benchmark, r+1, 2)
+
+	r += 3
+	for _, simulated := range result.Simulated {
+		note := simulated.Symbol + " is simulated by " + strings.Join(simulated.Proxies, ", ") + " before " + simulated.End.Format("2006-01-02")
+		setString(viewer.table, note, r, 0, tcell.ColorOrange, tview.AlignLeft)
+		r++
+	}
+	if len(result.Missing) > 0 {
+		setString(viewer.table, "No data for part of the scenario, held flat: "+strings.Join(result.Missing, ", "), r, 0, tcell.ColorOrange, tview.AlignLeft)
+	}
+}
+
+func (viewer *StressViewer) drawLoss(loss *portfolio.StressLoss, r int, c int) {
+	color := tcell.ColorWhite
+	if loss.Loss < 0 {
+		color = tcell.ColorRed
+	}
+
+	setDollarAmount(viewer.table, loss.Loss, r, c, color)
+	setPercent(viewer.table, loss.Percent, r, c+1, color)
+}
+
+func (viewer *StressViewer) drawHeader(header []string, r int) {
+	var cell *tview.TableCell
+
+	for c := 0; c < len(header); c++ {
+		cell = tview.NewTableCell(header[c]).SetTextColor(tcell.ColorYellow).SetAttributes(tcell.AttrBold).SetExpansion(1)
+		if c < 1 {
+			cell.SetAlign(tview.AlignLeft)
+		} else {
+			cell.SetAlign((tview.AlignRight))
+		}
+		viewer.table.SetCell(r, c, cell)
+	}
+}
@@ -15,14 +15,34 @@ type Terminal struct {
 	root                     *tview.Pages
 	profileFile              string
 	profile                  *portfolio.Profile
+	period                   *portfolio.Period
+	snapshots                *portfolio.SnapshotStore
 	marketViewer             *MarketViewer
 	profileViewer            *ProfileViewer
 	profilePerformanceViewer *PerformanceViewer
 	profileReturnViewer      *ReturnViewer
+	profileRelativeViewer    *RelativeViewer
 	portfolioViewers         []*PortfolioViewer
 	performanceViewers       []*PerformanceViewer
 	returnViewers            []*ReturnViewer
+	relativeViewers          []*RelativeViewer
+	netWorthViewer           *NetWorthViewer
+	drawdownViewer           *DrawdownViewer
+	riskViewer               *RiskViewer
+	rollingViewer            *RollingViewer
+	correlationViewer        *CorrelationViewer
+	projectionViewer         *ProjectionViewer
+	withdrawalViewer         *WithdrawalViewer
+	contributionViewer       *ContributionViewer
+	periodViewer             *PeriodViewer
+	calendarViewer           *CalendarViewer
+	attributionViewer        *AttributionViewer
+	stressViewer             *StressViewer
+	frontierViewer           *FrontierViewer
+	riskParityViewer         *RiskParityViewer
 	helpViewer               *HelpViewer
+	detailDraw               func()
+	detailKeys               func(event *tcell.EventKey) bool
 	currentViewer            int
 	signalRedrawMarket       chan int
 	signalRedrawProfile      chan int
@@ -36,9 +56,11 @@ func NewTerminal(profileFile string) *Terminal {
 	return &Terminal{
 		application:             tview.NewApplication(),
 		profileFile:             profileFile,
+		snapshots:               portfolio.NewSnapshotStore(portfolio.SnapshotFile(profileFile)),
 		portfolioViewers:        make([]*PortfolioViewer, 0),
 		performanceViewers:      make([]*PerformanceViewer, 0),
 		returnViewers:           make([]*ReturnViewer, 0),
+		relativeViewers:         make([]*RelativeViewer, 0),
 		currentViewer:           -1,
 		signalRedrawMarket:      make(chan int),
 		signalRedrawPortfolio:   make(chan int),
@@ -48,6 +70,12 @@ func NewTerminal(profileFile string) *Terminal {
 	}
 }
 
+// SetPeriod selects the dates and the granularity of the backtests, or the full history on monthly bars if nil
+func (term *Terminal) SetPeriod(period *portfolio.Period) *Terminal {
+	term.period = period
+	return term
+}
+
 // Start starts the terminal application
 func (term *Terminal) Start() error {
 	profile, err := term.loadProfile("Main")
@@ -57,6 +85,11 @@ func (term *Terminal) Start() error {
 
 	term.profile = profile
 
+	err = term.snapshots.Load()
+	if err != nil {
+		return err
+	}
+
 	term.setupViewers()
 
 	term.initializeViewer()
@@ -83,12 +116,17 @@ func (term *Terminal) reload() error {
 	term.marketViewer.Reload(profile.Market)
 	term.profileViewer.Reload(profile)
 	term.profilePerformanceViewer.Reload(profile.MergedPortfolio.Performance)
-	term.profileReturnViewer.Reload(profile.MergedPortfolio.Performance)
+	term.profileReturnViewer.Reload(profile.MergedPortfolio.Performance, profile.MergedPortfolio.MoneyWeighted, profile.MoneyWeighted)
+	term.profileReturnViewer.SetSnapshots(term.snapshots, "")
+	term.profileRelativeViewer.Reload(profile.MergedPortfolio.Performance)
+	term.netWorthViewer.Reload(profile)
 
 	for i, portfolio := range profile.Portfolios {
 		term.portfolioViewers[i].Reload(portfolio)
 		term.performanceViewers[i].Reload(portfolio.Performance)
-		term.returnViewers[i].Reload(portfolio.Performance)
+		term.returnViewers[i].Reload(portfolio.Performance, portfolio.MoneyWeighted)
+		term.returnViewers[i].SetSnapshots(term.snapshots, portfolio.Name)
+		term.relativeViewers[i].Reload(portfolio.Performance)
 	}
 
 	err = term.switchViewer(term.currentViewer)
@@ -108,6 +146,7 @@ func (term *Terminal) loadProfile(name string) (*portfolio.Profile, error) {
 	if err != nil {
 		return nil, err
 	}
+	p.SetPeriod(term.period)
 
 	return p, nil
 }
@@ -120,7 +159,9 @@ func (term *Terminal) setupViewers() {
 	term.profileViewer = profileViewer
 
 	term.profilePerformanceViewer = NewPerformanceViewer(term.profile.MergedPortfolio.Performance)
-	term.profileReturnViewer = NewReturnViewer(term.profile.MergedPortfolio.Performance)
+	term.profileReturnViewer = NewReturnViewer(term.profile.MergedPortfolio.Performance, term.profile.MergedPortfolio.MoneyWeighted, term.profile.MoneyWeighted).
+		SetSnapshots(term.snapshots, "")
+	term.profileRelativeViewer = NewRelativeViewer(term.profile.MergedPortfolio.Performance)
 
 	for _, portfolio := range term.profile.Portfolios {
 		portfolioViewer := NewPortfolioViewer(portfolio)
@@ -129,10 +170,29 @@ func (term *Terminal) setupViewers() {
 		performanceViewer := NewPerformanceViewer(portfolio.Performance)
 		term.performanceViewers = append(term.performanceViewers, performanceViewer)
 
-		returnViewer := NewReturnViewer(portfolio.Performance)
+		returnViewer := NewReturnViewer(portfolio.Performance, portfolio.MoneyWeighted).SetSnapshots(term.snapshots, portfolio.Name)
 		term.returnViewers = append(term.returnViewers, returnViewer)
+
+		relativeViewer := NewRelativeViewer(portfolio.Performance)
+		term.relativeViewers = append(term.relativeViewers, relativeViewer)
 	}
 
+	term.netWorthViewer = NewNetWorthViewer(term.profile, term.snapshots)
+	term.drawdownViewer = NewDrawdownViewer(term.profile.MergedPortfolio.Performance)
+	term.riskViewer = NewRiskViewer(term.profile.MergedPortfolio.Performance)
+	term.rollingViewer = NewRollingViewer(term.profile.MergedPortfolio.Performance)
+	term.correlationViewer = NewCorrelationViewer(term.profile.MergedPortfolio.Performance)
+	term.projectionViewer = NewProjectionViewer(term.profile.MergedPortfolio.Performance, term.profile.Projection)
+	term.withdrawalViewer = NewWithdrawalViewer(term.profile.MergedPortfolio.Performance, term.profile.Withdrawal, term.profile.CPI)
+	term.contributionViewer = NewContributionViewer(term.profile.MergedPortfolio.Performance)
+
+	term.periodViewer = NewPeriodViewer(term.applyPeriod, term.hidePeriod)
+	term.calendarViewer = NewCalendarViewer(term.profile.MergedPortfolio.Performance)
+	term.attributionViewer = NewAttributionViewer(term.profile.MergedPortfolio.Performance)
+	term.stressViewer = NewStressViewer(term.profile.MergedPortfolio, term.profile.StressScenarios)
+	term.frontierViewer = NewFrontierViewer(term.profile.MergedPortfolio.Performance, term.profile.Optimizer)
+	term.riskParityViewer = NewRiskParityViewer(term.profile.MergedPortfolio.Performance)
+
 	helpViewer := NewHelpViewer()
 	term.helpViewer = helpViewer
 }
@@ -234,9 +294,11 @@ func (term *Terminal) drawPerformance(index int) error {
 	if index < 0 {
 		term.profilePerformanceViewer.Draw()
 		term.profileReturnViewer.Draw()
+		term.profileRelativeViewer.Draw()
 	} else {
 		term.performanceViewers[index].Draw()
 		term.returnViewers[index].Draw()
+		term.relativeViewers[index].Draw()
 	}
 
 	return nil
@@ -261,26 +323,98 @@ func (term *Terminal) refreshProfile() error {
 
 	term.signalRedrawProfile <- 0
 
-	return nil
+	return term.recordSnapshot()
 }
 
 func (term *Terminal) refreshPortfolio(index int) error {
-	err := term.profile.Portfolios[index].Refresh()
+	// The end-of-day snapshot covers every portfolio, so the whole profile is refreshed when it is due
+	_, due, err := term.snapshotDue()
+	if err != nil {
+		return err
+	}
+
+	if due {
+		err = term.profile.Refresh()
+	} else {
+		err = term.profile.Portfolios[index].Refresh()
+	}
 	if err != nil {
 		return err
 	}
 
 	term.signalRedrawPortfolio <- index
 
+	if due {
+		return term.recordSnapshot()
+	}
+
+	return nil
+}
+
+// snapshotDue returns the time in New York, and true from the close of a trading day until its snapshot
+// has been recorded
+func (term *Terminal) snapshotDue() (time.Time, bool, error) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		return time.Time{}, false, err
+	}
+
+	now := time.Now().In(ny)
+	if !portfolio.IsTradingDay(now) || now.Hour() < marketCloseHour {
+		return now, false, nil
+	}
+
+	return now, !term.snapshots.Recorded(now), nil
+}
+
+// recordSnapshot records the end-of-day snapshot at the first refresh of the profile after the market closes.
+// The profile must have been refreshed.
+func (term *Terminal) recordSnapshot() error {
+	now, due, err := term.snapshotDue()
+	if err != nil || !due {
+		return err
+	}
+
+	err = term.snapshots.Record(term.profile, now)
+	if err != nil {
+		return err
+	}
+
+	term.signalRedrawPerformance <- -1
+
 	return nil
 }
 
+func (term *Terminal) toggleActualReturns() {
+	term.profileReturnViewer.ToggleActual()
+	for _, viewer := range term.returnViewers {
+		viewer.ToggleActual()
+	}
+
+	term.drawPerformance(term.currentViewer)
+}
+
+func (term *Terminal) toggleRealReturns() {
+	term.profilePerformanceViewer.ToggleReal()
+	term.profileReturnViewer.ToggleReal()
+	for i := range term.performanceViewers {
+		term.performanceViewers[i].ToggleReal()
+		term.returnViewers[i].ToggleReal()
+	}
+
+	term.drawPerformance(term.currentViewer)
+}
+
 func (term *Terminal) computeAllPerformance() error {
 	err := term.profile.MergedPortfolio.Performance.Compute()
 	if err != nil {
 		return err
 	}
 
+	// A failed money-weighted return is shown by the return viewer, and does not hold up the backtests
+	term.profile.MergedPortfolio.MoneyWeighted.Compute()
+	term.profile.MoneyWeighted.Compute()
+
 	for i := range term.profile.Portfolios {
 		err = term.computePerformance(i)
 		if err != nil {
@@ -299,6 +433,9 @@ func (term *Terminal) computePerformance(index int) error {
 	if err != nil {
 		return err
 	}
+
+	term.profile.Portfolios[index].MoneyWeighted.Compute()
+
 	return nil
 }
 
@@ -334,6 +471,9 @@ func (term *Terminal) doRefresh() {
 		case <-term.signalRedrawPerformance:
 			term.application.QueueUpdateDraw(func() {
 				term.drawPerformance(term.currentViewer)
+				if term.detailDraw != nil {
+					term.detailDraw()
+				}
 			})
 
 		case index := <-term.signalSwitchViewer:
@@ -353,17 +493,7 @@ func (term *Terminal) doRefresh() {
 }
 
 func (term *Terminal) showHelp() {
-	modal := func(p tview.Primitive, width, height int) tview.Primitive {
-		return tview.NewFlex().
-			AddItem(nil, 0, 1, false).
-			AddItem(tview.NewFlex().SetDirection(tview.FlexRow).
-				AddItem(nil, 0, 1, false).
-				AddItem(p, height, 1, false).
-				AddItem(nil, 0, 1, false), width, 1, false).
-			AddItem(nil, 0, 1, false)
-	}
-
-	term.root.AddPage(helpPage, modal(term.helpViewer.table, 60, 15), true, true)
+	term.root.AddPage(helpPage, modal(term.helpViewer.table, 60, len(term.helpViewer.help)+6), true, true)
 }
 
 func (term *Terminal) hideHelp() {
@@ -372,6 +502,156 @@ func (term *Terminal) hideHelp() {
 	}
 }
 
+// showDetail shows a full-screen page over the current page. The page is redrawn whenever the
+// performance data changes, and receives the keys not handled by the terminal.
+func (term *Terminal) showDetail(p tview.Primitive, draw func(), keys func(event *tcell.EventKey) bool) {
+	term.hideHelp()
+	draw()
+	term.root.AddPage(detailPage, p, true, true)
+	term.detailDraw = draw
+	term.detailKeys = keys
+}
+
+func (term *Terminal) hideDetail() {
+	if term.root.HasPage(detailPage) {
+		term.root.RemovePage(detailPage)
+	}
+	term.detailDraw = nil
+	term.detailKeys = nil
+}
+
+func (term *Terminal) showNetWorth() {
+	term.showDetail(term.netWorthViewer.layout, term.netWorthViewer.Draw, term.netWorthViewer.HandleKey)
+}
+
+// currentPerformance returns the performance of the portfolio shown on the current page
+func (term *Terminal) currentPerformance() *portfolio.Performance {
+	if term.currentViewer < 0 {
+		return term.profile.MergedPortfolio.Performance
+	}
+	return term.profile.Portfolios[term.currentViewer].Performance
+}
+
+func (term *Terminal) showDrawdowns() {
+	term.drawdownViewer.Reload(term.currentPerformance())
+	term.showDetail(term.drawdownViewer.layout, term.drawdownViewer.Draw, nil)
+}
+
+func (term *Terminal) showRisk() {
+	term.riskViewer.Reload(term.currentPerformance())
+	term.showDetail(term.riskViewer.table, term.riskViewer.Draw, term.riskViewer.HandleKey)
+}
+
+func (term *Terminal) showRolling() {
+	term.rollingViewer.Reload(term.currentPerformance())
+	term.showDetail(term.rollingViewer.layout, term.rollingViewer.Draw, term.rollingViewer.HandleKey)
+}
+
+func (term *Terminal) showCorrelation() {
+	term.correlationViewer.Reload(term.currentPerformance())
+	term.showDetail(term.correlationViewer.table, term.correlationViewer.Draw, term.correlationViewer.HandleKey)
+}
+
+// currentPortfolio returns the portfolio shown on the current page
+func (term *Terminal) currentPortfolio() *portfolio.Portfolio {
+	if term.currentViewer < 0 {
+		return term.profile.MergedPortfolio
+	}
+	return term.profile.Portfolios[term.currentViewer]
+}
+
+func (term *Terminal) showProjection() {
+	current := term.currentPortfolio()
+	term.projectionViewer.Reload(current.Performance, current.Status.Value)
+	term.showDetail(term.projectionViewer.layout, term.projectionViewer.Draw, term.projectionViewer.HandleKey)
+}
+
+func (term *Terminal) showWithdrawal() {
+	current := term.currentPortfolio()
+	term.withdrawalViewer.Reload(current.Performance, current.Status.Value)
+	term.showDetail(term.withdrawalViewer.layout, term.withdrawalViewer.Draw, term.withdrawalViewer.HandleKey)
+}
+
+func (term *Terminal) showCalendar() {
+	term.calendarViewer.Reload(term.currentPerformance())
+	term.showDetail(term.calendarViewer.table, term.calendarViewer.Draw, term.calendarViewer.HandleKey)
+}
+
+// currentReturnViewer returns the return viewer of the current page
+func (term *Terminal) currentReturnViewer() *ReturnViewer {
+	if term.currentViewer < 0 {
+		return term.profileReturnViewer
+	}
+	return term.returnViewers[term.currentViewer]
+}
+
+// showAttribution drills down into the trailing period selected in the return viewer of the current page
+func (term *Terminal) showAttribution() {
+	term.attributionViewer.Reload(term.currentPerformance(), term.currentReturnViewer().Period())
+	term.showDetail(term.attributionViewer.table, term.attributionViewer.Draw, term.attributionViewer.HandleKey)
+}
+
+// showStress replays the current portfolio through the stress scenarios in the background, since every
+// scenario fetches the daily bars of the holdings
+func (term *Terminal) showStress() {
+	current := term.currentPortfolio()
+	scenarios := term.profile.StressScenarios
+
+	term.stressViewer.Reload(current, scenarios)
+	term.showDetail(term.stressViewer.table, term.stressViewer.Draw, term.stressViewer.HandleKey)
+
+	go func() {
+		results, errors := term.stressViewer.Compute(current, scenarios)
+		term.application.QueueUpdateDraw(func() {
+			term.stressViewer.SetResults(current, results, errors)
+			if term.detailDraw != nil {
+				term.detailDraw()
+			}
+		})
+	}()
+}
+
+func (term *Terminal) showFrontier() {
+	term.frontierViewer.Reload(term.currentPerformance(), term.profile.Optimizer)
+	term.showDetail(term.frontierViewer.layout, term.frontierViewer.Draw, term.frontierViewer.HandleKey)
+}
+
+func (term *Terminal) showRiskParity() {
+	term.riskParityViewer.Reload(term.currentPerformance())
+	term.showDetail(term.riskParityViewer.table, term.riskParityViewer.Draw, term.riskParityViewer.HandleKey)
+}
+
+// showPeriod prompts for the backtest period. The prompt takes the focus until it is applied or canceled.
+func (term *Terminal) showPeriod() {
+	term.hideHelp()
+	term.hideDetail()
+	term.periodViewer.Reload(term.period)
+	term.root.AddPage(periodPage, modal(term.periodViewer.layout, 64, 10), true, true)
+	term.application.SetFocus(term.periodViewer.form)
+}
+
+func (term *Terminal) hidePeriod() {
+	if term.root.HasPage(periodPage) {
+		term.root.RemovePage(periodPage)
+	}
+}
+
+// applyPeriod recomputes the performance of every portfolio over the new backtest period
+func (term *Terminal) applyPeriod(period *portfolio.Period) {
+	term.hidePeriod()
+
+	term.period = period
+	term.profile.SetPeriod(period)
+	term.drawPerformance(term.currentViewer)
+
+	go term.computeAllPerformance()
+}
+
+func (term *Terminal) showContributions() {
+	term.contributionViewer.Reload(term.currentPerformance())
+	term.showDetail(term.contributionViewer.layout, term.contributionViewer.Draw, nil)
+}
+
 func (term *Terminal) initialize() {
 	pages := tview.NewPages()
 
@@ -388,28 +668,35 @@ func (term *Terminal) initialize() {
 }
 
 func (term *Terminal) createHomepage() *tview.Grid {
-	grid := tview.NewGrid().SetRows(4, 0, 8, 7).SetColumns(0).SetBorders(false)
+	grid := tview.NewGrid().SetRows(4, 0, 8, 11, 6).SetColumns(0).SetBorders(false)
 
 	grid.AddItem(term.marketViewer.table, 0, 0, 1, 1, 0, 0, false).
 		AddItem(term.profileViewer.table, 1, 0, 1, 1, 0, 0, false).
 		AddItem(term.profilePerformanceViewer.table, 2, 0, 1, 1, 0, 0, false).
-		AddItem(term.profileReturnViewer.table, 3, 0, 1, 1, 0, 0, false)
+		AddItem(term.profileReturnViewer.table, 3, 0, 1, 1, 0, 0, false).
+		AddItem(term.profileRelativeViewer.table, 4, 0, 1, 1, 0, 0, false)
 
 	return grid
 }
 
 func (term *Terminal) createPage(index int) *tview.Grid {
-	grid := tview.NewGrid().SetRows(4, 0, 8, 7).SetColumns(0).SetBorders(false)
+	grid := tview.NewGrid().SetRows(4, 0, 8, 9, 6).SetColumns(0).SetBorders(false)
 
 	grid.AddItem(term.marketViewer.table, 0, 0, 1, 1, 0, 0, false).
 		AddItem(term.portfolioViewers[index].table, 1, 0, 1, 1, 0, 0, false).
 		AddItem(term.performanceViewers[index].table, 2, 0, 1, 1, 0, 0, false).
-		AddItem(term.returnViewers[index].table, 3, 0, 1, 1, 0, 0, false)
+		AddItem(term.returnViewers[index].table, 3, 0, 1, 1, 0, 0, false).
+		AddItem(term.relativeViewers[index].table, 4, 0, 1, 1, 0, 0, false)
 
 	return grid
 }
 
 func (term *Terminal) keyCapture(event *tcell.EventKey) *tcell.EventKey {
+	// The period prompt handles its own keys
+	if term.root.HasPage(periodPage) {
+		return event
+	}
+
 	key := event.Key()
 	if key == tcell.KeyRune {
 		rune := event.Rune()
@@ -420,11 +707,13 @@ func (term *Terminal) keyCapture(event *tcell.EventKey) *tcell.EventKey {
 		} else if rune >= '0' && rune <= '9' { // Note that '0' will open the main profile page
 			index := int(rune - '1')
 			term.hideHelp()
+			term.hideDetail()
 			term.switchViewer(index)
 			return nil
 
 		} else if rune == 'm' {
 			term.hideHelp()
+			term.hideDetail()
 			term.switchViewer(-1)
 			return nil
 
@@ -434,12 +723,93 @@ func (term *Terminal) keyCapture(event *tcell.EventKey) *tcell.EventKey {
 
 		} else if rune == 'r' {
 			term.hideHelp()
+			term.hideDetail()
 			term.reload()
 			return nil
+
+		} else if rune == 't' {
+			term.hideHelp()
+			term.toggleActualReturns()
+			return nil
+
+		} else if rune == 'i' {
+			term.hideHelp()
+			term.toggleRealReturns()
+			return nil
+
+		} else if rune == 'c' {
+			term.showNetWorth()
+			return nil
+
+		} else if rune == 'd' {
+			term.showDrawdowns()
+			return nil
+
+		} else if rune == 'k' {
+			term.showRisk()
+			return nil
+
+		} else if rune == 'l' {
+			term.showRolling()
+			return nil
+
+		} else if rune == 'x' {
+			term.showCorrelation()
+			return nil
+
+		} else if rune == 'p' {
+			term.showProjection()
+			return nil
+
+		} else if rune == 'w' {
+			term.showWithdrawal()
+			return nil
+
+		} else if rune == 'f' {
+			term.showContributions()
+			return nil
+
+		} else if rune == 'g' {
+			term.showPeriod()
+			return nil
+
+		} else if rune == 'y' {
+			term.showCalendar()
+			return nil
+
+		} else if rune == 's' {
+			term.showStress()
+			return nil
+
+		} else if rune == 'e' {
+			term.showFrontier()
+			return nil
+
+		} else if rune == 'b' {
+			term.showRiskParity()
+			return nil
+		}
+
+	} else if key == tcell.KeyEnter && !term.root.HasPage(helpPage) && !term.root.HasPage(detailPage) {
+		term.showAttribution()
+		return nil
+
+	} else if (key == tcell.KeyLeft || key == tcell.KeyRight) && !term.root.HasPage(detailPage) {
+		delta := 1
+		if key == tcell.KeyLeft {
+			delta = -1
 		}
+		term.currentReturnViewer().SelectPeriod(delta)
+		term.currentReturnViewer().Draw()
+		return nil
 
-	} else if key == tcell.KeyEnter {
+	} else if key == tcell.KeyEnter || key == tcell.KeyEscape {
 		term.hideHelp()
+		term.hideDetail()
+	}
+
+	if term.detailKeys != nil && term.detailKeys(event) {
+		return nil
 	}
 
 	return event
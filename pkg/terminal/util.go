@@ -10,7 +10,10 @@ import (
 )
 
 const (
-	helpPage = "help"
+	helpPage        = "help"
+	detailPage      = "detail"
+	periodPage      = "period"
+	marketCloseHour = 16
 )
 
 func setNonZeroDollarAmount(table *tview.Table, value float64, r int, c int, color tcell.Color) {
@@ -38,6 +41,26 @@ func setPercentChange(table *tview.Table, value float64, r int, c int) {
 	setPercent(table, value, r, c, color)
 }
 
+// setReturn shows a percent change, or a dash if the return cannot be computed
+func setReturn(table *tview.Table, value float64, r int, c int) {
+	if math.IsNaN(value) {
+		setString(table, "-", r, c, tcell.ColorWhite, tview.AlignRight)
+		return
+	}
+
+	setPercentChange(table, value, r, c)
+}
+
+// setOptionalPercent shows a percentage, or a dash if it cannot be computed
+func setOptionalPercent(table *tview.Table, value float64, r int, c int) {
+	if math.IsNaN(value) {
+		setString(table, "-", r, c, tcell.ColorWhite, tview.AlignRight)
+		return
+	}
+
+	setPercent(table, value, r, c, tcell.ColorWhite)
+}
+
 func setDollarChange(table *tview.Table, value float64, r int, c int) {
 	color := tcell.ColorGreen
 	if value < 0 {
@@ -74,3 +97,49 @@ func setString(table *tview.Table, value string, r int, c int, color tcell.Color
 	cell := tview.NewTableCell(value).SetTextColor(color).SetAlign(align).SetExpansion(1)
 	table.SetCell(r, c, cell)
 }
+
+// modal centers a primitive of the given size over the current page
+func modal(p tview.Primitive, width, height int) tview.Primitive {
+	return tview.NewFlex().
+		AddItem(nil, 0, 1, false).
+		AddItem(tview.NewFlex().SetDirection(tview.FlexRow).
+			AddItem(nil, 0, 1, false).
+			AddItem(p, height, 1, false).
+			AddItem(nil, 0, 1, false), width, 1, false).
+		AddItem(nil, 0, 1, false)
+}
+
+// scrollTable scrolls a table that is not focused with the arrow and page keys, keeping its last row at the bottom
+func scrollTable(table *tview.Table, event *tcell.EventKey) bool {
+	row, column := table.GetOffset()
+	_, _, _, height := table.GetInnerRect()
+	page := height - 1
+
+	switch event.Key() {
+	case tcell.KeyUp:
+		row--
+	case tcell.KeyDown:
+		row++
+	case tcell.KeyPgUp:
+		row -= page
+	case tcell.KeyPgDn:
+		row += page
+	case tcell.KeyHome:
+		row = 0
+	case tcell.KeyEnd:
+		row = table.GetRowCount()
+	default:
+		return false
+	}
+
+	last := table.GetRowCount() - page
+	if row > last {
+		row = last
+	}
+	if row < 0 {
+		row = 0
+	}
+
+	table.SetOffset(row, column)
+	return true
+}
@@ -0,0 +1,181 @@
+package terminal
+
+import (
+	"fmt"
+
+	"github.com/cimomo/portfolio-go/pkg/portfolio"
+	"github.com/gdamore/tcell"
+	"github.com/rivo/tview"
+)
+
+// WithdrawalViewer displays the outcome of retiring on a portfolio at the start of every year of its backtest
+type WithdrawalViewer struct {
+	performance *portfolio.Performance
+	settings    *portfolio.Withdrawal
+	cpi         *portfolio.CPI
+	balance     float64
+	rule        int
+	years       int
+	summary     *tview.Table
+	note        *tview.TextView
+	table       *tview.Table
+	layout      *tview.Flex
+}
+
+// NewWithdrawalViewer returns a new viewer for the withdrawal simulation of a portfolio with the given settings
+func NewWithdrawalViewer(performance *portfolio.Performance, settings *portfolio.Withdrawal, cpi *portfolio.CPI) *WithdrawalViewer {
+	viewer := &WithdrawalViewer{
+		performance: performance,
+		settings:    settings,
+		cpi:         cpi,
+		years:       settings.Years,
+		summary:     tview.NewTable().SetBorders(false),
+		note:        tview.NewTextView().SetTextColor(tcell.ColorGray),
+		table:       tview.NewTable().SetBorders(false).SetFixed(1, 0),
+	}
+
+	for i, rule := range portfolio.WithdrawalRules {
+		if rule == settings.Rule {
+			viewer.rule = i
+		}
+	}
+
+	viewer.layout = tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(viewer.summary, 2, 0, false).
+		AddItem(viewer.note, 2, 0, false).
+		AddItem(viewer.table, 0, 1, false)
+	viewer.layout.SetTitle("Withdrawal (<Left>/<Right> rule, <->/<+> years, <Up>/<Down> to scroll)").
+		SetBorder(true).SetBorderPadding(0, 0, 1, 1)
+
+	return viewer
+}
+
+// Reload updates the performance data object and the current value of the portfolio
+func (viewer *WithdrawalViewer) Reload(performance *portfolio.Performance, balance float64) {
+	viewer.performance = performance
+	viewer.balance = balance
+	viewer.table.SetOffset(0, 0)
+}
+
+// HandleKey selects the withdrawal rule with the left and right keys, shortens or extends the horizon by
+// five years with the minus and plus keys, and scrolls the periods with the arrow and page keys
+func (viewer *WithdrawalViewer) HandleKey(event *tcell.EventKey) bool {
+	rules := len(portfolio.WithdrawalRules)
+
+	switch {
+	case event.Key() == tcell.KeyLeft:
+		viewer.rule = (viewer.rule + rules - 1) % rules
+	case event.Key() == tcell.KeyRight:
+		viewer.rule = (viewer.rule + 1) % rules
+	case event.Key() == tcell.KeyRune && event.Rune() == '-':
+		if viewer.years <= 5 {
+			return true
+		}
+		viewer.years -= 5
+	case event.Key() == tcell.KeyRune && event.Rune() == '+':
+		viewer.years += 5
+	default:
+		return scrollTable(viewer.table, event)
+	}
+
+	viewer.Draw()
+	return true
+}
+
+// Draw replays the withdrawals and refreshes the summary and the periods
+func (viewer *WithdrawalViewer) Draw() {
+	viewer.summary.Clear()
+	viewer.note.Clear()
+	viewer.table.Clear()
+	viewer.drawHeader()
+
+	if !viewer.performance.Ready {
+		viewer.note.SetText("Computing ...")
+		return
+	}
+
+	withdrawal := *viewer.settings
+	withdrawal.Rule = portfolio.WithdrawalRules[viewer.rule]
+	withdrawal.Years = viewer.years
+	if withdrawal.InitialBalance == 0 {
+		withdrawal.InitialBalance = viewer.balance
+	}
+
+	err := withdrawal.Run(viewer.performance.Result, viewer.cpi)
+	if err != nil {
+		viewer.note.SetText(err.Error())
+		return
+	}
+
+	viewer.drawSummary(&withdrawal)
+	viewer.drawPeriods(&withdrawal)
+}
+
+func (viewer *WithdrawalViewer) drawHeader() {
+	var cell *tview.TableCell
+	header := []string{"Portfolio", "Rule", "Rate", "Years", "Initial Balance", "Periods", "Success Rate", "Worst Ending", "Median Ending", "Safe Rate"}
+
+	for c := 0; c < len(header); c++ {
+		cell = tview.NewTableCell(header[c]).SetTextColor(tcell.ColorYellow).SetAttributes(tcell.AttrBold).SetExpansion(1)
+		if c < 2 {
+			cell.SetAlign(tview.AlignLeft)
+		} else {
+			cell.SetAlign((tview.AlignRight))
+		}
+		viewer.summary.SetCell(0, c, cell)
+	}
+
+	header = []string{"Start", "Ending Balance", "Total Withdrawn", "Lowest Year", "Depleted"}
+
+	for c := 0; c < len(header); c++ {
+		cell = tview.NewTableCell(header[c]).SetTextColor(tcell.ColorYellow).SetAttributes(tcell.AttrBold).SetExpansion(1)
+		if c < 1 {
+			cell.SetAlign(tview.AlignLeft)
+		} else {
+			cell.SetAlign((tview.AlignRight))
+		}
+		viewer.table.SetCell(0, c, cell)
+	}
+}
+
+func (viewer *WithdrawalViewer) drawSummary(withdrawal *portfolio.Withdrawal) {
+	setString(viewer.summary, viewer.performance.Portfolio.Name, 1, 0, tcell.ColorWhite, tview.AlignLeft)
+	setString(viewer.summary, string(withdrawal.Rule), 1, 1, tcell.ColorWhite, tview.AlignLeft)
+	setPercent(viewer.summary, withdrawal.Rate, 1, 2, tcell.ColorWhite)
+	setString(viewer.summary, fmt.Sprintf("%d", withdrawal.Years), 1, 3, tcell.ColorWhite, tview.AlignRight)
+	setDollarAmount(viewer.summary, withdrawal.InitialBalance, 1, 4, tcell.ColorWhite)
+	setString(viewer.summary, fmt.Sprintf("%d", len(withdrawal.Periods)), 1, 5, tcell.ColorWhite, tview.AlignRight)
+
+	color := tcell.ColorGreen
+	if withdrawal.SuccessRate < 100 {
+		color = tcell.ColorRed
+	}
+	setPercent(viewer.summary, withdrawal.SuccessRate, 1, 6, color)
+
+	setDollarAmount(viewer.summary, withdrawal.WorstEnding, 1, 7, tcell.ColorWhite)
+	setDollarAmount(viewer.summary, withdrawal.MedianEnding, 1, 8, tcell.ColorWhite)
+	setPercent(viewer.summary, withdrawal.SafeRate, 1, 9, tcell.ColorWhite)
+
+	note := "Amounts in real dollars of the start of each period"
+	if !withdrawal.Real {
+		note = "Amounts in nominal dollars (configure a CPI file in the profile for real dollars)"
+	}
+	viewer.note.SetText(note)
+}
+
+func (viewer *WithdrawalViewer) drawPeriods(withdrawal *portfolio.Withdrawal) {
+	for i, period := range withdrawal.Periods {
+		r := i + 1
+
+		setString(viewer.table, period.Start.Format("2006-01"), r, 0, tcell.ColorWhite, tview.AlignLeft)
+		setDollarAmount(viewer.table, period.Ending, r, 1, tcell.ColorWhite)
+		setDollarAmount(viewer.table, period.Withdrawn, r, 2, tcell.ColorWhite)
+		setDollarAmount(viewer.table, period.MinWithdrawal, r, 3, tcell.ColorWhite)
+
+		if period.Depleted {
+			setString(viewer.table, period.DepletedDate.Format("2006-01"), r, 4, tcell.ColorRed, tview.AlignRight)
+		} else {
+			setString(viewer.table, "-", r, 4, tcell.ColorGreen, tview.AlignRight)
+		}
+	}
+}